@@ -0,0 +1,214 @@
+// Command mockidp is a throwaway OpenID Connect provider for trying SSO
+// locally. It approves every authorization request as the user described
+// by its flags, so it must never be exposed beyond a developer machine.
+//
+//	go run ./cmd/mockidp -addr :9000 -email jane@example.com -groups staff
+//
+// and point the API at it with
+//
+//	OIDC_PROVIDERS=mock
+//	OIDC_MOCK_ISSUER=http://localhost:9000
+//	OIDC_MOCK_CLIENT_ID=go-trades
+//	OIDC_MOCK_ROLE_MAPPING=staff=admin
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const keyId = "mock"
+
+type grant struct {
+	ClientId      string
+	RedirectURI   string
+	Nonce         string
+	CodeChallenge string
+	ExpiresAt     time.Time
+}
+
+type provider struct {
+	issuer   string
+	subject  string
+	email    string
+	name     string
+	username string
+	groups   []string
+	key      *rsa.PrivateKey
+
+	mu     sync.Mutex
+	grants map[string]grant
+}
+
+func main() {
+	addr := flag.String("addr", ":9000", "listen address")
+	issuer := flag.String("issuer", "http://localhost:9000", "issuer URL the API is configured with")
+	subject := flag.String("sub", "mock-user-1", "subject of the signed-in user")
+	email := flag.String("email", "mock.user@example.com", "email of the signed-in user")
+	name := flag.String("name", "Mock User", "display name of the signed-in user")
+	username := flag.String("username", "mockuser", "preferred username of the signed-in user")
+	groups := flag.String("groups", "", "comma separated groups of the signed-in user")
+	flag.Parse()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		log.Fatalf("Generating signing key failed. Error : %v", err)
+	}
+
+	p := &provider{
+		issuer:   strings.TrimRight(*issuer, "/"),
+		subject:  *subject,
+		email:    *email,
+		name:     *name,
+		username: *username,
+		key:      key,
+		grants:   make(map[string]grant),
+	}
+	for _, group := range strings.Split(*groups, ",") {
+		if group = strings.TrimSpace(group); group != "" {
+			p.groups = append(p.groups, group)
+		}
+	}
+
+	http.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	http.HandleFunc("/authorize", p.authorize)
+	http.HandleFunc("/token", p.token)
+	http.HandleFunc("/jwks", p.jwks)
+
+	log.Printf("Mock identity provider listening on %s as %s", *addr, p.issuer)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
+
+func (p *provider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, 200, map[string]interface{}{
+		"issuer":                                p.issuer,
+		"authorization_endpoint":                p.issuer + "/authorize",
+		"token_endpoint":                        p.issuer + "/token",
+		"jwks_uri":                              p.issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+// authorize skips the login page and redirects straight back with a code.
+func (p *provider) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	redirectURI := query.Get("redirect_uri")
+	target, err := url.Parse(redirectURI)
+	if err != nil || redirectURI == "" {
+		http.Error(w, "invalid redirect_uri", 400)
+		return
+	}
+	if query.Get("response_type") != "code" || query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		http.Error(w, "only the code flow with S256 PKCE is supported", 400)
+		return
+	}
+
+	code := randomString()
+	p.mu.Lock()
+	p.grants[code] = grant{
+		ClientId:      query.Get("client_id"),
+		RedirectURI:   redirectURI,
+		Nonce:         query.Get("nonce"),
+		CodeChallenge: query.Get("code_challenge"),
+		ExpiresAt:     time.Now().Add(time.Minute),
+	}
+	p.mu.Unlock()
+
+	values := target.Query()
+	values.Set("code", code)
+	values.Set("state", query.Get("state"))
+	target.RawQuery = values.Encode()
+	http.Redirect(w, r, target.String(), 302)
+}
+
+func (p *provider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		writeJSON(w, 400, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+
+	code := r.PostForm.Get("code")
+	p.mu.Lock()
+	g, ok := p.grants[code]
+	delete(p.grants, code)
+	p.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || time.Now().After(g.ExpiresAt) ||
+		g.ClientId != r.PostForm.Get("client_id") ||
+		g.RedirectURI != r.PostForm.Get("redirect_uri") ||
+		g.CodeChallenge != base64.RawURLEncoding.EncodeToString(sum[:]) {
+		writeJSON(w, 400, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":                p.issuer,
+		"sub":                p.subject,
+		"aud":                g.ClientId,
+		"iat":                now.Unix(),
+		"exp":                now.Add(5 * time.Minute).Unix(),
+		"nonce":              g.Nonce,
+		"email":              p.email,
+		"email_verified":     true,
+		"name":               p.name,
+		"preferred_username": p.username,
+		"groups":             p.groups,
+	})
+	idToken.Header["kid"] = keyId
+	signed, err := idToken.SignedString(p.key)
+	if err != nil {
+		writeJSON(w, 500, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeJSON(w, 200, map[string]interface{}{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     signed,
+	})
+}
+
+func (p *provider) jwks(w http.ResponseWriter, r *http.Request) {
+	encode := base64.RawURLEncoding.EncodeToString
+	writeJSON(w, 200, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": keyId,
+			"alg": "RS256",
+			"use": "sig",
+			"n":   encode(p.key.N.Bytes()),
+			"e":   encode(big.NewInt(int64(p.key.E)).Bytes()),
+		}},
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func randomString() string {
+	b := make([]byte, 24)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -5,6 +5,8 @@ import (
 	"go-trades/entity"
 	"log"
 	"os"
+	"strings"
+	"time"
 
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
@@ -44,10 +46,162 @@ func Migrate(db *gorm.DB) {
 		&entity.OrderDetail{},
 		&entity.Inventory{},
 		&entity.ProductImage{},
+		&entity.Shipment{},
+		&entity.ShipmentItem{},
+		&entity.OrderReturn{},
+		&entity.OrderReturnItem{},
+		&entity.Cart{},
+		&entity.CartItem{},
+		&entity.Address{},
+		&entity.Invoice{},
+		&entity.InvoiceSequence{},
+		&entity.Subscription{},
+		&entity.SubscriptionItem{},
+		&entity.SubscriptionRun{},
+		&entity.Quote{},
+		&entity.QuoteItem{},
+		&entity.ImportJob{},
+		&entity.RoleChange{},
+		&entity.RoleDefinition{},
+		&entity.RolePermission{},
+		&entity.Session{},
+		&entity.RefreshToken{},
+		&entity.UserToken{},
+		&entity.TwoFactor{},
+		&entity.RecoveryCode{},
+		&entity.LoginAttempt{},
+		&entity.LoginLockout{},
+		&entity.ApiKey{},
+		&entity.ApiKeyPermission{},
+		&entity.OIDCState{},
+		&entity.OIDCIdentity{},
+		&entity.AuditLog{},
+		&entity.AuditChainHead{},
+		&entity.RateLimitBucket{},
+		&entity.IdempotencyKey{},
+		&entity.Migration{},
 	)
 	if err != nil {
 		log.Fatalf("Migration Failed. Error : %v", err)
 	}
 
+	seedRoles(db)
+	protectAuditLog(db)
+	runDataMigration(db, "backfill_email_verified_at", backfillEmailVerification)
+
 	log.Println("Migration Success....")
 }
+
+var defaultRoles = []entity.RoleDefinition{
+	{Name: entity.Admin, Description: "Full access to every staff operation"},
+	{Name: entity.Customer, Description: "Shops and manages their own orders"},
+	{Name: entity.Warehouse, Description: "Manages stock, fulfils orders and handles returns"},
+	{Name: entity.Finance, Description: "Reviews payments, quotes and reports"},
+	{Name: entity.Support, Description: "Assists customers with orders, returns and accounts"},
+}
+
+var defaultRolePermissions = map[entity.Role][]entity.Permission{
+	entity.Admin:    entity.StaffPermissions,
+	entity.Customer: entity.CustomerPermissions,
+	entity.Warehouse: {
+		entity.PermProductsRead, entity.PermCategoriesRead,
+		entity.PermInventoryRead, entity.PermInventoryWrite,
+		entity.PermOrdersRead, entity.PermOrdersProcess,
+		entity.PermShipmentsWrite, entity.PermReturnsProcess,
+	},
+	entity.Finance: {
+		entity.PermProductsRead, entity.PermOrdersRead,
+		entity.PermPaymentsRead, entity.PermReportsRead,
+		entity.PermQuotesWrite,
+	},
+	entity.Support: {
+		entity.PermProductsRead, entity.PermOrdersRead,
+		entity.PermReturnsProcess, entity.PermUsersRead,
+		entity.PermQuotesWrite,
+	},
+}
+
+// seedRoles creates the built-in roles that are missing. Roles that already
+// exist keep whatever permissions were assigned to them, except admin which
+// is always reset so it cannot be locked out.
+func seedRoles(db *gorm.DB) {
+	for _, def := range defaultRoles {
+		var role entity.RoleDefinition
+		err := db.Where("name = ?", def.Name).FirstOrCreate(&role, entity.RoleDefinition{
+			Name:        def.Name,
+			Description: def.Description,
+		}).Error
+		if err != nil {
+			log.Fatalf("Seeding role %s failed. Error : %v", def.Name, err)
+		}
+
+		var count int64
+		db.Model(&entity.RolePermission{}).Where("role_id = ?", role.ID).Count(&count)
+		if count > 0 && def.Name != entity.Admin {
+			continue
+		}
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("role_id = ?", role.ID).Delete(&entity.RolePermission{}).Error; err != nil {
+				return err
+			}
+			for _, permission := range defaultRolePermissions[def.Name] {
+				if err := tx.Create(&entity.RolePermission{RoleId: role.ID, Permission: permission}).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			log.Fatalf("Seeding role %s failed. Error : %v", def.Name, err)
+		}
+	}
+}
+
+// runDataMigration applies migrate unless it was applied before, and marks
+// it applied in the same transaction.
+func runDataMigration(db *gorm.DB, name string, migrate func(tx *gorm.DB) error) {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&entity.Migration{}).Where("name = ?", name).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return nil
+		}
+
+		if err := migrate(tx); err != nil {
+			return err
+		}
+		return tx.Create(&entity.Migration{Name: name, AppliedAt: time.Now()}).Error
+	})
+	if err != nil {
+		log.Fatalf("Data migration %s failed. Error : %v", name, err)
+	}
+}
+
+// backfillEmailVerification treats accounts that existed before email
+// verification as verified, so REQUIRE_EMAIL_VERIFICATION does not lock
+// them out.
+func backfillEmailVerification(tx *gorm.DB) error {
+	return tx.Model(&entity.User{}).
+		Where("email_verified_at IS NULL").
+		Update("email_verified_at", gorm.Expr("created_at")).Error
+}
+
+// protectAuditLog makes the database refuse updates and deletes of audit
+// entries. Creating triggers needs the TRIGGER privilege, so a failure is
+// only logged and the hash chain is left as the tamper evidence.
+func protectAuditLog(db *gorm.DB) {
+	for _, operation := range []string{"UPDATE", "DELETE"} {
+		name := "audit_logs_no_" + strings.ToLower(operation)
+		err := db.Exec("DROP TRIGGER IF EXISTS " + name).Error
+		if err == nil {
+			err = db.Exec("CREATE TRIGGER " + name + " BEFORE " + operation + " ON audit_logs FOR EACH ROW " +
+				"SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit log is append-only'").Error
+		}
+		if err != nil {
+			log.Printf("Protecting audit log against %s failed. Error : %v", operation, err)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package config
+
+import (
+	"os"
+	"time"
+)
+
+// GetIdempotencyKeyRetention is how long a stored response can be replayed
+// for its Idempotency-Key, from IDEMPOTENCY_KEY_RETENTION.
+func GetIdempotencyKeyRetention() time.Duration {
+	duration, err := time.ParseDuration(os.Getenv("IDEMPOTENCY_KEY_RETENTION"))
+	if err != nil {
+		return 24 * time.Hour
+	}
+
+	return duration
+}
@@ -0,0 +1,300 @@
+package config
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWTKey is a key tokens are signed or verified with. In HMAC mode there is
+// a single key without an id.
+type JWTKey struct {
+	KeyId  string
+	Method jwt.SigningMethod
+	Key    interface{}
+	public crypto.PublicKey
+}
+
+// JWK is one public key in the JSON Web Key Set.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+type jwtKeySet struct {
+	signing      *JWTKey
+	verification map[string]*JWTKey
+	keyIds       []string
+}
+
+var (
+	jwtKeys     *jwtKeySet
+	jwtKeysErr  error
+	jwtKeysOnce sync.Once
+)
+
+// LoadJWTKeys reads the signing and verification keys once. Setting
+// JWT_PRIVATE_KEY_FILE to an RSA, EC or Ed25519 private key switches signing
+// from HMAC to RS256, ES256 or EdDSA; the algorithm follows the key type.
+// JWT_KEY_ID names that key, and JWT_VERIFICATION_KEYS lists retired or
+// upcoming public keys as kid=path pairs so tokens signed with them stay
+// valid during a rotation. Without a private key, tokens are signed with
+// JWT_SECRET_KEY as before.
+func LoadJWTKeys() error {
+	jwtKeysOnce.Do(func() {
+		jwtKeys, jwtKeysErr = loadJWTKeySet()
+	})
+	return jwtKeysErr
+}
+
+func loadJWTKeySet() (*jwtKeySet, error) {
+	keys := &jwtKeySet{verification: make(map[string]*JWTKey)}
+
+	privateKeyFile := os.Getenv("JWT_PRIVATE_KEY_FILE")
+	if privateKeyFile == "" {
+		return keys, nil
+	}
+
+	signing, err := readJWTPrivateKey(privateKeyFile, os.Getenv("JWT_KEY_ID"))
+	if err != nil {
+		return nil, err
+	}
+	keys.signing = signing
+	keys.add(&JWTKey{KeyId: signing.KeyId, Method: signing.Method, Key: signing.public, public: signing.public})
+
+	for _, entry := range strings.Split(os.Getenv("JWT_VERIFICATION_KEYS"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		kid, path, found := strings.Cut(entry, "=")
+		if !found {
+			return nil, fmt.Errorf("JWT_VERIFICATION_KEYS entry %q must be kid=path", entry)
+		}
+		key, err := readJWTPublicKey(strings.TrimSpace(path), strings.TrimSpace(kid))
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := keys.verification[key.KeyId]; exists {
+			return nil, fmt.Errorf("duplicate JWT key id %q", key.KeyId)
+		}
+		keys.add(key)
+	}
+
+	return keys, nil
+}
+
+func (k *jwtKeySet) add(key *JWTKey) {
+	k.verification[key.KeyId] = key
+	k.keyIds = append(k.keyIds, key.KeyId)
+}
+
+func getJWTKeySet() (*jwtKeySet, error) {
+	if err := LoadJWTKeys(); err != nil {
+		return nil, err
+	}
+	return jwtKeys, nil
+}
+
+// GetJWTSigningKey returns the key new tokens are signed with.
+func GetJWTSigningKey() (*JWTKey, error) {
+	keys, err := getJWTKeySet()
+	if err != nil {
+		return nil, err
+	}
+	if keys.signing == nil {
+		return &JWTKey{Method: jwt.SigningMethodHS256, Key: GetJWTSecret()}, nil
+	}
+	return keys.signing, nil
+}
+
+// JWTKeyfunc picks the verification key for a token by its kid header. The
+// token's algorithm must match the key, so an HMAC token cannot be checked
+// against a public key.
+func JWTKeyfunc(token *jwt.Token) (interface{}, error) {
+	keys, err := getJWTKeySet()
+	if err != nil {
+		return nil, err
+	}
+
+	if keys.signing == nil {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrSignatureInvalid
+		}
+		return GetJWTSecret(), nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key, ok := keys.verification[kid]
+	if !ok {
+		return nil, errors.New("unknown signing key")
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, jwt.ErrSignatureInvalid
+	}
+	return key.Key, nil
+}
+
+// GetJWKS returns the public verification keys. It is empty in HMAC mode.
+func GetJWKS() (*JWKS, error) {
+	keys, err := getJWTKeySet()
+	if err != nil {
+		return nil, err
+	}
+
+	jwks := &JWKS{Keys: []JWK{}}
+	for _, kid := range keys.keyIds {
+		key := keys.verification[kid]
+		jwk := JWK{Kid: key.KeyId, Use: "sig", Alg: key.Method.Alg()}
+
+		switch public := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case *ecdsa.PublicKey:
+			size := (public.Curve.Params().BitSize + 7) / 8
+			jwk.Kty = "EC"
+			jwk.Crv = public.Curve.Params().Name
+			jwk.X = base64.RawURLEncoding.EncodeToString(public.X.FillBytes(make([]byte, size)))
+			jwk.Y = base64.RawURLEncoding.EncodeToString(public.Y.FillBytes(make([]byte, size)))
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		}
+
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	return jwks, nil
+}
+
+func readJWTPrivateKey(path, kid string) (*JWTKey, error) {
+	block, err := readPEMBlock(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var private interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		private, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse JWT private key %s: %w", path, err)
+	}
+
+	signer, ok := private.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported JWT private key %s", path)
+	}
+
+	key, err := newJWTKey(signer.Public(), kid)
+	if err != nil {
+		return nil, fmt.Errorf("JWT private key %s: %w", path, err)
+	}
+	key.Key = private
+	return key, nil
+}
+
+func readJWTPublicKey(path, kid string) (*JWTKey, error) {
+	block, err := readPEMBlock(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var public interface{}
+	if block.Type == "RSA PUBLIC KEY" {
+		public, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	} else {
+		public, err = x509.ParsePKIXPublicKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse JWT public key %s: %w", path, err)
+	}
+
+	key, err := newJWTKey(public, kid)
+	if err != nil {
+		return nil, fmt.Errorf("JWT public key %s: %w", path, err)
+	}
+	key.Key = public
+	return key, nil
+}
+
+// newJWTKey derives the algorithm from the key type. Without an explicit
+// kid the key is named after a hash of its public key.
+func newJWTKey(public crypto.PublicKey, kid string) (*JWTKey, error) {
+	key := &JWTKey{KeyId: kid, public: public}
+
+	switch public := public.(type) {
+	case *rsa.PublicKey:
+		key.Method = jwt.SigningMethodRS256
+	case *ecdsa.PublicKey:
+		switch public.Curve {
+		case elliptic.P256():
+			key.Method = jwt.SigningMethodES256
+		case elliptic.P384():
+			key.Method = jwt.SigningMethodES384
+		case elliptic.P521():
+			key.Method = jwt.SigningMethodES512
+		default:
+			return nil, errors.New("unsupported EC curve")
+		}
+	case ed25519.PublicKey:
+		key.Method = jwt.SigningMethodEdDSA
+	default:
+		return nil, errors.New("unsupported key type")
+	}
+
+	if key.KeyId == "" {
+		der, err := x509.MarshalPKIXPublicKey(public)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(der)
+		key.KeyId = hex.EncodeToString(sum[:8])
+	}
+	return key, nil
+}
+
+func readPEMBlock(path string) (*pem.Block, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not a PEM file", path)
+	}
+	return block, nil
+}
@@ -0,0 +1,293 @@
+package config
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// writePEM stores a PEM block in the test's temporary directory.
+func writePEM(t *testing.T, name, blockType string, der []byte) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	content := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func writePKIXPublicKey(t *testing.T, name string, public interface{}) string {
+	t.Helper()
+
+	der, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return writePEM(t, name, "PUBLIC KEY", der)
+}
+
+// useJWTKeys makes the package load its keys again from the current
+// environment, and again after the test.
+func useJWTKeys(t *testing.T) {
+	t.Helper()
+
+	reset := func() {
+		jwtKeys, jwtKeysErr = nil, nil
+		jwtKeysOnce = sync.Once{}
+	}
+	reset()
+	t.Cleanup(reset)
+}
+
+func TestReadJWTPrivateKey(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecDER, err := x509.MarshalECPrivateKey(ecKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edDER, err := x509.MarshalPKCS8PrivateKey(edKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		alg  string
+	}{
+		{"rsa pkcs1", writePEM(t, "rsa.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey)), "RS256"},
+		{"ec", writePEM(t, "ec.pem", "EC PRIVATE KEY", ecDER), "ES384"},
+		{"ed25519 pkcs8", writePEM(t, "ed.pem", "PRIVATE KEY", edDER), "EdDSA"},
+	}
+
+	for _, tt := range tests {
+		key, err := readJWTPrivateKey(tt.path, "")
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if key.Method.Alg() != tt.alg {
+			t.Errorf("%s: alg = %s, want %s", tt.name, key.Method.Alg(), tt.alg)
+		}
+		if len(key.KeyId) != 16 {
+			t.Errorf("%s: derived kid %q is not 16 hex characters", tt.name, key.KeyId)
+		}
+
+		again, err := readJWTPrivateKey(tt.path, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if again.KeyId != key.KeyId {
+			t.Errorf("%s: kid changed between reads: %s, %s", tt.name, key.KeyId, again.KeyId)
+		}
+	}
+
+	key, err := readJWTPrivateKey(tests[0].path, "2024-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.KeyId != "2024-01" {
+		t.Errorf("kid = %s, want 2024-01", key.KeyId)
+	}
+}
+
+func TestReadJWTKeyErrors(t *testing.T) {
+	notPEM := filepath.Join(t.TempDir(), "key.txt")
+	if err := os.WriteFile(notPEM, []byte("secret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	garbage := writePEM(t, "garbage.pem", "PRIVATE KEY", []byte("garbage"))
+
+	for _, path := range []string{filepath.Join(t.TempDir(), "missing.pem"), notPEM, garbage} {
+		if _, err := readJWTPrivateKey(path, ""); err == nil {
+			t.Errorf("readJWTPrivateKey(%s) returned no error", path)
+		}
+		if _, err := readJWTPublicKey(path, ""); err == nil {
+			t.Errorf("readJWTPublicKey(%s) returned no error", path)
+		}
+	}
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newJWTKey(&ecKey.PublicKey, ""); err == nil {
+		t.Error("newJWTKey accepted a P-224 key")
+	}
+}
+
+func TestLoadJWTKeySetErrors(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	private := writePEM(t, "private.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))
+	public := writePKIXPublicKey(t, "public.pem", &rsaKey.PublicKey)
+
+	tests := []struct {
+		name         string
+		verification string
+	}{
+		{"entry without kid", public},
+		{"duplicate kid", "current=" + public},
+		{"missing file", "old=" + filepath.Join(t.TempDir(), "missing.pem")},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("JWT_PRIVATE_KEY_FILE", private)
+		t.Setenv("JWT_KEY_ID", "current")
+		t.Setenv("JWT_VERIFICATION_KEYS", tt.verification)
+
+		if _, err := loadJWTKeySet(); err == nil {
+			t.Errorf("%s: loadJWTKeySet returned no error", tt.name)
+		}
+	}
+}
+
+func TestJWTKeysHMAC(t *testing.T) {
+	t.Setenv("JWT_PRIVATE_KEY_FILE", "")
+	t.Setenv("JWT_SECRET_KEY", "test-secret")
+	useJWTKeys(t)
+
+	jwks, err := GetJWKS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jwks.Keys) != 0 {
+		t.Errorf("GetJWKS returned %d keys in HMAC mode, want 0", len(jwks.Keys))
+	}
+
+	signing, err := GetJWTSigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := jwt.NewWithClaims(signing.Method, jwt.MapClaims{"sub": "1"}).SignedString(signing.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jwt.Parse(signed, JWTKeyfunc); err != nil {
+		t.Errorf("HMAC token was rejected: %v", err)
+	}
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err = jwt.New(jwt.SigningMethodEdDSA).SignedString(edKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jwt.Parse(signed, JWTKeyfunc); err == nil {
+		t.Error("EdDSA token was accepted in HMAC mode")
+	}
+}
+
+func TestJWTKeysRotation(t *testing.T) {
+	current, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	retired, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("JWT_PRIVATE_KEY_FILE", writePEM(t, "current.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(current)))
+	t.Setenv("JWT_KEY_ID", "current")
+	t.Setenv("JWT_VERIFICATION_KEYS", " retired = "+writePKIXPublicKey(t, "retired.pem", &retired.PublicKey)+", ")
+	t.Setenv("JWT_SECRET_KEY", "test-secret")
+	useJWTKeys(t)
+
+	jwks, err := GetJWKS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jwks.Keys) != 2 {
+		t.Fatalf("GetJWKS returned %d keys, want 2", len(jwks.Keys))
+	}
+
+	rsaJWK := jwks.Keys[0]
+	if rsaJWK.Kid != "current" || rsaJWK.Kty != "RSA" || rsaJWK.Alg != "RS256" || rsaJWK.Use != "sig" {
+		t.Errorf("signing key JWK = %+v", rsaJWK)
+	}
+	if rsaJWK.E != "AQAB" {
+		t.Errorf("RSA exponent = %s, want AQAB", rsaJWK.E)
+	}
+	if n, err := base64.RawURLEncoding.DecodeString(rsaJWK.N); err != nil || len(n) != 256 {
+		t.Errorf("RSA modulus is not a 2048-bit value: %v", err)
+	}
+
+	ecJWK := jwks.Keys[1]
+	if ecJWK.Kid != "retired" || ecJWK.Kty != "EC" || ecJWK.Crv != "P-256" || ecJWK.Alg != "ES256" {
+		t.Errorf("verification key JWK = %+v", ecJWK)
+	}
+	if len(ecJWK.X) != 43 || len(ecJWK.Y) != 43 {
+		t.Errorf("EC coordinates are not 32 bytes: x=%s y=%s", ecJWK.X, ecJWK.Y)
+	}
+	if strings.ContainsAny(ecJWK.X+ecJWK.Y+rsaJWK.N, "+/=") {
+		t.Error("JWK values are not unpadded base64url")
+	}
+
+	signing, err := GetJWTSigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := jwt.MapClaims{"sub": "1", "exp": time.Now().Add(time.Minute).Unix()}
+
+	sign := func(method jwt.SigningMethod, kid string, key interface{}) string {
+		token := jwt.NewWithClaims(method, claims)
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{"current key", sign(signing.Method, signing.KeyId, signing.Key), true},
+		{"retired key", sign(jwt.SigningMethodES256, "retired", retired), true},
+		{"unknown kid", sign(jwt.SigningMethodES256, "other", retired), false},
+		{"missing kid", sign(signing.Method, "", signing.Key), false},
+		{"wrong key for kid", sign(jwt.SigningMethodES256, "current", retired), false},
+		{"HMAC with public key", sign(jwt.SigningMethodHS256, "current", []byte("test-secret")), false},
+	}
+
+	for _, tt := range tests {
+		_, err := jwt.Parse(tt.token, JWTKeyfunc)
+		if (err == nil) != tt.valid {
+			t.Errorf("%s: valid = %v, want %v (err: %v)", tt.name, err == nil, tt.valid, err)
+		}
+	}
+}
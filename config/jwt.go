@@ -12,7 +12,16 @@ func GetJWTSecret() []byte {
 func GetJWTExpirationDuration() time.Duration {
 	duration, err := time.ParseDuration(os.Getenv("JWT_EXPIRES_IN"))
 	if err != nil {
-		return time.Hour
+		return 15 * time.Minute
+	}
+
+	return duration
+}
+
+func GetRefreshTokenExpirationDuration() time.Duration {
+	duration, err := time.ParseDuration(os.Getenv("JWT_REFRESH_EXPIRES_IN"))
+	if err != nil {
+		return 30 * 24 * time.Hour
 	}
 
 	return duration
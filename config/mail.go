@@ -0,0 +1,64 @@
+package config
+
+import (
+	"os"
+	"strings"
+)
+
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func GetMailDriver() string {
+	return strings.ToLower(os.Getenv("MAIL_DRIVER"))
+}
+
+func GetMailFrom() string {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		return "no-reply@go-trades.local"
+	}
+	return from
+}
+
+func GetMailDir() string {
+	dir := os.Getenv("MAIL_DIR")
+	if dir == "" {
+		return "uploads/mail"
+	}
+	return dir
+}
+
+func GetSMTPConfig() SMTPConfig {
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "587"
+	}
+
+	return SMTPConfig{
+		Host:     os.Getenv("SMTP_HOST"),
+		Port:     port,
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     GetMailFrom(),
+	}
+}
+
+// GetAppURL is the base of links sent by mail.
+func GetAppURL() string {
+	url := os.Getenv("APP_URL")
+	if url == "" {
+		return "http://localhost:3000"
+	}
+	return strings.TrimRight(url, "/")
+}
+
+// IsEmailVerificationRequired blocks unverified accounts from placing
+// orders when REQUIRE_EMAIL_VERIFICATION is true.
+func IsEmailVerificationRequired() bool {
+	return strings.EqualFold(os.Getenv("REQUIRE_EMAIL_VERIFICATION"), "true")
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"os"
+	"strings"
+)
+
+// OIDCProvider configures one identity provider. Each provider named in
+// OIDC_PROVIDERS reads its settings from OIDC_<NAME>_* variables, e.g.
+// OIDC_CORP_ISSUER for a provider called "corp".
+type OIDCProvider struct {
+	Name         string
+	Issuer       string
+	ClientId     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	GroupsClaim  string
+	// RoleMapping maps IdP groups to local roles, in priority order.
+	RoleMapping [][2]string
+	// DefaultRole is given to users none of whose groups are mapped. Leave
+	// it empty to refuse such users.
+	DefaultRole string
+	// AllowSignup creates local users on their first login.
+	AllowSignup bool
+}
+
+func GetOIDCProvider(name string) (*OIDCProvider, bool) {
+	name = strings.ToLower(name)
+	found := false
+	for _, configured := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		if strings.ToLower(strings.TrimSpace(configured)) == name && name != "" {
+			found = true
+		}
+	}
+	if !found {
+		return nil, false
+	}
+
+	prefix := "OIDC_" + strings.ToUpper(name) + "_"
+	env := func(key, fallback string) string {
+		if value := os.Getenv(prefix + key); value != "" {
+			return value
+		}
+		return fallback
+	}
+
+	provider := &OIDCProvider{
+		Name:         name,
+		Issuer:       strings.TrimRight(env("ISSUER", ""), "/"),
+		ClientId:     env("CLIENT_ID", ""),
+		ClientSecret: env("CLIENT_SECRET", ""),
+		RedirectURL:  env("REDIRECT_URL", GetAppURL()+"/api/v1/auth/oidc/"+name+"/callback"),
+		Scopes:       strings.Fields(env("SCOPES", "openid email profile")),
+		GroupsClaim:  env("GROUPS_CLAIM", "groups"),
+		DefaultRole:  env("DEFAULT_ROLE", ""),
+		AllowSignup:  !strings.EqualFold(env("ALLOW_SIGNUP", "true"), "false"),
+	}
+
+	for _, pair := range strings.Split(env("ROLE_MAPPING", ""), ",") {
+		group, role, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if ok {
+			provider.RoleMapping = append(provider.RoleMapping, [2]string{strings.TrimSpace(group), strings.TrimSpace(role)})
+		}
+	}
+
+	return provider, provider.Issuer != "" && provider.ClientId != ""
+}
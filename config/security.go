@@ -0,0 +1,38 @@
+package config
+
+import (
+	"os"
+	"strings"
+)
+
+// IsAdminTwoFactorRequired makes every admin enrol in and use TOTP when
+// REQUIRE_ADMIN_2FA is true.
+func IsAdminTwoFactorRequired() bool {
+	return strings.EqualFold(os.Getenv("REQUIRE_ADMIN_2FA"), "true")
+}
+
+// GetLoginAttemptStore selects where failed login counters live: "memory"
+// for a single instance, anything else for the shared database.
+func GetLoginAttemptStore() string {
+	return strings.ToLower(os.Getenv("LOGIN_ATTEMPT_STORE"))
+}
+
+// GetRateLimitStore selects where rate limit buckets live: "database" to
+// share them between instances, anything else for memory.
+func GetRateLimitStore() string {
+	return strings.ToLower(os.Getenv("RATE_LIMIT_STORE"))
+}
+
+// GetTrustedProxies lists the proxies, as IPs or CIDRs separated by commas,
+// whose X-Forwarded-For header is believed when working out a client's IP.
+// With TRUSTED_PROXIES unset no header is trusted and the peer address is
+// used, so clients cannot spoof the IP that allow-lists and throttles see.
+func GetTrustedProxies() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
@@ -0,0 +1,130 @@
+package controller
+
+import (
+	"go-trades/entity"
+	"go-trades/service"
+	"go-trades/utils"
+	errorMessages "go-trades/utils/error-messages"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AddressController struct {
+	Service service.AddressService
+}
+
+func NewAddressController(s service.AddressService) *AddressController {
+	return &AddressController{
+		Service: s,
+	}
+}
+
+func (c *AddressController) GetAddresses(ctx *gin.Context) {
+	userId, exists := ctx.Get("userId")
+	if !exists {
+		ctx.JSON(400, gin.H{"error": "user ID not found in context"})
+		return
+	}
+
+	resp, err := c.Service.GetUserAddresses(ctx, userId.(uint))
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
+func (c *AddressController) GetAddressById(ctx *gin.Context) {
+	userId, exists := ctx.Get("userId")
+	if !exists {
+		ctx.JSON(400, gin.H{"error": "user ID not found in context"})
+		return
+	}
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": errorMessages.ErrInvalidAddressId})
+		return
+	}
+
+	resp, err := c.Service.GetUserAddressById(ctx, userId.(uint), uint(id))
+	if err != nil {
+		ctx.JSON(404, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
+func (c *AddressController) CreateAddress(ctx *gin.Context) {
+	var req entity.AddressRequest
+
+	userId, exists := ctx.Get("userId")
+	if !exists {
+		ctx.JSON(400, gin.H{"error": "user ID not found in context"})
+		return
+	}
+
+	if err := utils.ValidateJson(ctx, &req); err != nil {
+		return
+	}
+
+	resp, err := c.Service.CreateAddress(ctx, userId.(uint), &req)
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(201, resp)
+}
+
+func (c *AddressController) UpdateAddress(ctx *gin.Context) {
+	var req entity.AddressRequest
+
+	userId, exists := ctx.Get("userId")
+	if !exists {
+		ctx.JSON(400, gin.H{"error": "user ID not found in context"})
+		return
+	}
+
+	if err := utils.ValidateJson(ctx, &req); err != nil {
+		return
+	}
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": errorMessages.ErrInvalidAddressId})
+		return
+	}
+
+	resp, err := c.Service.UpdateAddress(ctx, userId.(uint), uint(id), &req)
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
+func (c *AddressController) DeleteAddress(ctx *gin.Context) {
+	userId, exists := ctx.Get("userId")
+	if !exists {
+		ctx.JSON(400, gin.H{"error": "user ID not found in context"})
+		return
+	}
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": errorMessages.ErrInvalidAddressId})
+		return
+	}
+
+	if err := c.Service.DeleteAddress(ctx, userId.(uint), uint(id)); err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(204, nil)
+}
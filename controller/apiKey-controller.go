@@ -0,0 +1,95 @@
+package controller
+
+import (
+	"go-trades/entity"
+	"go-trades/middleware"
+	"go-trades/service"
+	"go-trades/utils"
+	errorMessages "go-trades/utils/error-messages"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ApiKeyController struct {
+	Service service.ApiKeyService
+}
+
+func NewApiKeyController(s service.ApiKeyService) *ApiKeyController {
+	return &ApiKeyController{
+		Service: s,
+	}
+}
+
+func (c *ApiKeyController) GetAllApiKeys(ctx *gin.Context) {
+	page := utils.DefaultPage
+	size := utils.DefaultSize
+
+	var pagination utils.Pagination
+	if err := ctx.ShouldBindQuery(&pagination); err == nil {
+		if pagination.Page > 0 {
+			page = pagination.Page
+		}
+		if pagination.Size > 0 {
+			size = pagination.Size
+		}
+	}
+
+	resp, totalSize, totalPage, err := c.Service.GetAllApiKeys(ctx, page, size)
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.Header("x-total-count", strconv.FormatInt(totalSize, 10))
+	ctx.Header("x-total-page", strconv.FormatInt(totalPage, 10))
+
+	ctx.JSON(200, resp)
+}
+
+func (c *ApiKeyController) CreateApiKey(ctx *gin.Context) {
+	userId, exists := ctx.Get("userId")
+	if !exists {
+		ctx.JSON(400, gin.H{"error": "user ID not found in context"})
+		return
+	}
+
+	var req entity.CreateApiKeyRequest
+	if err := utils.ValidateJson(ctx, &req); err != nil {
+		return
+	}
+
+	permissions, err := middleware.GetPermissions(ctx)
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := c.Service.CreateApiKey(ctx, userId.(uint), permissions, &req)
+	if err != nil {
+		if err.Error() == errorMessages.ErrApiKeyForOtherUser || strings.HasPrefix(err.Error(), errorMessages.ErrPermissionNotHeld) {
+			ctx.JSON(403, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(201, resp)
+}
+
+func (c *ApiKeyController) RevokeApiKey(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": errorMessages.ErrInvalidApiKeyId})
+		return
+	}
+
+	if err := c.Service.RevokeApiKey(ctx, uint(id)); err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(204, nil)
+}
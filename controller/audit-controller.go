@@ -0,0 +1,101 @@
+package controller
+
+import (
+	"go-trades/entity"
+	"go-trades/service"
+	"go-trades/utils"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuditController struct {
+	Service service.AuditService
+}
+
+func NewAuditController(s service.AuditService) *AuditController {
+	return &AuditController{
+		Service: s,
+	}
+}
+
+func (c *AuditController) GetAuditLogs(ctx *gin.Context) {
+	page := utils.DefaultPage
+	size := utils.DefaultSize
+
+	var pagination utils.Pagination
+	if err := ctx.ShouldBindQuery(&pagination); err == nil {
+		if pagination.Page > 0 {
+			page = pagination.Page
+		}
+		if pagination.Size > 0 {
+			size = pagination.Size
+		}
+	}
+
+	filter := entity.AuditFilter{
+		Action:     entity.AuditAction(ctx.Query("action")),
+		EntityType: ctx.Query("entityType"),
+		RequestId:  ctx.Query("requestId"),
+	}
+
+	if actorStr := ctx.Query("actorId"); actorStr != "" {
+		actorId, err := strconv.Atoi(actorStr)
+		if err != nil {
+			ctx.JSON(400, gin.H{"error": "invalid actorId filter"})
+			return
+		}
+		filter.ActorId = uint(actorId)
+	}
+
+	if entityStr := ctx.Query("entityId"); entityStr != "" {
+		entityId, err := strconv.Atoi(entityStr)
+		if err != nil {
+			ctx.JSON(400, gin.H{"error": "invalid entityId filter"})
+			return
+		}
+		filter.EntityId = uint(entityId)
+	}
+
+	if fromStr := ctx.Query("from"); fromStr != "" {
+		from, err := time.ParseInLocation("2006-01-02", fromStr, time.Local)
+		if err != nil {
+			ctx.JSON(400, gin.H{"error": "invalid from filter"})
+			return
+		}
+		filter.From = &from
+	}
+
+	// to is inclusive, so entries up to the end of that day match.
+	if toStr := ctx.Query("to"); toStr != "" {
+		to, err := time.ParseInLocation("2006-01-02", toStr, time.Local)
+		if err != nil {
+			ctx.JSON(400, gin.H{"error": "invalid to filter"})
+			return
+		}
+		to = to.AddDate(0, 0, 1)
+		filter.To = &to
+	}
+
+	resp, totalSize, totalPage, err := c.Service.GetAuditLogs(ctx, filter, page, size)
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.Header("x-total-count", strconv.FormatInt(totalSize, 10))
+	ctx.Header("x-total-page", strconv.FormatInt(totalPage, 10))
+
+	ctx.JSON(200, resp)
+}
+
+func (c *AuditController) VerifyChain(ctx *gin.Context) {
+	resp, err := c.Service.VerifyChain(ctx)
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
@@ -0,0 +1,137 @@
+package controller
+
+import (
+	"errors"
+	"go-trades/entity"
+	"go-trades/service"
+	"go-trades/utils"
+	errorMessages "go-trades/utils/error-messages"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CartController struct {
+	Service service.CartService
+}
+
+func NewCartController(s service.CartService) *CartController {
+	return &CartController{
+		Service: s,
+	}
+}
+
+func (c *CartController) GetCart(ctx *gin.Context) {
+	userId, exists := ctx.Get("userId")
+	if !exists {
+		ctx.JSON(400, gin.H{"error": "user ID not found in context"})
+		return
+	}
+
+	resp, err := c.Service.GetCart(ctx, userId.(uint))
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
+func (c *CartController) AddCartItem(ctx *gin.Context) {
+	var req entity.AddCartItemRequest
+
+	userId, exists := ctx.Get("userId")
+	if !exists {
+		ctx.JSON(400, gin.H{"error": "user ID not found in context"})
+		return
+	}
+
+	if err := utils.ValidateJson(ctx, &req); err != nil {
+		return
+	}
+
+	resp, err := c.Service.AddCartItem(ctx, userId.(uint), &req)
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
+func (c *CartController) UpdateCartItem(ctx *gin.Context) {
+	var req entity.UpdateCartItemRequest
+
+	userId, exists := ctx.Get("userId")
+	if !exists {
+		ctx.JSON(400, gin.H{"error": "user ID not found in context"})
+		return
+	}
+
+	if err := utils.ValidateJson(ctx, &req); err != nil {
+		return
+	}
+
+	productId, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": errorMessages.ErrInvalidProductId})
+		return
+	}
+
+	resp, err := c.Service.UpdateCartItem(ctx, userId.(uint), uint(productId), &req)
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
+func (c *CartController) RemoveCartItem(ctx *gin.Context) {
+	userId, exists := ctx.Get("userId")
+	if !exists {
+		ctx.JSON(400, gin.H{"error": "user ID not found in context"})
+		return
+	}
+
+	productId, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": errorMessages.ErrInvalidProductId})
+		return
+	}
+
+	resp, err := c.Service.RemoveCartItem(ctx, userId.(uint), uint(productId))
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
+func (c *CartController) CheckoutCart(ctx *gin.Context) {
+	var req entity.CheckoutCartRequest
+
+	userId, exists := ctx.Get("userId")
+	if !exists {
+		ctx.JSON(400, gin.H{"error": "user ID not found in context"})
+		return
+	}
+
+	if err := utils.ValidateJson(ctx, &req); err != nil {
+		return
+	}
+
+	resp, err := c.Service.CheckoutCart(ctx, userId.(uint), &req)
+	var invalid *service.CartCheckoutError
+	if errors.As(err, &invalid) {
+		ctx.JSON(409, gin.H{"error": err.Error(), "data": invalid.Lines})
+		return
+	}
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(resp.Status, resp)
+}
@@ -61,6 +61,11 @@ func (c *CategoryController) GetCategoryById(ctx *gin.Context) {
 		return
 	}
 
+	data := resp.Data.(entity.CategoryDataResponse)
+	if utils.NotModified(ctx, utils.ETag(data.Version)) {
+		return
+	}
+
 	ctx.JSON(200, resp)
 }
 
@@ -90,12 +95,19 @@ func (c *CategoryController) UpdateCategory(ctx *gin.Context) {
 		return
 	}
 
-	resp, err := c.Service.UpdateCategory(ctx, uint(id), &req)
+	resp, err := c.Service.UpdateCategory(ctx, uint(id), &req, ctx.GetHeader("If-Match"))
 	if err != nil {
+		if err.Error() == errorMessages.ErrPreconditionFailed {
+			ctx.JSON(412, gin.H{"error": err.Error()})
+			return
+		}
 		ctx.JSON(400, gin.H{"error": err.Error()})
 		return
 	}
 
+	data := resp.Data.(entity.CategoryDataResponse)
+	ctx.Header("ETag", utils.ETag(data.Version))
+
 	ctx.JSON(200, resp)
 }
 
@@ -107,7 +119,11 @@ func (c *CategoryController) DeleteCategory(ctx *gin.Context) {
 		return
 	}
 
-	if err := c.Service.DeleteCategory(ctx, uint(id)); err != nil {
+	if err := c.Service.DeleteCategory(ctx, uint(id), ctx.GetHeader("If-Match")); err != nil {
+		if err.Error() == errorMessages.ErrPreconditionFailed {
+			ctx.JSON(412, gin.H{"error": err.Error()})
+			return
+		}
 		ctx.JSON(400, gin.H{"error": err.Error()})
 		return
 	}
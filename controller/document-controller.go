@@ -0,0 +1,106 @@
+package controller
+
+import (
+	"go-trades/entity"
+	"go-trades/middleware"
+	"go-trades/service"
+	errorMessages "go-trades/utils/error-messages"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DocumentController struct {
+	Service service.DocumentService
+}
+
+func NewDocumentController(s service.DocumentService) *DocumentController {
+	return &DocumentController{
+		Service: s,
+	}
+}
+
+func (c *DocumentController) GetInvoice(ctx *gin.Context) {
+	var pdf []byte
+	var fileName string
+
+	userId, exists := ctx.Get("userId")
+	if !exists {
+		ctx.JSON(400, gin.H{"error": "user ID not found in context"})
+		return
+	}
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": errorMessages.ErrInvalidOrderId})
+		return
+	}
+
+	viewAll, err := middleware.HasPermission(ctx, entity.PermOrdersRead)
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	if viewAll {
+		pdf, fileName, err = c.Service.GetInvoicePdf(ctx, uint(id))
+	} else {
+		pdf, fileName, err = c.Service.GetUserInvoicePdf(ctx, userId.(uint), uint(id))
+	}
+
+	if err != nil {
+		documentError(ctx, err)
+		return
+	}
+
+	ctx.Header("Content-Disposition", "inline; filename="+fileName)
+	ctx.Data(200, "application/pdf", pdf)
+}
+
+func (c *DocumentController) GetPackingSlip(ctx *gin.Context) {
+	var pdf []byte
+	var fileName string
+
+	userId, exists := ctx.Get("userId")
+	if !exists {
+		ctx.JSON(400, gin.H{"error": "user ID not found in context"})
+		return
+	}
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": errorMessages.ErrInvalidOrderId})
+		return
+	}
+
+	viewAll, err := middleware.HasPermission(ctx, entity.PermOrdersRead)
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	if viewAll {
+		pdf, fileName, err = c.Service.GetPackingSlipPdf(ctx, uint(id))
+	} else {
+		pdf, fileName, err = c.Service.GetUserPackingSlipPdf(ctx, userId.(uint), uint(id))
+	}
+
+	if err != nil {
+		documentError(ctx, err)
+		return
+	}
+
+	ctx.Header("Content-Disposition", "inline; filename="+fileName)
+	ctx.Data(200, "application/pdf", pdf)
+}
+
+// documentError answers 404 when the order or a record the document needs
+// is missing, and 500 for anything else.
+func documentError(ctx *gin.Context, err error) {
+	switch err.Error() {
+	case errorMessages.ErrOrderNotFound, errorMessages.ErrInvoiceNotFound, errorMessages.ErrPaymentNotFound:
+		ctx.JSON(404, gin.H{"error": err.Error()})
+	default:
+		ctx.JSON(500, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,105 @@
+package controller
+
+import (
+	"go-trades/service"
+	"go-trades/utils"
+	errorMessages "go-trades/utils/error-messages"
+	"path/filepath"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ImportController struct {
+	Service service.ImportService
+}
+
+func NewImportController(s service.ImportService) *ImportController {
+	return &ImportController{
+		Service: s,
+	}
+}
+
+func (c *ImportController) GetAllImportJobs(ctx *gin.Context) {
+	page := utils.DefaultPage
+	size := utils.DefaultSize
+
+	var pagination utils.Pagination
+	if err := ctx.ShouldBindQuery(&pagination); err == nil {
+		if pagination.Page > 0 {
+			page = pagination.Page
+		}
+		if pagination.Size > 0 {
+			size = pagination.Size
+		}
+	}
+
+	resp, totalSize, totalPage, err := c.Service.GetAllImportJobs(ctx, page, size)
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.Header("x-total-count", strconv.FormatInt(totalSize, 10))
+	ctx.Header("x-total-page", strconv.FormatInt(totalPage, 10))
+
+	ctx.JSON(200, resp)
+}
+
+func (c *ImportController) GetImportJobById(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": errorMessages.ErrInvalidImportJobId})
+		return
+	}
+
+	resp, err := c.Service.GetImportJobById(ctx, uint(id))
+	if err != nil {
+		ctx.JSON(404, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
+func (c *ImportController) DownloadImportResult(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": errorMessages.ErrInvalidImportJobId})
+		return
+	}
+
+	path, err := c.Service.GetImportJobResult(ctx, uint(id))
+	if err != nil {
+		ctx.JSON(404, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.Header("Content-Type", "text/csv")
+	ctx.Header("Content-Disposition", "attachment; filename="+filepath.Base(path))
+	ctx.File(path)
+}
+
+func (c *ImportController) ImportOrders(ctx *gin.Context) {
+	userId, exists := ctx.Get("userId")
+	if !exists {
+		ctx.JSON(400, gin.H{"error": "user ID not found in context"})
+		return
+	}
+
+	file, err := ctx.FormFile("file")
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": "Failed to upload file"})
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(ctx.Query("dryRun"))
+
+	resp, err := c.Service.ImportOrders(ctx, userId.(uint), file, dryRun)
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(202, resp)
+}
@@ -61,6 +61,11 @@ func (c *InventoryController) GetInventoryById(ctx *gin.Context) {
 		return
 	}
 
+	data := resp.Data.(entity.InventoryDataResponse)
+	if utils.NotModified(ctx, utils.ETag(data.Version)) {
+		return
+	}
+
 	ctx.JSON(200, resp)
 }
 
@@ -90,12 +95,19 @@ func (c *InventoryController) UpdateInventory(ctx *gin.Context) {
 		return
 	}
 
-	resp, err := c.Service.UpdateInventory(ctx, uint(id), &req)
+	resp, err := c.Service.UpdateInventory(ctx, uint(id), &req, ctx.GetHeader("If-Match"))
 	if err != nil {
+		if err.Error() == errorMessages.ErrPreconditionFailed {
+			ctx.JSON(412, gin.H{"error": err.Error()})
+			return
+		}
 		ctx.JSON(400, gin.H{"error": err.Error()})
 		return
 	}
 
+	data := resp.Data.(entity.InventoryDataResponse)
+	ctx.Header("ETag", utils.ETag(data.Version))
+
 	ctx.JSON(200, resp)
 }
 
@@ -107,10 +119,50 @@ func (c *InventoryController) DeleteInventory(ctx *gin.Context) {
 		return
 	}
 
-	if err := c.Service.DeleteInventory(ctx, uint(id)); err != nil {
+	if err := c.Service.DeleteInventory(ctx, uint(id), ctx.GetHeader("If-Match")); err != nil {
+		if err.Error() == errorMessages.ErrPreconditionFailed {
+			ctx.JSON(412, gin.H{"error": err.Error()})
+			return
+		}
 		ctx.JSON(400, gin.H{"error": err.Error()})
 		return
 	}
 
 	ctx.JSON(204, nil)
 }
+
+func (c *InventoryController) ImportInventories(ctx *gin.Context) {
+	file, err := ctx.FormFile("file")
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": "Failed to upload file"})
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(ctx.Query("dryRun"))
+
+	resp, err := c.Service.ImportInventories(ctx, file, dryRun)
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(resp.Status, resp)
+}
+
+// ExportInventories streams the file as it is generated, so an error part-way
+// through can only be recorded, not reported in the response.
+func (c *InventoryController) ExportInventories(ctx *gin.Context) {
+	format, err := utils.TableFormat("inventories." + ctx.DefaultQuery("format", utils.TableCsv))
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.Header("Content-Type", utils.TableContentType(format))
+	ctx.Header("Content-Disposition", "attachment; filename=inventories."+format)
+	ctx.Status(200)
+
+	if err := c.Service.ExportInventories(ctx, format, ctx.Writer); err != nil {
+		ctx.Error(err)
+	}
+}
@@ -0,0 +1,26 @@
+package controller
+
+import (
+	"go-trades/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JWKSController publishes the public keys other services verify our
+// access tokens with.
+type JWKSController struct{}
+
+func NewJWKSController() *JWKSController {
+	return &JWKSController{}
+}
+
+func (c *JWKSController) GetJWKS(ctx *gin.Context) {
+	jwks, err := config.GetJWKS()
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(200, jwks)
+}
@@ -0,0 +1,53 @@
+package controller
+
+import (
+	"go-trades/entity"
+	"go-trades/service"
+	errorMessages "go-trades/utils/error-messages"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OIDCController struct {
+	Service service.OIDCService
+}
+
+func NewOIDCController(s service.OIDCService) *OIDCController {
+	return &OIDCController{
+		Service: s,
+	}
+}
+
+func (c *OIDCController) Login(ctx *gin.Context) {
+	authURL, err := c.Service.GetAuthorizationURL(ctx, ctx.Param("provider"))
+	if err != nil {
+		if err.Error() == errorMessages.ErrOIDCProviderNotFound {
+			ctx.JSON(404, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(502, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.Redirect(302, authURL)
+}
+
+func (c *OIDCController) Callback(ctx *gin.Context) {
+	var req entity.OIDCCallbackRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := c.Service.HandleCallback(ctx, ctx.Param("provider"), &req)
+	if err != nil {
+		if err.Error() == errorMessages.ErrOIDCProviderNotFound {
+			ctx.JSON(404, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(401, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
@@ -57,13 +57,13 @@ func (c *OrderController) GetAllOrders(ctx *gin.Context) {
 		status = uint(parsedId)
 	}
 
-	isAdmin, err := middleware.IsAdmin(ctx)
+	viewAll, err := middleware.HasPermission(ctx, entity.PermOrdersRead)
 	if err != nil {
 		ctx.JSON(500, gin.H{"error": err.Error()})
 		return
 	}
 
-	if isAdmin {
+	if viewAll {
 		resp, totalSize, totalPage, err = c.Service.GetAllOrders(ctx, page, size, status)
 	} else {
 		resp, totalSize, totalPage, err = c.Service.GetUserOrders(ctx, userId.(uint), page, size, status)
@@ -96,13 +96,13 @@ func (c *OrderController) GetOrderById(ctx *gin.Context) {
 		return
 	}
 
-	isAdmin, err := middleware.IsAdmin(ctx)
+	viewAll, err := middleware.HasPermission(ctx, entity.PermOrdersRead)
 	if err != nil {
 		ctx.JSON(500, gin.H{"error": err.Error()})
 		return
 	}
 
-	if isAdmin {
+	if viewAll {
 		resp, err = c.Service.GetOrderById(ctx, uint(id))
 	} else {
 		resp, err = c.Service.GetUserOrderById(ctx, userId.(uint), uint(id))
@@ -137,6 +137,34 @@ func (c *OrderController) CreateOrder(ctx *gin.Context) {
 	ctx.JSON(201, resp)
 }
 
+func (c *OrderController) AmendOrder(ctx *gin.Context) {
+	var req entity.AmendOrderRequest
+
+	userId, exists := ctx.Get("userId")
+	if !exists {
+		ctx.JSON(400, gin.H{"error": "user ID not found in context"})
+		return
+	}
+
+	if err := utils.ValidateJson(ctx, &req); err != nil {
+		return
+	}
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": errorMessages.ErrInvalidOrderId})
+		return
+	}
+
+	resp, err := c.Service.AmendOrder(ctx, userId.(uint), uint(id), &req)
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
 func (c *OrderController) ProcessOrder(ctx *gin.Context) {
 	id, err := strconv.Atoi(ctx.Param("id"))
 	if err != nil {
@@ -0,0 +1,222 @@
+package controller
+
+import (
+	"go-trades/entity"
+	"go-trades/middleware"
+	"go-trades/service"
+	"go-trades/utils"
+	errorMessages "go-trades/utils/error-messages"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OrderReturnController struct {
+	Service service.OrderReturnService
+}
+
+func NewOrderReturnController(s service.OrderReturnService) *OrderReturnController {
+	return &OrderReturnController{
+		Service: s,
+	}
+}
+
+func (c *OrderReturnController) GetAllOrderReturns(ctx *gin.Context) {
+	var resp *utils.Response
+	var totalSize, totalPage int64
+	var err error
+
+	userId, exists := ctx.Get("userId")
+	if !exists {
+		ctx.JSON(400, gin.H{"error": "user ID not found in context"})
+		return
+	}
+
+	page := utils.DefaultPage
+	size := utils.DefaultSize
+
+	var pagination utils.Pagination
+	if err := ctx.ShouldBindQuery(&pagination); err == nil {
+		if pagination.Page > 0 {
+			page = pagination.Page
+		}
+		if pagination.Size > 0 {
+			size = pagination.Size
+		}
+	}
+
+	viewAll, err := middleware.HasPermission(ctx, entity.PermReturnsProcess)
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	if viewAll {
+		resp, totalSize, totalPage, err = c.Service.GetAllOrderReturns(ctx, page, size)
+	} else {
+		resp, totalSize, totalPage, err = c.Service.GetUserOrderReturns(ctx, userId.(uint), page, size)
+	}
+
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.Header("x-total-count", strconv.FormatInt(totalSize, 10))
+	ctx.Header("x-total-page", strconv.FormatInt(totalPage, 10))
+
+	ctx.JSON(200, resp)
+}
+
+func (c *OrderReturnController) GetOrderReturnById(ctx *gin.Context) {
+	var resp *utils.Response
+	var err error
+
+	userId, exists := ctx.Get("userId")
+	if !exists {
+		ctx.JSON(400, gin.H{"error": "user ID not found in context"})
+		return
+	}
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": errorMessages.ErrInvalidReturnId})
+		return
+	}
+
+	viewAll, err := middleware.HasPermission(ctx, entity.PermReturnsProcess)
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	if viewAll {
+		resp, err = c.Service.GetOrderReturnById(ctx, uint(id))
+	} else {
+		resp, err = c.Service.GetUserOrderReturnById(ctx, userId.(uint), uint(id))
+	}
+
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
+func (c *OrderReturnController) CreateOrderReturn(ctx *gin.Context) {
+	var req entity.CreateOrderReturnRequest
+
+	userId, exists := ctx.Get("userId")
+	if !exists {
+		ctx.JSON(400, gin.H{"error": "user ID not found in context"})
+		return
+	}
+
+	if err := utils.ValidateJson(ctx, &req); err != nil {
+		return
+	}
+
+	resp, err := c.Service.CreateOrderReturn(ctx, userId.(uint), &req)
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(201, resp)
+}
+
+func (c *OrderReturnController) ApproveOrderReturn(ctx *gin.Context) {
+	var req entity.ReviewOrderReturnRequest
+	if err := utils.ValidateJson(ctx, &req); err != nil {
+		return
+	}
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": errorMessages.ErrInvalidReturnId})
+		return
+	}
+
+	resp, err := c.Service.ApproveOrderReturn(ctx, uint(id), &req)
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
+func (c *OrderReturnController) RejectOrderReturn(ctx *gin.Context) {
+	var req entity.ReviewOrderReturnRequest
+	if err := utils.ValidateJson(ctx, &req); err != nil {
+		return
+	}
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": errorMessages.ErrInvalidReturnId})
+		return
+	}
+
+	resp, err := c.Service.RejectOrderReturn(ctx, uint(id), &req)
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
+func (c *OrderReturnController) ReceiveOrderReturn(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": errorMessages.ErrInvalidReturnId})
+		return
+	}
+
+	resp, err := c.Service.ReceiveOrderReturn(ctx, uint(id))
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
+func (c *OrderReturnController) InspectOrderReturn(ctx *gin.Context) {
+	var req entity.InspectOrderReturnRequest
+	if err := utils.ValidateJson(ctx, &req); err != nil {
+		return
+	}
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": errorMessages.ErrInvalidReturnId})
+		return
+	}
+
+	resp, err := c.Service.InspectOrderReturn(ctx, uint(id), &req)
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
+func (c *OrderReturnController) RefundOrderReturn(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": errorMessages.ErrInvalidReturnId})
+		return
+	}
+
+	resp, err := c.Service.RefundOrderReturn(ctx, uint(id))
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
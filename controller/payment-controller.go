@@ -44,13 +44,13 @@ func (c *PaymentController) GetAllPayments(ctx *gin.Context) {
 		}
 	}
 
-	isAdmin, err := middleware.IsAdmin(ctx)
+	viewAll, err := middleware.HasPermission(ctx, entity.PermPaymentsRead)
 	if err != nil {
 		ctx.JSON(500, gin.H{"error": err.Error()})
 		return
 	}
 
-	if isAdmin {
+	if viewAll {
 		resp, totalSize, totalPage, err = c.Service.GetAllPayments(ctx, page, size)
 	} else {
 		resp, totalSize, totalPage, err = c.Service.GetUserPayments(ctx, userId.(uint), page, size)
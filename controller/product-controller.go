@@ -73,6 +73,11 @@ func (c *ProductController) GetProductById(ctx *gin.Context) {
 		return
 	}
 
+	data := resp.Data.(*entity.ProductDataResponse)
+	if utils.NotModified(ctx, service.ProductETag(data)) {
+		return
+	}
+
 	ctx.JSON(200, resp)
 }
 
@@ -102,12 +107,19 @@ func (c *ProductController) UpdateProduct(ctx *gin.Context) {
 		return
 	}
 
-	resp, err := c.Service.UpdateProduct(ctx, uint(id), &req)
+	resp, err := c.Service.UpdateProduct(ctx, uint(id), &req, ctx.GetHeader("If-Match"))
 	if err != nil {
+		if err.Error() == errorMessages.ErrPreconditionFailed {
+			ctx.JSON(412, gin.H{"error": err.Error()})
+			return
+		}
 		ctx.JSON(400, gin.H{"error": err.Error()})
 		return
 	}
 
+	data := resp.Data.(*entity.ProductDataResponse)
+	ctx.Header("ETag", service.ProductETag(data))
+
 	ctx.JSON(200, resp)
 }
 
@@ -119,10 +131,50 @@ func (c *ProductController) DeleteProduct(ctx *gin.Context) {
 		return
 	}
 
-	if err := c.Service.DeleteProduct(ctx, uint(id)); err != nil {
+	if err := c.Service.DeleteProduct(ctx, uint(id), ctx.GetHeader("If-Match")); err != nil {
+		if err.Error() == errorMessages.ErrPreconditionFailed {
+			ctx.JSON(412, gin.H{"error": err.Error()})
+			return
+		}
 		ctx.JSON(400, gin.H{"error": err.Error()})
 		return
 	}
 
 	ctx.JSON(204, nil)
 }
+
+func (c *ProductController) ImportProducts(ctx *gin.Context) {
+	file, err := ctx.FormFile("file")
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": "Failed to upload file"})
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(ctx.Query("dryRun"))
+
+	resp, err := c.Service.ImportProducts(ctx, file, dryRun)
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(resp.Status, resp)
+}
+
+// ExportProducts streams the file as it is generated, so an error part-way
+// through can only be recorded, not reported in the response.
+func (c *ProductController) ExportProducts(ctx *gin.Context) {
+	format, err := utils.TableFormat("products." + ctx.DefaultQuery("format", utils.TableCsv))
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.Header("Content-Type", utils.TableContentType(format))
+	ctx.Header("Content-Disposition", "attachment; filename=products."+format)
+	ctx.Status(200)
+
+	if err := c.Service.ExportProducts(ctx, format, ctx.Writer); err != nil {
+		ctx.Error(err)
+	}
+}
@@ -0,0 +1,199 @@
+package controller
+
+import (
+	"go-trades/entity"
+	"go-trades/middleware"
+	"go-trades/service"
+	"go-trades/utils"
+	errorMessages "go-trades/utils/error-messages"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type QuoteController struct {
+	Service service.QuoteService
+}
+
+func NewQuoteController(s service.QuoteService) *QuoteController {
+	return &QuoteController{
+		Service: s,
+	}
+}
+
+func (c *QuoteController) GetAllQuotes(ctx *gin.Context) {
+	var resp *utils.Response
+	var totalSize, totalPage int64
+	var err error
+
+	userId, exists := ctx.Get("userId")
+	if !exists {
+		ctx.JSON(400, gin.H{"error": "user ID not found in context"})
+		return
+	}
+
+	page := utils.DefaultPage
+	size := utils.DefaultSize
+
+	var pagination utils.Pagination
+	if err := ctx.ShouldBindQuery(&pagination); err == nil {
+		if pagination.Page > 0 {
+			page = pagination.Page
+		}
+		if pagination.Size > 0 {
+			size = pagination.Size
+		}
+	}
+
+	viewAll, err := middleware.HasPermission(ctx, entity.PermQuotesWrite)
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	if viewAll {
+		resp, totalSize, totalPage, err = c.Service.GetAllQuotes(ctx, page, size)
+	} else {
+		resp, totalSize, totalPage, err = c.Service.GetUserQuotes(ctx, userId.(uint), page, size)
+	}
+
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.Header("x-total-count", strconv.FormatInt(totalSize, 10))
+	ctx.Header("x-total-page", strconv.FormatInt(totalPage, 10))
+
+	ctx.JSON(200, resp)
+}
+
+func (c *QuoteController) GetQuoteById(ctx *gin.Context) {
+	var resp *utils.Response
+	var err error
+
+	userId, exists := ctx.Get("userId")
+	if !exists {
+		ctx.JSON(400, gin.H{"error": "user ID not found in context"})
+		return
+	}
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": errorMessages.ErrInvalidQuoteId})
+		return
+	}
+
+	viewAll, err := middleware.HasPermission(ctx, entity.PermQuotesWrite)
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	if viewAll {
+		resp, err = c.Service.GetQuoteById(ctx, uint(id))
+	} else {
+		resp, err = c.Service.GetUserQuoteById(ctx, userId.(uint), uint(id))
+	}
+
+	if err != nil {
+		ctx.JSON(404, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
+func (c *QuoteController) CreateQuote(ctx *gin.Context) {
+	var req entity.CreateQuoteRequest
+
+	userId, exists := ctx.Get("userId")
+	if !exists {
+		ctx.JSON(400, gin.H{"error": "user ID not found in context"})
+		return
+	}
+
+	if err := utils.ValidateJson(ctx, &req); err != nil {
+		return
+	}
+
+	resp, err := c.Service.CreateQuote(ctx, userId.(uint), &req)
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(201, resp)
+}
+
+func (c *QuoteController) AdjustQuote(ctx *gin.Context) {
+	var req entity.AdjustQuoteRequest
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": errorMessages.ErrInvalidQuoteId})
+		return
+	}
+
+	if err := utils.ValidateJson(ctx, &req); err != nil {
+		return
+	}
+
+	resp, err := c.Service.AdjustQuote(ctx, uint(id), &req)
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
+func (c *QuoteController) SendQuote(ctx *gin.Context) {
+	var req entity.SendQuoteRequest
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": errorMessages.ErrInvalidQuoteId})
+		return
+	}
+
+	if err := utils.ValidateJson(ctx, &req); err != nil {
+		return
+	}
+
+	resp, err := c.Service.SendQuote(ctx, uint(id), &req)
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
+func (c *QuoteController) AcceptQuote(ctx *gin.Context) {
+	var req entity.AcceptQuoteRequest
+
+	userId, exists := ctx.Get("userId")
+	if !exists {
+		ctx.JSON(400, gin.H{"error": "user ID not found in context"})
+		return
+	}
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": errorMessages.ErrInvalidQuoteId})
+		return
+	}
+
+	if err := utils.ValidateJson(ctx, &req); err != nil {
+		return
+	}
+
+	resp, err := c.Service.AcceptQuote(ctx, userId.(uint), uint(id), &req)
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(201, resp)
+}
@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"go-trades/entity"
+	"go-trades/service"
+	"go-trades/utils"
+	"strconv"
+
+	errorMessages "go-trades/utils/error-messages"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RoleController struct {
+	Service service.RoleService
+}
+
+func NewRoleController(s service.RoleService) *RoleController {
+	return &RoleController{
+		Service: s,
+	}
+}
+
+func (c *RoleController) GetAllRoles(ctx *gin.Context) {
+	resp, err := c.Service.GetAllRoles(ctx)
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
+func (c *RoleController) GetAllPermissions(ctx *gin.Context) {
+	ctx.JSON(200, c.Service.GetAllPermissions(ctx))
+}
+
+func (c *RoleController) CreateRole(ctx *gin.Context) {
+	var req entity.CreateRoleRequest
+	if err := utils.ValidateJson(ctx, &req); err != nil {
+		return
+	}
+
+	resp, err := c.Service.CreateRole(ctx, &req)
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(201, resp)
+}
+
+func (c *RoleController) UpdateRole(ctx *gin.Context) {
+	var req entity.UpdateRoleRequest
+	if err := utils.ValidateJson(ctx, &req); err != nil {
+		return
+	}
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": errorMessages.ErrInvalidRoleId})
+		return
+	}
+
+	resp, err := c.Service.UpdateRole(ctx, uint(id), &req)
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
+func (c *RoleController) DeleteRole(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": errorMessages.ErrInvalidRoleId})
+		return
+	}
+
+	if err := c.Service.DeleteRole(ctx, uint(id)); err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(204, nil)
+}
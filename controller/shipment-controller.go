@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"go-trades/entity"
+	"go-trades/middleware"
+	"go-trades/service"
+	"go-trades/utils"
+	"strconv"
+
+	errorMessages "go-trades/utils/error-messages"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ShipmentController struct {
+	Service service.ShipmentService
+}
+
+func NewShipmentController(s service.ShipmentService) *ShipmentController {
+	return &ShipmentController{
+		Service: s,
+	}
+}
+
+func (c *ShipmentController) GetOrderShipments(ctx *gin.Context) {
+	var resp *utils.Response
+
+	userId, exists := ctx.Get("userId")
+	if !exists {
+		ctx.JSON(400, gin.H{"error": "user ID not found in context"})
+		return
+	}
+
+	orderId, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": errorMessages.ErrInvalidOrderId})
+		return
+	}
+
+	viewAll, err := middleware.HasPermission(ctx, entity.PermOrdersRead)
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	if viewAll {
+		resp, err = c.Service.GetOrderShipments(ctx, uint(orderId))
+	} else {
+		resp, err = c.Service.GetUserOrderShipments(ctx, userId.(uint), uint(orderId))
+	}
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
+func (c *ShipmentController) CreateShipment(ctx *gin.Context) {
+	var req entity.CreateShipmentRequest
+	if err := utils.ValidateJson(ctx, &req); err != nil {
+		return
+	}
+
+	orderId, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": errorMessages.ErrInvalidOrderId})
+		return
+	}
+
+	resp, err := c.Service.CreateShipment(ctx, uint(orderId), &req)
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(201, resp)
+}
+
+func (c *ShipmentController) DeliverShipment(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": errorMessages.ErrInvalidShipmentId})
+		return
+	}
+
+	resp, err := c.Service.DeliverShipment(ctx, uint(id))
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
@@ -0,0 +1,192 @@
+package controller
+
+import (
+	"go-trades/entity"
+	"go-trades/middleware"
+	"go-trades/service"
+	"go-trades/utils"
+	errorMessages "go-trades/utils/error-messages"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SubscriptionController struct {
+	Service service.SubscriptionService
+}
+
+func NewSubscriptionController(s service.SubscriptionService) *SubscriptionController {
+	return &SubscriptionController{
+		Service: s,
+	}
+}
+
+func (c *SubscriptionController) GetAllSubscriptions(ctx *gin.Context) {
+	var resp *utils.Response
+	var totalSize, totalPage int64
+	var err error
+
+	userId, exists := ctx.Get("userId")
+	if !exists {
+		ctx.JSON(400, gin.H{"error": "user ID not found in context"})
+		return
+	}
+
+	page := utils.DefaultPage
+	size := utils.DefaultSize
+
+	var pagination utils.Pagination
+	if err := ctx.ShouldBindQuery(&pagination); err == nil {
+		if pagination.Page > 0 {
+			page = pagination.Page
+		}
+		if pagination.Size > 0 {
+			size = pagination.Size
+		}
+	}
+
+	viewAll, err := middleware.HasPermission(ctx, entity.PermOrdersRead)
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	if viewAll {
+		resp, totalSize, totalPage, err = c.Service.GetAllSubscriptions(ctx, page, size)
+	} else {
+		resp, totalSize, totalPage, err = c.Service.GetUserSubscriptions(ctx, userId.(uint), page, size)
+	}
+
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.Header("x-total-count", strconv.FormatInt(totalSize, 10))
+	ctx.Header("x-total-page", strconv.FormatInt(totalPage, 10))
+
+	ctx.JSON(200, resp)
+}
+
+func (c *SubscriptionController) GetSubscriptionById(ctx *gin.Context) {
+	var resp *utils.Response
+	var err error
+
+	userId, exists := ctx.Get("userId")
+	if !exists {
+		ctx.JSON(400, gin.H{"error": "user ID not found in context"})
+		return
+	}
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": errorMessages.ErrInvalidSubscriptionId})
+		return
+	}
+
+	viewAll, err := middleware.HasPermission(ctx, entity.PermOrdersRead)
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	if viewAll {
+		resp, err = c.Service.GetSubscriptionById(ctx, uint(id))
+	} else {
+		resp, err = c.Service.GetUserSubscriptionById(ctx, userId.(uint), uint(id))
+	}
+
+	if err != nil {
+		ctx.JSON(404, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
+func (c *SubscriptionController) CreateSubscription(ctx *gin.Context) {
+	var req entity.CreateSubscriptionRequest
+
+	userId, exists := ctx.Get("userId")
+	if !exists {
+		ctx.JSON(400, gin.H{"error": "user ID not found in context"})
+		return
+	}
+
+	if err := utils.ValidateJson(ctx, &req); err != nil {
+		return
+	}
+
+	resp, err := c.Service.CreateSubscription(ctx, userId.(uint), &req)
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(201, resp)
+}
+
+func (c *SubscriptionController) PauseSubscription(ctx *gin.Context) {
+	userId, exists := ctx.Get("userId")
+	if !exists {
+		ctx.JSON(400, gin.H{"error": "user ID not found in context"})
+		return
+	}
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": errorMessages.ErrInvalidSubscriptionId})
+		return
+	}
+
+	resp, err := c.Service.PauseSubscription(ctx, userId.(uint), uint(id))
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
+func (c *SubscriptionController) ResumeSubscription(ctx *gin.Context) {
+	userId, exists := ctx.Get("userId")
+	if !exists {
+		ctx.JSON(400, gin.H{"error": "user ID not found in context"})
+		return
+	}
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": errorMessages.ErrInvalidSubscriptionId})
+		return
+	}
+
+	resp, err := c.Service.ResumeSubscription(ctx, userId.(uint), uint(id))
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
+func (c *SubscriptionController) CancelSubscription(ctx *gin.Context) {
+	userId, exists := ctx.Get("userId")
+	if !exists {
+		ctx.JSON(400, gin.H{"error": "user ID not found in context"})
+		return
+	}
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": errorMessages.ErrInvalidSubscriptionId})
+		return
+	}
+
+	if err := c.Service.CancelSubscription(ctx, userId.(uint), uint(id)); err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(204, nil)
+}
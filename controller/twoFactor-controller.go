@@ -0,0 +1,157 @@
+package controller
+
+import (
+	"errors"
+	"go-trades/entity"
+	"go-trades/service"
+	"go-trades/utils"
+	"math"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TwoFactorController struct {
+	Service service.TwoFactorService
+}
+
+func NewTwoFactorController(s service.TwoFactorService) *TwoFactorController {
+	return &TwoFactorController{
+		Service: s,
+	}
+}
+
+func (c *TwoFactorController) Enroll(ctx *gin.Context) {
+	userId, exists := ctx.Get("userId")
+	if !exists {
+		ctx.JSON(400, gin.H{"error": "user ID not found in context"})
+		return
+	}
+
+	resp, err := c.Service.Enroll(ctx, userId.(uint))
+	if err != nil {
+		twoFactorError(ctx, 400, err)
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
+func (c *TwoFactorController) Confirm(ctx *gin.Context) {
+	userId, exists := ctx.Get("userId")
+	if !exists {
+		ctx.JSON(400, gin.H{"error": "user ID not found in context"})
+		return
+	}
+
+	var req entity.TwoFactorCodeRequest
+	if err := utils.ValidateJson(ctx, &req); err != nil {
+		return
+	}
+
+	resp, err := c.Service.Confirm(ctx, userId.(uint), &req)
+	if err != nil {
+		twoFactorError(ctx, 400, err)
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
+func (c *TwoFactorController) Disable(ctx *gin.Context) {
+	userId, exists := ctx.Get("userId")
+	if !exists {
+		ctx.JSON(400, gin.H{"error": "user ID not found in context"})
+		return
+	}
+
+	var req entity.TwoFactorCodeRequest
+	if err := utils.ValidateJson(ctx, &req); err != nil {
+		return
+	}
+
+	if err := c.Service.Disable(ctx, userId.(uint), &req); err != nil {
+		twoFactorError(ctx, 400, err)
+		return
+	}
+
+	ctx.JSON(204, nil)
+}
+
+func (c *TwoFactorController) RegenerateRecoveryCodes(ctx *gin.Context) {
+	userId, exists := ctx.Get("userId")
+	if !exists {
+		ctx.JSON(400, gin.H{"error": "user ID not found in context"})
+		return
+	}
+
+	var req entity.TwoFactorCodeRequest
+	if err := utils.ValidateJson(ctx, &req); err != nil {
+		return
+	}
+
+	resp, err := c.Service.RegenerateRecoveryCodes(ctx, userId.(uint), &req)
+	if err != nil {
+		twoFactorError(ctx, 400, err)
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
+func (c *TwoFactorController) EnrollForLogin(ctx *gin.Context) {
+	var req entity.TwoFactorTokenRequest
+	if err := utils.ValidateJson(ctx, &req); err != nil {
+		return
+	}
+
+	resp, err := c.Service.EnrollForLogin(ctx, &req)
+	if err != nil {
+		twoFactorError(ctx, 401, err)
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
+func (c *TwoFactorController) ConfirmForLogin(ctx *gin.Context) {
+	var req entity.TwoFactorLoginRequest
+	if err := utils.ValidateJson(ctx, &req); err != nil {
+		return
+	}
+
+	resp, err := c.Service.ConfirmForLogin(ctx, &req)
+	if err != nil {
+		twoFactorError(ctx, 401, err)
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
+func (c *TwoFactorController) CompleteLogin(ctx *gin.Context) {
+	var req entity.TwoFactorLoginRequest
+	if err := utils.ValidateJson(ctx, &req); err != nil {
+		return
+	}
+
+	resp, err := c.Service.CompleteLogin(ctx, &req)
+	if err != nil {
+		twoFactorError(ctx, 401, err)
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
+// twoFactorError answers err with status, or with 429 and Retry-After while
+// wrong codes keep the user locked out.
+func twoFactorError(ctx *gin.Context, status int, err error) {
+	var throttled *service.LoginThrottledError
+	if errors.As(err, &throttled) {
+		ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+		ctx.JSON(429, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(status, gin.H{"error": err.Error()})
+}
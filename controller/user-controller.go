@@ -1,10 +1,12 @@
 package controller
 
 import (
+	"errors"
 	"go-trades/entity"
 	"go-trades/service"
 	"go-trades/utils"
 	errorMessages "go-trades/utils/error-messages"
+	"math"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
@@ -42,6 +44,12 @@ func (c *UserController) Login(ctx *gin.Context) {
 	}
 
 	resp, err := c.Service.Login(ctx, &req)
+	var throttled *service.LoginThrottledError
+	if errors.As(err, &throttled) {
+		ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+		ctx.JSON(429, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		ctx.JSON(401, gin.H{"error": err.Error()})
 		return
@@ -50,6 +58,105 @@ func (c *UserController) Login(ctx *gin.Context) {
 	ctx.JSON(200, resp)
 }
 
+func (c *UserController) RefreshToken(ctx *gin.Context) {
+	var req entity.RefreshTokenRequest
+	if err := utils.ValidateJson(ctx, &req); err != nil {
+		return
+	}
+
+	resp, err := c.Service.RefreshToken(ctx, &req)
+	if err != nil {
+		ctx.JSON(401, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
+func (c *UserController) Logout(ctx *gin.Context) {
+	sessionId, exists := ctx.Get("sessionId")
+	if !exists {
+		ctx.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if err := c.Service.Logout(ctx, sessionId.(string)); err != nil {
+		ctx.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(204, nil)
+}
+
+func (c *UserController) LogoutAll(ctx *gin.Context) {
+	userId, exists := ctx.Get("userId")
+	if !exists {
+		ctx.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if err := c.Service.LogoutAll(ctx, userId.(uint)); err != nil {
+		ctx.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(204, nil)
+}
+
+func (c *UserController) ForgotPassword(ctx *gin.Context) {
+	var req entity.ForgotPasswordRequest
+	if err := utils.ValidateJson(ctx, &req); err != nil {
+		return
+	}
+
+	c.Service.ForgotPassword(ctx, &req)
+
+	ctx.JSON(200, gin.H{"message": "If the email belongs to an account, a reset link has been sent"})
+}
+
+func (c *UserController) ConfirmPasswordReset(ctx *gin.Context) {
+	var req entity.ConfirmPasswordResetRequest
+	if err := utils.ValidateJson(ctx, &req); err != nil {
+		return
+	}
+
+	if err := c.Service.ConfirmPasswordReset(ctx, &req); err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, gin.H{"message": "Password has been reset"})
+}
+
+func (c *UserController) SendEmailVerification(ctx *gin.Context) {
+	userId, exists := ctx.Get("userId")
+	if !exists {
+		ctx.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if err := c.Service.SendEmailVerification(ctx, userId.(uint)); err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, gin.H{"message": "Verification email sent"})
+}
+
+func (c *UserController) VerifyEmail(ctx *gin.Context) {
+	var req entity.VerifyEmailRequest
+	if err := utils.ValidateJson(ctx, &req); err != nil {
+		return
+	}
+
+	if err := c.Service.VerifyEmail(ctx, &req); err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, gin.H{"message": "Email verified"})
+}
+
 func (c *UserController) ChangePassword(ctx *gin.Context) {
 	var req entity.UserChangePassword
 	if err := utils.ValidateJson(ctx, &req); err != nil {
@@ -68,7 +175,11 @@ func (c *UserController) ChangePassword(ctx *gin.Context) {
 		return
 	}
 
-	resp, err := c.Service.ChangePassword(ctx, userId, &req)
+	// API keys have no session, so a change made with one signs out all.
+	sessionId, _ := ctx.Get("sessionId")
+	currentSession, _ := sessionId.(string)
+
+	resp, err := c.Service.ChangePassword(ctx, userId, currentSession, &req)
 	if err != nil {
 		ctx.JSON(400, gin.H{"error": err.Error()})
 		return
@@ -100,13 +211,19 @@ func (c *UserController) GetUser(ctx *gin.Context) {
 }
 
 func (c *UserController) AssignAsAdmin(ctx *gin.Context) {
+	actorId, exists := ctx.Get("userId")
+	if !exists {
+		ctx.JSON(400, gin.H{"error": "user ID not found in context"})
+		return
+	}
+
 	id, err := strconv.Atoi(ctx.Param("id"))
 	if err != nil {
 		ctx.JSON(400, gin.H{"error": errorMessages.ErrUserNotExists})
 		return
 	}
 
-	err = c.Service.AssignAsAdmin(ctx, uint(id))
+	err = c.Service.AssignAsAdmin(ctx, actorId.(uint), uint(id))
 	if err != nil {
 		ctx.JSON(404, gin.H{"error": err.Error()})
 		return
@@ -114,3 +231,186 @@ func (c *UserController) AssignAsAdmin(ctx *gin.Context) {
 
 	ctx.JSON(200, gin.H{"message": "User assigned as admin successfully"})
 }
+
+func (c *UserController) GetAllUsers(ctx *gin.Context) {
+	page := utils.DefaultPage
+	size := utils.DefaultSize
+
+	var pagination utils.Pagination
+	if err := ctx.ShouldBindQuery(&pagination); err == nil {
+		if pagination.Page > 0 {
+			page = pagination.Page
+		}
+		if pagination.Size > 0 {
+			size = pagination.Size
+		}
+	}
+
+	filter := entity.UserFilter{
+		Search: ctx.Query("search"),
+		Role:   entity.Role(ctx.Query("role")),
+	}
+
+	if blockedStr := ctx.Query("blocked"); blockedStr != "" {
+		blocked, err := strconv.ParseBool(blockedStr)
+		if err != nil {
+			ctx.JSON(400, gin.H{"error": "invalid blocked filter"})
+			return
+		}
+		filter.Blocked = &blocked
+	}
+
+	resp, totalSize, totalPage, err := c.Service.GetAllUsers(ctx, filter, page, size)
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.Header("x-total-count", strconv.FormatInt(totalSize, 10))
+	ctx.Header("x-total-page", strconv.FormatInt(totalPage, 10))
+
+	ctx.JSON(200, resp)
+}
+
+func (c *UserController) GetUserById(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": errorMessages.ErrInvalidUserId})
+		return
+	}
+
+	resp, err := c.Service.GetUserDetail(ctx, uint(id))
+	if err != nil {
+		ctx.JSON(404, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
+func (c *UserController) UpdateUser(ctx *gin.Context) {
+	var req entity.UpdateUserRequest
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": errorMessages.ErrInvalidUserId})
+		return
+	}
+
+	if err := utils.ValidateJson(ctx, &req); err != nil {
+		return
+	}
+
+	resp, err := c.Service.UpdateUser(ctx, uint(id), &req)
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
+func (c *UserController) DisableUser(ctx *gin.Context) {
+	c.setUserBlocked(ctx, true)
+}
+
+func (c *UserController) EnableUser(ctx *gin.Context) {
+	c.setUserBlocked(ctx, false)
+}
+
+func (c *UserController) setUserBlocked(ctx *gin.Context, blocked bool) {
+	actorId, exists := ctx.Get("userId")
+	if !exists {
+		ctx.JSON(400, gin.H{"error": "user ID not found in context"})
+		return
+	}
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": errorMessages.ErrInvalidUserId})
+		return
+	}
+
+	resp, err := c.Service.SetUserBlocked(ctx, actorId.(uint), uint(id), blocked)
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
+func (c *UserController) ChangeUserRole(ctx *gin.Context) {
+	var req entity.ChangeRoleRequest
+
+	actorId, exists := ctx.Get("userId")
+	if !exists {
+		ctx.JSON(400, gin.H{"error": "user ID not found in context"})
+		return
+	}
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": errorMessages.ErrInvalidUserId})
+		return
+	}
+
+	if err := utils.ValidateJson(ctx, &req); err != nil {
+		return
+	}
+
+	resp, err := c.Service.ChangeUserRole(ctx, actorId.(uint), uint(id), &req)
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
+func (c *UserController) ResetUserPassword(ctx *gin.Context) {
+	var req entity.ResetPasswordRequest
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": errorMessages.ErrInvalidUserId})
+		return
+	}
+
+	if err := utils.ValidateJson(ctx, &req); err != nil {
+		return
+	}
+
+	if err := c.Service.ResetUserPassword(ctx, uint(id), &req); err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, gin.H{"message": "Password reset successfully"})
+}
+
+func (c *UserController) GetLoginLockouts(ctx *gin.Context) {
+	page := utils.DefaultPage
+	size := utils.DefaultSize
+
+	var pagination utils.Pagination
+	if err := ctx.ShouldBindQuery(&pagination); err == nil {
+		if pagination.Page > 0 {
+			page = pagination.Page
+		}
+		if pagination.Size > 0 {
+			size = pagination.Size
+		}
+	}
+
+	resp, totalSize, totalPage, err := c.Service.GetLoginLockouts(ctx, page, size)
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.Header("x-total-count", strconv.FormatInt(totalSize, 10))
+	ctx.Header("x-total-page", strconv.FormatInt(totalPage, 10))
+
+	ctx.JSON(200, resp)
+}
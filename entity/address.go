@@ -0,0 +1,58 @@
+package entity
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+type PostalAddress struct {
+	Recipient  string `json:"recipient"`
+	Street     string `json:"street"`
+	City       string `json:"city"`
+	Region     string `json:"region"`
+	PostalCode string `json:"postalCode"`
+	Country    string `json:"country"`
+	Phone      string `json:"phone"`
+}
+
+type Address struct {
+	gorm.Model
+	UserId            uint   `gorm:"not null;index" json:"userId"`
+	Label             string `json:"label"`
+	PostalAddress     `gorm:"embedded"`
+	IsDefaultShipping bool `gorm:"not null;default:false" json:"isDefaultShipping"`
+	IsDefaultBilling  bool `gorm:"not null;default:false" json:"isDefaultBilling"`
+}
+
+type AddressRequest struct {
+	Label             string `json:"label"`
+	Recipient         string `json:"recipient" binding:"required"`
+	Street            string `json:"street" binding:"required"`
+	City              string `json:"city" binding:"required"`
+	Region            string `json:"region"`
+	PostalCode        string `json:"postalCode" binding:"required"`
+	Country           string `json:"country" binding:"required"`
+	Phone             string `json:"phone" binding:"required"`
+	IsDefaultShipping bool   `json:"isDefaultShipping"`
+	IsDefaultBilling  bool   `json:"isDefaultBilling"`
+}
+
+type AddressDataResponse struct {
+	ID    uint   `json:"id"`
+	Label string `json:"label"`
+	PostalAddress
+	IsDefaultShipping bool `json:"isDefaultShipping"`
+	IsDefaultBilling  bool `json:"isDefaultBilling"`
+}
+
+// String renders the address on a single line, skipping empty parts.
+func (a PostalAddress) String() string {
+	var parts []string
+	for _, part := range []string{a.Recipient, a.Street, a.City, strings.TrimSpace(a.Region + " " + a.PostalCode), a.Country, a.Phone} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,57 @@
+package entity
+
+import "time"
+
+// ApiKey lets an integration call the API as its owner without logging in.
+// It only grants the permissions listed on it that the owner's role also
+// has. Only the SHA-256 hash of the key is stored; Prefix identifies it in
+// listings.
+type ApiKey struct {
+	ID          uint   `gorm:"primaryKey;autoIncrement"`
+	Name        string `gorm:"not null;size:100"`
+	UserId      uint   `gorm:"not null;index"`
+	Prefix      string `gorm:"not null;size:16"`
+	KeyHash     string `gorm:"not null;unique;size:64"`
+	AllowedIPs  string `gorm:"type:text"`
+	ExpiresAt   *time.Time
+	LastUsedAt  *time.Time
+	LastUsedIP  string `gorm:"size:45"`
+	RevokedAt   *time.Time
+	CreatedBy   uint `gorm:"not null"`
+	CreatedAt   time.Time
+	Permissions []ApiKeyPermission `gorm:"foreignKey:ApiKeyId"`
+}
+
+type ApiKeyPermission struct {
+	ApiKeyId   uint       `gorm:"primaryKey"`
+	Permission Permission `gorm:"primaryKey;size:50"`
+}
+
+// CreateApiKeyRequest creates a key acting as UserId, or as the admin
+// creating it when UserId is 0. AllowedIPs takes addresses or CIDR ranges;
+// an empty list allows any address.
+type CreateApiKeyRequest struct {
+	Name        string       `json:"name" binding:"required"`
+	UserId      uint         `json:"userId"`
+	Permissions []Permission `json:"permissions" binding:"required"`
+	AllowedIPs  []string     `json:"allowedIps"`
+	ExpiresAt   string       `json:"expiresAt"`
+}
+
+type ApiKeyDataResponse struct {
+	ID          uint         `json:"id"`
+	Name        string       `json:"name"`
+	UserId      uint         `json:"userId"`
+	Prefix      string       `json:"prefix"`
+	Permissions []Permission `json:"permissions"`
+	AllowedIPs  []string     `json:"allowedIps"`
+	ExpiresAt   *time.Time   `json:"expiresAt"`
+	LastUsedAt  *time.Time   `json:"lastUsedAt"`
+	LastUsedIP  string       `json:"lastUsedIp"`
+	RevokedAt   *time.Time   `json:"revokedAt"`
+	CreatedBy   uint         `json:"createdBy"`
+	CreatedAt   time.Time    `json:"createdAt"`
+
+	// Key is only returned when the key is created.
+	Key string `json:"key,omitempty"`
+}
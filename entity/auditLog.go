@@ -0,0 +1,113 @@
+package entity
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type AuditAction string
+
+const (
+	AuditCreate AuditAction = "create"
+	AuditUpdate AuditAction = "update"
+	AuditDelete AuditAction = "delete"
+)
+
+// Entity types recorded in the audit log.
+const (
+	AuditAddress      = "address"
+	AuditApiKey       = "api_key"
+	AuditCart         = "cart"
+	AuditCategory     = "category"
+	AuditImportJob    = "import_job"
+	AuditInventory    = "inventory"
+	AuditInvoice      = "invoice"
+	AuditOrder        = "order"
+	AuditOrderReturn  = "order_return"
+	AuditPayment      = "payment"
+	AuditProduct      = "product"
+	AuditProductImage = "product_image"
+	AuditQuote        = "quote"
+	AuditRole         = "role"
+	AuditShipment     = "shipment"
+	AuditSubscription = "subscription"
+	AuditTwoFactor    = "two_factor"
+	AuditUser         = "user"
+)
+
+// AuditLog is one append-only entry of the audit trail. Each entry stores
+// the hash of the one before it, so editing or deleting a row breaks the
+// chain from that point on. ActorId is nil for changes made by background
+// jobs.
+type AuditLog struct {
+	ID         uint        `gorm:"primaryKey;autoIncrement" json:"id"`
+	Seq        uint64      `gorm:"not null;uniqueIndex" json:"seq"`
+	ActorId    *uint       `gorm:"index" json:"actorId"`
+	ApiKeyId   *uint       `json:"apiKeyId"`
+	Action     AuditAction `gorm:"not null;size:20" json:"action"`
+	EntityType string      `gorm:"not null;size:50;index:idx_audit_entity" json:"entityType"`
+	EntityId   uint        `gorm:"not null;index:idx_audit_entity" json:"entityId"`
+	Changes    string      `gorm:"type:text" json:"changes"`
+	RequestId  string      `gorm:"size:64;index" json:"requestId"`
+	IP         string      `gorm:"size:45" json:"ip"`
+	PrevHash   string      `gorm:"not null;size:64" json:"prevHash"`
+	Hash       string      `gorm:"not null;size:64" json:"hash"`
+	CreatedAt  time.Time   `gorm:"index" json:"createdAt"`
+}
+
+// AuditChainHead holds the sequence number and hash of the latest audit
+// entry. Its single row is locked while appending so entries chain in
+// order.
+type AuditChainHead struct {
+	ID   uint   `gorm:"primaryKey"`
+	Seq  uint64 `gorm:"not null"`
+	Hash string `gorm:"not null;size:64"`
+}
+
+// AuditFilter narrows the audit log. Zero values match everything.
+type AuditFilter struct {
+	ActorId    uint
+	Action     AuditAction
+	EntityType string
+	EntityId   uint
+	RequestId  string
+	From       *time.Time
+	To         *time.Time
+}
+
+type AuditVerifyResponse struct {
+	Valid    bool   `json:"valid"`
+	Checked  int64  `json:"checked"`
+	BrokenAt *uint  `json:"brokenAt,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// ComputeHash hashes the entry together with the previous entry's hash.
+func (a *AuditLog) ComputeHash() string {
+	optional := func(id *uint) string {
+		if id == nil {
+			return ""
+		}
+		return strconv.FormatUint(uint64(*id), 10)
+	}
+
+	fields := []string{
+		strconv.FormatUint(a.Seq, 10),
+		a.PrevHash,
+		optional(a.ActorId),
+		optional(a.ApiKeyId),
+		string(a.Action),
+		a.EntityType,
+		strconv.FormatUint(uint64(a.EntityId), 10),
+		a.Changes,
+		a.RequestId,
+		a.IP,
+		strconv.FormatInt(a.CreatedAt.UnixMilli(), 10),
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join(fields, "\n")))
+	return hex.EncodeToString(sum[:])
+}
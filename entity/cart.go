@@ -0,0 +1,60 @@
+package entity
+
+import "time"
+
+type Cart struct {
+	ID        uint       `gorm:"primaryKey;autoIncrement"`
+	UserId    uint       `gorm:"unique;not null" json:"userId"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+	CartItems []CartItem `gorm:"foreignKey:CartId"`
+}
+
+type CartItem struct {
+	CartId    uint      `gorm:"primaryKey"`
+	ProductId uint      `gorm:"primaryKey"`
+	Qty       uint      `gorm:"not null" json:"qty"`
+	Price     uint      `gorm:"not null" json:"price"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type AddCartItemRequest struct {
+	ProductId uint `json:"productId" binding:"required"`
+	Qty       uint `json:"qty" binding:"required"`
+}
+
+type UpdateCartItemRequest struct {
+	Qty uint `json:"qty" binding:"required"`
+}
+
+type CheckoutCartRequest struct {
+	ShippingAddress   string `json:"shippingAddress"`
+	ShippingAddressId uint   `json:"shippingAddressId"`
+	BillingAddressId  uint   `json:"billingAddressId"`
+}
+
+type CartDataResponse struct {
+	ID               uint               `json:"id"`
+	UserId           uint               `json:"userId"`
+	Total            uint               `json:"total"`
+	CartItemResponse []CartItemResponse `json:"items"`
+	UpdatedAt        time.Time          `json:"updatedAt"`
+}
+
+type CartItemResponse struct {
+	ProductId    uint   `json:"productId"`
+	ProductName  string `json:"productName"`
+	Qty          uint   `json:"qty"`
+	Price        uint   `json:"price"`
+	AddedPrice   uint   `json:"addedPrice"`
+	PriceChanged bool   `json:"priceChanged"`
+	Stock        uint   `json:"stock"`
+	Available    bool   `json:"available"`
+	Subtotal     uint   `json:"subtotal"`
+}
+
+type CartLineError struct {
+	ProductId uint   `json:"productId"`
+	Error     string `json:"error"`
+}
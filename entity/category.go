@@ -6,6 +6,7 @@ type Category struct {
 	gorm.Model
 	Code     string    `gorm:"unique;not null" json:"code"`
 	Name     string    `gorm:"unique;not null" json:"name"`
+	Version  uint      `gorm:"not null;default:1" json:"version"`
 	Products []Product `gorm:"foreignKey:CategoryId"`
 }
 
@@ -15,7 +16,8 @@ type CategoryRequest struct {
 }
 
 type CategoryDataResponse struct {
-	ID   uint   `json:"id"`
-	Code string `json:"code"`
-	Name string `json:"name"`
+	ID      uint   `json:"id"`
+	Code    string `json:"code"`
+	Name    string `json:"name"`
+	Version uint   `json:"version"`
 }
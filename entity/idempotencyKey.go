@@ -0,0 +1,20 @@
+package entity
+
+import "time"
+
+// IdempotencyKey remembers the response to a request sent with an
+// Idempotency-Key header so a retry gets the same response instead of
+// repeating the request. ResponseStatus is 0 while the first request is
+// still being handled.
+type IdempotencyKey struct {
+	ID             uint   `gorm:"primaryKey;autoIncrement"`
+	UserId         uint   `gorm:"not null;uniqueIndex:idx_idempotency_user_key"`
+	Key            string `gorm:"not null;size:191;uniqueIndex:idx_idempotency_user_key"`
+	Fingerprint    string `gorm:"not null;size:64"`
+	ResponseStatus int    `gorm:"not null;default:0"`
+	ContentType    string `gorm:"size:100"`
+	ResponseBody   []byte `gorm:"type:mediumblob"`
+	LockedUntil    time.Time
+	ExpiresAt      time.Time `gorm:"index"`
+	CreatedAt      time.Time
+}
@@ -0,0 +1,88 @@
+package entity
+
+import "time"
+
+type ImportKind string
+
+const (
+	OrderImport ImportKind = "orders"
+)
+
+// ImportJob tracks a file upload that is processed in the background. The
+// per-row outcome is written to a result file kept at ResultPath.
+type ImportJob struct {
+	ID            uint       `gorm:"primaryKey;autoIncrement"`
+	Kind          ImportKind `gorm:"not null;size:50" json:"kind"`
+	FileName      string     `gorm:"not null" json:"fileName"`
+	DryRun        bool       `gorm:"not null;default:false" json:"dryRun"`
+	Status        uint       `gorm:"not null" json:"status"`
+	TotalRows     uint       `gorm:"not null;default:0" json:"totalRows"`
+	SucceededRows uint       `gorm:"not null;default:0" json:"succeededRows"`
+	FailedRows    uint       `gorm:"not null;default:0" json:"failedRows"`
+	ResultPath    string     `json:"-"`
+	Error         string     `json:"error"`
+	CreatedBy     uint       `gorm:"not null" json:"createdBy"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	UpdatedAt     time.Time  `json:"updatedAt"`
+	CompletedAt   *time.Time `json:"completedAt"`
+}
+
+// OrderImportLine is one JSON-lines record of an order import. CSV files use
+// the same field names as column headers, with one row per order line and
+// rows sharing an orderRef grouped into one order.
+type OrderImportLine struct {
+	OrderRef          string               `json:"orderRef"`
+	CustomerEmail     string               `json:"customerEmail"`
+	ShippingAddress   string               `json:"shippingAddress"`
+	ShippingAddressId uint                 `json:"shippingAddressId"`
+	BillingAddressId  uint                 `json:"billingAddressId"`
+	Items             []OrderDetailRequest `json:"items"`
+}
+
+type ImportJobDataResponse struct {
+	ID            uint       `json:"id"`
+	Kind          ImportKind `json:"kind"`
+	FileName      string     `json:"fileName"`
+	DryRun        bool       `json:"dryRun"`
+	Status        uint       `json:"status"`
+	TotalRows     uint       `json:"totalRows"`
+	SucceededRows uint       `json:"succeededRows"`
+	FailedRows    uint       `json:"failedRows"`
+	Error         string     `json:"error"`
+	CreatedBy     uint       `json:"createdBy"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	CompletedAt   *time.Time `json:"completedAt"`
+}
+
+type ImportAction string
+
+const (
+	ImportCreate    ImportAction = "create"
+	ImportUpdate    ImportAction = "update"
+	ImportUnchanged ImportAction = "unchanged"
+	ImportFailed    ImportAction = "error"
+)
+
+type FieldChange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// ImportDiffRow describes what a catalogue import does, or would do in a dry
+// run, to the record matched by the row's natural key.
+type ImportDiffRow struct {
+	Row     int                    `json:"row"`
+	Key     string                 `json:"key"`
+	Action  ImportAction           `json:"action"`
+	Changes map[string]FieldChange `json:"changes,omitempty"`
+	Error   string                 `json:"error,omitempty"`
+}
+
+type ImportPreviewResponse struct {
+	DryRun    bool            `json:"dryRun"`
+	Created   uint            `json:"created"`
+	Updated   uint            `json:"updated"`
+	Unchanged uint            `json:"unchanged"`
+	Failed    uint            `json:"failed"`
+	Rows      []ImportDiffRow `json:"rows"`
+}
@@ -7,6 +7,7 @@ type Inventory struct {
 	Stock     uint   `gorm:"not null" json:"stock"`
 	Location  string `gorm:"not null" json:"location"`
 	ProductId uint   `json:"productId"`
+	Version   uint   `gorm:"not null;default:1" json:"version"`
 }
 
 type CreateInventoryRequest struct {
@@ -24,4 +25,5 @@ type InventoryDataResponse struct {
 	ProductId uint   `json:"productId"`
 	Stock     uint   `json:"stock"`
 	Location  string `json:"location"`
+	Version   uint   `json:"version"`
 }
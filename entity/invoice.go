@@ -0,0 +1,20 @@
+package entity
+
+import "time"
+
+type Invoice struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement"`
+	OrderId   uint      `gorm:"unique;not null" json:"orderId"`
+	PaymentId uint      `gorm:"not null" json:"paymentId"`
+	Sequence  uint      `gorm:"unique;not null" json:"sequence"`
+	Number    string    `gorm:"unique;not null;size:32" json:"number"`
+	IssuedAt  time.Time `gorm:"not null" json:"issuedAt"`
+}
+
+// InvoiceSequence holds the last allocated invoice sequence in a single row.
+// It is locked and incremented inside the payment transaction, so a rolled
+// back payment never leaves a gap in the numbering.
+type InvoiceSequence struct {
+	ID   uint `gorm:"primaryKey"`
+	Last uint `gorm:"not null"`
+}
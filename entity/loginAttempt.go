@@ -0,0 +1,23 @@
+package entity
+
+import "time"
+
+// LoginAttempt counts recent failed logins for one key, either a username
+// or a client IP.
+type LoginAttempt struct {
+	Key          string `gorm:"primaryKey;size:191"`
+	Failures     int    `gorm:"not null;default:0"`
+	LastFailedAt time.Time
+	LockedUntil  *time.Time
+}
+
+// LoginLockout records each time failed logins locked out a username or IP.
+type LoginLockout struct {
+	ID          uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Key         string    `gorm:"not null;size:191;index" json:"key"`
+	Username    string    `gorm:"size:50" json:"username"`
+	IP          string    `gorm:"size:45" json:"ip"`
+	Failures    int       `gorm:"not null" json:"failures"`
+	LockedUntil time.Time `json:"lockedUntil"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
@@ -0,0 +1,10 @@
+package entity
+
+import "time"
+
+// Migration marks a one-off data migration as applied, so it runs only
+// once even though Migrate runs on every start.
+type Migration struct {
+	Name      string    `gorm:"primaryKey;size:100"`
+	AppliedAt time.Time `gorm:"not null"`
+}
@@ -0,0 +1,40 @@
+package entity
+
+import "time"
+
+// OIDCState remembers an authorization request until the IdP redirects
+// back. It is deleted when the callback uses it.
+type OIDCState struct {
+	State        string    `gorm:"primaryKey;size:64"`
+	Provider     string    `gorm:"not null;size:50"`
+	Nonce        string    `gorm:"not null;size:64"`
+	CodeVerifier string    `gorm:"not null;size:128"`
+	ExpiresAt    time.Time `gorm:"not null"`
+	CreatedAt    time.Time
+}
+
+func (OIDCState) TableName() string {
+	return "oidc_states"
+}
+
+// OIDCIdentity links a provider's subject to a local user.
+type OIDCIdentity struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement"`
+	Provider  string `gorm:"not null;size:50;uniqueIndex:idx_oidc_subject"`
+	Subject   string `gorm:"not null;size:191;uniqueIndex:idx_oidc_subject"`
+	UserId    uint   `gorm:"not null;index"`
+	Email     string `gorm:"size:255"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (OIDCIdentity) TableName() string {
+	return "oidc_identities"
+}
+
+type OIDCCallbackRequest struct {
+	Code             string `form:"code"`
+	State            string `form:"state" binding:"required"`
+	Error            string `form:"error"`
+	ErrorDescription string `form:"error_description"`
+}
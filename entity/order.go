@@ -7,41 +7,79 @@ type Order struct {
 	UserId          uint          `json:"userId"`
 	Date            time.Time     `gorm:"not null" json:"date"`
 	ShippingAddress string        `gorm:"not null" json:"shippingAddress"`
+	ShippingDetail  PostalAddress `gorm:"embedded;embeddedPrefix:shipping_" json:"shippingDetail"`
+	BillingDetail   PostalAddress `gorm:"embedded;embeddedPrefix:billing_" json:"billingDetail"`
 	Total           uint          `gorm:"not null" json:"total"`
 	Status          uint          `gorm:"not null" json:"status"`
+	QuoteId         *uint         `json:"quoteId"`
 	OrderDetails    []OrderDetail `gorm:"foreignKey:OrderId"`
 	Payment         Payment       `gorm:"foreignKey:OrderId"`
+	Shipments       []Shipment    `gorm:"foreignKey:OrderId"`
 }
 
 type OrderDetail struct {
-	OrderId   uint `gorm:"primaryKey"`
-	ProductId uint `gorm:"primaryKey"`
-	Qty       uint `json:"qty"`
-	Subtotal  uint `json:"subtotal"`
+	OrderId        uint       `gorm:"primaryKey"`
+	ProductId      uint       `gorm:"primaryKey"`
+	Qty            uint       `json:"qty"`
+	Subtotal       uint       `json:"subtotal"`
+	BackorderedQty uint       `gorm:"not null;default:0" json:"backorderedQty"`
+	ExpectedAt     *time.Time `json:"expectedAt"`
 }
 
 type CreateOrderRequest struct {
-	ShippingAddress string               `json:"shippingAddress"`
-	OrderDetails    []OrderDetailRequest `json:"orderDetails" binding:"required,dive"`
+	ShippingAddress   string               `json:"shippingAddress"`
+	ShippingAddressId uint                 `json:"shippingAddressId"`
+	BillingAddressId  uint                 `json:"billingAddressId"`
+	OrderDetails      []OrderDetailRequest `json:"orderDetails" binding:"required,dive"`
+
+	// QuoteId links the order to the quote it was accepted from. Like
+	// OrderDetailRequest.Price it is only set internally.
+	QuoteId *uint `json:"-"`
+
+	// DryRun runs every check and write, then rolls the order back.
+	DryRun bool `json:"-"`
 }
 
 type OrderDetailRequest struct {
 	ProductId uint `json:"productId" binding:"required"`
 	Qty       uint `json:"qty" binding:"required"`
+
+	// Price fixes the unit price instead of the current catalogue price. It
+	// is set internally for accepted quotes and never bound from requests.
+	Price *uint `json:"-"`
+}
+
+// AmendOrderRequest changes a pending order. Only the listed products are
+// touched: a new product adds a line, a qty of 0 removes the line.
+type AmendOrderRequest struct {
+	ShippingAddress   string                    `json:"shippingAddress"`
+	ShippingAddressId uint                      `json:"shippingAddressId"`
+	BillingAddressId  uint                      `json:"billingAddressId"`
+	OrderDetails      []AmendOrderDetailRequest `json:"orderDetails" binding:"dive"`
+}
+
+type AmendOrderDetailRequest struct {
+	ProductId uint `json:"productId" binding:"required"`
+	Qty       uint `json:"qty"`
 }
 
 type OrderDataResponse struct {
-	ID                  uint                  `json:"id"`
-	UserId              uint                  `json:"userId"`
-	Date                time.Time             `json:"date"`
-	ShippingAddress     string                `json:"shippingAddress"`
-	Total               uint                  `json:"total"`
-	Status              uint                  `json:"status"`
-	OrderDetailResponse []OrderDetailResponse `json:"orderDetails"`
+	ID                  uint                   `json:"id"`
+	UserId              uint                   `json:"userId"`
+	Date                time.Time              `json:"date"`
+	ShippingAddress     string                 `json:"shippingAddress"`
+	ShippingDetail      PostalAddress          `json:"shippingDetail"`
+	BillingDetail       PostalAddress          `json:"billingDetail"`
+	Total               uint                   `json:"total"`
+	Status              uint                   `json:"status"`
+	OrderDetailResponse []OrderDetailResponse  `json:"orderDetails"`
+	ShipmentResponse    []ShipmentDataResponse `json:"shipments,omitempty"`
 }
 
 type OrderDetailResponse struct {
-	ProductId uint `json:"productId"`
-	Qty       uint `json:"qty"`
-	Subtotal  uint `json:"subtotal"`
+	ProductId      uint       `json:"productId"`
+	Qty            uint       `json:"qty"`
+	Subtotal       uint       `json:"subtotal"`
+	BackorderedQty uint       `json:"backorderedQty"`
+	ExpectedAt     *time.Time `json:"expectedAt"`
 }
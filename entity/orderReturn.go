@@ -0,0 +1,76 @@
+package entity
+
+import "time"
+
+type OrderReturn struct {
+	ID               uint              `gorm:"primaryKey;autoIncrement"`
+	OrderId          uint              `gorm:"not null" json:"orderId"`
+	UserId           uint              `gorm:"not null" json:"userId"`
+	Reason           string            `gorm:"not null;type:text" json:"reason"`
+	Status           uint              `gorm:"not null" json:"status"`
+	ReviewNote       string            `gorm:"type:text" json:"reviewNote"`
+	RefundAmount     uint              `json:"refundAmount"`
+	RequestedAt      time.Time         `gorm:"not null" json:"requestedAt"`
+	ReviewedAt       *time.Time        `json:"reviewedAt"`
+	ReceivedAt       *time.Time        `json:"receivedAt"`
+	InspectedAt      *time.Time        `json:"inspectedAt"`
+	RefundedAt       *time.Time        `json:"refundedAt"`
+	OrderReturnItems []OrderReturnItem `gorm:"foreignKey:OrderReturnId"`
+}
+
+type OrderReturnItem struct {
+	OrderReturnId uint `gorm:"primaryKey"`
+	ProductId     uint `gorm:"primaryKey"`
+	Qty           uint `gorm:"not null" json:"qty"`
+	Subtotal      uint `gorm:"not null" json:"subtotal"`
+	RestockedQty  uint `json:"restockedQty"`
+	WrittenOffQty uint `json:"writtenOffQty"`
+}
+
+type CreateOrderReturnRequest struct {
+	OrderId          uint                     `json:"orderId" binding:"required"`
+	Reason           string                   `json:"reason" binding:"required"`
+	OrderReturnItems []OrderReturnItemRequest `json:"items" binding:"required,dive"`
+}
+
+type OrderReturnItemRequest struct {
+	ProductId uint `json:"productId" binding:"required"`
+	Qty       uint `json:"qty" binding:"required"`
+}
+
+type ReviewOrderReturnRequest struct {
+	Note string `json:"note"`
+}
+
+type InspectOrderReturnRequest struct {
+	InspectItems []InspectOrderReturnItemRequest `json:"items" binding:"dive"`
+}
+
+type InspectOrderReturnItemRequest struct {
+	ProductId  uint `json:"productId" binding:"required"`
+	RestockQty uint `json:"restockQty"`
+}
+
+type OrderReturnDataResponse struct {
+	ID                      uint                      `json:"id"`
+	OrderId                 uint                      `json:"orderId"`
+	UserId                  uint                      `json:"userId"`
+	Reason                  string                    `json:"reason"`
+	Status                  uint                      `json:"status"`
+	ReviewNote              string                    `json:"reviewNote"`
+	RefundAmount            uint                      `json:"refundAmount"`
+	RequestedAt             time.Time                 `json:"requestedAt"`
+	ReviewedAt              *time.Time                `json:"reviewedAt"`
+	ReceivedAt              *time.Time                `json:"receivedAt"`
+	InspectedAt             *time.Time                `json:"inspectedAt"`
+	RefundedAt              *time.Time                `json:"refundedAt"`
+	OrderReturnItemResponse []OrderReturnItemResponse `json:"items"`
+}
+
+type OrderReturnItemResponse struct {
+	ProductId     uint `json:"productId"`
+	Qty           uint `json:"qty"`
+	Subtotal      uint `json:"subtotal"`
+	RestockedQty  uint `json:"restockedQty"`
+	WrittenOffQty uint `json:"writtenOffQty"`
+}
@@ -17,6 +17,7 @@ type Payment struct {
 	Method    Method    `gorm:"not null;type:enum('transfer', 'voucher')" json:"method"`
 	Amount    uint      `gorm:"not null" json:"amount"`
 	Status    uint      `gorm:"not null" json:"status"`
+	RefundOf  *uint     `gorm:"index" json:"refundOf"`
 	CreatedAt time.Time `gorm:"not null" json:"createdAt"`
 }
 
@@ -32,5 +33,6 @@ type PaymentDataResponse struct {
 	Method    Method    `json:"method"`
 	Amount    uint      `json:"amount"`
 	Status    uint      `json:"status"`
+	RefundOf  *uint     `json:"refundOf"`
 	CreatedAt time.Time `json:"createdAt"`
 }
@@ -6,38 +6,65 @@ import (
 	"gorm.io/gorm"
 )
 
+type BackorderPolicy string
+
+const (
+	NoBackorder BackorderPolicy = "none"
+	Backorder   BackorderPolicy = "backorder"
+	Preorder    BackorderPolicy = "preorder"
+)
+
 type Product struct {
 	gorm.Model
-	CategoryId    uint           `gorm:"not null" json:"categoryId"`
-	Name          string         `gorm:"not null;unique" json:"name"`
-	Description   string         `json:"description"`
-	Price         uint           `gorm:"not null" json:"price"`
-	Inventories   []Inventory    `gorm:"foreignKey:ProductId"`
-	OrderDetails  []OrderDetail  `gorm:"foreignKey:ProductId"`
-	ProductImages []ProductImage `gorm:"foreignKey:ProductId"`
+	CategoryId      uint            `gorm:"not null" json:"categoryId"`
+	Name            string          `gorm:"not null;unique" json:"name"`
+	Sku             *string         `gorm:"size:64;unique" json:"sku"`
+	Description     string          `json:"description"`
+	Price           uint            `gorm:"not null" json:"price"`
+	BackorderPolicy BackorderPolicy `gorm:"not null;type:enum('none', 'backorder', 'preorder');default:none" json:"backorderPolicy"`
+	AvailableAt     *time.Time      `json:"availableAt"`
+	Version         uint            `gorm:"not null;default:1" json:"version"`
+	Inventories     []Inventory     `gorm:"foreignKey:ProductId"`
+	OrderDetails    []OrderDetail   `gorm:"foreignKey:ProductId"`
+	ProductImages   []ProductImage  `gorm:"foreignKey:ProductId"`
 }
 
 type CreateProductRequest struct {
-	CategoryId  uint   `json:"categoryId" binding:"required"`
-	Name        string `json:"name" binding:"required"`
-	Description string `json:"description"`
-	Price       uint   `json:"price" binding:"required"`
+	CategoryId      uint            `json:"categoryId" binding:"required"`
+	Name            string          `json:"name" binding:"required"`
+	Sku             string          `json:"sku"`
+	Description     string          `json:"description"`
+	Price           uint            `json:"price" binding:"required"`
+	BackorderPolicy BackorderPolicy `json:"backorderPolicy"`
+	AvailableAt     string          `json:"availableAt"`
 }
 
 type UpdateProductRequest struct {
-	CategoryId  uint   `json:"categoryId" binding:"omitempty"`
-	Name        string `json:"name" binding:"omitempty"`
-	Description string `json:"description" binding:"omitempty"`
-	Price       uint   `json:"price" binding:"omitempty"`
+	CategoryId      uint            `json:"categoryId" binding:"omitempty"`
+	Name            string          `json:"name" binding:"omitempty"`
+	Sku             string          `json:"sku" binding:"omitempty"`
+	Description     string          `json:"description" binding:"omitempty"`
+	Price           uint            `json:"price" binding:"omitempty"`
+	BackorderPolicy BackorderPolicy `json:"backorderPolicy" binding:"omitempty"`
+	AvailableAt     string          `json:"availableAt" binding:"omitempty"`
 }
 
 type ProductDataResponse struct {
-	ID          uint      `json:"id"`
-	CategoryId  uint      `json:"categoryId"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	Price       uint      `json:"price"`
-	Stock       uint      `json:"stock"`
-	CreatedAt   time.Time `json:"createdAt"`
-	UpdatedAt   time.Time `json:"updatedAt"`
+	ID              uint            `json:"id"`
+	CategoryId      uint            `json:"categoryId"`
+	Name            string          `json:"name"`
+	Sku             *string         `json:"sku"`
+	Description     string          `json:"description"`
+	Price           uint            `json:"price"`
+	Stock           uint            `json:"stock"`
+	BackorderPolicy BackorderPolicy `json:"backorderPolicy"`
+	AvailableAt     *time.Time      `json:"availableAt"`
+	Version         uint            `json:"version"`
+	CreatedAt       time.Time       `json:"createdAt"`
+	UpdatedAt       time.Time       `json:"updatedAt"`
+}
+
+// AllowsBackorder reports whether orders may exceed the stock on hand.
+func (p *Product) AllowsBackorder() bool {
+	return p.BackorderPolicy == Backorder || p.BackorderPolicy == Preorder
 }
@@ -0,0 +1,69 @@
+package entity
+
+import "time"
+
+type Quote struct {
+	ID         uint        `gorm:"primaryKey;autoIncrement"`
+	UserId     uint        `gorm:"not null;index" json:"userId"`
+	Status     uint        `gorm:"not null" json:"status"`
+	Note       string      `json:"note"`
+	ValidUntil time.Time   `gorm:"not null" json:"validUntil"`
+	Total      uint        `gorm:"not null" json:"total"`
+	OrderId    *uint       `json:"orderId"`
+	CreatedAt  time.Time   `json:"createdAt"`
+	UpdatedAt  time.Time   `json:"updatedAt"`
+	QuoteItems []QuoteItem `gorm:"foreignKey:QuoteId"`
+}
+
+// QuoteItem keeps the unit price agreed on the quote, which is what the
+// order is charged once the quote is accepted.
+type QuoteItem struct {
+	QuoteId   uint `gorm:"primaryKey"`
+	ProductId uint `gorm:"primaryKey"`
+	Qty       uint `gorm:"not null" json:"qty"`
+	Price     uint `gorm:"not null" json:"price"`
+	Subtotal  uint `gorm:"not null" json:"subtotal"`
+}
+
+type CreateQuoteRequest struct {
+	Note       string               `json:"note"`
+	QuoteItems []OrderDetailRequest `json:"items" binding:"required,dive"`
+}
+
+type AdjustQuoteRequest struct {
+	QuoteItems []QuotePriceRequest `json:"items" binding:"required,dive"`
+}
+
+type QuotePriceRequest struct {
+	ProductId uint `json:"productId" binding:"required"`
+	Price     uint `json:"price"`
+}
+
+type SendQuoteRequest struct {
+	ValidUntil string `json:"validUntil"`
+}
+
+type AcceptQuoteRequest struct {
+	ShippingAddress   string `json:"shippingAddress"`
+	ShippingAddressId uint   `json:"shippingAddressId"`
+	BillingAddressId  uint   `json:"billingAddressId"`
+}
+
+type QuoteDataResponse struct {
+	ID                uint                `json:"id"`
+	UserId            uint                `json:"userId"`
+	Status            uint                `json:"status"`
+	Note              string              `json:"note"`
+	ValidUntil        time.Time           `json:"validUntil"`
+	Total             uint                `json:"total"`
+	OrderId           *uint               `json:"orderId"`
+	CreatedAt         time.Time           `json:"createdAt"`
+	QuoteItemResponse []QuoteItemResponse `json:"items"`
+}
+
+type QuoteItemResponse struct {
+	ProductId uint `json:"productId"`
+	Qty       uint `json:"qty"`
+	Price     uint `json:"price"`
+	Subtotal  uint `json:"subtotal"`
+}
@@ -0,0 +1,59 @@
+package entity
+
+import (
+	"math"
+	"time"
+)
+
+// RateLimit is a token bucket: a client may make Burst requests at once and
+// earns Requests more every Per. Name keeps the buckets of different limits
+// apart, so a route can sit under a group limit and a stricter one of its
+// own.
+type RateLimit struct {
+	Name     string
+	Requests int
+	Per      time.Duration
+	Burst    int
+}
+
+// RateLimitBucket is the state of one client's bucket for one limit.
+type RateLimitBucket struct {
+	Key       string  `gorm:"primaryKey;size:191"`
+	Tokens    float64 `gorm:"not null"`
+	CheckedAt time.Time
+	FullAt    time.Time `gorm:"index"`
+}
+
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+// Take refills the bucket for the time since it was last checked and spends
+// one token when there is one. Reset is how long until the bucket is full
+// again and RetryAfter how long until the next token when none was left.
+func (b *RateLimitBucket) Take(limit RateLimit, now time.Time) RateLimitResult {
+	interval := limit.Per / time.Duration(limit.Requests)
+	burst := float64(limit.Burst)
+
+	if elapsed := now.Sub(b.CheckedAt); elapsed > 0 {
+		b.Tokens = math.Min(burst, b.Tokens+float64(elapsed)/float64(interval))
+		b.CheckedAt = now
+	}
+
+	result := RateLimitResult{Limit: limit.Burst}
+	if b.Tokens >= 1 {
+		b.Tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - b.Tokens) * float64(interval))
+	}
+
+	result.Remaining = int(math.Floor(b.Tokens))
+	result.Reset = time.Duration((burst - b.Tokens) * float64(interval))
+	b.FullAt = now.Add(result.Reset)
+	return result
+}
@@ -0,0 +1,73 @@
+package entity
+
+import (
+	"testing"
+	"time"
+)
+
+// testLimit earns one token a second and holds at most three.
+var testLimit = RateLimit{Name: "test", Requests: 60, Per: time.Minute, Burst: 3}
+
+func newTestBucket(now time.Time) *RateLimitBucket {
+	return &RateLimitBucket{Key: "test:1", Tokens: float64(testLimit.Burst), CheckedAt: now}
+}
+
+func TestRateLimitBucketTake(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	bucket := newTestBucket(start)
+
+	steps := []struct {
+		name      string
+		after     time.Duration
+		allowed   bool
+		remaining int
+		reset     time.Duration
+		retry     time.Duration
+	}{
+		{"first request", 0, true, 2, time.Second, 0},
+		{"second request", 0, true, 1, 2 * time.Second, 0},
+		{"third request", 0, true, 0, 3 * time.Second, 0},
+		{"burst spent", 0, false, 0, 3 * time.Second, time.Second},
+		{"half a token earned", 500 * time.Millisecond, false, 0, 2500 * time.Millisecond, 500 * time.Millisecond},
+		{"token earned", time.Second, true, 0, 3 * time.Second, 0},
+		{"idle refills to burst", time.Hour, true, 2, time.Second, 0},
+	}
+
+	for _, step := range steps {
+		now := start.Add(step.after)
+		result := bucket.Take(testLimit, now)
+
+		if result.Allowed != step.allowed {
+			t.Errorf("%s: Allowed = %v, want %v", step.name, result.Allowed, step.allowed)
+		}
+		if result.Limit != testLimit.Burst {
+			t.Errorf("%s: Limit = %d, want %d", step.name, result.Limit, testLimit.Burst)
+		}
+		if result.Remaining != step.remaining {
+			t.Errorf("%s: Remaining = %d, want %d", step.name, result.Remaining, step.remaining)
+		}
+		if result.Reset != step.reset {
+			t.Errorf("%s: Reset = %v, want %v", step.name, result.Reset, step.reset)
+		}
+		if result.RetryAfter != step.retry {
+			t.Errorf("%s: RetryAfter = %v, want %v", step.name, result.RetryAfter, step.retry)
+		}
+		if !bucket.FullAt.Equal(now.Add(result.Reset)) {
+			t.Errorf("%s: FullAt = %v, want %v", step.name, bucket.FullAt, now.Add(result.Reset))
+		}
+	}
+}
+
+func TestRateLimitBucketTakeClockSkew(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	bucket := newTestBucket(now)
+	bucket.Tokens = 0
+
+	result := bucket.Take(testLimit, now.Add(-time.Minute))
+	if result.Allowed {
+		t.Error("a clock going backwards refilled the bucket")
+	}
+	if !bucket.CheckedAt.Equal(now) {
+		t.Errorf("CheckedAt = %v, want %v", bucket.CheckedAt, now)
+	}
+}
@@ -0,0 +1,139 @@
+package entity
+
+type Permission string
+
+// Staff permissions.
+const (
+	PermProductsWrite   Permission = "products:write"
+	PermCategoriesRead  Permission = "categories:read"
+	PermCategoriesWrite Permission = "categories:write"
+	PermInventoryRead   Permission = "inventory:read"
+	PermInventoryWrite  Permission = "inventory:write"
+	PermOrdersRead      Permission = "orders:read"
+	PermOrdersProcess   Permission = "orders:process"
+	PermShipmentsWrite  Permission = "shipments:write"
+	PermReturnsProcess  Permission = "returns:process"
+	PermPaymentsRead    Permission = "payments:read"
+	PermQuotesWrite     Permission = "quotes:write"
+	PermImportsWrite    Permission = "imports:write"
+	PermReportsRead     Permission = "reports:read"
+	PermUsersRead       Permission = "users:read"
+	PermUsersWrite      Permission = "users:write"
+	PermRolesWrite      Permission = "roles:write"
+	PermApiKeysWrite    Permission = "api_keys:write"
+	PermAuditRead       Permission = "audit:read"
+)
+
+// Customer permissions. They act on the caller's own records.
+const (
+	PermOrdersPlace         Permission = "orders:place"
+	PermPaymentsCreate      Permission = "payments:create"
+	PermReturnsRequest      Permission = "returns:request"
+	PermSubscriptionsManage Permission = "subscriptions:manage"
+	PermQuotesRequest       Permission = "quotes:request"
+)
+
+// Shared by staff and customers.
+const (
+	PermProductsRead Permission = "products:read"
+)
+
+var StaffPermissions = []Permission{
+	PermProductsRead,
+	PermProductsWrite,
+	PermCategoriesRead,
+	PermCategoriesWrite,
+	PermInventoryRead,
+	PermInventoryWrite,
+	PermOrdersRead,
+	PermOrdersProcess,
+	PermShipmentsWrite,
+	PermReturnsProcess,
+	PermPaymentsRead,
+	PermQuotesWrite,
+	PermImportsWrite,
+	PermReportsRead,
+	PermUsersRead,
+	PermUsersWrite,
+	PermRolesWrite,
+	PermApiKeysWrite,
+	PermAuditRead,
+}
+
+var CustomerPermissions = []Permission{
+	PermProductsRead,
+	PermOrdersPlace,
+	PermPaymentsCreate,
+	PermReturnsRequest,
+	PermSubscriptionsManage,
+	PermQuotesRequest,
+}
+
+// Built-in staff roles. Like Admin and Customer they are seeded on
+// migration and cannot be deleted.
+const (
+	Warehouse Role = "warehouse"
+	Finance   Role = "finance"
+	Support   Role = "support"
+)
+
+func (r Role) IsBuiltIn() bool {
+	switch r {
+	case Admin, Customer, Warehouse, Finance, Support:
+		return true
+	}
+	return false
+}
+
+// RoleDefinition is a named set of permissions. A user's Role holds the
+// name of one of these.
+type RoleDefinition struct {
+	ID          uint             `gorm:"primaryKey;autoIncrement"`
+	Name        Role             `gorm:"not null;unique;size:50" json:"name"`
+	Description string           `json:"description"`
+	Permissions []RolePermission `gorm:"foreignKey:RoleId"`
+}
+
+func (RoleDefinition) TableName() string {
+	return "roles"
+}
+
+type RolePermission struct {
+	RoleId     uint       `gorm:"primaryKey"`
+	Permission Permission `gorm:"primaryKey;size:50"`
+}
+
+type CreateRoleRequest struct {
+	Name        Role         `json:"name" binding:"required"`
+	Description string       `json:"description"`
+	Permissions []Permission `json:"permissions" binding:"required"`
+}
+
+// UpdateRoleRequest replaces a role's description and permissions. Roles
+// cannot be renamed since users reference them by name.
+type UpdateRoleRequest struct {
+	Description string       `json:"description"`
+	Permissions []Permission `json:"permissions" binding:"required"`
+}
+
+type RoleDataResponse struct {
+	ID          uint         `json:"id"`
+	Name        Role         `json:"name"`
+	Description string       `json:"description"`
+	Permissions []Permission `json:"permissions"`
+}
+
+// IsValidPermission reports whether p is one of the permissions above.
+func IsValidPermission(p Permission) bool {
+	for _, known := range StaffPermissions {
+		if p == known {
+			return true
+		}
+	}
+	for _, known := range CustomerPermissions {
+		if p == known {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,21 @@
+package entity
+
+import "time"
+
+// RoleChange records every change to a user's role and the admin who made it.
+// ChangedBy is 0 when the role was synced from an identity provider's groups.
+type RoleChange struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement"`
+	UserId    uint      `gorm:"not null;index" json:"userId"`
+	FromRole  Role      `gorm:"not null;size:20" json:"fromRole"`
+	ToRole    Role      `gorm:"not null;size:20" json:"toRole"`
+	ChangedBy uint      `gorm:"not null" json:"changedBy"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type RoleChangeResponse struct {
+	FromRole  Role      `json:"fromRole"`
+	ToRole    Role      `json:"toRole"`
+	ChangedBy uint      `json:"changedBy"`
+	CreatedAt time.Time `json:"createdAt"`
+}
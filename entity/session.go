@@ -0,0 +1,42 @@
+package entity
+
+import "time"
+
+// Session is one login. Its refresh tokens form a family: each refresh
+// rotates to a new token and marks the old one used. Access tokens carry
+// the session id so revoking the session ends them before they expire.
+type Session struct {
+	ID            string         `gorm:"primaryKey;size:36" json:"id"`
+	UserId        uint           `gorm:"not null;index" json:"userId"`
+	IP            string         `gorm:"size:45" json:"ip"`
+	UserAgent     string         `json:"userAgent"`
+	RevokedAt     *time.Time     `json:"revokedAt"`
+	RevokedReason string         `gorm:"size:50" json:"revokedReason"`
+	CreatedAt     time.Time      `json:"createdAt"`
+	UpdatedAt     time.Time      `json:"updatedAt"`
+	RefreshTokens []RefreshToken `gorm:"foreignKey:SessionId"`
+}
+
+// RefreshToken stores only the SHA-256 hash of the token handed out.
+type RefreshToken struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement"`
+	SessionId string    `gorm:"not null;index;size:36"`
+	TokenHash string    `gorm:"not null;unique;size:64"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refreshToken" binding:"required"`
+}
+
+// Session revocation reasons.
+const (
+	SessionLogout      = "logout"
+	SessionLogoutAll   = "logout_all"
+	SessionTokenReused = "refresh_token_reused"
+	SessionPasswordSet = "password_reset"
+	SessionPasswordNew = "password_changed"
+	SessionBlocked     = "blocked"
+)
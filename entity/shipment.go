@@ -0,0 +1,45 @@
+package entity
+
+import "time"
+
+type Shipment struct {
+	ID             uint           `gorm:"primaryKey;autoIncrement"`
+	OrderId        uint           `gorm:"not null" json:"orderId"`
+	Carrier        string         `gorm:"not null" json:"carrier"`
+	TrackingNumber string         `gorm:"not null" json:"trackingNumber"`
+	ShippedAt      time.Time      `gorm:"not null" json:"shippedAt"`
+	DeliveredAt    *time.Time     `json:"deliveredAt"`
+	ShipmentItems  []ShipmentItem `gorm:"foreignKey:ShipmentId"`
+}
+
+type ShipmentItem struct {
+	ShipmentId uint `gorm:"primaryKey"`
+	ProductId  uint `gorm:"primaryKey"`
+	Qty        uint `gorm:"not null" json:"qty"`
+}
+
+type CreateShipmentRequest struct {
+	Carrier        string                `json:"carrier" binding:"required"`
+	TrackingNumber string                `json:"trackingNumber" binding:"required"`
+	ShipmentItems  []ShipmentItemRequest `json:"items" binding:"required,dive"`
+}
+
+type ShipmentItemRequest struct {
+	ProductId uint `json:"productId" binding:"required"`
+	Qty       uint `json:"qty" binding:"required"`
+}
+
+type ShipmentDataResponse struct {
+	ID                   uint                   `json:"id"`
+	OrderId              uint                   `json:"orderId"`
+	Carrier              string                 `json:"carrier"`
+	TrackingNumber       string                 `json:"trackingNumber"`
+	ShippedAt            time.Time              `json:"shippedAt"`
+	DeliveredAt          *time.Time             `json:"deliveredAt"`
+	ShipmentItemResponse []ShipmentItemResponse `json:"items"`
+}
+
+type ShipmentItemResponse struct {
+	ProductId uint `json:"productId"`
+	Qty       uint `json:"qty"`
+}
@@ -0,0 +1,78 @@
+package entity
+
+import "time"
+
+type Frequency string
+
+const (
+	Daily   Frequency = "daily"
+	Weekly  Frequency = "weekly"
+	Monthly Frequency = "monthly"
+	Cron    Frequency = "cron"
+)
+
+type Subscription struct {
+	ID                uint               `gorm:"primaryKey;autoIncrement"`
+	UserId            uint               `gorm:"not null;index" json:"userId"`
+	Frequency         Frequency          `gorm:"not null;type:enum('daily', 'weekly', 'monthly', 'cron')" json:"frequency"`
+	CronExpr          string             `json:"cronExpr"`
+	ShippingAddressId uint               `json:"shippingAddressId"`
+	BillingAddressId  uint               `json:"billingAddressId"`
+	Status            uint               `gorm:"not null" json:"status"`
+	NextRunAt         time.Time          `gorm:"not null;index" json:"nextRunAt"`
+	ScheduledAt       time.Time          `gorm:"not null" json:"scheduledAt"`
+	RetryCount        uint               `gorm:"not null;default:0" json:"retryCount"`
+	CreatedAt         time.Time          `json:"createdAt"`
+	UpdatedAt         time.Time          `json:"updatedAt"`
+	SubscriptionItems []SubscriptionItem `gorm:"foreignKey:SubscriptionId"`
+	SubscriptionRuns  []SubscriptionRun  `gorm:"foreignKey:SubscriptionId"`
+}
+
+type SubscriptionItem struct {
+	SubscriptionId uint `gorm:"primaryKey"`
+	ProductId      uint `gorm:"primaryKey"`
+	Qty            uint `gorm:"not null" json:"qty"`
+}
+
+type SubscriptionRun struct {
+	ID             uint      `gorm:"primaryKey;autoIncrement"`
+	SubscriptionId uint      `gorm:"not null;index" json:"subscriptionId"`
+	RunAt          time.Time `gorm:"not null" json:"runAt"`
+	Attempt        uint      `gorm:"not null" json:"attempt"`
+	Success        bool      `gorm:"not null" json:"success"`
+	OrderId        *uint     `json:"orderId"`
+	Error          string    `gorm:"type:text" json:"error"`
+}
+
+type CreateSubscriptionRequest struct {
+	Frequency         Frequency            `json:"frequency" binding:"required"`
+	CronExpr          string               `json:"cronExpr"`
+	StartAt           string               `json:"startAt"`
+	ShippingAddressId uint                 `json:"shippingAddressId"`
+	BillingAddressId  uint                 `json:"billingAddressId"`
+	SubscriptionItems []OrderDetailRequest `json:"items" binding:"required,dive"`
+}
+
+type SubscriptionDataResponse struct {
+	ID                       uint                      `json:"id"`
+	UserId                   uint                      `json:"userId"`
+	Frequency                Frequency                 `json:"frequency"`
+	CronExpr                 string                    `json:"cronExpr"`
+	ShippingAddressId        uint                      `json:"shippingAddressId"`
+	BillingAddressId         uint                      `json:"billingAddressId"`
+	Status                   uint                      `json:"status"`
+	NextRunAt                time.Time                 `json:"nextRunAt"`
+	RetryCount               uint                      `json:"retryCount"`
+	CreatedAt                time.Time                 `json:"createdAt"`
+	SubscriptionItemResponse []OrderDetailRequest      `json:"items"`
+	SubscriptionRunResponse  []SubscriptionRunResponse `json:"runs,omitempty"`
+}
+
+type SubscriptionRunResponse struct {
+	ID      uint      `json:"id"`
+	RunAt   time.Time `json:"runAt"`
+	Attempt uint      `json:"attempt"`
+	Success bool      `json:"success"`
+	OrderId *uint     `json:"orderId"`
+	Error   string    `json:"error"`
+}
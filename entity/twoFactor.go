@@ -0,0 +1,51 @@
+package entity
+
+import "time"
+
+// TwoFactor holds a user's TOTP secret. It only protects logins once
+// ConfirmedAt is set, which happens after the user proves their app
+// generates valid codes.
+type TwoFactor struct {
+	UserId       uint       `gorm:"primaryKey"`
+	Secret       string     `gorm:"not null;size:64"`
+	ConfirmedAt  *time.Time `json:"confirmedAt"`
+	LastUsedStep int64      `gorm:"not null;default:0"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// RecoveryCode is a single-use fallback for a lost authenticator. Only its
+// SHA-256 hash is stored.
+type RecoveryCode struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement"`
+	UserId    uint   `gorm:"not null;index"`
+	CodeHash  string `gorm:"not null;size:64"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type TwoFactorTokenRequest struct {
+	TwoFactorToken string `json:"twoFactorToken" binding:"required"`
+}
+
+// TwoFactorLoginRequest completes a login with either an authenticator code
+// or a recovery code.
+type TwoFactorLoginRequest struct {
+	TwoFactorToken string `json:"twoFactorToken" binding:"required"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recoveryCode"`
+}
+
+type TwoFactorEnrollResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningUri string `json:"provisioningUri"`
+}
+
+type TwoFactorConfirmResponse struct {
+	RecoveryCodes []string           `json:"recoveryCodes"`
+	Login         *UserLoginResponse `json:"login,omitempty"`
+}
@@ -24,8 +24,19 @@ type User struct {
 	Address     string    `gorm:"not null" json:"address"`
 	Email       string    `gorm:"unique;not null;size:255" json:"email"`
 	Phonenumber string    `gorm:"unique;not null" json:"phoneNumber"`
-	Role        Role      `gorm:"not null;type:enum('admin', 'customer');default:customer" json:"role"`
+	Role        Role      `gorm:"not null;size:50;default:customer" json:"role"`
+	IsBlocked   bool      `gorm:"not null;default:false" json:"isBlocked"`
 	Orders      []Order   `gorm:"foreignKey:UserId"`
+
+	EmailVerifiedAt *time.Time `json:"emailVerifiedAt"`
+}
+
+// UserFilter narrows the admin user list. Search matches username, email
+// and names; a nil Blocked lists both blocked and active users.
+type UserFilter struct {
+	Search  string
+	Role    Role
+	Blocked *bool
 }
 
 type UserRegisterRequest struct {
@@ -44,18 +55,38 @@ type UserLoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+type UpdateUserRequest struct {
+	Firstname   string `json:"firstname" binding:"omitempty"`
+	Lastname    string `json:"lastname" binding:"omitempty"`
+	Dob         string `json:"dob" binding:"omitempty"`
+	Address     string `json:"address" binding:"omitempty"`
+	Email       string `json:"email" binding:"omitempty"`
+	Phonenumber string `json:"phoneNumber" binding:"omitempty"`
+}
+
+type ChangeRoleRequest struct {
+	Role Role `json:"role" binding:"required"`
+}
+
+type ResetPasswordRequest struct {
+	NewPassword string `json:"newPassword" binding:"required"`
+}
+
 type UserDataResponse struct {
-	Id          int       `json:"id"`
-	Username    string    `json:"username"`
-	Firstname   string    `json:"firstname"`
-	Lastname    string    `json:"lastname"`
-	Dob         time.Time `json:"dob"`
-	Address     string    `json:"address"`
-	Email       string    `json:"email"`
-	Phonenumber string    `json:"phoneNumber"`
-	Role        Role      `json:"role"`
-	CreatedAt   time.Time `json:"createdAt"`
-	UpdatedAt   time.Time `json:"updatedAt"`
+	Id              int                  `json:"id"`
+	Username        string               `json:"username"`
+	Firstname       string               `json:"firstname"`
+	Lastname        string               `json:"lastname"`
+	Dob             time.Time            `json:"dob"`
+	Address         string               `json:"address"`
+	Email           string               `json:"email"`
+	Phonenumber     string               `json:"phoneNumber"`
+	Role            Role                 `json:"role"`
+	IsBlocked       bool                 `json:"isBlocked"`
+	EmailVerifiedAt *time.Time           `json:"emailVerifiedAt"`
+	CreatedAt       time.Time            `json:"createdAt"`
+	UpdatedAt       time.Time            `json:"updatedAt"`
+	RoleChanges     []RoleChangeResponse `json:"roleChanges,omitempty"`
 }
 
 type UserChangePassword struct {
@@ -67,8 +98,16 @@ type UserChangePasswordResponse struct {
 	Message string `json:"message"`
 }
 
+// UserLoginResponse carries either a token pair or, when a second factor is
+// needed, a short-lived TwoFactorToken to finish the login with.
 type UserLoginResponse struct {
-	Token string `json:"accessToken"`
+	Token        string `json:"accessToken,omitempty"`
+	RefreshToken string `json:"refreshToken,omitempty"`
+	ExpiresIn    int64  `json:"expiresIn"`
+
+	TwoFactorRequired  bool   `json:"twoFactorRequired,omitempty"`
+	EnrollmentRequired bool   `json:"enrollmentRequired,omitempty"`
+	TwoFactorToken     string `json:"twoFactorToken,omitempty"`
 }
 
 func (u *User) HashPassword(password string) error {
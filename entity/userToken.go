@@ -0,0 +1,38 @@
+package entity
+
+import "time"
+
+type TokenPurpose string
+
+const (
+	PasswordResetToken     TokenPurpose = "password_reset"
+	EmailVerificationToken TokenPurpose = "email_verification"
+	// TwoFactorLoginToken stores the jti of the interim token Login returns
+	// when a second factor is needed, so it can only log in once.
+	TwoFactorLoginToken TokenPurpose = "two_factor_login"
+)
+
+// UserToken is a single-use token mailed to a user. Only its SHA-256 hash
+// is stored.
+type UserToken struct {
+	ID        uint         `gorm:"primaryKey;autoIncrement"`
+	UserId    uint         `gorm:"not null;index"`
+	Purpose   TokenPurpose `gorm:"not null;size:30"`
+	TokenHash string       `gorm:"not null;unique;size:64"`
+	ExpiresAt time.Time    `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+type ConfirmPasswordResetRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"newPassword" binding:"required"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
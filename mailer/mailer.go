@@ -0,0 +1,121 @@
+package mailer
+
+import (
+	"errors"
+	"fmt"
+	"go-trades/config"
+	"log"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type Mailer interface {
+	Send(to, subject, body string) error
+}
+
+// New returns the mailer selected by MAIL_DRIVER: "smtp" sends real mail,
+// "file" writes each message to MAIL_DIR and "log" only logs it. The driver
+// has no default, so a deployment cannot silently drop its mail.
+func New() (Mailer, error) {
+	switch driver := config.GetMailDriver(); driver {
+	case "smtp":
+		c := config.GetSMTPConfig()
+		if c.Host == "" {
+			return nil, errors.New("SMTP_HOST is required for the smtp mail driver")
+		}
+		return NewSMTPMailer(c), nil
+	case "file":
+		return NewFileMailer(config.GetMailDir(), config.GetMailFrom()), nil
+	case "log":
+		return NewLogMailer(config.GetMailFrom()), nil
+	case "":
+		return nil, errors.New("MAIL_DRIVER is not set")
+	default:
+		return nil, fmt.Errorf("unknown MAIL_DRIVER %q", driver)
+	}
+}
+
+type smtpMailer struct {
+	config config.SMTPConfig
+}
+
+func NewSMTPMailer(c config.SMTPConfig) Mailer {
+	return &smtpMailer{
+		config: c,
+	}
+}
+
+func (m *smtpMailer) Send(to, subject, body string) error {
+	addr := net.JoinHostPort(m.config.Host, m.config.Port)
+
+	var auth smtp.Auth
+	if m.config.Username != "" {
+		auth = smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host)
+	}
+
+	return smtp.SendMail(addr, auth, m.config.From, []string{to}, buildMessage(m.config.From, to, subject, body))
+}
+
+type fileMailer struct {
+	dir  string
+	from string
+}
+
+// NewFileMailer writes every message to dir as an .eml file, so local
+// development and tests can read the links that would have been mailed.
+func NewFileMailer(dir, from string) Mailer {
+	return &fileMailer{
+		dir:  dir,
+		from: from,
+	}
+}
+
+func (m *fileMailer) Send(to, subject, body string) error {
+	if err := os.MkdirAll(m.dir, 0755); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), sanitizeFileName(to))
+	return os.WriteFile(filepath.Join(m.dir, name), buildMessage(m.from, to, subject, body), 0644)
+}
+
+type logMailer struct {
+	from string
+}
+
+func NewLogMailer(from string) Mailer {
+	return &logMailer{
+		from: from,
+	}
+}
+
+func (m *logMailer) Send(to, subject, body string) error {
+	log.Printf("Mail to %s: %s\n%s", to, subject, body)
+	return nil
+}
+
+func buildMessage(from, to, subject, body string) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + to + "\r\n")
+	b.WriteString("Subject: " + subject + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+func sanitizeFileName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ':' {
+			return '_'
+		}
+		return r
+	}, s)
+}
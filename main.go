@@ -13,6 +13,11 @@ func main() {
 		log.Fatal("Error Loading Environment")
 	}
 
+	//LOAD JWT KEYS
+	if err := config.LoadJWTKeys(); err != nil {
+		log.Fatalf("Loading JWT keys failed. Error : %v", err)
+	}
+
 	//SETUP CONNECTION DB
 	db := config.ConnectDatabase()
 
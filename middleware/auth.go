@@ -1,17 +1,34 @@
 package middleware
 
 import (
-	"errors"
 	"go-trades/config"
 	"go-trades/entity"
+	"go-trades/repository"
+	"go-trades/utils"
+	errorMessages "go-trades/utils/error-messages"
+	"log"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func AuthMiddleware() gin.HandlerFunc {
+// apiKeyTouchInterval limits how often a busy key writes its last-used
+// time.
+const apiKeyTouchInterval = time.Minute
+
+// AuthMiddleware authenticates either a bearer token or an X-API-Key header
+// and loads the user behind it. The role and its permissions are taken from
+// the database rather than the token, so blocking an account or changing a
+// role applies to tokens that were already issued.
+func AuthMiddleware(userRepository repository.UserRepository, roleRepository repository.RoleRepository, sessionRepository repository.SessionRepository, apiKeyRepository repository.ApiKeyRepository) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
+		if key := ctx.GetHeader("X-API-Key"); key != "" {
+			authenticateApiKey(ctx, key, userRepository, roleRepository, apiKeyRepository)
+			return
+		}
+
 		authHeader := ctx.GetHeader("Authorization")
 		if authHeader == "" {
 			ctx.JSON(401, gin.H{"error": "authorization header required"})
@@ -26,12 +43,7 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		token, err := jwt.Parse(parts[1], func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, jwt.ErrSignatureInvalid
-			}
-			return config.GetJWTSecret(), nil
-		})
+		token, err := jwt.Parse(parts[1], config.JWTKeyfunc)
 
 		if err != nil || !token.Valid {
 			ctx.JSON(401, gin.H{"error": "invalid or expired token"})
@@ -53,28 +65,116 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		role, ok := claims["role"].(string)
+		// Tokens are tied to a login session so logging out revokes them
+		// before they expire.
+		sessionId, ok := claims["sid"].(string)
 		if !ok {
-			ctx.JSON(401, gin.H{"error": "invalid role in token"})
+			ctx.JSON(401, gin.H{"error": "invalid session in token"})
 			ctx.Abort()
 			return
 		}
 
-		ctx.Set("userId", uint(userId))
-		ctx.Set("role", entity.Role(role))
+		session, err := sessionRepository.FindById(ctx, sessionId)
+		if err != nil || session == nil || session.UserId != uint(userId) {
+			ctx.JSON(401, gin.H{"error": "invalid session in token"})
+			ctx.Abort()
+			return
+		}
+
+		if session.RevokedAt != nil {
+			ctx.JSON(401, gin.H{"error": errorMessages.ErrSessionRevoked})
+			ctx.Abort()
+			return
+		}
+
+		if !setUser(ctx, uint(userId), nil, userRepository, roleRepository) {
+			return
+		}
+
+		ctx.Set("sessionId", session.ID)
 		ctx.Next()
 	}
 }
 
-func IsAdmin(ctx *gin.Context) (bool, error) {
-	role, exists := ctx.Get("role")
-	if !exists {
-		return false, errors.New("role not found in context")
+func authenticateApiKey(ctx *gin.Context, key string, userRepository repository.UserRepository, roleRepository repository.RoleRepository, apiKeyRepository repository.ApiKeyRepository) {
+	apiKey, err := apiKeyRepository.FindByHash(ctx, utils.HashToken(key))
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": err.Error()})
+		ctx.Abort()
+		return
+	}
+
+	now := time.Now()
+	if apiKey == nil || apiKey.RevokedAt != nil || (apiKey.ExpiresAt != nil && now.After(*apiKey.ExpiresAt)) {
+		ctx.JSON(401, gin.H{"error": errorMessages.ErrInvalidApiKey})
+		ctx.Abort()
+		return
 	}
 
-	if role.(entity.Role) != entity.Admin {
-		return false, nil
+	if !utils.IPAllowed(ctx.ClientIP(), apiKey.AllowedIPs) {
+		ctx.JSON(403, gin.H{"error": errorMessages.ErrApiKeyIPNotAllowed})
+		ctx.Abort()
+		return
+	}
 
+	scopes := make(map[entity.Permission]bool)
+	for _, permission := range apiKey.Permissions {
+		scopes[permission.Permission] = true
 	}
-	return true, nil
+
+	if !setUser(ctx, apiKey.UserId, scopes, userRepository, roleRepository) {
+		return
+	}
+
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) > apiKeyTouchInterval {
+		if err := apiKeyRepository.TouchLastUsed(ctx, apiKey.ID, now, ctx.ClientIP()); err != nil {
+			log.Printf("Updating last use of api key %d failed. Error : %v", apiKey.ID, err)
+		}
+	}
+
+	ctx.Set("apiKeyId", apiKey.ID)
+	updateCaller(ctx, func(caller *utils.Caller) { caller.ApiKeyId = apiKey.ID })
+	ctx.Next()
+}
+
+// setUser loads the user and the permissions of their role into the
+// context. A non-nil scopes limits the permissions to those listed, as for
+// API keys. It aborts the request and returns false when the user cannot
+// act.
+func setUser(ctx *gin.Context, userId uint, scopes map[entity.Permission]bool, userRepository repository.UserRepository, roleRepository repository.RoleRepository) bool {
+	user, err := userRepository.FindById(ctx, userId)
+	if err != nil {
+		ctx.JSON(401, gin.H{"error": "invalid user ID in token"})
+		ctx.Abort()
+		return false
+	}
+
+	if user.IsBlocked {
+		ctx.JSON(403, gin.H{"error": errorMessages.ErrUserBlocked})
+		ctx.Abort()
+		return false
+	}
+
+	role, err := roleRepository.FindByName(ctx, user.Role)
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": err.Error()})
+		ctx.Abort()
+		return false
+	}
+
+	permissions := make(map[entity.Permission]bool)
+	if role != nil {
+		for _, permission := range role.Permissions {
+			if scopes == nil || scopes[permission.Permission] {
+				permissions[permission.Permission] = true
+			}
+		}
+	}
+
+	ctx.Set("userId", user.ID)
+	ctx.Set("role", user.Role)
+	ctx.Set("emailVerified", user.EmailVerifiedAt != nil)
+	ctx.Set("permissions", permissions)
+	updateCaller(ctx, func(caller *utils.Caller) { caller.UserId = user.ID })
+	return true
 }
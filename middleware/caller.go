@@ -0,0 +1,16 @@
+package middleware
+
+import (
+	"go-trades/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// updateCaller applies update to the caller carried by the request context,
+// which is how services learn who they are acting for without reaching
+// into gin.
+func updateCaller(ctx *gin.Context, update func(caller *utils.Caller)) {
+	caller := utils.GetCaller(ctx.Request.Context())
+	update(&caller)
+	ctx.Request = ctx.Request.WithContext(utils.WithCaller(ctx.Request.Context(), caller))
+}
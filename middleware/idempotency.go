@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"go-trades/config"
+	"go-trades/entity"
+	"go-trades/repository"
+	errorMessages "go-trades/utils/error-messages"
+	"io"
+	"log"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// idempotencyLock is how long a request holds its key before a retry may
+// assume it died and run it again.
+const idempotencyLock = time.Minute
+
+// Idempotency makes a request sent with an Idempotency-Key header safe to
+// retry: the first response is stored and replayed to repeats from the same
+// user. Reusing a key for a different request is refused, and a repeat that
+// arrives while the first is still running gets a 409. Server errors are not
+// stored so the request can be tried again. It needs the user, so it runs
+// after AuthMiddleware, and after RateLimit so a 429 is never stored.
+func Idempotency(repo repository.IdempotencyRepository) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		key := ctx.GetHeader("Idempotency-Key")
+		if key == "" {
+			ctx.Next()
+			return
+		}
+
+		if !validIdempotencyKey(key) {
+			ctx.JSON(400, gin.H{"error": errorMessages.ErrInvalidIdempotencyKey})
+			ctx.Abort()
+			return
+		}
+
+		userId, exists := ctx.Get("userId")
+		if !exists {
+			ctx.JSON(400, gin.H{"error": "user ID not found in context"})
+			ctx.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(ctx.Request.Body)
+		if err != nil {
+			ctx.JSON(400, gin.H{"error": err.Error()})
+			ctx.Abort()
+			return
+		}
+		ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		now := time.Now()
+		record := &entity.IdempotencyKey{
+			UserId:      userId.(uint),
+			Key:         key,
+			Fingerprint: requestFingerprint(ctx, body),
+			LockedUntil: now.Add(idempotencyLock),
+			ExpiresAt:   now.Add(config.GetIdempotencyKeyRetention()),
+		}
+
+		stored, claimed, err := repo.Claim(ctx, record, now)
+		if err != nil {
+			ctx.JSON(500, gin.H{"error": err.Error()})
+			ctx.Abort()
+			return
+		}
+
+		if !claimed {
+			switch {
+			case stored.Fingerprint != record.Fingerprint:
+				ctx.JSON(422, gin.H{"error": errorMessages.ErrIdempotencyKeyReused})
+			case stored.ResponseStatus == 0:
+				ctx.Header("Retry-After", "1")
+				ctx.JSON(409, gin.H{"error": errorMessages.ErrIdempotencyInProgress})
+			default:
+				ctx.Header("Idempotent-Replayed", "true")
+				ctx.Data(stored.ResponseStatus, stored.ContentType, stored.ResponseBody)
+			}
+			ctx.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: ctx.Writer}
+		ctx.Writer = recorder
+		ctx.Next()
+
+		status := recorder.Status()
+		if status >= 500 {
+			err = repo.Delete(ctx, stored.ID)
+		} else {
+			err = repo.Complete(ctx, stored.ID, status, recorder.Header().Get("Content-Type"), recorder.body.Bytes())
+		}
+		if err != nil {
+			log.Printf("Storing response for idempotency key %d failed. Error : %v", stored.ID, err)
+		}
+	}
+}
+
+func validIdempotencyKey(key string) bool {
+	if len(key) > 128 {
+		return false
+	}
+	for _, c := range key {
+		if c < 0x21 || c > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// requestFingerprint tells apart requests that share a key, so a key cannot
+// replay an order for a different cart.
+func requestFingerprint(ctx *gin.Context, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(ctx.Request.Method + " " + ctx.Request.URL.Path + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// responseRecorder keeps a copy of the body written to the client.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
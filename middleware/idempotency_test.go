@@ -0,0 +1,210 @@
+package middleware
+
+import (
+	"context"
+	"go-trades/entity"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeIdempotencyRepository keeps keys in memory and claims them the way
+// the database repository does.
+type fakeIdempotencyRepository struct {
+	records map[uint]*entity.IdempotencyKey
+	nextId  uint
+}
+
+func newFakeIdempotencyRepository() *fakeIdempotencyRepository {
+	return &fakeIdempotencyRepository{records: make(map[uint]*entity.IdempotencyKey)}
+}
+
+func (r *fakeIdempotencyRepository) Claim(ctx context.Context, record *entity.IdempotencyKey, now time.Time) (*entity.IdempotencyKey, bool, error) {
+	for id, existing := range r.records {
+		if existing.UserId != record.UserId || existing.Key != record.Key {
+			continue
+		}
+		if existing.ExpiresAt.Before(now) {
+			delete(r.records, id)
+			break
+		}
+		if existing.ResponseStatus == 0 && existing.LockedUntil.Before(now) && existing.Fingerprint == record.Fingerprint {
+			existing.LockedUntil = record.LockedUntil
+			stored := *existing
+			return &stored, true, nil
+		}
+		stored := *existing
+		return &stored, false, nil
+	}
+
+	r.nextId++
+	record.ID = r.nextId
+	stored := *record
+	r.records[record.ID] = &stored
+	return record, true, nil
+}
+
+func (r *fakeIdempotencyRepository) Complete(ctx context.Context, id uint, status int, contentType string, body []byte) error {
+	record := r.records[id]
+	record.ResponseStatus = status
+	record.ContentType = contentType
+	record.ResponseBody = body
+	return nil
+}
+
+func (r *fakeIdempotencyRepository) Delete(ctx context.Context, id uint) error {
+	delete(r.records, id)
+	return nil
+}
+
+func (r *fakeIdempotencyRepository) DeleteExpired(ctx context.Context) error {
+	return nil
+}
+
+// newIdempotencyRouter serves POST /orders for user 1 behind Idempotency.
+// The handler answers with status and counts how often it ran.
+func newIdempotencyRouter(repo *fakeIdempotencyRepository, status *int, calls *int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(func(ctx *gin.Context) {
+		ctx.Set("userId", uint(1))
+	})
+	router.Use(Idempotency(repo))
+	router.POST("/orders", func(ctx *gin.Context) {
+		*calls++
+		body, _ := io.ReadAll(ctx.Request.Body)
+		ctx.JSON(*status, gin.H{"call": *calls, "body": string(body)})
+	})
+	return router
+}
+
+func sendIdempotent(router *gin.Engine, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotencyReplaysResponse(t *testing.T) {
+	repo := newFakeIdempotencyRepository()
+	status, calls := 201, 0
+	router := newIdempotencyRouter(repo, &status, &calls)
+
+	first := sendIdempotent(router, "order-1", `{"cartId":1}`)
+	if first.Code != 201 {
+		t.Fatalf("first request status = %d, want 201", first.Code)
+	}
+
+	repeat := sendIdempotent(router, "order-1", `{"cartId":1}`)
+	if calls != 1 {
+		t.Errorf("handler ran %d times, want 1", calls)
+	}
+	if repeat.Code != 201 || repeat.Body.String() != first.Body.String() {
+		t.Errorf("repeat = %d %s, want 201 %s", repeat.Code, repeat.Body.String(), first.Body.String())
+	}
+	if repeat.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("repeat is missing the Idempotent-Replayed header")
+	}
+	if repeat.Header().Get("Content-Type") != first.Header().Get("Content-Type") {
+		t.Errorf("repeat Content-Type = %s, want %s", repeat.Header().Get("Content-Type"), first.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(first.Body.String(), `{\"cartId\":1}`) {
+		t.Errorf("handler did not receive the request body: %s", first.Body.String())
+	}
+}
+
+func TestIdempotencyWithoutKey(t *testing.T) {
+	repo := newFakeIdempotencyRepository()
+	status, calls := 201, 0
+	router := newIdempotencyRouter(repo, &status, &calls)
+
+	sendIdempotent(router, "", `{}`)
+	sendIdempotent(router, "", `{}`)
+	if calls != 2 {
+		t.Errorf("handler ran %d times, want 2", calls)
+	}
+	if len(repo.records) != 0 {
+		t.Errorf("stored %d keys for requests without one, want 0", len(repo.records))
+	}
+}
+
+func TestIdempotencyInvalidKey(t *testing.T) {
+	status, calls := 201, 0
+	router := newIdempotencyRouter(newFakeIdempotencyRepository(), &status, &calls)
+
+	for _, key := range []string{"has space", "tab\tkey", strings.Repeat("k", 129)} {
+		if w := sendIdempotent(router, key, `{}`); w.Code != 400 {
+			t.Errorf("key %q: status = %d, want 400", key, w.Code)
+		}
+	}
+	if calls != 0 {
+		t.Errorf("handler ran %d times, want 0", calls)
+	}
+}
+
+func TestIdempotencyKeyReused(t *testing.T) {
+	status, calls := 201, 0
+	router := newIdempotencyRouter(newFakeIdempotencyRepository(), &status, &calls)
+
+	sendIdempotent(router, "order-1", `{"cartId":1}`)
+	if w := sendIdempotent(router, "order-1", `{"cartId":2}`); w.Code != 422 {
+		t.Errorf("status = %d, want 422", w.Code)
+	}
+	if calls != 1 {
+		t.Errorf("handler ran %d times, want 1", calls)
+	}
+}
+
+func TestIdempotencyInProgress(t *testing.T) {
+	repo := newFakeIdempotencyRepository()
+	status, calls := 201, 0
+	router := newIdempotencyRouter(repo, &status, &calls)
+
+	sendIdempotent(router, "order-1", `{}`)
+	for _, record := range repo.records {
+		record.ResponseStatus = 0
+		record.LockedUntil = time.Now().Add(time.Minute)
+	}
+
+	w := sendIdempotent(router, "order-1", `{}`)
+	if w.Code != 409 {
+		t.Errorf("status = %d, want 409", w.Code)
+	}
+	if w.Header().Get("Retry-After") != "1" {
+		t.Errorf("Retry-After = %q, want 1", w.Header().Get("Retry-After"))
+	}
+	if calls != 1 {
+		t.Errorf("handler ran %d times, want 1", calls)
+	}
+}
+
+func TestIdempotencyServerErrorIsRetried(t *testing.T) {
+	repo := newFakeIdempotencyRepository()
+	status, calls := 500, 0
+	router := newIdempotencyRouter(repo, &status, &calls)
+
+	if w := sendIdempotent(router, "order-1", `{}`); w.Code != 500 {
+		t.Fatalf("status = %d, want 500", w.Code)
+	}
+	if len(repo.records) != 0 {
+		t.Errorf("kept %d keys after a server error, want 0", len(repo.records))
+	}
+
+	status = 201
+	w := sendIdempotent(router, "order-1", `{}`)
+	if w.Code != 201 || calls != 2 {
+		t.Errorf("retry = %d after %d calls, want 201 after 2", w.Code, calls)
+	}
+	if w.Header().Get("Idempotent-Replayed") != "" {
+		t.Error("retry after a server error was replayed")
+	}
+}
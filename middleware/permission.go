@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"errors"
+	"go-trades/entity"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequirePermission lets the request through when the user's role grants
+// any of the given permissions. Routes open to both staff and customers list
+// one permission for each, and the handler narrows the result set.
+func RequirePermission(permissions ...entity.Permission) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		for _, permission := range permissions {
+			granted, err := HasPermission(ctx, permission)
+			if err != nil {
+				ctx.JSON(400, gin.H{"error": err.Error()})
+				ctx.Abort()
+				return
+			}
+			if granted {
+				ctx.Next()
+				return
+			}
+		}
+
+		ctx.JSON(403, gin.H{"error": "access denied"})
+		ctx.Abort()
+	}
+}
+
+func HasPermission(ctx *gin.Context, permission entity.Permission) (bool, error) {
+	permissions, err := GetPermissions(ctx)
+	if err != nil {
+		return false, err
+	}
+	return permissions[permission], nil
+}
+
+// GetPermissions returns what the caller may do: their role's permissions,
+// narrowed to the key's scopes for API key calls.
+func GetPermissions(ctx *gin.Context) (map[entity.Permission]bool, error) {
+	value, exists := ctx.Get("permissions")
+	if !exists {
+		return nil, errors.New("permissions not found in context")
+	}
+
+	permissions, ok := value.(map[entity.Permission]bool)
+	if !ok {
+		return nil, errors.New("invalid permissions type")
+	}
+	return permissions, nil
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"fmt"
+	"go-trades/entity"
+	"go-trades/repository"
+	errorMessages "go-trades/utils/error-messages"
+	"log"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimit limits each client to the given token bucket. Clients are told
+// apart by API key, then user, then IP, so on protected routes it has to run
+// after AuthMiddleware. When several limits cover a route the innermost one
+// reports its headers.
+func RateLimit(store repository.RateLimitStore, limit entity.RateLimit) gin.HandlerFunc {
+	return rateLimit(store, limit, rateLimitClient)
+}
+
+// IPRateLimit limits each client IP to the given token bucket. It runs in
+// front of AuthMiddleware, so requests with bad credentials are limited too.
+func IPRateLimit(store repository.RateLimitStore, limit entity.RateLimit) gin.HandlerFunc {
+	return rateLimit(store, limit, func(ctx *gin.Context) string {
+		return "ip:" + ctx.ClientIP()
+	})
+}
+
+func rateLimit(store repository.RateLimitStore, limit entity.RateLimit, client func(ctx *gin.Context) string) gin.HandlerFunc {
+	policy := fmt.Sprintf("%d;w=%d;burst=%d", limit.Requests, int(limit.Per.Seconds()), limit.Burst)
+
+	return func(ctx *gin.Context) {
+		result, err := store.Take(ctx, limit.Name+":"+client(ctx), limit, time.Now())
+		if err != nil {
+			// Without the store nothing is limited, so refuse the request
+			// rather than let it through unchecked.
+			log.Printf("Rate limiting %s failed. Error : %v", limit.Name, err)
+			ctx.JSON(503, gin.H{"error": errorMessages.ErrRateLimitUnavailable})
+			ctx.Abort()
+			return
+		}
+
+		ctx.Header("RateLimit-Policy", policy)
+		ctx.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		ctx.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		ctx.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+
+		if !result.Allowed {
+			ctx.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			ctx.JSON(429, gin.H{"error": errorMessages.ErrTooManyRequests})
+			ctx.Abort()
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+func rateLimitClient(ctx *gin.Context) string {
+	if apiKeyId, ok := ctx.Get("apiKeyId"); ok {
+		return fmt.Sprintf("key:%v", apiKeyId)
+	}
+	if userId, ok := ctx.Get("userId"); ok {
+		return fmt.Sprintf("user:%v", userId)
+	}
+	return "ip:" + ctx.ClientIP()
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"go-trades/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const maxRequestIdLength = 64
+
+// RequestID tags every request with an id, reusing the caller's
+// X-Request-ID when it sends a sensible one, and echoes it in the response
+// so log lines and audit entries can be traced back to a call.
+func RequestID() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		requestId := ctx.GetHeader("X-Request-ID")
+		if !validRequestId(requestId) {
+			requestId = uuid.NewString()
+		}
+
+		ctx.Set("requestId", requestId)
+		updateCaller(ctx, func(caller *utils.Caller) {
+			caller.RequestId = requestId
+			caller.IP = ctx.ClientIP()
+			caller.UserAgent = ctx.Request.UserAgent()
+		})
+		ctx.Header("X-Request-ID", requestId)
+		ctx.Next()
+	}
+}
+
+func validRequestId(id string) bool {
+	if id == "" || len(id) > maxRequestIdLength {
+		return false
+	}
+	for _, c := range id {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
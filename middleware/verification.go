@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"go-trades/config"
+	errorMessages "go-trades/utils/error-messages"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireVerifiedEmail guards the routes that place orders. It only applies
+// when REQUIRE_EMAIL_VERIFICATION is enabled; orders created by background
+// jobs and admin imports are not affected.
+func RequireVerifiedEmail() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !config.IsEmailVerificationRequired() {
+			ctx.Next()
+			return
+		}
+
+		if !ctx.GetBool("emailVerified") {
+			ctx.JSON(403, gin.H{"error": errorMessages.ErrEmailNotVerified})
+			ctx.Abort()
+			return
+		}
+
+		ctx.Next()
+	}
+}
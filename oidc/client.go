@@ -0,0 +1,291 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-trades/config"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Client speaks the authorization code flow with PKCE to one provider. The
+// discovery document and signing keys are fetched on first use and cached.
+type Client struct {
+	Provider   *config.OIDCProvider
+	HTTPClient *http.Client
+
+	mu       sync.Mutex
+	metadata *metadata
+	keys     map[string]interface{}
+}
+
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksURI               string `json:"jwks_uri"`
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	IdToken     string `json:"id_token"`
+	TokenType   string `json:"token_type"`
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func NewClient(provider *config.OIDCProvider) *Client {
+	return &Client{
+		Provider:   provider,
+		HTTPClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// NewRandomString returns a URL-safe random string for state, nonce and
+// PKCE verifiers.
+func NewRandomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// CodeChallenge derives the S256 PKCE challenge from a verifier.
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func (c *Client) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	meta, err := c.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", c.Provider.ClientId)
+	query.Set("redirect_uri", c.Provider.RedirectURL)
+	query.Set("scope", strings.Join(c.Provider.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", CodeChallenge(verifier))
+	query.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(meta.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return meta.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// Exchange redeems the authorization code and returns the verified ID token
+// claims.
+func (c *Client) Exchange(ctx context.Context, code, verifier, nonce string) (jwt.MapClaims, error) {
+	meta, err := c.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", c.Provider.RedirectURL)
+	form.Set("client_id", c.Provider.ClientId)
+	form.Set("code_verifier", verifier)
+	if c.Provider.ClientSecret != "" {
+		form.Set("client_secret", c.Provider.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	var tokens tokenResponse
+	if err := c.doJSON(req, &tokens); err != nil {
+		return nil, fmt.Errorf("token exchange: %w", err)
+	}
+	if tokens.IdToken == "" {
+		return nil, errors.New("token response has no id_token")
+	}
+
+	return c.verifyIdToken(ctx, meta, tokens.IdToken, nonce)
+}
+
+func (c *Client) verifyIdToken(ctx context.Context, meta *metadata, rawIdToken, nonce string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(rawIdToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return c.key(ctx, meta, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512", "EdDSA"}),
+		jwt.WithIssuer(meta.Issuer),
+		jwt.WithAudience(c.Provider.ClientId),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("id token: %w", err)
+	}
+
+	if claims["nonce"] != nonce {
+		return nil, errors.New("id token: nonce mismatch")
+	}
+	return claims, nil
+}
+
+func (c *Client) discover(ctx context.Context) (*metadata, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.metadata != nil {
+		return c.metadata, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.Provider.Issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var meta metadata
+	if err := c.doJSON(req, &meta); err != nil {
+		return nil, fmt.Errorf("discovery: %w", err)
+	}
+	if strings.TrimRight(meta.Issuer, "/") != c.Provider.Issuer {
+		return nil, fmt.Errorf("discovery: issuer %q does not match %q", meta.Issuer, c.Provider.Issuer)
+	}
+
+	c.metadata = &meta
+	return c.metadata, nil
+}
+
+// key returns the provider key for kid, fetching the key set again when
+// the kid is unknown since the provider may have rotated.
+func (c *Client) key(ctx context.Context, meta *metadata, kid string) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if key, ok := c.keys[kid]; ok {
+		return key, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, meta.JwksURI, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := c.doJSON(req, &set); err != nil {
+		return nil, fmt.Errorf("jwks: %w", err)
+	}
+
+	c.keys = make(map[string]interface{})
+	for _, jwk := range set.Keys {
+		key, err := jwk.publicKey()
+		if err != nil {
+			continue
+		}
+		c.keys[jwk.Kid] = key
+	}
+
+	key, ok := c.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("jwks: no key %q", kid)
+	}
+	return key, nil
+}
+
+func (c *Client) doJSON(req *http.Request, v interface{}) error {
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %d: %s", req.URL.Path, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.Unmarshal(body, v)
+}
+
+func (k jsonWebKey) publicKey() (interface{}, error) {
+	decode := base64.RawURLEncoding.DecodeString
+
+	switch k.Kty {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"go-trades/entity"
+	"go-trades/utils"
+
+	"gorm.io/gorm"
+)
+
+type addressRepository struct {
+	DB *gorm.DB
+}
+
+type AddressRepository interface {
+	FindAllByUserId(ctx context.Context, userId uint) ([]entity.Address, error)
+	FindByUserIdWithId(ctx context.Context, userId, id uint) (*entity.Address, error)
+	FindDefaultShippingByUserId(ctx context.Context, userId uint) (*entity.Address, error)
+	FindDefaultBillingByUserId(ctx context.Context, userId uint) (*entity.Address, error)
+	CreateAddress(ctx context.Context, address *entity.Address) error
+	UpdateAddress(ctx context.Context, address *entity.Address) error
+	DeleteAddress(ctx context.Context, id uint) error
+	ClearDefaults(ctx context.Context, userId uint, shipping, billing bool) error
+}
+
+func NewAddressRepository(db *gorm.DB) AddressRepository {
+	return &addressRepository{
+		DB: db,
+	}
+}
+
+func (r *addressRepository) FindAllByUserId(ctx context.Context, userId uint) ([]entity.Address, error) {
+	var result []entity.Address
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.Where("user_id = ?", userId).Order("id").Find(&result).Error
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *addressRepository) FindByUserIdWithId(ctx context.Context, userId, id uint) (*entity.Address, error) {
+	var result entity.Address
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.Where("user_id = ? AND id = ?", userId, id).First(&result).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (r *addressRepository) FindDefaultShippingByUserId(ctx context.Context, userId uint) (*entity.Address, error) {
+	var result entity.Address
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.Where("user_id = ? AND is_default_shipping = ?", userId, true).First(&result).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (r *addressRepository) FindDefaultBillingByUserId(ctx context.Context, userId uint) (*entity.Address, error) {
+	var result entity.Address
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.Where("user_id = ? AND is_default_billing = ?", userId, true).First(&result).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (r *addressRepository) CreateAddress(ctx context.Context, address *entity.Address) error {
+	db := utils.GetTx(ctx, r.DB)
+	return db.Create(address).Error
+}
+
+func (r *addressRepository) UpdateAddress(ctx context.Context, address *entity.Address) error {
+	db := utils.GetTx(ctx, r.DB)
+	return db.Save(address).Error
+}
+
+func (r *addressRepository) DeleteAddress(ctx context.Context, id uint) error {
+	db := utils.GetTx(ctx, r.DB)
+	return db.Delete(&entity.Address{}, id).Error
+}
+
+func (r *addressRepository) ClearDefaults(ctx context.Context, userId uint, shipping, billing bool) error {
+	db := utils.GetTx(ctx, r.DB)
+
+	if shipping {
+		if err := db.Model(&entity.Address{}).Where("user_id = ?", userId).Update("is_default_shipping", false).Error; err != nil {
+			return err
+		}
+	}
+	if billing {
+		if err := db.Model(&entity.Address{}).Where("user_id = ?", userId).Update("is_default_billing", false).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
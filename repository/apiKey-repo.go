@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"go-trades/entity"
+	"go-trades/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type apiKeyRepository struct {
+	DB *gorm.DB
+}
+
+type ApiKeyRepository interface {
+	FindAll(ctx context.Context, page, size int) ([]entity.ApiKey, int64, error)
+	FindById(ctx context.Context, id uint) (*entity.ApiKey, error)
+	FindByHash(ctx context.Context, hash string) (*entity.ApiKey, error)
+	CreateApiKey(ctx context.Context, apiKey *entity.ApiKey) error
+	Revoke(ctx context.Context, id uint) error
+	RevokeAllByUserId(ctx context.Context, userId uint) error
+	TouchLastUsed(ctx context.Context, id uint, usedAt time.Time, ip string) error
+}
+
+func NewApiKeyRepository(db *gorm.DB) ApiKeyRepository {
+	return &apiKeyRepository{
+		DB: db,
+	}
+}
+
+func (r *apiKeyRepository) FindAll(ctx context.Context, page, size int) ([]entity.ApiKey, int64, error) {
+	var result []entity.ApiKey
+	var total int64
+	db := utils.GetTx(ctx, r.DB)
+
+	if err := db.Model(&entity.ApiKey{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * size
+	err := db.Preload("Permissions").Order("id DESC").Offset(offset).Limit(size).Find(&result).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return result, total, nil
+}
+
+func (r *apiKeyRepository) FindById(ctx context.Context, id uint) (*entity.ApiKey, error) {
+	var result entity.ApiKey
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.Preload("Permissions").Where("id = ?", id).First(&result).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (r *apiKeyRepository) FindByHash(ctx context.Context, hash string) (*entity.ApiKey, error) {
+	var result entity.ApiKey
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.Preload("Permissions").Where("key_hash = ?", hash).First(&result).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (r *apiKeyRepository) CreateApiKey(ctx context.Context, apiKey *entity.ApiKey) error {
+	db := utils.GetTx(ctx, r.DB)
+	return db.Create(apiKey).Error
+}
+
+func (r *apiKeyRepository) Revoke(ctx context.Context, id uint) error {
+	db := utils.GetTx(ctx, r.DB)
+	return db.Model(&entity.ApiKey{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *apiKeyRepository) RevokeAllByUserId(ctx context.Context, userId uint) error {
+	db := utils.GetTx(ctx, r.DB)
+	return db.Model(&entity.ApiKey{}).
+		Where("user_id = ? AND revoked_at IS NULL", userId).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *apiKeyRepository) TouchLastUsed(ctx context.Context, id uint, usedAt time.Time, ip string) error {
+	db := utils.GetTx(ctx, r.DB)
+	return db.Model(&entity.ApiKey{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"last_used_at": usedAt, "last_used_ip": ip}).Error
+}
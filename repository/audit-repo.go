@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"go-trades/entity"
+	"go-trades/utils"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const auditChainHeadId = 1
+
+// AuditGenesisHash is the previous hash of the first audit entry.
+var AuditGenesisHash = strings.Repeat("0", 64)
+
+type auditRepository struct {
+	DB *gorm.DB
+}
+
+// AuditRepository only appends: entries are never updated or deleted.
+type AuditRepository interface {
+	Append(ctx context.Context, entry *entity.AuditLog) error
+	FindAll(ctx context.Context, filter entity.AuditFilter, page, size int) ([]entity.AuditLog, int64, error)
+	FindBySeqAfter(ctx context.Context, seq uint64, limit int) ([]entity.AuditLog, error)
+	FindHead(ctx context.Context) (*entity.AuditChainHead, error)
+}
+
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return &auditRepository{
+		DB: db,
+	}
+}
+
+// Append chains the entry onto the latest one. It joins the transaction in
+// ctx when there is one, so the entry is only kept if the change it
+// describes is committed. Chaining locks the single chain head, so it is
+// put off until just before that transaction commits rather than holding
+// the head for the whole unit of work.
+func (r *auditRepository) Append(ctx context.Context, entry *entity.AuditLog) error {
+	return utils.BeforeCommit(ctx, func(ctx context.Context) error {
+		return r.chain(ctx, entry)
+	})
+}
+
+func (r *auditRepository) chain(ctx context.Context, entry *entity.AuditLog) error {
+	db := utils.GetTx(ctx, r.DB)
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		head := entity.AuditChainHead{ID: auditChainHeadId, Hash: AuditGenesisHash}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&head).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&head, auditChainHeadId).Error; err != nil {
+			return err
+		}
+
+		entry.Seq = head.Seq + 1
+		entry.PrevHash = head.Hash
+		entry.CreatedAt = time.Now().Truncate(time.Millisecond)
+		entry.Hash = entry.ComputeHash()
+		if err := tx.Create(entry).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&head).Updates(map[string]interface{}{"seq": entry.Seq, "hash": entry.Hash}).Error
+	})
+}
+
+func (r *auditRepository) FindAll(ctx context.Context, filter entity.AuditFilter, page, size int) ([]entity.AuditLog, int64, error) {
+	var result []entity.AuditLog
+	var total int64
+	db := utils.GetTx(ctx, r.DB)
+
+	query := db.Model(&entity.AuditLog{})
+	if filter.ActorId != 0 {
+		query = query.Where("actor_id = ?", filter.ActorId)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.EntityType != "" {
+		query = query.Where("entity_type = ?", filter.EntityType)
+	}
+	if filter.EntityId != 0 {
+		query = query.Where("entity_id = ?", filter.EntityId)
+	}
+	if filter.RequestId != "" {
+		query = query.Where("request_id = ?", filter.RequestId)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * size
+	if err := query.Order("seq DESC").Offset(offset).Limit(size).Find(&result).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return result, total, nil
+}
+
+func (r *auditRepository) FindBySeqAfter(ctx context.Context, seq uint64, limit int) ([]entity.AuditLog, error) {
+	var result []entity.AuditLog
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.Where("seq > ?", seq).Order("seq").Limit(limit).Find(&result).Error
+	return result, err
+}
+
+func (r *auditRepository) FindHead(ctx context.Context) (*entity.AuditChainHead, error) {
+	var head entity.AuditChainHead
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.First(&head, auditChainHeadId).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &head, nil
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"go-trades/entity"
+	"go-trades/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type cartRepository struct {
+	DB *gorm.DB
+}
+
+type CartRepository interface {
+	FindByUserId(ctx context.Context, userId uint) (*entity.Cart, error)
+	FindByUserIdForUpdate(ctx context.Context, userId uint) (*entity.Cart, error)
+	CreateCart(ctx context.Context, cart *entity.Cart) (bool, error)
+	SaveCartItem(ctx context.Context, item *entity.CartItem) error
+	DeleteCartItem(ctx context.Context, cartId, productId uint) error
+	ClearCart(ctx context.Context, cartId uint) error
+}
+
+func NewCartRepository(db *gorm.DB) CartRepository {
+	return &cartRepository{
+		DB: db,
+	}
+}
+
+func (r *cartRepository) FindByUserId(ctx context.Context, userId uint) (*entity.Cart, error) {
+	var result entity.Cart
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.Preload("CartItems", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at")
+	}).Where("user_id = ?", userId).First(&result).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// FindByUserIdForUpdate locks the user's cart and its lines until the
+// caller's transaction ends.
+func (r *cartRepository) FindByUserIdForUpdate(ctx context.Context, userId uint) (*entity.Cart, error) {
+	var result entity.Cart
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("CartItems", func(db *gorm.DB) *gorm.DB {
+		return db.Clauses(clause.Locking{Strength: "UPDATE"}).Order("created_at")
+	}).Where("user_id = ?", userId).First(&result).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// CreateCart inserts the cart unless the user already has one, and reports
+// whether it did.
+func (r *cartRepository) CreateCart(ctx context.Context, cart *entity.Cart) (bool, error) {
+	db := utils.GetTx(ctx, r.DB)
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(cart)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *cartRepository) SaveCartItem(ctx context.Context, item *entity.CartItem) error {
+	db := utils.GetTx(ctx, r.DB)
+	if err := db.Save(item).Error; err != nil {
+		return err
+	}
+	return db.Model(&entity.Cart{}).Where("id = ?", item.CartId).Update("updated_at", item.UpdatedAt).Error
+}
+
+func (r *cartRepository) DeleteCartItem(ctx context.Context, cartId, productId uint) error {
+	db := utils.GetTx(ctx, r.DB)
+	return db.Where("cart_id = ? AND product_id = ?", cartId, productId).Delete(&entity.CartItem{}).Error
+}
+
+func (r *cartRepository) ClearCart(ctx context.Context, cartId uint) error {
+	db := utils.GetTx(ctx, r.DB)
+	return db.Where("cart_id = ?", cartId).Delete(&entity.CartItem{}).Error
+}
@@ -1,11 +1,14 @@
 package repository
 
 import (
+	"context"
 	"errors"
 	"go-trades/entity"
+	"go-trades/utils"
+	errorMessages "go-trades/utils/error-messages"
 
-	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type categoryRepository struct {
@@ -13,13 +16,13 @@ type categoryRepository struct {
 }
 
 type CategoryRepository interface {
-	FindAll(ctx *gin.Context, page, size int) ([]entity.Category, int64, error)
-	FindById(ctx *gin.Context, id uint) (*entity.Category, error)
-	FindByName(ctx *gin.Context, name string) (*entity.Category, error)
-	FindByCode(ctx *gin.Context, code string) (*entity.Category, error)
-	CreateCategory(ctx *gin.Context, category *entity.Category) error
-	UpdateCategory(ctx *gin.Context, category *entity.Category) error
-	DeleteCategory(ctx *gin.Context, id uint) error
+	FindAll(ctx context.Context, page, size int) ([]entity.Category, int64, error)
+	FindById(ctx context.Context, id uint) (*entity.Category, error)
+	FindByName(ctx context.Context, name string) (*entity.Category, error)
+	FindByCode(ctx context.Context, code string) (*entity.Category, error)
+	CreateCategory(ctx context.Context, category *entity.Category) error
+	UpdateCategory(ctx context.Context, category *entity.Category) error
+	DeleteCategory(ctx context.Context, category *entity.Category) error
 }
 
 func NewCategoryRepository(db *gorm.DB) CategoryRepository {
@@ -28,16 +31,17 @@ func NewCategoryRepository(db *gorm.DB) CategoryRepository {
 	}
 }
 
-func (r *categoryRepository) FindAll(ctx *gin.Context, page, size int) ([]entity.Category, int64, error) {
+func (r *categoryRepository) FindAll(ctx context.Context, page, size int) ([]entity.Category, int64, error) {
 	var result []entity.Category
 	var total int64
+	db := utils.GetTx(ctx, r.DB)
 
-	if err := r.DB.Model(&entity.Category{}).Count(&total).Error; err != nil {
+	if err := db.Model(&entity.Category{}).Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
 	offset := (page - 1) * size
-	err := r.DB.Offset(offset).Limit(size).Find(&result).Error
+	err := db.Offset(offset).Limit(size).Find(&result).Error
 	if err != nil {
 		return nil, 0, err
 	}
@@ -45,9 +49,11 @@ func (r *categoryRepository) FindAll(ctx *gin.Context, page, size int) ([]entity
 	return result, total, nil
 }
 
-func (r *categoryRepository) FindById(ctx *gin.Context, id uint) (*entity.Category, error) {
+func (r *categoryRepository) FindById(ctx context.Context, id uint) (*entity.Category, error) {
 	var result entity.Category
-	err := r.DB.Where("id = ?", id).First(&result).Error
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.Where("id = ?", id).First(&result).Error
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, nil
 	}
@@ -57,9 +63,11 @@ func (r *categoryRepository) FindById(ctx *gin.Context, id uint) (*entity.Catego
 	return &result, nil
 }
 
-func (r *categoryRepository) FindByCode(ctx *gin.Context, code string) (*entity.Category, error) {
+func (r *categoryRepository) FindByCode(ctx context.Context, code string) (*entity.Category, error) {
 	var result entity.Category
-	err := r.DB.Where("code = ?", code).First(&result).Error
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.Where("code = ?", code).First(&result).Error
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, nil
 	}
@@ -69,9 +77,11 @@ func (r *categoryRepository) FindByCode(ctx *gin.Context, code string) (*entity.
 	return &result, nil
 }
 
-func (r *categoryRepository) FindByName(ctx *gin.Context, name string) (*entity.Category, error) {
+func (r *categoryRepository) FindByName(ctx context.Context, name string) (*entity.Category, error) {
 	var result entity.Category
-	err := r.DB.Where("name = ?", name).First(&result).Error
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.Where("name = ?", name).First(&result).Error
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, nil
 	}
@@ -81,18 +91,38 @@ func (r *categoryRepository) FindByName(ctx *gin.Context, name string) (*entity.
 	return &result, nil
 }
 
-func (r *categoryRepository) CreateCategory(ctx *gin.Context, category *entity.Category) error {
-	return r.DB.Create(category).Error
+func (r *categoryRepository) CreateCategory(ctx context.Context, category *entity.Category) error {
+	category.Version = 1
+	db := utils.GetTx(ctx, r.DB)
+	return db.Create(category).Error
 }
 
-func (r *categoryRepository) UpdateCategory(ctx *gin.Context, category *entity.Category) error {
-	return r.DB.Save(category).Error
+// UpdateCategory saves the category only if it is still at the version it
+// was read at, and moves it to the next version.
+func (r *categoryRepository) UpdateCategory(ctx context.Context, category *entity.Category) error {
+	version := category.Version
+	category.Version++
+	db := utils.GetTx(ctx, r.DB)
+
+	result := db.Model(category).Where("version = ?", version).Select("*").Omit(clause.Associations).Updates(category)
+	if result.Error != nil {
+		category.Version = version
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		category.Version = version
+		return errors.New(errorMessages.ErrPreconditionFailed)
+	}
+	return nil
 }
 
-func (r *categoryRepository) DeleteCategory(ctx *gin.Context, id uint) error {
-	var category entity.Category
-	if err := r.DB.First(&category, id).Error; err != nil {
-		return err
+// DeleteCategory deletes the category only if it is still at the version
+// it was read at.
+func (r *categoryRepository) DeleteCategory(ctx context.Context, category *entity.Category) error {
+	db := utils.GetTx(ctx, r.DB)
+	result := db.Where("version = ?", category.Version).Delete(category)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return errors.New(errorMessages.ErrPreconditionFailed)
 	}
-	return r.DB.Delete(&category).Error
+	return result.Error
 }
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"go-trades/entity"
+	"go-trades/utils"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IdempotencyRepository interface {
+	// Claim stores record unless the user already used its key. It returns
+	// the stored record and whether the caller now owns it. A record whose
+	// request stopped before finishing can be claimed again once its lock
+	// has run out.
+	Claim(ctx context.Context, record *entity.IdempotencyKey, now time.Time) (*entity.IdempotencyKey, bool, error)
+	Complete(ctx context.Context, id uint, status int, contentType string, body []byte) error
+	Delete(ctx context.Context, id uint) error
+	DeleteExpired(ctx context.Context) error
+}
+
+type idempotencyRepository struct {
+	DB *gorm.DB
+}
+
+func NewIdempotencyRepository(db *gorm.DB) IdempotencyRepository {
+	return &idempotencyRepository{
+		DB: db,
+	}
+}
+
+func (r *idempotencyRepository) Claim(ctx context.Context, record *entity.IdempotencyKey, now time.Time) (*entity.IdempotencyKey, bool, error) {
+	var existing entity.IdempotencyKey
+	claimed := false
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("user_id = ? AND `key` = ? AND expires_at < ?", record.UserId, record.Key, now).
+			Delete(&entity.IdempotencyKey{}).Error
+		if err != nil {
+			return err
+		}
+
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 1 {
+			existing = *record
+			claimed = true
+			return nil
+		}
+
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ? AND `key` = ?", record.UserId, record.Key).
+			First(&existing).Error
+		if err != nil {
+			return err
+		}
+
+		if existing.ResponseStatus == 0 && existing.Fingerprint == record.Fingerprint && existing.LockedUntil.Before(now) {
+			existing.LockedUntil = record.LockedUntil
+			claimed = true
+			return tx.Model(&existing).Update("locked_until", existing.LockedUntil).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return &existing, claimed, nil
+}
+
+func (r *idempotencyRepository) Complete(ctx context.Context, id uint, status int, contentType string, body []byte) error {
+	db := utils.GetTx(ctx, r.DB)
+	return db.Model(&entity.IdempotencyKey{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"response_status": status,
+		"content_type":    contentType,
+		"response_body":   body,
+	}).Error
+}
+
+func (r *idempotencyRepository) Delete(ctx context.Context, id uint) error {
+	db := utils.GetTx(ctx, r.DB)
+	return db.Delete(&entity.IdempotencyKey{}, id).Error
+}
+
+func (r *idempotencyRepository) DeleteExpired(ctx context.Context) error {
+	db := utils.GetTx(ctx, r.DB)
+	return db.Where("expires_at < ?", time.Now()).Delete(&entity.IdempotencyKey{}).Error
+}
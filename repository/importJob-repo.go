@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"go-trades/entity"
+	"go-trades/utils"
+
+	"gorm.io/gorm"
+)
+
+type importJobRepository struct {
+	DB *gorm.DB
+}
+
+type ImportJobRepository interface {
+	FindAll(ctx context.Context, page, size int) ([]entity.ImportJob, int64, error)
+	FindById(ctx context.Context, id uint) (*entity.ImportJob, error)
+	CreateImportJob(ctx context.Context, job *entity.ImportJob) error
+	UpdateImportJob(ctx context.Context, job *entity.ImportJob) error
+}
+
+func NewImportJobRepository(db *gorm.DB) ImportJobRepository {
+	return &importJobRepository{
+		DB: db,
+	}
+}
+
+func (r *importJobRepository) FindAll(ctx context.Context, page, size int) ([]entity.ImportJob, int64, error) {
+	var result []entity.ImportJob
+	var total int64
+	db := utils.GetTx(ctx, r.DB)
+
+	if err := db.Model(&entity.ImportJob{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * size
+	err := db.Order("id DESC").Offset(offset).Limit(size).Find(&result).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return result, total, nil
+}
+
+func (r *importJobRepository) FindById(ctx context.Context, id uint) (*entity.ImportJob, error) {
+	var result entity.ImportJob
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.Where("id = ?", id).First(&result).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (r *importJobRepository) CreateImportJob(ctx context.Context, job *entity.ImportJob) error {
+	db := utils.GetTx(ctx, r.DB)
+	return db.Create(job).Error
+}
+
+func (r *importJobRepository) UpdateImportJob(ctx context.Context, job *entity.ImportJob) error {
+	db := utils.GetTx(ctx, r.DB)
+	return db.Save(job).Error
+}
@@ -1,11 +1,12 @@
 package repository
 
 import (
+	"context"
 	"errors"
 	"go-trades/entity"
 	"go-trades/utils"
+	errorMessages "go-trades/utils/error-messages"
 
-	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
@@ -14,15 +15,17 @@ type inventoryRepository struct {
 }
 
 type InventoryRepository interface {
-	FindAll(ctx *gin.Context, page, size int) ([]entity.Inventory, int64, error)
-	FindById(ctx *gin.Context, id uint) (*entity.Inventory, error)
-	FindFirstByProductId(ctx *gin.Context, id uint) (*entity.Inventory, error)
-	FindByName(ctx *gin.Context, name string) (*entity.Inventory, error)
-	FindByCode(ctx *gin.Context, code string) (*entity.Inventory, error)
-	CreateInventory(ctx *gin.Context, inventory *entity.Inventory) error
-	UpdateInventory(ctx *gin.Context, inventory *entity.Inventory) error
-	UpdateInventoryForOrder(ctx *gin.Context, inventory *entity.Inventory, qty uint, action string) error
-	DeleteInventory(ctx *gin.Context, id uint) error
+	FindAll(ctx context.Context, page, size int) ([]entity.Inventory, int64, error)
+	FindById(ctx context.Context, id uint) (*entity.Inventory, error)
+	FindFirstByProductId(ctx context.Context, id uint) (*entity.Inventory, error)
+	FindByProductIdAndLocation(ctx context.Context, productId uint, location string) (*entity.Inventory, error)
+	FindInBatches(ctx context.Context, size int, fn func(inventories []entity.Inventory) error) error
+	FindByName(ctx context.Context, name string) (*entity.Inventory, error)
+	FindByCode(ctx context.Context, code string) (*entity.Inventory, error)
+	CreateInventory(ctx context.Context, inventory *entity.Inventory) error
+	UpdateInventory(ctx context.Context, inventory *entity.Inventory) error
+	UpdateInventoryForOrder(ctx context.Context, inventory *entity.Inventory, qty uint, action string) error
+	DeleteInventory(ctx context.Context, inventory *entity.Inventory) error
 	resolveDB(tx *gorm.DB) *gorm.DB
 }
 
@@ -39,16 +42,17 @@ func (r *inventoryRepository) resolveDB(tx *gorm.DB) *gorm.DB {
 	return r.DB
 }
 
-func (r *inventoryRepository) FindAll(ctx *gin.Context, page, size int) ([]entity.Inventory, int64, error) {
+func (r *inventoryRepository) FindAll(ctx context.Context, page, size int) ([]entity.Inventory, int64, error) {
 	var result []entity.Inventory
 	var total int64
+	db := utils.GetTx(ctx, r.DB)
 
-	if err := r.DB.Model(&entity.Inventory{}).Count(&total).Error; err != nil {
+	if err := db.Model(&entity.Inventory{}).Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
 	offset := (page - 1) * size
-	err := r.DB.Offset(offset).Limit(size).Find(&result).Error
+	err := db.Offset(offset).Limit(size).Find(&result).Error
 	if err != nil {
 		return nil, 0, err
 	}
@@ -56,9 +60,11 @@ func (r *inventoryRepository) FindAll(ctx *gin.Context, page, size int) ([]entit
 	return result, total, nil
 }
 
-func (r *inventoryRepository) FindById(ctx *gin.Context, id uint) (*entity.Inventory, error) {
+func (r *inventoryRepository) FindById(ctx context.Context, id uint) (*entity.Inventory, error) {
 	var result entity.Inventory
-	err := r.DB.Where("id = ?", id).First(&result).Error
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.Where("id = ?", id).First(&result).Error
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, nil
 	}
@@ -68,9 +74,11 @@ func (r *inventoryRepository) FindById(ctx *gin.Context, id uint) (*entity.Inven
 	return &result, nil
 }
 
-func (r *inventoryRepository) FindByCode(ctx *gin.Context, code string) (*entity.Inventory, error) {
+func (r *inventoryRepository) FindByCode(ctx context.Context, code string) (*entity.Inventory, error) {
 	var result entity.Inventory
-	err := r.DB.Where("code = ?", code).First(&result).Error
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.Where("code = ?", code).First(&result).Error
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, nil
 	}
@@ -80,9 +88,11 @@ func (r *inventoryRepository) FindByCode(ctx *gin.Context, code string) (*entity
 	return &result, nil
 }
 
-func (r *inventoryRepository) FindByName(ctx *gin.Context, name string) (*entity.Inventory, error) {
+func (r *inventoryRepository) FindByName(ctx context.Context, name string) (*entity.Inventory, error) {
 	var result entity.Inventory
-	err := r.DB.Where("name = ?", name).First(&result).Error
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.Where("name = ?", name).First(&result).Error
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, nil
 	}
@@ -92,7 +102,7 @@ func (r *inventoryRepository) FindByName(ctx *gin.Context, name string) (*entity
 	return &result, nil
 }
 
-func (r *inventoryRepository) FindFirstByProductId(ctx *gin.Context, id uint) (*entity.Inventory, error) {
+func (r *inventoryRepository) FindFirstByProductId(ctx context.Context, id uint) (*entity.Inventory, error) {
 	var result entity.Inventory
 	db := utils.GetTx(ctx, r.DB)
 	err := db.Where("product_id = ?", id).First(&result).Error
@@ -105,34 +115,82 @@ func (r *inventoryRepository) FindFirstByProductId(ctx *gin.Context, id uint) (*
 	return &result, nil
 }
 
-func (r *inventoryRepository) CreateInventory(ctx *gin.Context, inventory *entity.Inventory) error {
-	return r.DB.Create(inventory).Error
+func (r *inventoryRepository) FindByProductIdAndLocation(ctx context.Context, productId uint, location string) (*entity.Inventory, error) {
+	var result entity.Inventory
+	db := utils.GetTx(ctx, r.DB)
+	err := db.Where("product_id = ? AND location = ?", productId, location).First(&result).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (r *inventoryRepository) FindInBatches(ctx context.Context, size int, fn func(inventories []entity.Inventory) error) error {
+	var batch []entity.Inventory
+	db := utils.GetTx(ctx, r.DB)
+
+	return db.FindInBatches(&batch, size, func(tx *gorm.DB, _ int) error {
+		return fn(batch)
+	}).Error
+}
+
+func (r *inventoryRepository) CreateInventory(ctx context.Context, inventory *entity.Inventory) error {
+	db := utils.GetTx(ctx, r.DB)
+	inventory.Version = 1
+	return db.Create(inventory).Error
 }
 
-func (r *inventoryRepository) UpdateInventory(ctx *gin.Context, inventory *entity.Inventory) error {
-	return r.DB.Save(inventory).Error
+// UpdateInventory saves the inventory only if it is still at the version it
+// was read at, and moves it to the next version.
+func (r *inventoryRepository) UpdateInventory(ctx context.Context, inventory *entity.Inventory) error {
+	db := utils.GetTx(ctx, r.DB)
+	version := inventory.Version
+	inventory.Version++
+
+	result := db.Model(inventory).Where("version = ?", version).Select("*").Updates(inventory)
+	if result.Error != nil {
+		inventory.Version = version
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		inventory.Version = version
+		return errors.New(errorMessages.ErrPreconditionFailed)
+	}
+	return nil
 }
 
-func (r *inventoryRepository) UpdateInventoryForOrder(ctx *gin.Context, inventory *entity.Inventory, qty uint, action string) error {
+func (r *inventoryRepository) UpdateInventoryForOrder(ctx context.Context, inventory *entity.Inventory, qty uint, action string) error {
 	db := utils.GetTx(ctx, r.DB)
 
 	switch action {
 	case "create":
-		if err := db.Model(&inventory).Where("id = ?", inventory.ID).Update("stock", gorm.Expr("stock - ?", qty)).Error; err != nil {
+		if err := db.Model(&inventory).Where("id = ?", inventory.ID).Updates(map[string]interface{}{
+			"stock":   gorm.Expr("stock - ?", qty),
+			"version": gorm.Expr("version + 1"),
+		}).Error; err != nil {
 			return err
 		}
-	case "cancel":
-		if err := db.Model(&inventory).Where("id = ?", inventory.ID).Update("stock", gorm.Expr("stock + ?", qty)).Error; err != nil {
+	case "cancel", "restock":
+		if err := db.Model(&inventory).Where("id = ?", inventory.ID).Updates(map[string]interface{}{
+			"stock":   gorm.Expr("stock + ?", qty),
+			"version": gorm.Expr("version + 1"),
+		}).Error; err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (r *inventoryRepository) DeleteInventory(ctx *gin.Context, id uint) error {
-	var inventory entity.Inventory
-	if err := r.DB.First(&inventory, id).Error; err != nil {
-		return err
+// DeleteInventory deletes the inventory only if it is still at the version
+// it was read at.
+func (r *inventoryRepository) DeleteInventory(ctx context.Context, inventory *entity.Inventory) error {
+	db := utils.GetTx(ctx, r.DB)
+	result := db.Where("version = ?", inventory.Version).Delete(inventory)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return errors.New(errorMessages.ErrPreconditionFailed)
 	}
-	return r.DB.Delete(&inventory).Error
+	return result.Error
 }
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"go-trades/entity"
+	"go-trades/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const invoiceSequenceId = 1
+
+type invoiceRepository struct {
+	DB *gorm.DB
+}
+
+type InvoiceRepository interface {
+	FindByOrderId(ctx context.Context, orderId uint) (*entity.Invoice, error)
+	NextSequence(ctx context.Context) (uint, error)
+	CreateInvoice(ctx context.Context, invoice *entity.Invoice) error
+}
+
+func NewInvoiceRepository(db *gorm.DB) InvoiceRepository {
+	return &invoiceRepository{
+		DB: db,
+	}
+}
+
+func (r *invoiceRepository) FindByOrderId(ctx context.Context, orderId uint) (*entity.Invoice, error) {
+	var result entity.Invoice
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.Where("order_id = ?", orderId).First(&result).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// NextSequence must run inside a transaction; the row lock it takes is what
+// serialises concurrent payments.
+func (r *invoiceRepository) NextSequence(ctx context.Context) (uint, error) {
+	db := utils.GetTx(ctx, r.DB)
+
+	sequence := entity.InvoiceSequence{ID: invoiceSequenceId}
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&sequence).Error; err != nil {
+		return 0, err
+	}
+
+	if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&sequence, invoiceSequenceId).Error; err != nil {
+		return 0, err
+	}
+
+	sequence.Last++
+	if err := db.Model(&sequence).Update("last", sequence.Last).Error; err != nil {
+		return 0, err
+	}
+
+	return sequence.Last, nil
+}
+
+func (r *invoiceRepository) CreateInvoice(ctx context.Context, invoice *entity.Invoice) error {
+	db := utils.GetTx(ctx, r.DB)
+	return db.Create(invoice).Error
+}
@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"go-trades/entity"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LoginAttemptStore keeps failed login counters. The database store is
+// shared by every instance of the API; the memory store suits a single
+// instance and local development.
+type LoginAttemptStore interface {
+	// RecordFailure counts an attempt as failed before its outcome is known,
+	// so concurrent guesses cannot all get in under the limit, and locks key
+	// for backoff(failures). It counts nothing and returns false while key
+	// is still locked. Failures older than window are forgotten first.
+	RecordFailure(ctx context.Context, key string, now time.Time, window time.Duration, backoff func(failures int) time.Duration) (*entity.LoginAttempt, bool, error)
+	// Release takes back a failure recorded for an attempt that succeeded.
+	Release(ctx context.Context, key string, backoff func(failures int) time.Duration) error
+	Reset(ctx context.Context, key string) error
+}
+
+type loginAttemptStore struct {
+	DB *gorm.DB
+}
+
+func NewLoginAttemptStore(db *gorm.DB) LoginAttemptStore {
+	return &loginAttemptStore{
+		DB: db,
+	}
+}
+
+// RecordFailure locks the counter row so concurrent failures all count.
+func (s *loginAttemptStore) RecordFailure(ctx context.Context, key string, now time.Time, window time.Duration, backoff func(failures int) time.Duration) (*entity.LoginAttempt, bool, error) {
+	var attempt entity.LoginAttempt
+	counted := false
+
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		seed := entity.LoginAttempt{Key: key, LastFailedAt: now}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&seed).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("`key` = ?", key).First(&attempt).Error; err != nil {
+			return err
+		}
+
+		if attempt.LockedUntil != nil && attempt.LockedUntil.After(now) {
+			return nil
+		}
+
+		recordFailure(&attempt, now, window, backoff)
+		counted = true
+		return tx.Save(&attempt).Error
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return &attempt, counted, nil
+}
+
+func (s *loginAttemptStore) Release(ctx context.Context, key string, backoff func(failures int) time.Duration) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		var attempt entity.LoginAttempt
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("`key` = ?", key).First(&attempt).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		releaseFailure(&attempt, backoff)
+		return tx.Save(&attempt).Error
+	})
+}
+
+func (s *loginAttemptStore) Reset(ctx context.Context, key string) error {
+	return s.DB.Where("`key` = ?", key).Delete(&entity.LoginAttempt{}).Error
+}
+
+type memoryLoginAttemptStore struct {
+	mu       sync.Mutex
+	attempts map[string]*entity.LoginAttempt
+}
+
+func NewMemoryLoginAttemptStore() LoginAttemptStore {
+	return &memoryLoginAttemptStore{
+		attempts: make(map[string]*entity.LoginAttempt),
+	}
+}
+
+func (s *memoryLoginAttemptStore) RecordFailure(ctx context.Context, key string, now time.Time, window time.Duration, backoff func(failures int) time.Duration) (*entity.LoginAttempt, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.prune(now, window)
+
+	attempt, ok := s.attempts[key]
+	if !ok {
+		attempt = &entity.LoginAttempt{Key: key}
+		s.attempts[key] = attempt
+	}
+	if attempt.LockedUntil != nil && attempt.LockedUntil.After(now) {
+		result := *attempt
+		return &result, false, nil
+	}
+
+	recordFailure(attempt, now, window, backoff)
+	result := *attempt
+	return &result, true, nil
+}
+
+func (s *memoryLoginAttemptStore) Release(ctx context.Context, key string, backoff func(failures int) time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if attempt, ok := s.attempts[key]; ok {
+		releaseFailure(attempt, backoff)
+	}
+	return nil
+}
+
+func (s *memoryLoginAttemptStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.attempts, key)
+	return nil
+}
+
+// prune drops counters that have gone quiet and are no longer locked, so
+// the map does not grow with every username ever tried.
+func (s *memoryLoginAttemptStore) prune(now time.Time, window time.Duration) {
+	for key, attempt := range s.attempts {
+		if now.Sub(attempt.LastFailedAt) > window && (attempt.LockedUntil == nil || now.After(*attempt.LockedUntil)) {
+			delete(s.attempts, key)
+		}
+	}
+}
+
+func recordFailure(attempt *entity.LoginAttempt, now time.Time, window time.Duration, backoff func(failures int) time.Duration) {
+	if now.Sub(attempt.LastFailedAt) > window {
+		attempt.Failures = 0
+		attempt.LockedUntil = nil
+	}
+	attempt.Failures++
+	attempt.LastFailedAt = now
+
+	if delay := backoff(attempt.Failures); delay > 0 {
+		lockedUntil := now.Add(delay)
+		attempt.LockedUntil = &lockedUntil
+	}
+}
+
+// releaseFailure undoes recordFailure, lifting the lock when the remaining
+// failures would not have caused one.
+func releaseFailure(attempt *entity.LoginAttempt, backoff func(failures int) time.Duration) {
+	if attempt.Failures > 0 {
+		attempt.Failures--
+	}
+	if backoff(attempt.Failures) == 0 {
+		attempt.LockedUntil = nil
+	}
+}
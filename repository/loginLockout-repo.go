@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"go-trades/entity"
+	"go-trades/utils"
+
+	"gorm.io/gorm"
+)
+
+type loginLockoutRepository struct {
+	DB *gorm.DB
+}
+
+type LoginLockoutRepository interface {
+	FindAll(ctx context.Context, page, size int) ([]entity.LoginLockout, int64, error)
+	Create(ctx context.Context, lockout *entity.LoginLockout) error
+}
+
+func NewLoginLockoutRepository(db *gorm.DB) LoginLockoutRepository {
+	return &loginLockoutRepository{
+		DB: db,
+	}
+}
+
+func (r *loginLockoutRepository) FindAll(ctx context.Context, page, size int) ([]entity.LoginLockout, int64, error) {
+	var result []entity.LoginLockout
+	var total int64
+	db := utils.GetTx(ctx, r.DB)
+
+	if err := db.Model(&entity.LoginLockout{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * size
+	err := db.Order("id DESC").Offset(offset).Limit(size).Find(&result).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return result, total, nil
+}
+
+func (r *loginLockoutRepository) Create(ctx context.Context, lockout *entity.LoginLockout) error {
+	db := utils.GetTx(ctx, r.DB)
+	return db.Create(lockout).Error
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"go-trades/entity"
+	"go-trades/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type oidcRepository struct {
+	DB *gorm.DB
+}
+
+type OIDCRepository interface {
+	CreateState(ctx context.Context, state *entity.OIDCState) error
+	ConsumeState(ctx context.Context, state string) (*entity.OIDCState, error)
+	DeleteExpiredStates(ctx context.Context, now time.Time) error
+	FindIdentity(ctx context.Context, provider, subject string) (*entity.OIDCIdentity, error)
+	CreateIdentity(ctx context.Context, identity *entity.OIDCIdentity) error
+}
+
+func NewOIDCRepository(db *gorm.DB) OIDCRepository {
+	return &oidcRepository{
+		DB: db,
+	}
+}
+
+func (r *oidcRepository) CreateState(ctx context.Context, state *entity.OIDCState) error {
+	db := utils.GetTx(ctx, r.DB)
+	return db.Create(state).Error
+}
+
+// ConsumeState loads and deletes the state in one go. Only the request that
+// deletes the row gets it, so a callback URL cannot be replayed.
+func (r *oidcRepository) ConsumeState(ctx context.Context, state string) (*entity.OIDCState, error) {
+	var result entity.OIDCState
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.Where("state = ?", state).First(&result).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	deleted := db.Where("state = ?", state).Delete(&entity.OIDCState{})
+	if deleted.Error != nil {
+		return nil, deleted.Error
+	}
+	if deleted.RowsAffected != 1 {
+		return nil, nil
+	}
+	return &result, nil
+}
+
+func (r *oidcRepository) DeleteExpiredStates(ctx context.Context, now time.Time) error {
+	db := utils.GetTx(ctx, r.DB)
+	return db.Where("expires_at < ?", now).Delete(&entity.OIDCState{}).Error
+}
+
+func (r *oidcRepository) FindIdentity(ctx context.Context, provider, subject string) (*entity.OIDCIdentity, error) {
+	var result entity.OIDCIdentity
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.Where("provider = ? AND subject = ?", provider, subject).First(&result).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (r *oidcRepository) CreateIdentity(ctx context.Context, identity *entity.OIDCIdentity) error {
+	db := utils.GetTx(ctx, r.DB)
+	return db.Create(identity).Error
+}
@@ -1,12 +1,13 @@
 package repository
 
 import (
+	"context"
 	"errors"
 	"go-trades/entity"
 	"go-trades/utils"
 
-	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type orderRepository struct {
@@ -14,15 +15,20 @@ type orderRepository struct {
 }
 
 type OrderRepository interface {
-	FindAll(ctx *gin.Context, page, size int) ([]entity.Order, int64, error)
-	FindById(ctx *gin.Context, id uint) (*entity.Order, error)
-	FindByStatus(ctx *gin.Context, page, size int, status uint) ([]entity.Order, int64, error)
-	FindAllByUserId(ctx *gin.Context, userId uint, page, size int) ([]entity.Order, int64, error)
-	FindByUserIdWithId(ctx *gin.Context, userId uint, id uint) (*entity.Order, error)
-	FindAllByUserIdWithStatus(ctx *gin.Context, userId uint, page, size int, status uint) ([]entity.Order, int64, error)
-	CreateOrder(ctx *gin.Context, order *entity.Order) error
-	UpdateOrder(ctx *gin.Context, order *entity.Order) error
-	DeleteOrder(ctx *gin.Context, id uint) error
+	FindAll(ctx context.Context, page, size int) ([]entity.Order, int64, error)
+	FindById(ctx context.Context, id uint) (*entity.Order, error)
+	FindByIdForUpdate(ctx context.Context, id uint) (*entity.Order, error)
+	FindByStatus(ctx context.Context, page, size int, status uint) ([]entity.Order, int64, error)
+	FindAllByUserId(ctx context.Context, userId uint, page, size int) ([]entity.Order, int64, error)
+	FindByUserIdWithId(ctx context.Context, userId uint, id uint) (*entity.Order, error)
+	FindByUserIdWithIdForUpdate(ctx context.Context, userId uint, id uint) (*entity.Order, error)
+	FindAllByUserIdWithStatus(ctx context.Context, userId uint, page, size int, status uint) ([]entity.Order, int64, error)
+	CreateOrder(ctx context.Context, order *entity.Order) error
+	UpdateOrder(ctx context.Context, order *entity.Order) error
+	ReplaceOrderDetails(ctx context.Context, orderId uint, orderDetails []entity.OrderDetail) error
+	FindBackorderedDetails(ctx context.Context, productId uint, statuses []uint) ([]entity.OrderDetail, error)
+	UpdateOrderDetail(ctx context.Context, orderDetail *entity.OrderDetail) error
+	DeleteOrder(ctx context.Context, id uint) error
 }
 
 func NewOrderRepository(db *gorm.DB) OrderRepository {
@@ -31,16 +37,17 @@ func NewOrderRepository(db *gorm.DB) OrderRepository {
 	}
 }
 
-func (r *orderRepository) FindAll(ctx *gin.Context, page, size int) ([]entity.Order, int64, error) {
+func (r *orderRepository) FindAll(ctx context.Context, page, size int) ([]entity.Order, int64, error) {
 	var result []entity.Order
 	var total int64
+	db := utils.GetTx(ctx, r.DB)
 
-	if err := r.DB.Model(&entity.Order{}).Count(&total).Error; err != nil {
+	if err := db.Model(&entity.Order{}).Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
 	offset := (page - 1) * size
-	err := r.DB.Preload("OrderDetails").Offset(offset).Limit(size).Find(&result).Error
+	err := db.Preload("OrderDetails").Offset(offset).Limit(size).Find(&result).Error
 	if err != nil {
 		return nil, 0, err
 	}
@@ -48,11 +55,33 @@ func (r *orderRepository) FindAll(ctx *gin.Context, page, size int) ([]entity.Or
 	return result, total, nil
 }
 
-func (r *orderRepository) FindById(ctx *gin.Context, id uint) (*entity.Order, error) {
+func (r *orderRepository) FindById(ctx context.Context, id uint) (*entity.Order, error) {
 	var result entity.Order
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.Preload("OrderDetails").Where("id = ?", id).First(&result).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
 
-	err := r.DB.Preload("OrderDetails").Where("id = ?", id).First(&result).Error
+// FindByIdForUpdate locks the order and its lines until the transaction in
+// ctx ends, so concurrent changes to the same order run one after another.
+func (r *orderRepository) FindByIdForUpdate(ctx context.Context, id uint) (*entity.Order, error) {
+	var result entity.Order
+	db := utils.GetTx(ctx, r.DB)
 
+	err := db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Preload("OrderDetails", func(db *gorm.DB) *gorm.DB {
+			return db.Clauses(clause.Locking{Strength: "UPDATE"})
+		}).
+		Where("id = ?", id).
+		First(&result).Error
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, nil
 	}
@@ -62,17 +91,18 @@ func (r *orderRepository) FindById(ctx *gin.Context, id uint) (*entity.Order, er
 	return &result, nil
 }
 
-func (r *orderRepository) FindByStatus(ctx *gin.Context, page, size int, status uint) ([]entity.Order, int64, error) {
+func (r *orderRepository) FindByStatus(ctx context.Context, page, size int, status uint) ([]entity.Order, int64, error) {
 	var result []entity.Order
 	var total int64
+	db := utils.GetTx(ctx, r.DB)
 
-	if err := r.DB.Model(&entity.Order{}).Where("status = ?", status).Count(&total).Error; err != nil {
+	if err := db.Model(&entity.Order{}).Where("status = ?", status).Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
 	offset := (page - 1) * size
 
-	err := r.DB.Preload("OrderDetails").Offset(offset).Limit(size).Where("status = ?", status).Find(&result).Error
+	err := db.Preload("OrderDetails").Offset(offset).Limit(size).Where("status = ?", status).Find(&result).Error
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, 0, nil
 	}
@@ -82,16 +112,17 @@ func (r *orderRepository) FindByStatus(ctx *gin.Context, page, size int, status
 	return result, total, nil
 }
 
-func (r *orderRepository) FindAllByUserId(ctx *gin.Context, userId uint, page, size int) ([]entity.Order, int64, error) {
+func (r *orderRepository) FindAllByUserId(ctx context.Context, userId uint, page, size int) ([]entity.Order, int64, error) {
 	var result []entity.Order
 	var total int64
+	db := utils.GetTx(ctx, r.DB)
 
-	if err := r.DB.Model(&entity.Order{}).Where("user_id = ?", userId).Count(&total).Error; err != nil {
+	if err := db.Model(&entity.Order{}).Where("user_id = ?", userId).Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
 	offset := (page - 1) * size
-	err := r.DB.Preload("OrderDetails").Where("user_id = ?", userId).Offset(offset).Limit(size).Find(&result).Error
+	err := db.Preload("OrderDetails").Where("user_id = ?", userId).Offset(offset).Limit(size).Find(&result).Error
 	if err != nil {
 		return nil, 0, err
 	}
@@ -99,11 +130,33 @@ func (r *orderRepository) FindAllByUserId(ctx *gin.Context, userId uint, page, s
 	return result, total, nil
 }
 
-func (r *orderRepository) FindByUserIdWithId(ctx *gin.Context, userId uint, id uint) (*entity.Order, error) {
+func (r *orderRepository) FindByUserIdWithId(ctx context.Context, userId uint, id uint) (*entity.Order, error) {
 	var result entity.Order
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.Preload("OrderDetails").Where("user_id = ? AND id = ?", userId, id).First(&result).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
 
-	err := r.DB.Preload("OrderDetails").Where("user_id = ? AND id = ?", userId, id).First(&result).Error
+// FindByUserIdWithIdForUpdate is FindByIdForUpdate for an order the user
+// owns.
+func (r *orderRepository) FindByUserIdWithIdForUpdate(ctx context.Context, userId uint, id uint) (*entity.Order, error) {
+	var result entity.Order
+	db := utils.GetTx(ctx, r.DB)
 
+	err := db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Preload("OrderDetails", func(db *gorm.DB) *gorm.DB {
+			return db.Clauses(clause.Locking{Strength: "UPDATE"})
+		}).
+		Where("user_id = ? AND id = ?", userId, id).
+		First(&result).Error
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, nil
 	}
@@ -113,17 +166,18 @@ func (r *orderRepository) FindByUserIdWithId(ctx *gin.Context, userId uint, id u
 	return &result, nil
 }
 
-func (r *orderRepository) FindAllByUserIdWithStatus(ctx *gin.Context, userId uint, page, size int, status uint) ([]entity.Order, int64, error) {
+func (r *orderRepository) FindAllByUserIdWithStatus(ctx context.Context, userId uint, page, size int, status uint) ([]entity.Order, int64, error) {
 	var result []entity.Order
 	var total int64
+	db := utils.GetTx(ctx, r.DB)
 
-	if err := r.DB.Model(&entity.Order{}).Where("user_id = ? AND status = ?", userId, status).Count(&total).Error; err != nil {
+	if err := db.Model(&entity.Order{}).Where("user_id = ? AND status = ?", userId, status).Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
 	offset := (page - 1) * size
 
-	err := r.DB.Preload("OrderDetails").Where("user_id = ? AND status = ?", userId, status).Offset(offset).Limit(size).Find(&result).Error
+	err := db.Preload("OrderDetails").Where("user_id = ? AND status = ?", userId, status).Offset(offset).Limit(size).Find(&result).Error
 	if err != nil {
 		return nil, 0, err
 	}
@@ -131,17 +185,58 @@ func (r *orderRepository) FindAllByUserIdWithStatus(ctx *gin.Context, userId uin
 	return result, total, nil
 }
 
-func (r *orderRepository) CreateOrder(ctx *gin.Context, order *entity.Order) error {
+func (r *orderRepository) CreateOrder(ctx context.Context, order *entity.Order) error {
 	db := utils.GetTx(ctx, r.DB)
 	return db.Create(order).Error
 }
 
-func (r *orderRepository) UpdateOrder(ctx *gin.Context, order *entity.Order) error {
+func (r *orderRepository) UpdateOrder(ctx context.Context, order *entity.Order) error {
 	db := utils.GetTx(ctx, r.DB)
 	return db.Save(order).Error
 }
 
-func (r *orderRepository) DeleteOrder(ctx *gin.Context, id uint) error {
+func (r *orderRepository) ReplaceOrderDetails(ctx context.Context, orderId uint, orderDetails []entity.OrderDetail) error {
+	db := utils.GetTx(ctx, r.DB)
+
+	if err := db.Where("order_id = ?", orderId).Delete(&entity.OrderDetail{}).Error; err != nil {
+		return err
+	}
+
+	for i := range orderDetails {
+		orderDetails[i].OrderId = orderId
+	}
+	return db.Create(&orderDetails).Error
+}
+
+// FindBackorderedDetails returns the lines still waiting for stock of the
+// product in orders with one of the given statuses, oldest order first.
+func (r *orderRepository) FindBackorderedDetails(ctx context.Context, productId uint, statuses []uint) ([]entity.OrderDetail, error) {
+	var result []entity.OrderDetail
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.Model(&entity.OrderDetail{}).
+		Joins("JOIN orders ON orders.id = order_details.order_id").
+		Where("order_details.product_id = ? AND order_details.backordered_qty > 0", productId).
+		Where("orders.status IN ?", statuses).
+		Order("orders.date, orders.id").
+		Find(&result).Error
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *orderRepository) UpdateOrderDetail(ctx context.Context, orderDetail *entity.OrderDetail) error {
+	db := utils.GetTx(ctx, r.DB)
+	return db.Model(&entity.OrderDetail{}).
+		Where("order_id = ? AND product_id = ?", orderDetail.OrderId, orderDetail.ProductId).
+		Updates(map[string]interface{}{
+			"backordered_qty": orderDetail.BackorderedQty,
+			"expected_at":     orderDetail.ExpectedAt,
+		}).Error
+}
+
+func (r *orderRepository) DeleteOrder(ctx context.Context, id uint) error {
 	db := utils.GetTx(ctx, r.DB)
 
 	var order entity.Order
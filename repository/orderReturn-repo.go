@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"go-trades/entity"
+	"go-trades/utils"
+
+	"gorm.io/gorm"
+)
+
+type orderReturnRepository struct {
+	DB *gorm.DB
+}
+
+type OrderReturnRepository interface {
+	FindAll(ctx context.Context, page, size int) ([]entity.OrderReturn, int64, error)
+	FindById(ctx context.Context, id uint) (*entity.OrderReturn, error)
+	FindAllByUserId(ctx context.Context, userId uint, page, size int) ([]entity.OrderReturn, int64, error)
+	FindByUserIdWithId(ctx context.Context, userId uint, id uint) (*entity.OrderReturn, error)
+	FindAllByOrderId(ctx context.Context, orderId uint) ([]entity.OrderReturn, error)
+	CreateOrderReturn(ctx context.Context, orderReturn *entity.OrderReturn) error
+	UpdateOrderReturn(ctx context.Context, orderReturn *entity.OrderReturn) error
+}
+
+func NewOrderReturnRepository(db *gorm.DB) OrderReturnRepository {
+	return &orderReturnRepository{
+		DB: db,
+	}
+}
+
+func (r *orderReturnRepository) FindAll(ctx context.Context, page, size int) ([]entity.OrderReturn, int64, error) {
+	var result []entity.OrderReturn
+	var total int64
+	db := utils.GetTx(ctx, r.DB)
+
+	if err := db.Model(&entity.OrderReturn{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * size
+	err := db.Preload("OrderReturnItems").Offset(offset).Limit(size).Find(&result).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return result, total, nil
+}
+
+func (r *orderReturnRepository) FindById(ctx context.Context, id uint) (*entity.OrderReturn, error) {
+	var result entity.OrderReturn
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.Preload("OrderReturnItems").Where("id = ?", id).First(&result).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (r *orderReturnRepository) FindAllByUserId(ctx context.Context, userId uint, page, size int) ([]entity.OrderReturn, int64, error) {
+	var result []entity.OrderReturn
+	var total int64
+	db := utils.GetTx(ctx, r.DB)
+
+	if err := db.Model(&entity.OrderReturn{}).Where("user_id = ?", userId).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * size
+	err := db.Preload("OrderReturnItems").Where("user_id = ?", userId).Offset(offset).Limit(size).Find(&result).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return result, total, nil
+}
+
+func (r *orderReturnRepository) FindByUserIdWithId(ctx context.Context, userId uint, id uint) (*entity.OrderReturn, error) {
+	var result entity.OrderReturn
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.Preload("OrderReturnItems").Where("user_id = ? AND id = ?", userId, id).First(&result).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (r *orderReturnRepository) FindAllByOrderId(ctx context.Context, orderId uint) ([]entity.OrderReturn, error) {
+	var result []entity.OrderReturn
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.Preload("OrderReturnItems").Where("order_id = ?", orderId).Find(&result).Error
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *orderReturnRepository) CreateOrderReturn(ctx context.Context, orderReturn *entity.OrderReturn) error {
+	db := utils.GetTx(ctx, r.DB)
+	return db.Create(orderReturn).Error
+}
+
+func (r *orderReturnRepository) UpdateOrderReturn(ctx context.Context, orderReturn *entity.OrderReturn) error {
+	db := utils.GetTx(ctx, r.DB)
+	return db.Session(&gorm.Session{FullSaveAssociations: true}).Save(orderReturn).Error
+}
@@ -1,10 +1,11 @@
 package repository
 
 import (
+	"context"
+	"errors"
 	"go-trades/entity"
 	"go-trades/utils"
 
-	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
@@ -13,9 +14,11 @@ type paymentRepository struct {
 }
 
 type PaymentRepository interface {
-	FindAll(ctx *gin.Context, page, size int) ([]entity.Payment, int64, error)
-	CreatePayment(ctx *gin.Context, payment *entity.Payment) error
-	FindAllByUserId(ctx *gin.Context, userId uint, page, size int) ([]entity.Payment, int64, error)
+	FindAll(ctx context.Context, page, size int) ([]entity.Payment, int64, error)
+	FindByOrderId(ctx context.Context, orderId uint) (*entity.Payment, error)
+	SumRefunds(ctx context.Context, paymentId uint) (uint, error)
+	CreatePayment(ctx context.Context, payment *entity.Payment) error
+	FindAllByUserId(ctx context.Context, userId uint, page, size int) ([]entity.Payment, int64, error)
 }
 
 func NewPaymentRepository(db *gorm.DB) PaymentRepository {
@@ -24,16 +27,17 @@ func NewPaymentRepository(db *gorm.DB) PaymentRepository {
 	}
 }
 
-func (r *paymentRepository) FindAll(ctx *gin.Context, page, size int) ([]entity.Payment, int64, error) {
+func (r *paymentRepository) FindAll(ctx context.Context, page, size int) ([]entity.Payment, int64, error) {
 	var result []entity.Payment
 	var total int64
+	db := utils.GetTx(ctx, r.DB)
 
-	if err := r.DB.Model(&entity.Payment{}).Count(&total).Error; err != nil {
+	if err := db.Model(&entity.Payment{}).Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
 	offset := (page - 1) * size
-	err := r.DB.Offset(offset).Limit(size).Find(&result).Error
+	err := db.Offset(offset).Limit(size).Find(&result).Error
 	if err != nil {
 		return nil, 0, err
 	}
@@ -41,11 +45,12 @@ func (r *paymentRepository) FindAll(ctx *gin.Context, page, size int) ([]entity.
 	return result, total, nil
 }
 
-func (r *paymentRepository) FindAllByUserId(ctx *gin.Context, userId uint, page, size int) ([]entity.Payment, int64, error) {
+func (r *paymentRepository) FindAllByUserId(ctx context.Context, userId uint, page, size int) ([]entity.Payment, int64, error) {
 	var result []entity.Payment
 	var total int64
+	db := utils.GetTx(ctx, r.DB)
 
-	if err := r.DB.Model(&entity.Payment{}).
+	if err := db.Model(&entity.Payment{}).
 		Joins("JOIN orders ON orders.id = payments.order_id").
 		Where("orders.user_id = ?", userId).
 		Count(&total).Error; err != nil {
@@ -53,7 +58,7 @@ func (r *paymentRepository) FindAllByUserId(ctx *gin.Context, userId uint, page,
 	}
 
 	offset := (page - 1) * size
-	err := r.DB.Model(&entity.Payment{}).
+	err := db.Model(&entity.Payment{}).
 		Joins("JOIN orders ON orders.id = payments.order_id").
 		Where("orders.user_id = ?", userId).
 		Offset(offset).
@@ -66,7 +71,32 @@ func (r *paymentRepository) FindAllByUserId(ctx *gin.Context, userId uint, page,
 	return result, total, nil
 }
 
-func (r *paymentRepository) CreatePayment(ctx *gin.Context, payment *entity.Payment) error {
+func (r *paymentRepository) FindByOrderId(ctx context.Context, orderId uint) (*entity.Payment, error) {
+	var result entity.Payment
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.Where("order_id = ? AND refund_of IS NULL", orderId).First(&result).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (r *paymentRepository) SumRefunds(ctx context.Context, paymentId uint) (uint, error) {
+	var total uint
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.Model(&entity.Payment{}).
+		Where("refund_of = ?", paymentId).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&total).Error
+	return total, err
+}
+
+func (r *paymentRepository) CreatePayment(ctx context.Context, payment *entity.Payment) error {
 	db := utils.GetTx(ctx, r.DB)
 	return db.Create(payment).Error
 }
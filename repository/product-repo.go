@@ -1,12 +1,14 @@
 package repository
 
 import (
+	"context"
 	"errors"
 	"go-trades/entity"
 	"go-trades/utils"
+	errorMessages "go-trades/utils/error-messages"
 
-	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type productRepository struct {
@@ -14,16 +16,19 @@ type productRepository struct {
 }
 
 type ProductRepository interface {
-	FindAll(ctx *gin.Context, page, size int) ([]entity.Product, int64, error)
-	FindAllWithStock(ctx *gin.Context, page, size int) ([]entity.ProductDataResponse, int64, error)
-	FindByCategoryId(ctx *gin.Context, page, size int, id uint) ([]entity.Product, int64, error)
-	FindByCategoryIdWithStock(ctx *gin.Context, page, size int, id uint) ([]entity.ProductDataResponse, int64, error)
-	FindById(ctx *gin.Context, id uint) (*entity.Product, error)
-	FindByIdWithStock(ctx *gin.Context, id uint) (*entity.ProductDataResponse, error)
-	FindByName(ctx *gin.Context, name string) (*entity.Product, error)
-	CreateProduct(ctx *gin.Context, product *entity.Product) error
-	UpdateProduct(ctx *gin.Context, product *entity.Product) error
-	DeleteProduct(ctx *gin.Context, id uint) error
+	FindAll(ctx context.Context, page, size int) ([]entity.Product, int64, error)
+	FindAllWithStock(ctx context.Context, page, size int) ([]entity.ProductDataResponse, int64, error)
+	FindByCategoryId(ctx context.Context, page, size int, id uint) ([]entity.Product, int64, error)
+	FindByCategoryIdWithStock(ctx context.Context, page, size int, id uint) ([]entity.ProductDataResponse, int64, error)
+	FindById(ctx context.Context, id uint) (*entity.Product, error)
+	FindByIdWithStock(ctx context.Context, id uint) (*entity.ProductDataResponse, error)
+	FindByName(ctx context.Context, name string) (*entity.Product, error)
+	FindBySku(ctx context.Context, sku string) (*entity.Product, error)
+	FindAllByIds(ctx context.Context, ids []uint) ([]entity.Product, error)
+	FindInBatches(ctx context.Context, size int, fn func(products []entity.Product) error) error
+	CreateProduct(ctx context.Context, product *entity.Product) error
+	UpdateProduct(ctx context.Context, product *entity.Product) error
+	DeleteProduct(ctx context.Context, product *entity.Product) error
 }
 
 func NewProductRepository(db *gorm.DB) ProductRepository {
@@ -32,16 +37,17 @@ func NewProductRepository(db *gorm.DB) ProductRepository {
 	}
 }
 
-func (r *productRepository) FindAll(ctx *gin.Context, page, size int) ([]entity.Product, int64, error) {
+func (r *productRepository) FindAll(ctx context.Context, page, size int) ([]entity.Product, int64, error) {
 	var result []entity.Product
 	var total int64
+	db := utils.GetTx(ctx, r.DB)
 
-	if err := r.DB.Model(&entity.Product{}).Count(&total).Error; err != nil {
+	if err := db.Model(&entity.Product{}).Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
 	offset := (page - 1) * size
-	err := r.DB.Offset(offset).Limit(size).Find(&result).Error
+	err := db.Offset(offset).Limit(size).Find(&result).Error
 	if err != nil {
 		return nil, 0, err
 	}
@@ -49,17 +55,18 @@ func (r *productRepository) FindAll(ctx *gin.Context, page, size int) ([]entity.
 	return result, total, nil
 }
 
-func (r *productRepository) FindAllWithStock(ctx *gin.Context, page, size int) ([]entity.ProductDataResponse, int64, error) {
+func (r *productRepository) FindAllWithStock(ctx context.Context, page, size int) ([]entity.ProductDataResponse, int64, error) {
 	var result []entity.ProductDataResponse
 	var total int64
+	db := utils.GetTx(ctx, r.DB)
 
-	if err := r.DB.Model(&entity.Product{}).Count(&total).Error; err != nil {
+	if err := db.Model(&entity.Product{}).Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
 	offset := (page - 1) * size
 
-	err := r.DB.Model(&entity.Product{}).
+	err := db.Model(&entity.Product{}).
 		Select("products.*, COALESCE(SUM(inventories.stock), 0) as stock").
 		Joins("LEFT JOIN inventories ON inventories.product_id = products.id").
 		Group("products.id").
@@ -74,34 +81,36 @@ func (r *productRepository) FindAllWithStock(ctx *gin.Context, page, size int) (
 	return result, total, nil
 }
 
-func (r *productRepository) FindByCategoryId(ctx *gin.Context, page, size int, id uint) ([]entity.Product, int64, error) {
+func (r *productRepository) FindByCategoryId(ctx context.Context, page, size int, id uint) ([]entity.Product, int64, error) {
 	var result []entity.Product
 	var total int64
+	db := utils.GetTx(ctx, r.DB)
 
-	if err := r.DB.Model(&entity.Product{}).Where("category_id = ?", id).Count(&total).Error; err != nil {
+	if err := db.Model(&entity.Product{}).Where("category_id = ?", id).Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
 	offset := (page - 1) * size
 
-	err := r.DB.Offset(offset).Limit(size).Where("category_id = ?", id).Find(&result).Error
+	err := db.Offset(offset).Limit(size).Where("category_id = ?", id).Find(&result).Error
 	if err != nil {
 		return nil, 0, err
 	}
 	return result, total, nil
 }
 
-func (r *productRepository) FindByCategoryIdWithStock(ctx *gin.Context, page, size int, id uint) ([]entity.ProductDataResponse, int64, error) {
+func (r *productRepository) FindByCategoryIdWithStock(ctx context.Context, page, size int, id uint) ([]entity.ProductDataResponse, int64, error) {
 	var result []entity.ProductDataResponse
 	var total int64
+	db := utils.GetTx(ctx, r.DB)
 
-	if err := r.DB.Model(&entity.Product{}).Where("category_id = ?", id).Count(&total).Error; err != nil {
+	if err := db.Model(&entity.Product{}).Where("category_id = ?", id).Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
 	offset := (page - 1) * size
 
-	err := r.DB.Model(&entity.Product{}).
+	err := db.Model(&entity.Product{}).
 		Select("products.*, COALESCE(SUM(inventories.stock), 0) as stock").
 		Joins("LEFT JOIN inventories ON inventories.product_id = products.id").
 		Where("products.category_id = ? AND inventories.deleted_at IS NULL", id).
@@ -116,7 +125,7 @@ func (r *productRepository) FindByCategoryIdWithStock(ctx *gin.Context, page, si
 	return result, total, nil
 }
 
-func (r *productRepository) FindById(ctx *gin.Context, id uint) (*entity.Product, error) {
+func (r *productRepository) FindById(ctx context.Context, id uint) (*entity.Product, error) {
 	var result entity.Product
 	db := utils.GetTx(ctx, r.DB)
 
@@ -130,7 +139,7 @@ func (r *productRepository) FindById(ctx *gin.Context, id uint) (*entity.Product
 	return &result, nil
 }
 
-func (r *productRepository) FindByIdWithStock(ctx *gin.Context, id uint) (*entity.ProductDataResponse, error) {
+func (r *productRepository) FindByIdWithStock(ctx context.Context, id uint) (*entity.ProductDataResponse, error) {
 	var result entity.ProductDataResponse
 	db := utils.GetTx(ctx, r.DB)
 
@@ -149,9 +158,11 @@ func (r *productRepository) FindByIdWithStock(ctx *gin.Context, id uint) (*entit
 	return &result, nil
 }
 
-func (r *productRepository) FindByName(ctx *gin.Context, name string) (*entity.Product, error) {
+func (r *productRepository) FindByName(ctx context.Context, name string) (*entity.Product, error) {
 	var result entity.Product
-	err := r.DB.Where("name = ?", name).First(&result).Error
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.Where("name = ?", name).First(&result).Error
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, nil
 	}
@@ -161,18 +172,73 @@ func (r *productRepository) FindByName(ctx *gin.Context, name string) (*entity.P
 	return &result, nil
 }
 
-func (r *productRepository) CreateProduct(ctx *gin.Context, product *entity.Product) error {
-	return r.DB.Create(product).Error
+func (r *productRepository) FindBySku(ctx context.Context, sku string) (*entity.Product, error) {
+	var result entity.Product
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.Where("sku = ?", sku).First(&result).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
 }
 
-func (r *productRepository) UpdateProduct(ctx *gin.Context, product *entity.Product) error {
-	return r.DB.Save(product).Error
+func (r *productRepository) FindAllByIds(ctx context.Context, ids []uint) ([]entity.Product, error) {
+	var result []entity.Product
+	db := utils.GetTx(ctx, r.DB)
+
+	if err := db.Where("id IN ?", ids).Find(&result).Error; err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// FindInBatches walks every product in id order, size rows at a time, so
+// exports can stream the catalogue without loading it all at once.
+func (r *productRepository) FindInBatches(ctx context.Context, size int, fn func(products []entity.Product) error) error {
+	var batch []entity.Product
+	db := utils.GetTx(ctx, r.DB)
+
+	return db.FindInBatches(&batch, size, func(tx *gorm.DB, _ int) error {
+		return fn(batch)
+	}).Error
+}
+
+func (r *productRepository) CreateProduct(ctx context.Context, product *entity.Product) error {
+	db := utils.GetTx(ctx, r.DB)
+	product.Version = 1
+	return db.Create(product).Error
 }
 
-func (r *productRepository) DeleteProduct(ctx *gin.Context, id uint) error {
-	var product entity.Product
-	if err := r.DB.First(&product, id).Error; err != nil {
-		return err
+// UpdateProduct saves the product only if it is still at the version it was
+// read at, and moves it to the next version.
+func (r *productRepository) UpdateProduct(ctx context.Context, product *entity.Product) error {
+	db := utils.GetTx(ctx, r.DB)
+	version := product.Version
+	product.Version++
+
+	result := db.Model(product).Where("version = ?", version).Select("*").Omit(clause.Associations).Updates(product)
+	if result.Error != nil {
+		product.Version = version
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		product.Version = version
+		return errors.New(errorMessages.ErrPreconditionFailed)
+	}
+	return nil
+}
+
+// DeleteProduct deletes the product only if it is still at the version it
+// was read at.
+func (r *productRepository) DeleteProduct(ctx context.Context, product *entity.Product) error {
+	db := utils.GetTx(ctx, r.DB)
+	result := db.Where("version = ?", product.Version).Delete(product)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return errors.New(errorMessages.ErrPreconditionFailed)
 	}
-	return r.DB.Delete(&product).Error
+	return result.Error
 }
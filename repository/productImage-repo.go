@@ -1,10 +1,10 @@
 package repository
 
 import (
+	"context"
 	"go-trades/entity"
 	"go-trades/utils"
 
-	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
@@ -13,8 +13,8 @@ type productImageRepository struct {
 }
 
 type ProductImageRepository interface {
-	CreateProductImage(ctx *gin.Context, productImage *entity.ProductImage) error
-	FindAllByProductId(ctx *gin.Context, productId uint) ([]entity.ProductImage, error)
+	CreateProductImage(ctx context.Context, productImage *entity.ProductImage) error
+	FindAllByProductId(ctx context.Context, productId uint) ([]entity.ProductImage, error)
 }
 
 func NewProductImageRepository(db *gorm.DB) ProductImageRepository {
@@ -23,12 +23,12 @@ func NewProductImageRepository(db *gorm.DB) ProductImageRepository {
 	}
 }
 
-func (r *productImageRepository) CreateProductImage(ctx *gin.Context, productImage *entity.ProductImage) error {
+func (r *productImageRepository) CreateProductImage(ctx context.Context, productImage *entity.ProductImage) error {
 	db := utils.GetTx(ctx, r.DB)
 	return db.Create(productImage).Error
 }
 
-func (r *productImageRepository) FindAllByProductId(ctx *gin.Context, productId uint) ([]entity.ProductImage, error) {
+func (r *productImageRepository) FindAllByProductId(ctx context.Context, productId uint) ([]entity.ProductImage, error) {
 	var productImages []entity.ProductImage
 	db := utils.GetTx(ctx, r.DB)
 	err := db.Where("product_id = ?", productId).Find(&productImages).Error
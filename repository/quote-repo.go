@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"go-trades/entity"
+	"go-trades/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type quoteRepository struct {
+	DB *gorm.DB
+}
+
+type QuoteRepository interface {
+	FindAll(ctx context.Context, page, size int) ([]entity.Quote, int64, error)
+	FindById(ctx context.Context, id uint) (*entity.Quote, error)
+	FindAllByUserId(ctx context.Context, userId uint, page, size int) ([]entity.Quote, int64, error)
+	FindByUserIdWithId(ctx context.Context, userId, id uint) (*entity.Quote, error)
+	CreateQuote(ctx context.Context, quote *entity.Quote) error
+	UpdateQuote(ctx context.Context, quote *entity.Quote) error
+	UpdateQuoteItem(ctx context.Context, item *entity.QuoteItem) error
+	UpdateStatus(ctx context.Context, id uint, from, to uint) (bool, error)
+	ExpireSent(ctx context.Context, now time.Time, from, to uint) error
+}
+
+func NewQuoteRepository(db *gorm.DB) QuoteRepository {
+	return &quoteRepository{
+		DB: db,
+	}
+}
+
+func (r *quoteRepository) FindAll(ctx context.Context, page, size int) ([]entity.Quote, int64, error) {
+	var result []entity.Quote
+	var total int64
+	db := utils.GetTx(ctx, r.DB)
+
+	if err := db.Model(&entity.Quote{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * size
+	err := db.Preload("QuoteItems").Order("id DESC").Offset(offset).Limit(size).Find(&result).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return result, total, nil
+}
+
+func (r *quoteRepository) FindById(ctx context.Context, id uint) (*entity.Quote, error) {
+	var result entity.Quote
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.Preload("QuoteItems").Where("id = ?", id).First(&result).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (r *quoteRepository) FindAllByUserId(ctx context.Context, userId uint, page, size int) ([]entity.Quote, int64, error) {
+	var result []entity.Quote
+	var total int64
+	db := utils.GetTx(ctx, r.DB)
+
+	if err := db.Model(&entity.Quote{}).Where("user_id = ?", userId).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * size
+	err := db.Preload("QuoteItems").Where("user_id = ?", userId).Order("id DESC").Offset(offset).Limit(size).Find(&result).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return result, total, nil
+}
+
+func (r *quoteRepository) FindByUserIdWithId(ctx context.Context, userId, id uint) (*entity.Quote, error) {
+	var result entity.Quote
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.Preload("QuoteItems").Where("user_id = ? AND id = ?", userId, id).First(&result).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (r *quoteRepository) CreateQuote(ctx context.Context, quote *entity.Quote) error {
+	db := utils.GetTx(ctx, r.DB)
+	return db.Create(quote).Error
+}
+
+func (r *quoteRepository) UpdateQuote(ctx context.Context, quote *entity.Quote) error {
+	db := utils.GetTx(ctx, r.DB)
+	return db.Omit("QuoteItems").Save(quote).Error
+}
+
+func (r *quoteRepository) UpdateQuoteItem(ctx context.Context, item *entity.QuoteItem) error {
+	db := utils.GetTx(ctx, r.DB)
+	return db.Save(item).Error
+}
+
+// UpdateStatus moves a quote from one status to another only if it is still
+// in the expected one, so two concurrent accepts cannot both win.
+func (r *quoteRepository) UpdateStatus(ctx context.Context, id uint, from, to uint) (bool, error) {
+	db := utils.GetTx(ctx, r.DB)
+
+	result := db.Model(&entity.Quote{}).Where("id = ? AND status = ?", id, from).Update("status", to)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *quoteRepository) ExpireSent(ctx context.Context, now time.Time, from, to uint) error {
+	db := utils.GetTx(ctx, r.DB)
+	return db.Model(&entity.Quote{}).
+		Where("status = ? AND valid_until < ?", from, now).
+		Update("status", to).Error
+}
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"go-trades/entity"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RateLimitStore keeps token buckets. The memory store suits a single
+// instance; the database store is shared by every instance of the API.
+type RateLimitStore interface {
+	// Take spends a token from the bucket for key, starting from a full
+	// bucket when there is none.
+	Take(ctx context.Context, key string, limit entity.RateLimit, now time.Time) (*entity.RateLimitResult, error)
+	// DeleteFull drops buckets that have refilled completely, since they
+	// behave the same as no bucket at all.
+	DeleteFull(ctx context.Context) error
+}
+
+type rateLimitStore struct {
+	DB *gorm.DB
+}
+
+func NewRateLimitStore(db *gorm.DB) RateLimitStore {
+	return &rateLimitStore{
+		DB: db,
+	}
+}
+
+// Take locks the bucket row so concurrent requests from one client cannot
+// spend the same token.
+func (s *rateLimitStore) Take(ctx context.Context, key string, limit entity.RateLimit, now time.Time) (*entity.RateLimitResult, error) {
+	var result entity.RateLimitResult
+
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		seed := entity.RateLimitBucket{Key: key, Tokens: float64(limit.Burst), CheckedAt: now, FullAt: now}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&seed).Error; err != nil {
+			return err
+		}
+
+		var bucket entity.RateLimitBucket
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("`key` = ?", key).First(&bucket).Error; err != nil {
+			return err
+		}
+
+		result = bucket.Take(limit, now)
+		return tx.Save(&bucket).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (s *rateLimitStore) DeleteFull(ctx context.Context) error {
+	return s.DB.Where("full_at < ?", time.Now()).Delete(&entity.RateLimitBucket{}).Error
+}
+
+type memoryRateLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string]*entity.RateLimitBucket
+}
+
+func NewMemoryRateLimitStore() RateLimitStore {
+	return &memoryRateLimitStore{
+		buckets: make(map[string]*entity.RateLimitBucket),
+	}
+}
+
+func (s *memoryRateLimitStore) Take(ctx context.Context, key string, limit entity.RateLimit, now time.Time) (*entity.RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &entity.RateLimitBucket{Key: key, Tokens: float64(limit.Burst), CheckedAt: now}
+		s.buckets[key] = bucket
+	}
+
+	result := bucket.Take(limit, now)
+	return &result, nil
+}
+
+func (s *memoryRateLimitStore) DeleteFull(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for key, bucket := range s.buckets {
+		if bucket.FullAt.Before(now) {
+			delete(s.buckets, key)
+		}
+	}
+	return nil
+}
@@ -1,12 +1,12 @@
 package repository
 
 import (
+	"context"
 	"go-trades/entity"
 	"go-trades/utils"
 	"log"
 	"time"
 
-	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
@@ -15,9 +15,9 @@ type reportRepository struct {
 }
 
 type ReportRepository interface {
-	FindBestSelling(ctx *gin.Context, start time.Time, end time.Time) ([]entity.BestSellingProduct, error)
-	FindLowStock(ctx *gin.Context) ([]entity.LowInventoryItem, error)
-	GenerateOrderSummary(ctx *gin.Context, start time.Time, end time.Time) (*entity.OrderSummary, error)
+	FindBestSelling(ctx context.Context, start time.Time, end time.Time, statuses []uint) ([]entity.BestSellingProduct, error)
+	FindLowStock(ctx context.Context) ([]entity.LowInventoryItem, error)
+	GenerateOrderSummary(ctx context.Context, start time.Time, end time.Time, statuses []uint) (*entity.OrderSummary, error)
 }
 
 func NewReportRepository(db *gorm.DB) ReportRepository {
@@ -26,7 +26,9 @@ func NewReportRepository(db *gorm.DB) ReportRepository {
 	}
 }
 
-func (r *reportRepository) FindBestSelling(ctx *gin.Context, start time.Time, end time.Time) ([]entity.BestSellingProduct, error) {
+// FindBestSelling ranks products by units sold in orders with one of the
+// given statuses.
+func (r *reportRepository) FindBestSelling(ctx context.Context, start time.Time, end time.Time, statuses []uint) ([]entity.BestSellingProduct, error) {
 	db := utils.GetTx(ctx, r.DB)
 	var results []entity.BestSellingProduct
 	err := db.Raw(`
@@ -41,13 +43,13 @@ func (r *reportRepository) FindBestSelling(ctx *gin.Context, start time.Time, en
             JOIN 
                 products p ON p.id = od.product_id
         WHERE 
-            o.status IN (2,3,4) AND o.date BETWEEN ? AND ?
+            o.status IN ? AND o.date BETWEEN ? AND ?
         GROUP BY 
             p.id, p.name
         ORDER BY 
             qty_sold DESC
         LIMIT 5
-    `, start, end).Scan(&results).Error
+    `, statuses, start, end).Scan(&results).Error
 
 	if err != nil {
 		log.Printf("Error in FindBestSelling: %v", err)
@@ -55,7 +57,7 @@ func (r *reportRepository) FindBestSelling(ctx *gin.Context, start time.Time, en
 	log.Printf("FindBestSelling results: %+v", results)
 	return results, err
 }
-func (r *reportRepository) FindLowStock(ctx *gin.Context) ([]entity.LowInventoryItem, error) {
+func (r *reportRepository) FindLowStock(ctx context.Context) ([]entity.LowInventoryItem, error) {
 	db := utils.GetTx(ctx, r.DB)
 	var results []entity.LowInventoryItem
 	err := db.Raw(`
@@ -77,7 +79,8 @@ func (r *reportRepository) FindLowStock(ctx *gin.Context) ([]entity.LowInventory
 	return results, err
 }
 
-func (r *reportRepository) GenerateOrderSummary(ctx *gin.Context, start time.Time, end time.Time) (*entity.OrderSummary, error) {
+// GenerateOrderSummary totals the orders with one of the given statuses.
+func (r *reportRepository) GenerateOrderSummary(ctx context.Context, start time.Time, end time.Time, statuses []uint) (*entity.OrderSummary, error) {
 	db := utils.GetTx(ctx, r.DB)
 	var result entity.OrderSummary
 	err := db.Raw(`
@@ -88,8 +91,8 @@ func (r *reportRepository) GenerateOrderSummary(ctx *gin.Context, start time.Tim
         FROM 
             orders o
         WHERE 
-            o.status IN (2,3,4) AND o.date BETWEEN ? AND ?
-    `, start, end).Scan(&result).Error
+            o.status IN ? AND o.date BETWEEN ? AND ?
+    `, statuses, start, end).Scan(&result).Error
 
 	return &result, err
 }
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"go-trades/entity"
+	"go-trades/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type roleRepository struct {
+	DB *gorm.DB
+}
+
+type RoleRepository interface {
+	FindAll(ctx context.Context) ([]entity.RoleDefinition, error)
+	FindById(ctx context.Context, id uint) (*entity.RoleDefinition, error)
+	FindByName(ctx context.Context, name entity.Role) (*entity.RoleDefinition, error)
+	FindByIdForUpdate(ctx context.Context, id uint) (*entity.RoleDefinition, error)
+	FindByNameForShare(ctx context.Context, name entity.Role) (*entity.RoleDefinition, error)
+	CountUsers(ctx context.Context, name entity.Role) (int64, error)
+	CreateRole(ctx context.Context, role *entity.RoleDefinition) error
+	UpdateRole(ctx context.Context, role *entity.RoleDefinition) error
+	DeleteRole(ctx context.Context, id uint) error
+}
+
+func NewRoleRepository(db *gorm.DB) RoleRepository {
+	return &roleRepository{
+		DB: db,
+	}
+}
+
+func (r *roleRepository) FindAll(ctx context.Context) ([]entity.RoleDefinition, error) {
+	var result []entity.RoleDefinition
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.Preload("Permissions").Order("id").Find(&result).Error
+	return result, err
+}
+
+func (r *roleRepository) FindById(ctx context.Context, id uint) (*entity.RoleDefinition, error) {
+	var result entity.RoleDefinition
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.Preload("Permissions").Where("id = ?", id).First(&result).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (r *roleRepository) FindByName(ctx context.Context, name entity.Role) (*entity.RoleDefinition, error) {
+	var result entity.RoleDefinition
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.Preload("Permissions").Where("name = ?", name).First(&result).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// FindByIdForUpdate locks the role until the transaction in ctx ends, so
+// no user can be given it meanwhile.
+func (r *roleRepository) FindByIdForUpdate(ctx context.Context, id uint) (*entity.RoleDefinition, error) {
+	return r.findLocked(ctx, "UPDATE", "id = ?", id)
+}
+
+// FindByNameForShare keeps the role from being deleted until the
+// transaction in ctx ends.
+func (r *roleRepository) FindByNameForShare(ctx context.Context, name entity.Role) (*entity.RoleDefinition, error) {
+	return r.findLocked(ctx, "SHARE", "name = ?", name)
+}
+
+func (r *roleRepository) findLocked(ctx context.Context, strength string, query string, arg interface{}) (*entity.RoleDefinition, error) {
+	var result entity.RoleDefinition
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.Clauses(clause.Locking{Strength: strength}).Preload("Permissions").Where(query, arg).First(&result).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (r *roleRepository) CountUsers(ctx context.Context, name entity.Role) (int64, error) {
+	var total int64
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.Model(&entity.User{}).Where("role = ?", name).Count(&total).Error
+	return total, err
+}
+
+func (r *roleRepository) CreateRole(ctx context.Context, role *entity.RoleDefinition) error {
+	db := utils.GetTx(ctx, r.DB)
+	return db.Create(role).Error
+}
+
+// UpdateRole saves the role and replaces its permission set.
+func (r *roleRepository) UpdateRole(ctx context.Context, role *entity.RoleDefinition) error {
+	db := utils.GetTx(ctx, r.DB)
+	if err := db.Omit("Permissions").Save(role).Error; err != nil {
+		return err
+	}
+	if err := db.Where("role_id = ?", role.ID).Delete(&entity.RolePermission{}).Error; err != nil {
+		return err
+	}
+	for i := range role.Permissions {
+		role.Permissions[i].RoleId = role.ID
+	}
+	if len(role.Permissions) == 0 {
+		return nil
+	}
+	return db.Create(&role.Permissions).Error
+}
+
+func (r *roleRepository) DeleteRole(ctx context.Context, id uint) error {
+	db := utils.GetTx(ctx, r.DB)
+	if err := db.Where("role_id = ?", id).Delete(&entity.RolePermission{}).Error; err != nil {
+		return err
+	}
+	return db.Delete(&entity.RoleDefinition{}, id).Error
+}
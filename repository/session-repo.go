@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"go-trades/entity"
+	"go-trades/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type sessionRepository struct {
+	DB *gorm.DB
+}
+
+type SessionRepository interface {
+	FindById(ctx context.Context, id string) (*entity.Session, error)
+	FindRefreshTokenByHash(ctx context.Context, hash string) (*entity.RefreshToken, error)
+	CreateSession(ctx context.Context, session *entity.Session) error
+	CreateRefreshToken(ctx context.Context, token *entity.RefreshToken) error
+	MarkRefreshTokenUsed(ctx context.Context, id uint, usedAt time.Time) (bool, error)
+	RevokeSession(ctx context.Context, id string, reason string) error
+	RevokeAllByUserId(ctx context.Context, userId uint, reason string) error
+	RevokeOthersByUserId(ctx context.Context, userId uint, keepId string, reason string) error
+}
+
+func NewSessionRepository(db *gorm.DB) SessionRepository {
+	return &sessionRepository{
+		DB: db,
+	}
+}
+
+func (r *sessionRepository) FindById(ctx context.Context, id string) (*entity.Session, error) {
+	var result entity.Session
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.Where("id = ?", id).First(&result).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (r *sessionRepository) FindRefreshTokenByHash(ctx context.Context, hash string) (*entity.RefreshToken, error) {
+	var result entity.RefreshToken
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.Where("token_hash = ?", hash).First(&result).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (r *sessionRepository) CreateSession(ctx context.Context, session *entity.Session) error {
+	db := utils.GetTx(ctx, r.DB)
+	return db.Create(session).Error
+}
+
+func (r *sessionRepository) CreateRefreshToken(ctx context.Context, token *entity.RefreshToken) error {
+	db := utils.GetTx(ctx, r.DB)
+	return db.Create(token).Error
+}
+
+// MarkRefreshTokenUsed only succeeds for a token that has not been used yet,
+// so two concurrent refreshes with the same token cannot both rotate.
+func (r *sessionRepository) MarkRefreshTokenUsed(ctx context.Context, id uint, usedAt time.Time) (bool, error) {
+	db := utils.GetTx(ctx, r.DB)
+	result := db.Model(&entity.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", usedAt)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *sessionRepository) RevokeSession(ctx context.Context, id string, reason string) error {
+	db := utils.GetTx(ctx, r.DB)
+	return db.Model(&entity.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "revoked_reason": reason}).Error
+}
+
+func (r *sessionRepository) RevokeAllByUserId(ctx context.Context, userId uint, reason string) error {
+	db := utils.GetTx(ctx, r.DB)
+	return db.Model(&entity.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userId).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "revoked_reason": reason}).Error
+}
+
+func (r *sessionRepository) RevokeOthersByUserId(ctx context.Context, userId uint, keepId string, reason string) error {
+	db := utils.GetTx(ctx, r.DB)
+	return db.Model(&entity.Session{}).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userId, keepId).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "revoked_reason": reason}).Error
+}
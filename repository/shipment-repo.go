@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"go-trades/entity"
+	"go-trades/utils"
+
+	"gorm.io/gorm"
+)
+
+type shipmentRepository struct {
+	DB *gorm.DB
+}
+
+type ShipmentRepository interface {
+	FindById(ctx context.Context, id uint) (*entity.Shipment, error)
+	FindAllByOrderId(ctx context.Context, orderId uint) ([]entity.Shipment, error)
+	CreateShipment(ctx context.Context, shipment *entity.Shipment) error
+	UpdateShipment(ctx context.Context, shipment *entity.Shipment) error
+}
+
+func NewShipmentRepository(db *gorm.DB) ShipmentRepository {
+	return &shipmentRepository{
+		DB: db,
+	}
+}
+
+func (r *shipmentRepository) FindById(ctx context.Context, id uint) (*entity.Shipment, error) {
+	var result entity.Shipment
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.Preload("ShipmentItems").Where("id = ?", id).First(&result).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (r *shipmentRepository) FindAllByOrderId(ctx context.Context, orderId uint) ([]entity.Shipment, error) {
+	var result []entity.Shipment
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.Preload("ShipmentItems").Where("order_id = ?", orderId).Order("shipped_at").Find(&result).Error
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *shipmentRepository) CreateShipment(ctx context.Context, shipment *entity.Shipment) error {
+	db := utils.GetTx(ctx, r.DB)
+	return db.Create(shipment).Error
+}
+
+func (r *shipmentRepository) UpdateShipment(ctx context.Context, shipment *entity.Shipment) error {
+	db := utils.GetTx(ctx, r.DB)
+	return db.Omit("ShipmentItems").Save(shipment).Error
+}
@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"go-trades/entity"
+	"go-trades/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type subscriptionRepository struct {
+	DB *gorm.DB
+}
+
+type SubscriptionRepository interface {
+	FindAll(ctx context.Context, page, size int) ([]entity.Subscription, int64, error)
+	FindAllByUserId(ctx context.Context, userId uint, page, size int) ([]entity.Subscription, int64, error)
+	FindById(ctx context.Context, id uint) (*entity.Subscription, error)
+	FindByUserIdWithId(ctx context.Context, userId, id uint) (*entity.Subscription, error)
+	FindDue(ctx context.Context, now time.Time, status uint) ([]entity.Subscription, error)
+	ClaimDue(ctx context.Context, id uint, status uint, nextRunAt, leaseUntil time.Time) (bool, error)
+	CreateSubscription(ctx context.Context, subscription *entity.Subscription) error
+	UpdateSubscription(ctx context.Context, subscription *entity.Subscription) error
+	UpdateSchedule(ctx context.Context, subscription *entity.Subscription) error
+	CreateSubscriptionRun(ctx context.Context, run *entity.SubscriptionRun) error
+}
+
+func NewSubscriptionRepository(db *gorm.DB) SubscriptionRepository {
+	return &subscriptionRepository{
+		DB: db,
+	}
+}
+
+func (r *subscriptionRepository) FindAll(ctx context.Context, page, size int) ([]entity.Subscription, int64, error) {
+	var result []entity.Subscription
+	var total int64
+	db := utils.GetTx(ctx, r.DB)
+
+	if err := db.Model(&entity.Subscription{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * size
+	err := db.Preload("SubscriptionItems").Offset(offset).Limit(size).Find(&result).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return result, total, nil
+}
+
+func (r *subscriptionRepository) FindAllByUserId(ctx context.Context, userId uint, page, size int) ([]entity.Subscription, int64, error) {
+	var result []entity.Subscription
+	var total int64
+	db := utils.GetTx(ctx, r.DB)
+
+	if err := db.Model(&entity.Subscription{}).Where("user_id = ?", userId).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * size
+	err := db.Preload("SubscriptionItems").Where("user_id = ?", userId).Offset(offset).Limit(size).Find(&result).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return result, total, nil
+}
+
+func (r *subscriptionRepository) FindById(ctx context.Context, id uint) (*entity.Subscription, error) {
+	var result entity.Subscription
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.Preload("SubscriptionItems").Preload("SubscriptionRuns", func(db *gorm.DB) *gorm.DB {
+		return db.Order("run_at DESC")
+	}).Where("id = ?", id).First(&result).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (r *subscriptionRepository) FindByUserIdWithId(ctx context.Context, userId, id uint) (*entity.Subscription, error) {
+	var result entity.Subscription
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.Preload("SubscriptionItems").Preload("SubscriptionRuns", func(db *gorm.DB) *gorm.DB {
+		return db.Order("run_at DESC")
+	}).Where("user_id = ? AND id = ?", userId, id).First(&result).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (r *subscriptionRepository) FindDue(ctx context.Context, now time.Time, status uint) ([]entity.Subscription, error) {
+	var result []entity.Subscription
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.Preload("SubscriptionItems").
+		Where("status = ? AND next_run_at <= ?", status, now).
+		Order("next_run_at").
+		Find(&result).Error
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ClaimDue pushes next_run_at forward to leaseUntil, but only if it still
+// holds the value the caller read and the subscription is still in status.
+// Whoever wins the update owns the run, so a subscription is never ordered
+// twice when several schedulers are running, nor after it was cancelled.
+func (r *subscriptionRepository) ClaimDue(ctx context.Context, id uint, status uint, nextRunAt, leaseUntil time.Time) (bool, error) {
+	db := utils.GetTx(ctx, r.DB)
+	result := db.Model(&entity.Subscription{}).
+		Where("id = ? AND status = ? AND next_run_at = ?", id, status, nextRunAt).
+		Update("next_run_at", leaseUntil)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *subscriptionRepository) CreateSubscription(ctx context.Context, subscription *entity.Subscription) error {
+	db := utils.GetTx(ctx, r.DB)
+	return db.Create(subscription).Error
+}
+
+func (r *subscriptionRepository) UpdateSubscription(ctx context.Context, subscription *entity.Subscription) error {
+	db := utils.GetTx(ctx, r.DB)
+	return db.Omit("SubscriptionItems", "SubscriptionRuns").Save(subscription).Error
+}
+
+// UpdateSchedule only writes the scheduling columns, so a run finishing
+// does not undo a pause or cancel made while it was placing the order.
+func (r *subscriptionRepository) UpdateSchedule(ctx context.Context, subscription *entity.Subscription) error {
+	db := utils.GetTx(ctx, r.DB)
+	return db.Model(&entity.Subscription{}).Where("id = ?", subscription.ID).Updates(map[string]interface{}{
+		"next_run_at":  subscription.NextRunAt,
+		"scheduled_at": subscription.ScheduledAt,
+		"retry_count":  subscription.RetryCount,
+	}).Error
+}
+
+func (r *subscriptionRepository) CreateSubscriptionRun(ctx context.Context, run *entity.SubscriptionRun) error {
+	db := utils.GetTx(ctx, r.DB)
+	return db.Create(run).Error
+}
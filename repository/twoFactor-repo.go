@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"go-trades/entity"
+	"go-trades/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type twoFactorRepository struct {
+	DB *gorm.DB
+}
+
+type TwoFactorRepository interface {
+	FindByUserId(ctx context.Context, userId uint) (*entity.TwoFactor, error)
+	Save(ctx context.Context, twoFactor *entity.TwoFactor) error
+	Delete(ctx context.Context, userId uint) error
+	MarkStepUsed(ctx context.Context, userId uint, step int64) (bool, error)
+	ReplaceRecoveryCodes(ctx context.Context, userId uint, codeHashes []string) error
+	UseRecoveryCode(ctx context.Context, userId uint, codeHash string, usedAt time.Time) (bool, error)
+}
+
+func NewTwoFactorRepository(db *gorm.DB) TwoFactorRepository {
+	return &twoFactorRepository{
+		DB: db,
+	}
+}
+
+func (r *twoFactorRepository) FindByUserId(ctx context.Context, userId uint) (*entity.TwoFactor, error) {
+	var result entity.TwoFactor
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.Where("user_id = ?", userId).First(&result).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (r *twoFactorRepository) Save(ctx context.Context, twoFactor *entity.TwoFactor) error {
+	db := utils.GetTx(ctx, r.DB)
+	return db.Save(twoFactor).Error
+}
+
+func (r *twoFactorRepository) Delete(ctx context.Context, userId uint) error {
+	db := utils.GetTx(ctx, r.DB)
+	if err := db.Where("user_id = ?", userId).Delete(&entity.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+	return db.Where("user_id = ?", userId).Delete(&entity.TwoFactor{}).Error
+}
+
+// MarkStepUsed records the time step of an accepted code. It fails when that
+// step or a later one was already used, so a code cannot be replayed.
+func (r *twoFactorRepository) MarkStepUsed(ctx context.Context, userId uint, step int64) (bool, error) {
+	db := utils.GetTx(ctx, r.DB)
+	result := db.Model(&entity.TwoFactor{}).
+		Where("user_id = ? AND last_used_step < ?", userId, step).
+		Update("last_used_step", step)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *twoFactorRepository) ReplaceRecoveryCodes(ctx context.Context, userId uint, codeHashes []string) error {
+	db := utils.GetTx(ctx, r.DB)
+	if err := db.Where("user_id = ?", userId).Delete(&entity.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+
+	codes := make([]entity.RecoveryCode, len(codeHashes))
+	for i, hash := range codeHashes {
+		codes[i] = entity.RecoveryCode{UserId: userId, CodeHash: hash}
+	}
+	return db.Create(&codes).Error
+}
+
+func (r *twoFactorRepository) UseRecoveryCode(ctx context.Context, userId uint, codeHash string, usedAt time.Time) (bool, error) {
+	db := utils.GetTx(ctx, r.DB)
+	result := db.Model(&entity.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userId, codeHash).
+		Update("used_at", usedAt)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
@@ -1,20 +1,26 @@
 package repository
 
 import (
+	"context"
 	"go-trades/entity"
+	"go-trades/utils"
 
-	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type UserRepository interface {
-	Create(ctx *gin.Context, user *entity.User) error
-	FindAll(ctx *gin.Context) ([]entity.User, error)
-	FindByUsername(ctx *gin.Context, username string) (*entity.User, error)
-	FindById(ctx *gin.Context, id uint) (*entity.User, error)
-	FindByPhoneNumber(ctx *gin.Context, phoneNumber string) (*entity.User, error)
-	FindByEmail(ctx *gin.Context, email string) (*entity.User, error)
-	Update(ctx *gin.Context, user *entity.User) error
+	Create(ctx context.Context, user *entity.User) error
+	FindAll(ctx context.Context) ([]entity.User, error)
+	FindAllWithFilter(ctx context.Context, filter entity.UserFilter, page, size int) ([]entity.User, int64, error)
+	FindByUsername(ctx context.Context, username string) (*entity.User, error)
+	FindById(ctx context.Context, id uint) (*entity.User, error)
+	FindByIdForUpdate(ctx context.Context, id uint) (*entity.User, error)
+	FindByPhoneNumber(ctx context.Context, phoneNumber string) (*entity.User, error)
+	FindByEmail(ctx context.Context, email string) (*entity.User, error)
+	Update(ctx context.Context, user *entity.User) error
+	FindRoleChangesByUserId(ctx context.Context, userId uint) ([]entity.RoleChange, error)
+	CreateRoleChange(ctx context.Context, roleChange *entity.RoleChange) error
 }
 
 type userRepository struct {
@@ -27,52 +33,119 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 	}
 }
 
-func (r *userRepository) FindAll(ctx *gin.Context) ([]entity.User, error) {
+func (r *userRepository) FindAll(ctx context.Context) ([]entity.User, error) {
 	var users []entity.User
-	err := r.DB.Find(&users).Error
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.Find(&users).Error
 	return users, err
 }
 
-func (r *userRepository) FindByUsername(ctx *gin.Context, username string) (*entity.User, error) {
+func (r *userRepository) FindAllWithFilter(ctx context.Context, filter entity.UserFilter, page, size int) ([]entity.User, int64, error) {
+	var users []entity.User
+	var total int64
+	db := utils.GetTx(ctx, r.DB)
+
+	query := db.Model(&entity.User{})
+	if filter.Search != "" {
+		like := "%" + filter.Search + "%"
+		query = query.Where("username LIKE ? OR email LIKE ? OR firstname LIKE ? OR lastname LIKE ?", like, like, like, like)
+	}
+	if filter.Role != "" {
+		query = query.Where("role = ?", filter.Role)
+	}
+	if filter.Blocked != nil {
+		query = query.Where("is_blocked = ?", *filter.Blocked)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * size
+	if err := query.Order("id").Offset(offset).Limit(size).Find(&users).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
+func (r *userRepository) FindByUsername(ctx context.Context, username string) (*entity.User, error) {
+	var user entity.User
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.Where("username = ?", username).First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *userRepository) FindByPhoneNumber(ctx context.Context, phonenumber string) (*entity.User, error) {
 	var user entity.User
-	err := r.DB.Where("username = ?", username).First(&user).Error
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.Where("phonenumber = ?", phonenumber).First(&user).Error
 	if err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
-func (r *userRepository) FindByPhoneNumber(ctx *gin.Context, phonenumber string) (*entity.User, error) {
+func (r *userRepository) FindByEmail(ctx context.Context, email string) (*entity.User, error) {
 	var user entity.User
-	err := r.DB.Where("phonenumber = ?", phonenumber).First(&user).Error
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.Where("email = ?", email).First(&user).Error
 	if err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
-func (r *userRepository) FindByEmail(ctx *gin.Context, email string) (*entity.User, error) {
+func (r *userRepository) FindById(ctx context.Context, id uint) (*entity.User, error) {
 	var user entity.User
-	err := r.DB.Where("email = ?", email).First(&user).Error
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.First(&user, id).Error
 	if err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
-func (r *userRepository) FindById(ctx *gin.Context, id uint) (*entity.User, error) {
+// FindByIdForUpdate locks the user until the transaction in ctx ends, so a
+// change read from it cannot overwrite one made in the meantime.
+func (r *userRepository) FindByIdForUpdate(ctx context.Context, id uint) (*entity.User, error) {
 	var user entity.User
-	err := r.DB.First(&user, id).Error
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, id).Error
 	if err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
-func (r *userRepository) Create(ctx *gin.Context, user *entity.User) error {
-	return r.DB.Create(user).Error
+func (r *userRepository) Create(ctx context.Context, user *entity.User) error {
+	db := utils.GetTx(ctx, r.DB)
+	return db.Create(user).Error
+}
+
+func (r *userRepository) Update(ctx context.Context, user *entity.User) error {
+	db := utils.GetTx(ctx, r.DB)
+	return db.Save(user).Error
+}
+
+func (r *userRepository) FindRoleChangesByUserId(ctx context.Context, userId uint) ([]entity.RoleChange, error) {
+	var roleChanges []entity.RoleChange
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.Where("user_id = ?", userId).Order("created_at DESC").Find(&roleChanges).Error
+	return roleChanges, err
 }
 
-func (r *userRepository) Update(ctx *gin.Context, user *entity.User) error {
-	return r.DB.Save(user).Error
+func (r *userRepository) CreateRoleChange(ctx context.Context, roleChange *entity.RoleChange) error {
+	db := utils.GetTx(ctx, r.DB)
+	return db.Create(roleChange).Error
 }
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"go-trades/entity"
+	"go-trades/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type userTokenRepository struct {
+	DB *gorm.DB
+}
+
+type UserTokenRepository interface {
+	FindByHash(ctx context.Context, purpose entity.TokenPurpose, hash string) (*entity.UserToken, error)
+	Create(ctx context.Context, token *entity.UserToken) error
+	MarkUsed(ctx context.Context, id uint, usedAt time.Time) (bool, error)
+	InvalidateByUserId(ctx context.Context, userId uint, purpose entity.TokenPurpose) error
+}
+
+func NewUserTokenRepository(db *gorm.DB) UserTokenRepository {
+	return &userTokenRepository{
+		DB: db,
+	}
+}
+
+func (r *userTokenRepository) FindByHash(ctx context.Context, purpose entity.TokenPurpose, hash string) (*entity.UserToken, error) {
+	var result entity.UserToken
+	db := utils.GetTx(ctx, r.DB)
+
+	err := db.Where("purpose = ? AND token_hash = ?", purpose, hash).First(&result).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (r *userTokenRepository) Create(ctx context.Context, token *entity.UserToken) error {
+	db := utils.GetTx(ctx, r.DB)
+	return db.Create(token).Error
+}
+
+// MarkUsed only succeeds for an unused token, so a token cannot be redeemed
+// twice by concurrent requests.
+func (r *userTokenRepository) MarkUsed(ctx context.Context, id uint, usedAt time.Time) (bool, error) {
+	db := utils.GetTx(ctx, r.DB)
+	result := db.Model(&entity.UserToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", usedAt)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// InvalidateByUserId marks every outstanding token of the purpose as used,
+// so only the most recently mailed token works.
+func (r *userTokenRepository) InvalidateByUserId(ctx context.Context, userId uint, purpose entity.TokenPurpose) error {
+	db := utils.GetTx(ctx, r.DB)
+	return db.Model(&entity.UserToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userId, purpose).
+		Update("used_at", time.Now()).Error
+}
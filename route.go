@@ -1,11 +1,17 @@
 package main
 
 import (
+	"go-trades/config"
 	"go-trades/controller"
 	"go-trades/entity"
+	"go-trades/mailer"
 	"go-trades/middleware"
 	"go-trades/repository"
+	"go-trades/scheduler"
 	"go-trades/service"
+	"go-trades/utils"
+	"log"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
@@ -15,108 +21,299 @@ import (
 func routeInit(conn *gorm.DB) *gin.Engine {
 	// ============== Dependency Injection ============
 
+	txManager := utils.NewTxManager(conn)
+
+	auditRepository := repository.NewAuditRepository(conn)
+	auditService := service.NewAuditService(auditRepository)
+	auditController := controller.NewAuditController(auditService)
+
 	userRepository := repository.NewUserRepository(conn)
-	userService := service.NewUserService(userRepository)
+	roleRepository := repository.NewRoleRepository(conn)
+	sessionRepository := repository.NewSessionRepository(conn)
+	userTokenRepository := repository.NewUserTokenRepository(conn)
+	twoFactorRepository := repository.NewTwoFactorRepository(conn)
+	loginLockoutRepository := repository.NewLoginLockoutRepository(conn)
+	apiKeyRepository := repository.NewApiKeyRepository(conn)
+	loginAttemptStore := repository.NewLoginAttemptStore(conn)
+	if config.GetLoginAttemptStore() == "memory" {
+		loginAttemptStore = repository.NewMemoryLoginAttemptStore()
+	}
+	mail, err := mailer.New()
+	if err != nil {
+		log.Fatalf("Configuring mail failed. Error : %v", err)
+	}
+	userService := service.NewUserService(txManager, userRepository, roleRepository, sessionRepository, userTokenRepository, twoFactorRepository, apiKeyRepository, loginAttemptStore, loginLockoutRepository, mail, auditService)
 	userController := controller.NewUserController(userService)
 
+	twoFactorService := service.NewTwoFactorService(txManager, twoFactorRepository, userRepository, userTokenRepository, loginAttemptStore, userService, auditService)
+	twoFactorController := controller.NewTwoFactorController(twoFactorService)
+
+	jwksController := controller.NewJWKSController()
+
+	oidcRepository := repository.NewOIDCRepository(conn)
+	oidcService := service.NewOIDCService(txManager, oidcRepository, userRepository, roleRepository, userService, auditService)
+	oidcController := controller.NewOIDCController(oidcService)
+
+	apiKeyService := service.NewApiKeyService(txManager, apiKeyRepository, userRepository, roleRepository, auditService)
+	apiKeyController := controller.NewApiKeyController(apiKeyService)
+
+	roleService := service.NewRoleService(txManager, roleRepository, auditService)
+	roleController := controller.NewRoleController(roleService)
+
+	addressRepository := repository.NewAddressRepository(conn)
+	addressService := service.NewAddressService(txManager, addressRepository, auditService)
+	addressController := controller.NewAddressController(addressService)
+
 	categoryRepository := repository.NewCategoryRepository(conn)
-	categoryService := service.NewCategoryService(categoryRepository)
+	categoryService := service.NewCategoryService(txManager, categoryRepository, auditService)
 	categoryController := controller.NewCategoryController(categoryService)
 
 	productRepository := repository.NewProductRepository(conn)
-	productService := service.NewProductService(productRepository, categoryRepository)
+	productService := service.NewProductService(txManager, productRepository, categoryRepository, auditService)
 	productController := controller.NewProductController(productService)
 
 	productImageRepository := repository.NewProductImageRepository(conn)
-	productImageService := service.NewProductImageService(conn, productImageRepository, productRepository)
+	productImageService := service.NewProductImageService(txManager, productImageRepository, productRepository, auditService)
 	productImageController := controller.NewProductImageController(productImageService)
 
 	inventoryRepository := repository.NewInventoryRepository(conn)
-	inventoryService := service.NewInventoryService(inventoryRepository, productRepository)
+	orderRepository := repository.NewOrderRepository(conn)
+	inventoryService := service.NewInventoryService(txManager, inventoryRepository, productRepository, orderRepository, auditService)
 	inventoryController := controller.NewInventoryController(inventoryService)
 
-	orderRepository := repository.NewOrderRepository(conn)
-	orderService := service.NewOrderService(conn, orderRepository, productRepository, inventoryRepository)
+	shipmentRepository := repository.NewShipmentRepository(conn)
+	orderService := service.NewOrderService(txManager, orderRepository, productRepository, inventoryRepository, shipmentRepository, addressRepository, auditService)
 	orderController := controller.NewOrderController(orderService)
 
+	shipmentService := service.NewShipmentService(txManager, shipmentRepository, orderRepository, auditService)
+	shipmentController := controller.NewShipmentController(shipmentService)
+
+	cartRepository := repository.NewCartRepository(conn)
+	cartService := service.NewCartService(txManager, cartRepository, productRepository, inventoryRepository, orderService, auditService)
+	cartController := controller.NewCartController(cartService)
+
+	orderReturnRepository := repository.NewOrderReturnRepository(conn)
 	paymentRepository := repository.NewPaymentRepository(conn)
-	paymentService := service.NewPaymentService(conn, paymentRepository, orderRepository)
+	orderReturnService := service.NewOrderReturnService(txManager, orderReturnRepository, orderRepository, inventoryRepository, paymentRepository, auditService)
+	orderReturnController := controller.NewOrderReturnController(orderReturnService)
+
+	subscriptionRepository := repository.NewSubscriptionRepository(conn)
+	subscriptionService := service.NewSubscriptionService(txManager, subscriptionRepository, userRepository, productRepository, addressRepository, orderService, auditService)
+	subscriptionController := controller.NewSubscriptionController(subscriptionService)
+
+	quoteRepository := repository.NewQuoteRepository(conn)
+	quoteService := service.NewQuoteService(txManager, quoteRepository, productRepository, orderService, auditService)
+	quoteController := controller.NewQuoteController(quoteService)
+
+	importJobRepository := repository.NewImportJobRepository(conn)
+	importService := service.NewImportService(txManager, importJobRepository, userRepository, orderService, auditService)
+	importController := controller.NewImportController(importService)
+
+	invoiceRepository := repository.NewInvoiceRepository(conn)
+	paymentService := service.NewPaymentService(txManager, paymentRepository, orderRepository, invoiceRepository, auditService)
 	paymentController := controller.NewPaymentController(paymentService)
 
+	documentService := service.NewDocumentService(orderRepository, productRepository, paymentRepository, invoiceRepository)
+	documentController := controller.NewDocumentController(documentService)
+
 	reportRepository := repository.NewReportRepository(conn)
 	reportService := service.NewReportService(reportRepository)
 	reportController := controller.NewReportController(reportService)
 
+	// ============== Rate Limits ============
+
+	rateLimitStore := repository.NewMemoryRateLimitStore()
+	if config.GetRateLimitStore() == "database" {
+		rateLimitStore = repository.NewRateLimitStore(conn)
+	}
+	// Sign-in and sign-up routes are keyed by IP; everything behind
+	// authentication is keyed by IP before it and by API key or user after.
+	authRateLimit := middleware.RateLimit(rateLimitStore, entity.RateLimit{Name: "auth", Requests: 20, Per: time.Minute, Burst: 10})
+	ipRateLimit := middleware.IPRateLimit(rateLimitStore, entity.RateLimit{Name: "ip", Requests: 1200, Per: time.Minute, Burst: 200})
+	apiRateLimit := middleware.RateLimit(rateLimitStore, entity.RateLimit{Name: "api", Requests: 600, Per: time.Minute, Burst: 100})
+	orderRateLimit := middleware.RateLimit(rateLimitStore, entity.RateLimit{Name: "orders", Requests: 10, Per: time.Minute, Burst: 5})
+
+	// Retried order and payment requests replay the first response.
+	idempotencyRepository := repository.NewIdempotencyRepository(conn)
+	idempotent := middleware.Idempotency(idempotencyRepository)
+
+	// ============== Background Jobs ============
+
+	scheduler.Every("subscriptions", time.Minute, subscriptionService.RunDueSubscriptions)
+	scheduler.Every("quotes", time.Hour, quoteService.ExpireQuotes)
+	scheduler.Every("oidc-states", time.Hour, oidcService.DeleteExpiredStates)
+	scheduler.Every("rate-limits", 10*time.Minute, rateLimitStore.DeleteFull)
+	scheduler.Every("idempotency-keys", time.Hour, idempotencyRepository.DeleteExpired)
+
 	r := gin.Default()
+	// Services read the caller and transaction from ctx.Value, which gin only
+	// forwards to the request context when this is set.
+	r.ContextWithFallback = true
+	if err := r.SetTrustedProxies(config.GetTrustedProxies()); err != nil {
+		log.Fatalf("Setting trusted proxies failed. Error : %v", err)
+	}
+	r.Use(middleware.RequestID())
+	r.GET("/.well-known/jwks.json", jwksController.GetJWKS)
+
 	api := r.Group("/api/v1")
 
-	api.POST("/register", userController.Register)
-	api.POST("/login", userController.Login)
+	public := api.Group("")
+	public.Use(authRateLimit)
+	{
+		public.POST("/register", userController.Register)
+		public.POST("/login", userController.Login)
+		public.POST("/login/2fa", twoFactorController.CompleteLogin)
+		public.POST("/login/2fa/enroll", twoFactorController.EnrollForLogin)
+		public.POST("/login/2fa/confirm", twoFactorController.ConfirmForLogin)
+		public.POST("/token/refresh", userController.RefreshToken)
+		public.POST("/password/forgot", userController.ForgotPassword)
+		public.POST("/password/reset", userController.ConfirmPasswordReset)
+		public.POST("/email/verify", userController.VerifyEmail)
+		public.GET("/auth/oidc/:provider/login", oidcController.Login)
+		public.GET("/auth/oidc/:provider/callback", oidcController.Callback)
+	}
 
-	// Protected routes (require authentication)
+	// Protected routes (require authentication). Every route below declares
+	// the permission it needs; routes listing a staff and a customer
+	// permission return all records to staff and only their own to customers.
 	protected := api.Group("")
-	protected.Use(middleware.AuthMiddleware())
+	protected.Use(ipRateLimit, middleware.AuthMiddleware(userRepository, roleRepository, sessionRepository, apiKeyRepository), apiRateLimit)
 	{
-		// User routes (Admin & Customer)
+		// User routes
+		protected.POST("/logout", userController.Logout)
+		protected.POST("/logout/all", userController.LogoutAll)
 		protected.PUT("/user/password", userController.ChangePassword)
 		protected.GET("/user/me", userController.GetUser)
+		protected.POST("/user/email/verification", userController.SendEmailVerification)
+
+		// Two-factor authentication routes
+		protected.POST("/user/2fa/enroll", twoFactorController.Enroll)
+		protected.POST("/user/2fa/confirm", twoFactorController.Confirm)
+		protected.POST("/user/2fa/disable", twoFactorController.Disable)
+		protected.POST("/user/2fa/recovery-codes", twoFactorController.RegenerateRecoveryCodes)
+
+		// Address book routes
+		protected.GET("/user/addresses", addressController.GetAddresses)
+		protected.GET("/user/addresses/:id", addressController.GetAddressById)
+		protected.POST("/user/addresses", addressController.CreateAddress)
+		protected.PUT("/user/addresses/:id", addressController.UpdateAddress)
+		protected.DELETE("/user/addresses/:id", addressController.DeleteAddress)
+
+		// User management routes
+		protected.POST("/admin/:id", middleware.RequirePermission(entity.PermRolesWrite), userController.AssignAsAdmin)
+		protected.GET("/users", middleware.RequirePermission(entity.PermUsersRead), userController.GetAllUsers)
+		protected.GET("/users/:id", middleware.RequirePermission(entity.PermUsersRead), userController.GetUserById)
+		protected.PATCH("/users/:id", middleware.RequirePermission(entity.PermUsersWrite), userController.UpdateUser)
+		protected.POST("/users/:id/disable", middleware.RequirePermission(entity.PermUsersWrite), userController.DisableUser)
+		protected.POST("/users/:id/enable", middleware.RequirePermission(entity.PermUsersWrite), userController.EnableUser)
+		protected.PUT("/users/:id/role", middleware.RequirePermission(entity.PermRolesWrite), userController.ChangeUserRole)
+		protected.PUT("/users/:id/password", middleware.RequirePermission(entity.PermUsersWrite), userController.ResetUserPassword)
+		protected.GET("/login-lockouts", middleware.RequirePermission(entity.PermUsersRead), userController.GetLoginLockouts)
+
+		// Role routes
+		protected.GET("/roles", middleware.RequirePermission(entity.PermRolesWrite), roleController.GetAllRoles)
+		protected.GET("/permissions", middleware.RequirePermission(entity.PermRolesWrite), roleController.GetAllPermissions)
+		protected.POST("/roles", middleware.RequirePermission(entity.PermRolesWrite), roleController.CreateRole)
+		protected.PUT("/roles/:id", middleware.RequirePermission(entity.PermRolesWrite), roleController.UpdateRole)
+		protected.DELETE("/roles/:id", middleware.RequirePermission(entity.PermRolesWrite), roleController.DeleteRole)
+
+		// API key routes
+		protected.GET("/api-keys", middleware.RequirePermission(entity.PermApiKeysWrite), apiKeyController.GetAllApiKeys)
+		protected.POST("/api-keys", middleware.RequirePermission(entity.PermApiKeysWrite), apiKeyController.CreateApiKey)
+		protected.DELETE("/api-keys/:id", middleware.RequirePermission(entity.PermApiKeysWrite), apiKeyController.RevokeApiKey)
+
+		// Audit routes
+		protected.GET("/audit", middleware.RequirePermission(entity.PermAuditRead), auditController.GetAuditLogs)
+		protected.GET("/audit/verify", middleware.RequirePermission(entity.PermAuditRead), auditController.VerifyChain)
+
+		// Category routes
+		protected.GET("/categories", middleware.RequirePermission(entity.PermCategoriesRead), categoryController.GetAllCategories)
+		protected.GET("/categories/:id", middleware.RequirePermission(entity.PermCategoriesRead), categoryController.GetCategoryById)
+		protected.POST("/categories", middleware.RequirePermission(entity.PermCategoriesWrite), categoryController.CreateCategory)
+		protected.PUT("/categories/:id", middleware.RequirePermission(entity.PermCategoriesWrite), categoryController.UpdateCategory)
+		protected.DELETE("/categories/:id", middleware.RequirePermission(entity.PermCategoriesWrite), categoryController.DeleteCategory)
+
+		// Product routes
+		protected.GET("/products", middleware.RequirePermission(entity.PermProductsRead), productController.GetAllProducts)
+		protected.GET("/products/:id", middleware.RequirePermission(entity.PermProductsRead), productController.GetProductById)
+		protected.GET("/products/:id/images", middleware.RequirePermission(entity.PermProductsRead), productImageController.DownloadProductImages)
+		protected.POST("/products", middleware.RequirePermission(entity.PermProductsWrite), productController.CreateProduct)
+		protected.PUT("/products/:id", middleware.RequirePermission(entity.PermProductsWrite), productController.UpdateProduct)
+		protected.DELETE("/products/:id", middleware.RequirePermission(entity.PermProductsWrite), productController.DeleteProduct)
+		protected.POST("/products/:id/images", middleware.RequirePermission(entity.PermProductsWrite), productImageController.UploadProductImage)
+		protected.POST("/products/import", middleware.RequirePermission(entity.PermProductsWrite), productController.ImportProducts)
+		protected.GET("/products/export", middleware.RequirePermission(entity.PermProductsWrite), productController.ExportProducts)
+
+		// Inventory routes
+		protected.GET("/inventories", middleware.RequirePermission(entity.PermInventoryRead), inventoryController.GetAllInventories)
+		protected.GET("/inventories/:id", middleware.RequirePermission(entity.PermInventoryRead), inventoryController.GetInventoryById)
+		protected.POST("/inventories", middleware.RequirePermission(entity.PermInventoryWrite), inventoryController.CreateInventory)
+		protected.PUT("/inventories/:id", middleware.RequirePermission(entity.PermInventoryWrite), inventoryController.UpdateInventory)
+		protected.DELETE("/inventories/:id", middleware.RequirePermission(entity.PermInventoryWrite), inventoryController.DeleteInventory)
+		protected.POST("/inventories/import", middleware.RequirePermission(entity.PermInventoryWrite), inventoryController.ImportInventories)
+		protected.GET("/inventories/export", middleware.RequirePermission(entity.PermInventoryRead), inventoryController.ExportInventories)
+
+		// Order routes
+		protected.GET("/orders", middleware.RequirePermission(entity.PermOrdersRead, entity.PermOrdersPlace), orderController.GetAllOrders)
+		protected.GET("/orders/:id", middleware.RequirePermission(entity.PermOrdersRead, entity.PermOrdersPlace), orderController.GetOrderById)
+		protected.GET("/orders/:id/invoice.pdf", middleware.RequirePermission(entity.PermOrdersRead, entity.PermOrdersPlace), documentController.GetInvoice)
+		protected.GET("/orders/:id/packing-slip.pdf", middleware.RequirePermission(entity.PermOrdersRead, entity.PermOrdersPlace), documentController.GetPackingSlip)
+		protected.POST("/orders", middleware.RequirePermission(entity.PermOrdersPlace), middleware.RequireVerifiedEmail(), orderRateLimit, idempotent, orderController.CreateOrder)
+		protected.PATCH("/orders/:id", middleware.RequirePermission(entity.PermOrdersPlace), orderController.AmendOrder)
+		protected.POST("/orders/:id/confirm", middleware.RequirePermission(entity.PermOrdersPlace), orderController.ConfirmOrder)
+		protected.DELETE("/orders/:id", middleware.RequirePermission(entity.PermOrdersPlace), orderController.CancelOrder)
+		protected.POST("/orders/:id/process", middleware.RequirePermission(entity.PermOrdersProcess), orderController.ProcessOrder)
+
+		// Shipment routes
+		protected.GET("/orders/:id/shipments", middleware.RequirePermission(entity.PermOrdersRead, entity.PermOrdersPlace), shipmentController.GetOrderShipments)
+		protected.POST("/orders/:id/shipments", middleware.RequirePermission(entity.PermShipmentsWrite), shipmentController.CreateShipment)
+		protected.POST("/shipments/:id/deliver", middleware.RequirePermission(entity.PermShipmentsWrite), shipmentController.DeliverShipment)
+
+		// Payment routes
+		protected.GET("/payments", middleware.RequirePermission(entity.PermPaymentsRead, entity.PermPaymentsCreate), paymentController.GetAllPayments)
+		protected.POST("/payments", middleware.RequirePermission(entity.PermPaymentsCreate), idempotent, paymentController.CreatePayment)
+
+		// Cart routes
+		protected.GET("/cart", middleware.RequirePermission(entity.PermOrdersPlace), cartController.GetCart)
+		protected.POST("/cart/items", middleware.RequirePermission(entity.PermOrdersPlace), cartController.AddCartItem)
+		protected.PUT("/cart/items/:id", middleware.RequirePermission(entity.PermOrdersPlace), cartController.UpdateCartItem)
+		protected.DELETE("/cart/items/:id", middleware.RequirePermission(entity.PermOrdersPlace), cartController.RemoveCartItem)
+		protected.POST("/cart/checkout", middleware.RequirePermission(entity.PermOrdersPlace), middleware.RequireVerifiedEmail(), orderRateLimit, idempotent, cartController.CheckoutCart)
+
+		// Return routes
+		protected.GET("/returns", middleware.RequirePermission(entity.PermReturnsProcess, entity.PermReturnsRequest), orderReturnController.GetAllOrderReturns)
+		protected.GET("/returns/:id", middleware.RequirePermission(entity.PermReturnsProcess, entity.PermReturnsRequest), orderReturnController.GetOrderReturnById)
+		protected.POST("/returns", middleware.RequirePermission(entity.PermReturnsRequest), orderReturnController.CreateOrderReturn)
+		protected.POST("/returns/:id/approve", middleware.RequirePermission(entity.PermReturnsProcess), orderReturnController.ApproveOrderReturn)
+		protected.POST("/returns/:id/reject", middleware.RequirePermission(entity.PermReturnsProcess), orderReturnController.RejectOrderReturn)
+		protected.POST("/returns/:id/receive", middleware.RequirePermission(entity.PermReturnsProcess), orderReturnController.ReceiveOrderReturn)
+		protected.POST("/returns/:id/inspect", middleware.RequirePermission(entity.PermReturnsProcess), orderReturnController.InspectOrderReturn)
+		protected.POST("/returns/:id/refund", middleware.RequirePermission(entity.PermReturnsProcess), orderReturnController.RefundOrderReturn)
+
+		// Subscription routes
+		protected.GET("/subscriptions", middleware.RequirePermission(entity.PermOrdersRead, entity.PermSubscriptionsManage), subscriptionController.GetAllSubscriptions)
+		protected.GET("/subscriptions/:id", middleware.RequirePermission(entity.PermOrdersRead, entity.PermSubscriptionsManage), subscriptionController.GetSubscriptionById)
+		protected.POST("/subscriptions", middleware.RequirePermission(entity.PermSubscriptionsManage), middleware.RequireVerifiedEmail(), subscriptionController.CreateSubscription)
+		protected.POST("/subscriptions/:id/pause", middleware.RequirePermission(entity.PermSubscriptionsManage), subscriptionController.PauseSubscription)
+		protected.POST("/subscriptions/:id/resume", middleware.RequirePermission(entity.PermSubscriptionsManage), subscriptionController.ResumeSubscription)
+		protected.DELETE("/subscriptions/:id", middleware.RequirePermission(entity.PermSubscriptionsManage), subscriptionController.CancelSubscription)
+
+		// Quote routes
+		protected.GET("/quotes", middleware.RequirePermission(entity.PermQuotesWrite, entity.PermQuotesRequest), quoteController.GetAllQuotes)
+		protected.GET("/quotes/:id", middleware.RequirePermission(entity.PermQuotesWrite, entity.PermQuotesRequest), quoteController.GetQuoteById)
+		protected.POST("/quotes", middleware.RequirePermission(entity.PermQuotesRequest), quoteController.CreateQuote)
+		protected.POST("/quotes/:id/accept", middleware.RequirePermission(entity.PermQuotesRequest), middleware.RequireVerifiedEmail(), quoteController.AcceptQuote)
+		protected.PUT("/quotes/:id/prices", middleware.RequirePermission(entity.PermQuotesWrite), quoteController.AdjustQuote)
+		protected.POST("/quotes/:id/send", middleware.RequirePermission(entity.PermQuotesWrite), quoteController.SendQuote)
+
+		// Import routes
+		protected.GET("/imports", middleware.RequirePermission(entity.PermImportsWrite), importController.GetAllImportJobs)
+		protected.GET("/imports/:id", middleware.RequirePermission(entity.PermImportsWrite), importController.GetImportJobById)
+		protected.GET("/imports/:id/result", middleware.RequirePermission(entity.PermImportsWrite), importController.DownloadImportResult)
+		protected.POST("/imports/orders", middleware.RequirePermission(entity.PermImportsWrite), importController.ImportOrders)
 
-		// Routes accessible to both Admin & Customer
-		bothRoles := protected.Group("")
-		bothRoles.Use(middleware.RBACMiddleware(entity.Admin, entity.Customer))
-		{
-			bothRoles.GET("/products", productController.GetAllProducts)
-			bothRoles.GET("/products/:id", productController.GetProductById)
-			bothRoles.GET("/orders", orderController.GetAllOrders)
-			bothRoles.GET("/orders/:id", orderController.GetOrderById)
-			bothRoles.GET("/payments", paymentController.GetAllPayments)
-			bothRoles.GET("/products/:id/images", productImageController.DownloadProductImages)
-			bothRoles.POST("/admin/:id", userController.AssignAsAdmin)
-		}
-
-		// Admin-only routes
-		admin := protected.Group("")
-		admin.Use(middleware.RBACMiddleware(entity.Admin))
-		{
-			// Category routes
-			admin.GET("/categories", categoryController.GetAllCategories)
-			admin.GET("/categories/:id", categoryController.GetCategoryById)
-			admin.POST("/categories", categoryController.CreateCategory)
-			admin.PUT("/categories/:id", categoryController.UpdateCategory)
-			admin.DELETE("/categories/:id", categoryController.DeleteCategory)
-
-			// Product routes (write operations)
-			admin.POST("/products", productController.CreateProduct)
-			admin.PUT("/products/:id", productController.UpdateProduct)
-			admin.DELETE("/products/:id", productController.DeleteProduct)
-			admin.POST("/products/:id/images", productImageController.UploadProductImage)
-
-			// Inventory routes
-			admin.GET("/inventories", inventoryController.GetAllInventories)
-			admin.GET("/inventories/:id", inventoryController.GetInventoryById)
-			admin.POST("/inventories", inventoryController.CreateInventory)
-			admin.PUT("/inventories/:id", inventoryController.UpdateInventory)
-			admin.DELETE("/inventories/:id", inventoryController.DeleteInventory)
-
-			// Order routes
-			admin.POST("/orders/:id/process", orderController.ProcessOrder)
-
-			// Report routes
-			admin.GET("/reports", reportController.GetReport)
-		}
-
-		// Customer-only routes
-		customer := protected.Group("")
-		customer.Use(middleware.RBACMiddleware(entity.Customer))
-		{
-			// Order routes
-			customer.POST("/orders", orderController.CreateOrder)
-			customer.POST("/orders/:id/confirm", orderController.ConfirmOrder)
-			customer.DELETE("/orders/:id", orderController.CancelOrder)
-
-			// Payment routes
-			customer.POST("/payments", paymentController.CreatePayment)
-		}
+		// Report routes
+		protected.GET("/reports", middleware.RequirePermission(entity.PermReportsRead), reportController.GetReport)
 	}
 
 	return r
@@ -0,0 +1,24 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+type Job func(ctx context.Context) error
+
+// Every runs job in the background once per interval. Each run gets a fresh
+// context, and a failing run is logged without stopping later ones.
+func Every(name string, interval time.Duration, job Job) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := job(context.Background()); err != nil {
+				log.Printf("Scheduled job %s failed: %v", name, err)
+			}
+		}
+	}()
+}
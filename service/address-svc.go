@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"go-trades/entity"
+	"go-trades/repository"
+	"go-trades/utils"
+	errorMessages "go-trades/utils/error-messages"
+)
+
+type addressService struct {
+	txManager         utils.TxManager
+	AddressRepository repository.AddressRepository
+	Audit             AuditService
+}
+
+type AddressService interface {
+	GetUserAddresses(ctx context.Context, userId uint) (*utils.Response, error)
+	GetUserAddressById(ctx context.Context, userId, id uint) (*utils.Response, error)
+	CreateAddress(ctx context.Context, userId uint, req *entity.AddressRequest) (*utils.Response, error)
+	UpdateAddress(ctx context.Context, userId, id uint, req *entity.AddressRequest) (*utils.Response, error)
+	DeleteAddress(ctx context.Context, userId, id uint) error
+}
+
+func NewAddressService(txManager utils.TxManager, ar repository.AddressRepository, audit AuditService) AddressService {
+	return &addressService{
+		txManager:         txManager,
+		AddressRepository: ar,
+		Audit:             audit,
+	}
+}
+
+func (s *addressService) GetUserAddresses(ctx context.Context, userId uint) (*utils.Response, error) {
+	addresses, err := s.AddressRepository.FindAllByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]entity.AddressDataResponse, len(addresses))
+	for i, address := range addresses {
+		data[i] = toAddressDataResponse(address)
+	}
+
+	return &utils.Response{
+		Status:  200,
+		Message: "Success",
+		Data:    data,
+	}, nil
+}
+
+func (s *addressService) GetUserAddressById(ctx context.Context, userId, id uint) (*utils.Response, error) {
+	address, err := s.AddressRepository.FindByUserIdWithId(ctx, userId, id)
+	if err != nil {
+		return nil, err
+	}
+	if address == nil {
+		return nil, errors.New(errorMessages.ErrAddressNotFound)
+	}
+
+	return &utils.Response{
+		Status:  200,
+		Message: "Success",
+		Data:    toAddressDataResponse(*address),
+	}, nil
+}
+
+func (s *addressService) CreateAddress(ctx context.Context, userId uint, req *entity.AddressRequest) (*utils.Response, error) {
+	existing, err := s.AddressRepository.FindAllByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	address := entity.Address{
+		UserId:            userId,
+		Label:             req.Label,
+		PostalAddress:     toPostalAddress(req),
+		IsDefaultShipping: req.IsDefaultShipping || len(existing) == 0,
+		IsDefaultBilling:  req.IsDefaultBilling || len(existing) == 0,
+	}
+
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.AddressRepository.ClearDefaults(ctx, userId, address.IsDefaultShipping, address.IsDefaultBilling); err != nil {
+			return err
+		}
+
+		if err := s.AddressRepository.CreateAddress(ctx, &address); err != nil {
+			return err
+		}
+
+		return s.Audit.Record(ctx, entity.AuditCreate, entity.AuditAddress, address.ID, nil, address)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &utils.Response{
+		Status:  201,
+		Message: "Address successfully created",
+		Data:    toAddressDataResponse(address),
+	}, nil
+}
+
+func (s *addressService) UpdateAddress(ctx context.Context, userId, id uint, req *entity.AddressRequest) (*utils.Response, error) {
+	address, err := s.AddressRepository.FindByUserIdWithId(ctx, userId, id)
+	if err != nil {
+		return nil, err
+	}
+	if address == nil {
+		return nil, errors.New(errorMessages.ErrAddressNotFound)
+	}
+
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		// Defaults can only be moved to another address, never unset.
+		if err := s.AddressRepository.ClearDefaults(ctx, userId, req.IsDefaultShipping, req.IsDefaultBilling); err != nil {
+			return err
+		}
+
+		before := utils.AuditSnapshot(address)
+		address.Label = req.Label
+		address.PostalAddress = toPostalAddress(req)
+		address.IsDefaultShipping = address.IsDefaultShipping || req.IsDefaultShipping
+		address.IsDefaultBilling = address.IsDefaultBilling || req.IsDefaultBilling
+
+		if err := s.AddressRepository.UpdateAddress(ctx, address); err != nil {
+			return err
+		}
+
+		return s.Audit.Record(ctx, entity.AuditUpdate, entity.AuditAddress, address.ID, before, address)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &utils.Response{
+		Status:  200,
+		Message: "Address successfully updated",
+		Data:    toAddressDataResponse(*address),
+	}, nil
+}
+
+// DeleteAddress moves the defaults the address held to the user's oldest
+// remaining address, so a user with addresses always has both defaults.
+func (s *addressService) DeleteAddress(ctx context.Context, userId, id uint) error {
+	address, err := s.AddressRepository.FindByUserIdWithId(ctx, userId, id)
+	if err != nil {
+		return err
+	}
+	if address == nil {
+		return errors.New(errorMessages.ErrAddressNotFound)
+	}
+
+	return s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.AddressRepository.DeleteAddress(ctx, id); err != nil {
+			return err
+		}
+
+		if err := s.Audit.Record(ctx, entity.AuditDelete, entity.AuditAddress, address.ID, address, nil); err != nil {
+			return err
+		}
+
+		if !address.IsDefaultShipping && !address.IsDefaultBilling {
+			return nil
+		}
+
+		remaining, err := s.AddressRepository.FindAllByUserId(ctx, userId)
+		if err != nil {
+			return err
+		}
+		if len(remaining) == 0 {
+			return nil
+		}
+
+		promoted := &remaining[0]
+		before := utils.AuditSnapshot(promoted)
+		promoted.IsDefaultShipping = promoted.IsDefaultShipping || address.IsDefaultShipping
+		promoted.IsDefaultBilling = promoted.IsDefaultBilling || address.IsDefaultBilling
+		if err := s.AddressRepository.UpdateAddress(ctx, promoted); err != nil {
+			return err
+		}
+
+		return s.Audit.Record(ctx, entity.AuditUpdate, entity.AuditAddress, promoted.ID, before, promoted)
+	})
+}
+
+func toPostalAddress(req *entity.AddressRequest) entity.PostalAddress {
+	return entity.PostalAddress{
+		Recipient:  req.Recipient,
+		Street:     req.Street,
+		City:       req.City,
+		Region:     req.Region,
+		PostalCode: req.PostalCode,
+		Country:    req.Country,
+		Phone:      req.Phone,
+	}
+}
+
+func toAddressDataResponse(address entity.Address) entity.AddressDataResponse {
+	return entity.AddressDataResponse{
+		ID:                address.ID,
+		Label:             address.Label,
+		PostalAddress:     address.PostalAddress,
+		IsDefaultShipping: address.IsDefaultShipping,
+		IsDefaultBilling:  address.IsDefaultBilling,
+	}
+}
@@ -0,0 +1,216 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"go-trades/entity"
+	"go-trades/repository"
+	"go-trades/utils"
+	errorMessages "go-trades/utils/error-messages"
+	"strings"
+	"time"
+)
+
+const apiKeyPrefix = "gt_"
+
+type apiKeyService struct {
+	txManager      utils.TxManager
+	Repository     repository.ApiKeyRepository
+	UserRepository repository.UserRepository
+	RoleRepository repository.RoleRepository
+	Audit          AuditService
+}
+
+type ApiKeyService interface {
+	GetAllApiKeys(ctx context.Context, page, size int) (*utils.Response, int64, int64, error)
+	CreateApiKey(ctx context.Context, actorId uint, actorPermissions map[entity.Permission]bool, req *entity.CreateApiKeyRequest) (*utils.Response, error)
+	RevokeApiKey(ctx context.Context, id uint) error
+}
+
+func NewApiKeyService(txManager utils.TxManager, r repository.ApiKeyRepository, ur repository.UserRepository, rr repository.RoleRepository, audit AuditService) ApiKeyService {
+	return &apiKeyService{
+		txManager:      txManager,
+		Repository:     r,
+		UserRepository: ur,
+		RoleRepository: rr,
+		Audit:          audit,
+	}
+}
+
+func (s *apiKeyService) GetAllApiKeys(ctx context.Context, page, size int) (*utils.Response, int64, int64, error) {
+	apiKeys, totalSize, err := s.Repository.FindAll(ctx, page, size)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	data := make([]entity.ApiKeyDataResponse, len(apiKeys))
+	for i := range apiKeys {
+		data[i] = toApiKeyDataResponse(&apiKeys[i])
+	}
+
+	totalPage := utils.GetTotalPage(totalSize, size)
+
+	return &utils.Response{
+		Status:  200,
+		Message: "Success",
+		Data:    data,
+	}, totalSize, totalPage, nil
+}
+
+// CreateApiKey returns the plain key in the response. It cannot be shown
+// again, since only its hash is kept. actorPermissions are those of the
+// caller, who can only hand out permissions they hold themselves.
+func (s *apiKeyService) CreateApiKey(ctx context.Context, actorId uint, actorPermissions map[entity.Permission]bool, req *entity.CreateApiKeyRequest) (*utils.Response, error) {
+	ownerId := req.UserId
+	if ownerId == 0 {
+		ownerId = actorId
+	}
+
+	// A key acts as its owner, so creating one for someone else is as
+	// sensitive as changing roles.
+	if ownerId != actorId && !actorPermissions[entity.PermRolesWrite] {
+		return nil, errors.New(errorMessages.ErrApiKeyForOtherUser)
+	}
+
+	owner, err := s.UserRepository.FindById(ctx, ownerId)
+	if err != nil {
+		return nil, errors.New(errorMessages.ErrUserNotExists)
+	}
+
+	role, err := s.RoleRepository.FindByName(ctx, owner.Role)
+	if err != nil {
+		return nil, err
+	}
+	granted := make(map[entity.Permission]bool)
+	if role != nil {
+		for _, permission := range role.Permissions {
+			granted[permission.Permission] = true
+		}
+	}
+
+	// A key can narrow its owner's access but never extend it, nor reach
+	// beyond what its creator may do.
+	var permissions []entity.ApiKeyPermission
+	seen := make(map[entity.Permission]bool)
+	for _, permission := range req.Permissions {
+		if !entity.IsValidPermission(permission) {
+			return nil, errors.New(errorMessages.ErrInvalidPermission + ": " + string(permission))
+		}
+		if !granted[permission] {
+			return nil, errors.New(errorMessages.ErrPermissionNotGranted + ": " + string(permission))
+		}
+		if !actorPermissions[permission] {
+			return nil, errors.New(errorMessages.ErrPermissionNotHeld + ": " + string(permission))
+		}
+		if seen[permission] {
+			continue
+		}
+		seen[permission] = true
+		permissions = append(permissions, entity.ApiKeyPermission{Permission: permission})
+	}
+
+	for _, rule := range req.AllowedIPs {
+		if !utils.ValidIPRule(strings.TrimSpace(rule)) {
+			return nil, errors.New(errorMessages.ErrInvalidIPRule + ": " + rule)
+		}
+	}
+
+	// Like quote validity, an expiry date lasts through the end of that day.
+	var expiresAt *time.Time
+	if req.ExpiresAt != "" {
+		date, err := time.ParseInLocation("2006-01-02", req.ExpiresAt, time.Local)
+		if err != nil {
+			return nil, errors.New("invalid date format")
+		}
+		expiry := date.AddDate(0, 0, 1).Add(-time.Second)
+		if expiry.Before(time.Now()) {
+			return nil, errors.New(errorMessages.ErrApiKeyExpiryPassed)
+		}
+		expiresAt = &expiry
+	}
+
+	secret, err := utils.NewOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	key := apiKeyPrefix + secret
+
+	apiKey := &entity.ApiKey{
+		Name:        req.Name,
+		UserId:      owner.ID,
+		Prefix:      key[:len(apiKeyPrefix)+8],
+		KeyHash:     utils.HashToken(key),
+		AllowedIPs:  strings.Join(req.AllowedIPs, ","),
+		ExpiresAt:   expiresAt,
+		CreatedBy:   actorId,
+		Permissions: permissions,
+	}
+
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.Repository.CreateApiKey(ctx, apiKey); err != nil {
+			return err
+		}
+
+		return s.Audit.Record(ctx, entity.AuditCreate, entity.AuditApiKey, apiKey.ID, nil, toApiKeyDataResponse(apiKey))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	data := toApiKeyDataResponse(apiKey)
+	data.Key = key
+
+	return &utils.Response{
+		Status:  201,
+		Message: "API key created. Store it now, it will not be shown again",
+		Data:    data,
+	}, nil
+}
+
+func (s *apiKeyService) RevokeApiKey(ctx context.Context, id uint) error {
+	apiKey, err := s.Repository.FindById(ctx, id)
+	if err != nil {
+		return err
+	}
+	if apiKey == nil {
+		return errors.New(errorMessages.ErrApiKeyNotFound)
+	}
+
+	return s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.Repository.Revoke(ctx, id); err != nil {
+			return err
+		}
+
+		before := toApiKeyDataResponse(apiKey)
+		now := time.Now()
+		apiKey.RevokedAt = &now
+		return s.Audit.Record(ctx, entity.AuditUpdate, entity.AuditApiKey, apiKey.ID, before, toApiKeyDataResponse(apiKey))
+	})
+}
+
+func toApiKeyDataResponse(apiKey *entity.ApiKey) entity.ApiKeyDataResponse {
+	permissions := make([]entity.Permission, len(apiKey.Permissions))
+	for i, permission := range apiKey.Permissions {
+		permissions[i] = permission.Permission
+	}
+
+	allowedIPs := []string{}
+	if apiKey.AllowedIPs != "" {
+		allowedIPs = strings.Split(apiKey.AllowedIPs, ",")
+	}
+
+	return entity.ApiKeyDataResponse{
+		ID:          apiKey.ID,
+		Name:        apiKey.Name,
+		UserId:      apiKey.UserId,
+		Prefix:      apiKey.Prefix,
+		Permissions: permissions,
+		AllowedIPs:  allowedIPs,
+		ExpiresAt:   apiKey.ExpiresAt,
+		LastUsedAt:  apiKey.LastUsedAt,
+		LastUsedIP:  apiKey.LastUsedIP,
+		RevokedAt:   apiKey.RevokedAt,
+		CreatedBy:   apiKey.CreatedBy,
+		CreatedAt:   apiKey.CreatedAt,
+	}
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"go-trades/entity"
+	"go-trades/repository"
+	"go-trades/utils"
+)
+
+// auditVerifyBatch is how many entries VerifyChain loads at a time.
+const auditVerifyBatch = 500
+
+type auditService struct {
+	Repository repository.AuditRepository
+}
+
+type AuditService interface {
+	Record(ctx context.Context, action entity.AuditAction, entityType string, entityId uint, before, after interface{}) error
+	GetAuditLogs(ctx context.Context, filter entity.AuditFilter, page, size int) (*utils.Response, int64, int64, error)
+	VerifyChain(ctx context.Context) (*entity.AuditVerifyResponse, error)
+}
+
+func NewAuditService(r repository.AuditRepository) AuditService {
+	return &auditService{
+		Repository: r,
+	}
+}
+
+// Record appends an entry describing a change to the audit log. Pass nil
+// as before for a create and as after for a delete. Snapshot a record with
+// utils.AuditSnapshot before modifying it in place. The actor, API key,
+// request id and IP are taken from the caller stored in ctx.
+func (s *auditService) Record(ctx context.Context, action entity.AuditAction, entityType string, entityId uint, before, after interface{}) error {
+	changes, err := utils.AuditDiff(utils.AuditSnapshot(before), utils.AuditSnapshot(after))
+	if err != nil {
+		return err
+	}
+	if action == entity.AuditUpdate && changes == "{}" {
+		return nil
+	}
+
+	caller := utils.GetCaller(ctx)
+	entry := &entity.AuditLog{
+		Action:     action,
+		EntityType: entityType,
+		EntityId:   entityId,
+		Changes:    changes,
+		RequestId:  caller.RequestId,
+		IP:         caller.IP,
+	}
+	if caller.UserId != 0 {
+		entry.ActorId = &caller.UserId
+	}
+	if caller.ApiKeyId != 0 {
+		entry.ApiKeyId = &caller.ApiKeyId
+	}
+
+	return s.Repository.Append(ctx, entry)
+}
+
+func (s *auditService) GetAuditLogs(ctx context.Context, filter entity.AuditFilter, page, size int) (*utils.Response, int64, int64, error) {
+	logs, totalSize, err := s.Repository.FindAll(ctx, filter, page, size)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	totalPage := utils.GetTotalPage(totalSize, size)
+
+	return &utils.Response{
+		Status:  200,
+		Message: "Audit logs successfully retrieved",
+		Data:    logs,
+	}, totalSize, totalPage, nil
+}
+
+// VerifyChain recomputes every entry's hash in order and checks that each
+// links to the one before it and that the last one matches the chain head.
+// Any edited, deleted or reordered entry makes it report where the chain
+// breaks.
+func (s *auditService) VerifyChain(ctx context.Context) (*entity.AuditVerifyResponse, error) {
+	head, err := s.Repository.FindHead(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &entity.AuditVerifyResponse{Valid: true}
+	broken := func(entry *entity.AuditLog, reason string) (*entity.AuditVerifyResponse, error) {
+		resp.Valid = false
+		resp.Reason = reason
+		if entry != nil {
+			resp.BrokenAt = &entry.ID
+		}
+		return resp, nil
+	}
+
+	var seq uint64
+	prevHash := repository.AuditGenesisHash
+	for {
+		entries, err := s.Repository.FindBySeqAfter(ctx, seq, auditVerifyBatch)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range entries {
+			entry := &entries[i]
+			resp.Checked++
+
+			if entry.Seq != seq+1 {
+				return broken(entry, "entries are missing before this one")
+			}
+			if entry.PrevHash != prevHash {
+				return broken(entry, "entry does not link to the previous one")
+			}
+			if entry.ComputeHash() != entry.Hash {
+				return broken(entry, "entry was modified")
+			}
+
+			seq = entry.Seq
+			prevHash = entry.Hash
+		}
+
+		if len(entries) < auditVerifyBatch {
+			break
+		}
+	}
+
+	if head == nil {
+		if seq != 0 {
+			return broken(nil, "chain head is missing")
+		}
+		return resp, nil
+	}
+	if head.Seq != seq || head.Hash != prevHash {
+		return broken(nil, "entries are missing at the end of the log")
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"go-trades/entity"
+	"go-trades/repository"
+	"go-trades/utils"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeAuditRepository keeps the chain in memory and appends the way the
+// database repository does.
+type fakeAuditRepository struct {
+	entries []entity.AuditLog
+	head    *entity.AuditChainHead
+}
+
+func (r *fakeAuditRepository) Append(ctx context.Context, entry *entity.AuditLog) error {
+	if r.head == nil {
+		r.head = &entity.AuditChainHead{ID: 1, Hash: repository.AuditGenesisHash}
+	}
+
+	entry.ID = uint(len(r.entries) + 1)
+	entry.Seq = r.head.Seq + 1
+	entry.PrevHash = r.head.Hash
+	entry.CreatedAt = time.Now().Truncate(time.Millisecond)
+	entry.Hash = entry.ComputeHash()
+	r.entries = append(r.entries, *entry)
+
+	r.head.Seq = entry.Seq
+	r.head.Hash = entry.Hash
+	return nil
+}
+
+func (r *fakeAuditRepository) FindAll(ctx context.Context, filter entity.AuditFilter, page, size int) ([]entity.AuditLog, int64, error) {
+	return r.entries, int64(len(r.entries)), nil
+}
+
+func (r *fakeAuditRepository) FindBySeqAfter(ctx context.Context, seq uint64, limit int) ([]entity.AuditLog, error) {
+	var result []entity.AuditLog
+	for _, entry := range r.entries {
+		if entry.Seq > seq && len(result) < limit {
+			result = append(result, entry)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeAuditRepository) FindHead(ctx context.Context) (*entity.AuditChainHead, error) {
+	if r.head == nil {
+		return nil, nil
+	}
+	head := *r.head
+	return &head, nil
+}
+
+// newAuditChain records n product updates by the same user.
+func newAuditChain(t *testing.T, n int) (*fakeAuditRepository, AuditService) {
+	t.Helper()
+
+	repo := &fakeAuditRepository{}
+	svc := NewAuditService(repo)
+	ctx := utils.WithCaller(context.Background(), utils.Caller{UserId: 7, RequestId: "req-1", IP: "10.0.0.1"})
+
+	for i := 0; i < n; i++ {
+		before := map[string]interface{}{"price": i}
+		after := map[string]interface{}{"price": i + 1}
+		if err := svc.Record(ctx, entity.AuditUpdate, entity.AuditProduct, uint(i+1), before, after); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return repo, svc
+}
+
+func TestAuditRecordChainsEntries(t *testing.T) {
+	repo, _ := newAuditChain(t, 3)
+
+	if len(repo.entries) != 3 {
+		t.Fatalf("recorded %d entries, want 3", len(repo.entries))
+	}
+	prevHash := repository.AuditGenesisHash
+	for _, entry := range repo.entries {
+		if entry.PrevHash != prevHash {
+			t.Errorf("entry %d PrevHash = %s, want %s", entry.Seq, entry.PrevHash, prevHash)
+		}
+		if entry.ActorId == nil || *entry.ActorId != 7 {
+			t.Errorf("entry %d ActorId = %v, want 7", entry.Seq, entry.ActorId)
+		}
+		if entry.RequestId != "req-1" || entry.IP != "10.0.0.1" {
+			t.Errorf("entry %d caller = %s %s, want req-1 10.0.0.1", entry.Seq, entry.RequestId, entry.IP)
+		}
+		prevHash = entry.Hash
+	}
+}
+
+func TestAuditRecordSkipsEmptyUpdate(t *testing.T) {
+	repo := &fakeAuditRepository{}
+	svc := NewAuditService(repo)
+
+	same := map[string]interface{}{"price": 1}
+	if err := svc.Record(context.Background(), entity.AuditUpdate, entity.AuditProduct, 1, same, same); err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.entries) != 0 {
+		t.Errorf("recorded %d entries for an update without changes, want 0", len(repo.entries))
+	}
+}
+
+func TestVerifyChainValid(t *testing.T) {
+	for _, n := range []int{0, 1, auditVerifyBatch, auditVerifyBatch + 1} {
+		_, svc := newAuditChain(t, n)
+
+		resp, err := svc.VerifyChain(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !resp.Valid || resp.Checked != int64(n) {
+			t.Errorf("%d entries: VerifyChain = %+v, want valid with %d checked", n, resp, n)
+		}
+	}
+}
+
+func TestVerifyChainBroken(t *testing.T) {
+	tests := []struct {
+		name     string
+		tamper   func(repo *fakeAuditRepository)
+		brokenAt uint
+		reason   string
+	}{
+		{
+			name:     "modified entry",
+			tamper:   func(repo *fakeAuditRepository) { repo.entries[1].Changes = `{"price":{"from":0,"to":100}}` },
+			brokenAt: 2,
+			reason:   "modified",
+		},
+		{
+			name: "rehashed entry",
+			tamper: func(repo *fakeAuditRepository) {
+				repo.entries[1].EntityId = 99
+				repo.entries[1].Hash = repo.entries[1].ComputeHash()
+			},
+			brokenAt: 3,
+			reason:   "link",
+		},
+		{
+			name:     "deleted entry",
+			tamper:   func(repo *fakeAuditRepository) { repo.entries = append(repo.entries[:1], repo.entries[2:]...) },
+			brokenAt: 3,
+			reason:   "missing",
+		},
+		{
+			name: "reordered entries",
+			tamper: func(repo *fakeAuditRepository) {
+				repo.entries[0].Seq, repo.entries[1].Seq = repo.entries[1].Seq, repo.entries[0].Seq
+				repo.entries[0], repo.entries[1] = repo.entries[1], repo.entries[0]
+			},
+			brokenAt: 2,
+			reason:   "link",
+		},
+		{
+			name:   "deleted last entry",
+			tamper: func(repo *fakeAuditRepository) { repo.entries = repo.entries[:2] },
+			reason: "end of the log",
+		},
+		{
+			name:   "deleted head",
+			tamper: func(repo *fakeAuditRepository) { repo.head = nil },
+			reason: "head is missing",
+		},
+	}
+
+	for _, tt := range tests {
+		repo, svc := newAuditChain(t, 3)
+		tt.tamper(repo)
+
+		resp, err := svc.VerifyChain(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Valid {
+			t.Errorf("%s: VerifyChain reported a valid chain", tt.name)
+			continue
+		}
+		if !strings.Contains(resp.Reason, tt.reason) {
+			t.Errorf("%s: Reason = %q, want it to mention %q", tt.name, resp.Reason, tt.reason)
+		}
+		switch {
+		case tt.brokenAt == 0 && resp.BrokenAt != nil:
+			t.Errorf("%s: BrokenAt = %d, want none", tt.name, *resp.BrokenAt)
+		case tt.brokenAt != 0 && (resp.BrokenAt == nil || *resp.BrokenAt != tt.brokenAt):
+			t.Errorf("%s: BrokenAt = %v, want %d", tt.name, resp.BrokenAt, tt.brokenAt)
+		}
+	}
+}
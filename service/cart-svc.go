@@ -0,0 +1,343 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"go-trades/entity"
+	"go-trades/repository"
+	"go-trades/utils"
+	errorMessages "go-trades/utils/error-messages"
+)
+
+// CartCheckoutError lists the cart lines that kept a checkout from going
+// through.
+type CartCheckoutError struct {
+	Lines []entity.CartLineError
+}
+
+func (e *CartCheckoutError) Error() string {
+	return errorMessages.ErrCartCheckoutInvalid
+}
+
+type cartService struct {
+	txManager           utils.TxManager
+	CartRepository      repository.CartRepository
+	ProductRepository   repository.ProductRepository
+	InventoryRepository repository.InventoryRepository
+	OrderService        OrderService
+	Audit               AuditService
+}
+
+type CartService interface {
+	GetCart(ctx context.Context, userId uint) (*utils.Response, error)
+	AddCartItem(ctx context.Context, userId uint, req *entity.AddCartItemRequest) (*utils.Response, error)
+	UpdateCartItem(ctx context.Context, userId, productId uint, req *entity.UpdateCartItemRequest) (*utils.Response, error)
+	RemoveCartItem(ctx context.Context, userId, productId uint) (*utils.Response, error)
+	CheckoutCart(ctx context.Context, userId uint, req *entity.CheckoutCartRequest) (*utils.Response, error)
+}
+
+func NewCartService(txManager utils.TxManager, cr repository.CartRepository, pr repository.ProductRepository, ir repository.InventoryRepository, os OrderService, audit AuditService) CartService {
+	return &cartService{
+		txManager:           txManager,
+		CartRepository:      cr,
+		ProductRepository:   pr,
+		InventoryRepository: ir,
+		OrderService:        os,
+		Audit:               audit,
+	}
+}
+
+func (s *cartService) GetCart(ctx context.Context, userId uint) (*utils.Response, error) {
+	cart, err := s.findOrCreateCart(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := s.toCartDataResponse(ctx, cart)
+	if err != nil {
+		return nil, err
+	}
+
+	return &utils.Response{
+		Status:  200,
+		Message: "Success",
+		Data:    data,
+	}, nil
+}
+
+func (s *cartService) AddCartItem(ctx context.Context, userId uint, req *entity.AddCartItemRequest) (*utils.Response, error) {
+	product, err := s.ProductRepository.FindById(ctx, req.ProductId)
+	if err != nil {
+		return nil, err
+	}
+	if product == nil {
+		return nil, errors.New(errorMessages.ErrProductNotFound)
+	}
+
+	cart, err := s.findOrCreateCart(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	action := entity.AuditCreate
+	item := entity.CartItem{
+		CartId:    cart.ID,
+		ProductId: product.ID,
+	}
+	for _, existing := range cart.CartItems {
+		if existing.ProductId == product.ID {
+			action = entity.AuditUpdate
+			item = existing
+			break
+		}
+	}
+
+	var before map[string]interface{}
+	if action == entity.AuditUpdate {
+		before = utils.AuditSnapshot(item)
+	}
+	item.Qty += req.Qty
+	item.Price = product.Price
+
+	if err := s.saveCartItem(ctx, action, before, &item); err != nil {
+		return nil, err
+	}
+
+	return s.GetCart(ctx, userId)
+}
+
+func (s *cartService) UpdateCartItem(ctx context.Context, userId, productId uint, req *entity.UpdateCartItemRequest) (*utils.Response, error) {
+	cart, err := s.findOrCreateCart(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	var item *entity.CartItem
+	for i := range cart.CartItems {
+		if cart.CartItems[i].ProductId == productId {
+			item = &cart.CartItems[i]
+			break
+		}
+	}
+	if item == nil {
+		return nil, errors.New(errorMessages.ErrCartItemNotFound)
+	}
+
+	before := utils.AuditSnapshot(item)
+	item.Qty = req.Qty
+
+	if err := s.saveCartItem(ctx, entity.AuditUpdate, before, item); err != nil {
+		return nil, err
+	}
+
+	return s.GetCart(ctx, userId)
+}
+
+func (s *cartService) RemoveCartItem(ctx context.Context, userId, productId uint) (*utils.Response, error) {
+	cart, err := s.findOrCreateCart(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	var item *entity.CartItem
+	for i := range cart.CartItems {
+		if cart.CartItems[i].ProductId == productId {
+			item = &cart.CartItems[i]
+			break
+		}
+	}
+	if item == nil {
+		return nil, errors.New(errorMessages.ErrCartItemNotFound)
+	}
+
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.CartRepository.DeleteCartItem(ctx, cart.ID, productId); err != nil {
+			return err
+		}
+
+		return s.Audit.Record(ctx, entity.AuditDelete, entity.AuditCart, cart.ID, item, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetCart(ctx, userId)
+}
+
+// CheckoutCart validates every cart line against the live catalogue before
+// handing the cart to OrderService.CreateOrder. The cart stays locked from
+// validation until it is cleared, so concurrent checkouts of one cart place a
+// single order. Lines whose price changed are updated to the new price, so
+// checking out again accepts it.
+func (s *cartService) CheckoutCart(ctx context.Context, userId uint, req *entity.CheckoutCartRequest) (*utils.Response, error) {
+	var resp *utils.Response
+	var lineErrors []entity.CartLineError
+
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		cart, err := s.CartRepository.FindByUserIdForUpdate(ctx, userId)
+		if err != nil {
+			return err
+		}
+		if cart == nil || len(cart.CartItems) == 0 {
+			return errors.New(errorMessages.ErrCartEmpty)
+		}
+
+		orderDetails := make([]entity.OrderDetailRequest, len(cart.CartItems))
+		for i, item := range cart.CartItems {
+			orderDetails[i] = entity.OrderDetailRequest{
+				ProductId: item.ProductId,
+				Qty:       item.Qty,
+			}
+
+			product, err := s.ProductRepository.FindById(ctx, item.ProductId)
+			if err != nil {
+				return err
+			}
+			if product == nil {
+				lineErrors = append(lineErrors, entity.CartLineError{ProductId: item.ProductId, Error: errorMessages.ErrCartProductUnavailable})
+				continue
+			}
+
+			if product.Price != item.Price {
+				lineErrors = append(lineErrors, entity.CartLineError{ProductId: item.ProductId, Error: errorMessages.ErrCartPriceChanged})
+
+				before := utils.AuditSnapshot(item)
+				item.Price = product.Price
+				if err := s.saveCartItem(ctx, entity.AuditUpdate, before, &item); err != nil {
+					return err
+				}
+				continue
+			}
+
+			inventory, err := s.InventoryRepository.FindFirstByProductId(ctx, item.ProductId)
+			if err != nil {
+				return err
+			}
+			if (inventory == nil || inventory.Stock < item.Qty) && !product.AllowsBackorder() {
+				lineErrors = append(lineErrors, entity.CartLineError{ProductId: item.ProductId, Error: errorMessages.ErrInventoryInsufficientStock})
+			}
+		}
+
+		// Commit the refreshed prices and report the failed lines afterwards.
+		if len(lineErrors) > 0 {
+			return nil
+		}
+
+		resp, err = s.OrderService.CreateOrder(ctx, userId, &entity.CreateOrderRequest{
+			ShippingAddress:   req.ShippingAddress,
+			ShippingAddressId: req.ShippingAddressId,
+			BillingAddressId:  req.BillingAddressId,
+			OrderDetails:      orderDetails,
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := s.CartRepository.ClearCart(ctx, cart.ID); err != nil {
+			return err
+		}
+
+		before := utils.AuditSnapshot(cart)
+		cart.CartItems = nil
+		return s.Audit.Record(ctx, entity.AuditUpdate, entity.AuditCart, cart.ID, before, cart)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(lineErrors) > 0 {
+		return nil, &CartCheckoutError{Lines: lineErrors}
+	}
+	return resp, nil
+}
+
+// findOrCreateCart returns the user's cart, creating it on first use. A
+// request that loses the race to create it reads the winner's cart instead.
+func (s *cartService) findOrCreateCart(ctx context.Context, userId uint) (*entity.Cart, error) {
+	cart, err := s.CartRepository.FindByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if cart != nil {
+		return cart, nil
+	}
+
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		cart := &entity.Cart{UserId: userId}
+		created, err := s.CartRepository.CreateCart(ctx, cart)
+		if err != nil {
+			return err
+		}
+		if !created {
+			return nil
+		}
+
+		return s.Audit.Record(ctx, entity.AuditCreate, entity.AuditCart, cart.ID, nil, cart)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cart, err = s.CartRepository.FindByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if cart == nil {
+		return nil, errors.New(errorMessages.ErrCartNotFound)
+	}
+	return cart, nil
+}
+
+// saveCartItem saves a cart line and records it under its cart. Pass nil as
+// before for a new line.
+func (s *cartService) saveCartItem(ctx context.Context, action entity.AuditAction, before map[string]interface{}, item *entity.CartItem) error {
+	return s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.CartRepository.SaveCartItem(ctx, item); err != nil {
+			return err
+		}
+
+		return s.Audit.Record(ctx, action, entity.AuditCart, item.CartId, before, item)
+	})
+}
+
+func (s *cartService) toCartDataResponse(ctx context.Context, cart *entity.Cart) (*entity.CartDataResponse, error) {
+	data := entity.CartDataResponse{
+		ID:               cart.ID,
+		UserId:           cart.UserId,
+		UpdatedAt:        cart.UpdatedAt,
+		CartItemResponse: make([]entity.CartItemResponse, len(cart.CartItems)),
+	}
+
+	for i, item := range cart.CartItems {
+		line := entity.CartItemResponse{
+			ProductId:  item.ProductId,
+			Qty:        item.Qty,
+			AddedPrice: item.Price,
+		}
+
+		product, err := s.ProductRepository.FindById(ctx, item.ProductId)
+		if err != nil {
+			return nil, err
+		}
+		if product != nil {
+			inventory, err := s.InventoryRepository.FindFirstByProductId(ctx, item.ProductId)
+			if err != nil {
+				return nil, err
+			}
+			if inventory != nil {
+				line.Stock = inventory.Stock
+			}
+
+			line.ProductName = product.Name
+			line.Price = product.Price
+			line.PriceChanged = product.Price != item.Price
+			line.Available = line.Stock >= item.Qty || product.AllowsBackorder()
+			line.Subtotal = product.Price * item.Qty
+			data.Total += line.Subtotal
+		}
+
+		data.CartItemResponse[i] = line
+	}
+
+	return &data, nil
+}
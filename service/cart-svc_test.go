@@ -0,0 +1,250 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"go-trades/entity"
+	"go-trades/repository"
+	errorMessages "go-trades/utils/error-messages"
+	"reflect"
+	"testing"
+)
+
+// fakeCartRepository keeps carts by user id. Checkout may only read the cart
+// through FindByUserIdForUpdate.
+type fakeCartRepository struct {
+	repository.CartRepository
+	carts  map[uint]*entity.Cart
+	nextId uint
+	// racer, when set, is created by another request just before CreateCart
+	// runs.
+	racer *entity.Cart
+}
+
+func copyCart(cart entity.Cart) *entity.Cart {
+	cart.CartItems = append([]entity.CartItem(nil), cart.CartItems...)
+	return &cart
+}
+
+func (r *fakeCartRepository) snapshot() func() {
+	carts := make(map[uint]*entity.Cart, len(r.carts))
+	for userId, cart := range r.carts {
+		carts[userId] = copyCart(*cart)
+	}
+	nextId := r.nextId
+	return func() {
+		r.carts = carts
+		r.nextId = nextId
+	}
+}
+
+func (r *fakeCartRepository) FindByUserId(ctx context.Context, userId uint) (*entity.Cart, error) {
+	cart, ok := r.carts[userId]
+	if !ok {
+		return nil, nil
+	}
+	return copyCart(*cart), nil
+}
+
+func (r *fakeCartRepository) FindByUserIdForUpdate(ctx context.Context, userId uint) (*entity.Cart, error) {
+	return r.FindByUserId(ctx, userId)
+}
+
+func (r *fakeCartRepository) CreateCart(ctx context.Context, cart *entity.Cart) (bool, error) {
+	if r.racer != nil {
+		r.carts[r.racer.UserId] = r.racer
+		r.racer = nil
+	}
+	if _, ok := r.carts[cart.UserId]; ok {
+		return false, nil
+	}
+	r.nextId++
+	cart.ID = r.nextId
+	r.carts[cart.UserId] = copyCart(*cart)
+	return true, nil
+}
+
+func (r *fakeCartRepository) SaveCartItem(ctx context.Context, item *entity.CartItem) error {
+	for _, cart := range r.carts {
+		if cart.ID != item.CartId {
+			continue
+		}
+		for i := range cart.CartItems {
+			if cart.CartItems[i].ProductId == item.ProductId {
+				cart.CartItems[i] = *item
+				return nil
+			}
+		}
+		cart.CartItems = append(cart.CartItems, *item)
+		return nil
+	}
+	return errors.New("no such cart")
+}
+
+func (r *fakeCartRepository) ClearCart(ctx context.Context, cartId uint) error {
+	for _, cart := range r.carts {
+		if cart.ID == cartId {
+			cart.CartItems = nil
+		}
+	}
+	return nil
+}
+
+type cartFixture struct {
+	carts     *fakeCartRepository
+	orders    *fakeOrderRepository
+	inventory *fakeInventoryRepository
+	audit     *fakeAuditRepository
+	svc       CartService
+}
+
+// newCartFixture gives user 1 cart 1 with the given lines, over the same
+// catalogue and stock as newOrderFixture and with no orders placed yet.
+func newCartFixture(items ...entity.CartItem) *cartFixture {
+	f := &cartFixture{
+		carts: &fakeCartRepository{
+			carts:  map[uint]*entity.Cart{1: {ID: 1, UserId: 1, CartItems: items}},
+			nextId: 1,
+		},
+		orders:    newFakeOrderRepository(),
+		inventory: newFakeInventoryRepository(map[uint]uint{1: 5, 2: 5, 3: 1}),
+		audit:     &fakeAuditRepository{},
+	}
+	products := newFakeProductRepository(
+		testProduct(1, 100, entity.NoBackorder),
+		testProduct(2, 250, entity.NoBackorder),
+		testProduct(3, 40, entity.Backorder),
+	)
+	txManager := &fakeTxManager{stores: []fakeStore{f.carts, f.orders, f.inventory, f.audit}}
+	orders := NewOrderService(txManager, f.orders, products, f.inventory, nil, &fakeAddressRepository{}, NewAuditService(f.audit))
+	f.svc = NewCartService(txManager, f.carts, products, f.inventory, orders, NewAuditService(f.audit))
+	return f
+}
+
+func (f *cartFixture) assertStock(t *testing.T, want map[uint]uint) {
+	t.Helper()
+	if !reflect.DeepEqual(f.inventory.stock, want) {
+		t.Errorf("stock = %v, want %v", f.inventory.stock, want)
+	}
+}
+
+var checkoutRequest = &entity.CheckoutCartRequest{ShippingAddress: "1 Main Street"}
+
+func TestCheckoutCartPlacesOrderAndClearsCart(t *testing.T) {
+	f := newCartFixture(
+		entity.CartItem{CartId: 1, ProductId: 1, Qty: 2, Price: 100},
+		entity.CartItem{CartId: 1, ProductId: 3, Qty: 2, Price: 40},
+	)
+
+	if _, err := f.svc.CheckoutCart(context.Background(), 1, checkoutRequest); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(f.orders.orders) != 1 {
+		t.Fatalf("placed %d orders, want 1", len(f.orders.orders))
+	}
+	order := f.orders.orders[1]
+	if order.UserId != 1 || order.Total != 280 {
+		t.Errorf("order = %+v, want user 1's order over 280", order)
+	}
+	f.assertStock(t, map[uint]uint{1: 3, 2: 5, 3: 0})
+	if items := f.carts.carts[1].CartItems; len(items) != 0 {
+		t.Errorf("cart still holds %+v", items)
+	}
+}
+
+func TestCheckoutCartTwicePlacesOneOrder(t *testing.T) {
+	f := newCartFixture(entity.CartItem{CartId: 1, ProductId: 1, Qty: 1, Price: 100})
+
+	if _, err := f.svc.CheckoutCart(context.Background(), 1, checkoutRequest); err != nil {
+		t.Fatal(err)
+	}
+	_, err := f.svc.CheckoutCart(context.Background(), 1, checkoutRequest)
+	if err == nil || err.Error() != errorMessages.ErrCartEmpty {
+		t.Errorf("second checkout error = %v, want %s", err, errorMessages.ErrCartEmpty)
+	}
+
+	if len(f.orders.orders) != 1 {
+		t.Errorf("placed %d orders, want 1", len(f.orders.orders))
+	}
+	f.assertStock(t, map[uint]uint{1: 4, 2: 5, 3: 1})
+}
+
+func TestCheckoutCartReportsInvalidLines(t *testing.T) {
+	f := newCartFixture(
+		entity.CartItem{CartId: 1, ProductId: 1, Qty: 9, Price: 100},
+		entity.CartItem{CartId: 1, ProductId: 2, Qty: 1, Price: 200},
+		entity.CartItem{CartId: 1, ProductId: 7, Qty: 1, Price: 10},
+	)
+
+	_, err := f.svc.CheckoutCart(context.Background(), 1, checkoutRequest)
+	var invalid *CartCheckoutError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("error = %v, want a CartCheckoutError", err)
+	}
+
+	want := []entity.CartLineError{
+		{ProductId: 1, Error: errorMessages.ErrInventoryInsufficientStock},
+		{ProductId: 2, Error: errorMessages.ErrCartPriceChanged},
+		{ProductId: 7, Error: errorMessages.ErrCartProductUnavailable},
+	}
+	if !reflect.DeepEqual(invalid.Lines, want) {
+		t.Errorf("lines = %+v, want %+v", invalid.Lines, want)
+	}
+	if len(f.orders.orders) != 0 {
+		t.Error("an invalid cart placed an order")
+	}
+	f.assertStock(t, map[uint]uint{1: 5, 2: 5, 3: 1})
+
+	// The new price is kept so the next checkout accepts it.
+	if price := f.carts.carts[1].CartItems[1].Price; price != 250 {
+		t.Errorf("product 2 price = %d, want 250", price)
+	}
+}
+
+func TestCheckoutCartWithoutCart(t *testing.T) {
+	f := newCartFixture()
+
+	_, err := f.svc.CheckoutCart(context.Background(), 2, checkoutRequest)
+	if err == nil || err.Error() != errorMessages.ErrCartEmpty {
+		t.Errorf("error = %v, want %s", err, errorMessages.ErrCartEmpty)
+	}
+	if _, ok := f.carts.carts[2]; ok {
+		t.Error("checkout created a cart")
+	}
+}
+
+func TestGetCartCreatesCartOnce(t *testing.T) {
+	f := newCartFixture()
+
+	for i := 0; i < 2; i++ {
+		if _, err := f.svc.GetCart(context.Background(), 2); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if cart, ok := f.carts.carts[2]; !ok || cart.ID != 2 {
+		t.Errorf("user 2 cart = %+v, want cart 2", cart)
+	}
+	if len(f.audit.entries) != 1 || f.audit.entries[0].Action != entity.AuditCreate {
+		t.Errorf("audit entries = %+v, want one create", f.audit.entries)
+	}
+}
+
+func TestAddCartItemUsesCartCreatedConcurrently(t *testing.T) {
+	f := newCartFixture()
+	f.carts.racer = &entity.Cart{ID: 5, UserId: 2, CartItems: []entity.CartItem{{CartId: 5, ProductId: 1, Qty: 1, Price: 100}}}
+
+	if _, err := f.svc.AddCartItem(context.Background(), 2, &entity.AddCartItemRequest{ProductId: 1, Qty: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	cart := f.carts.carts[2]
+	want := []entity.CartItem{{CartId: 5, ProductId: 1, Qty: 3, Price: 100}}
+	if cart.ID != 5 || !reflect.DeepEqual(cart.CartItems, want) {
+		t.Errorf("user 2 cart = %+v, want cart 5 holding %+v", cart, want)
+	}
+	if len(f.audit.entries) != 1 || f.audit.entries[0].Action != entity.AuditUpdate {
+		t.Errorf("audit entries = %+v, want only the line update", f.audit.entries)
+	}
+}
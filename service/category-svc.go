@@ -1,35 +1,41 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"go-trades/entity"
 	"go-trades/repository"
 	"go-trades/utils"
 	errorMessages "go-trades/utils/error-messages"
 
-	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
 type categoryService struct {
+	txManager  utils.TxManager
 	Repository repository.CategoryRepository
+	Audit      AuditService
 }
 
 type CategoryService interface {
-	GetAllCategories(ctx *gin.Context, page, size int) (*utils.Response, int64, int64, error)
-	GetCategoryById(ctx *gin.Context, id uint) (*utils.Response, error)
-	CreateCategory(ctx *gin.Context, req *entity.CategoryRequest) (*utils.Response, error)
-	UpdateCategory(ctx *gin.Context, id uint, req *entity.CategoryRequest) (*utils.Response, error)
-	DeleteCategory(ctx *gin.Context, id uint) error
+	GetAllCategories(ctx context.Context, page, size int) (*utils.Response, int64, int64, error)
+	GetCategoryById(ctx context.Context, id uint) (*utils.Response, error)
+	CreateCategory(ctx context.Context, req *entity.CategoryRequest) (*utils.Response, error)
+	// UpdateCategory and DeleteCategory refuse to act when ifMatch is set
+	// and does not hold the category's current ETag.
+	UpdateCategory(ctx context.Context, id uint, req *entity.CategoryRequest, ifMatch string) (*utils.Response, error)
+	DeleteCategory(ctx context.Context, id uint, ifMatch string) error
 }
 
-func NewCategoryService(r repository.CategoryRepository) CategoryService {
+func NewCategoryService(txManager utils.TxManager, r repository.CategoryRepository, audit AuditService) CategoryService {
 	return &categoryService{
+		txManager:  txManager,
 		Repository: r,
+		Audit:      audit,
 	}
 }
 
-func (s *categoryService) GetAllCategories(ctx *gin.Context, page, size int) (*utils.Response, int64, int64, error) {
+func (s *categoryService) GetAllCategories(ctx context.Context, page, size int) (*utils.Response, int64, int64, error) {
 	categories, totalSize, err := s.Repository.FindAll(ctx, page, size)
 	if err != nil {
 		return nil, 0, 0, err
@@ -38,9 +44,10 @@ func (s *categoryService) GetAllCategories(ctx *gin.Context, page, size int) (*u
 	data := make([]entity.CategoryDataResponse, len(categories))
 	for i, category := range categories {
 		data[i] = entity.CategoryDataResponse{
-			ID:   category.ID,
-			Code: category.Code,
-			Name: category.Name,
+			ID:      category.ID,
+			Code:    category.Code,
+			Name:    category.Name,
+			Version: category.Version,
 		}
 	}
 
@@ -53,7 +60,7 @@ func (s *categoryService) GetAllCategories(ctx *gin.Context, page, size int) (*u
 	}, totalSize, totalPage, nil
 }
 
-func (s *categoryService) GetCategoryById(ctx *gin.Context, id uint) (*utils.Response, error) {
+func (s *categoryService) GetCategoryById(ctx context.Context, id uint) (*utils.Response, error) {
 	category, err := s.Repository.FindById(ctx, id)
 	if err != nil {
 		return nil, err
@@ -64,9 +71,10 @@ func (s *categoryService) GetCategoryById(ctx *gin.Context, id uint) (*utils.Res
 	}
 
 	data := entity.CategoryDataResponse{
-		ID:   category.ID,
-		Code: category.Code,
-		Name: category.Name,
+		ID:      category.ID,
+		Code:    category.Code,
+		Name:    category.Name,
+		Version: category.Version,
 	}
 	return &utils.Response{
 		Status:  200,
@@ -75,7 +83,7 @@ func (s *categoryService) GetCategoryById(ctx *gin.Context, id uint) (*utils.Res
 	}, nil
 }
 
-func (s *categoryService) CreateCategory(ctx *gin.Context, req *entity.CategoryRequest) (*utils.Response, error) {
+func (s *categoryService) CreateCategory(ctx context.Context, req *entity.CategoryRequest) (*utils.Response, error) {
 
 	existingByName, err := s.Repository.FindByName(ctx, req.Name)
 	if err == nil && existingByName != nil {
@@ -98,7 +106,14 @@ func (s *categoryService) CreateCategory(ctx *gin.Context, req *entity.CategoryR
 		Name: req.Name,
 	}
 
-	if err := s.Repository.CreateCategory(ctx, category); err != nil {
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.Repository.CreateCategory(ctx, category); err != nil {
+			return err
+		}
+
+		return s.Audit.Record(ctx, entity.AuditCreate, entity.AuditCategory, category.ID, nil, category)
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -108,9 +123,10 @@ func (s *categoryService) CreateCategory(ctx *gin.Context, req *entity.CategoryR
 	}
 
 	data := entity.CategoryDataResponse{
-		ID:   savedCategory.ID,
-		Code: savedCategory.Code,
-		Name: savedCategory.Name,
+		ID:      savedCategory.ID,
+		Code:    savedCategory.Code,
+		Name:    savedCategory.Name,
+		Version: savedCategory.Version,
 	}
 
 	return &utils.Response{
@@ -120,7 +136,7 @@ func (s *categoryService) CreateCategory(ctx *gin.Context, req *entity.CategoryR
 	}, nil
 }
 
-func (s *categoryService) UpdateCategory(ctx *gin.Context, id uint, req *entity.CategoryRequest) (*utils.Response, error) {
+func (s *categoryService) UpdateCategory(ctx context.Context, id uint, req *entity.CategoryRequest, ifMatch string) (*utils.Response, error) {
 
 	category, err := s.Repository.FindById(ctx, id)
 	if err != nil {
@@ -131,6 +147,10 @@ func (s *categoryService) UpdateCategory(ctx *gin.Context, id uint, req *entity.
 		return nil, errors.New(errorMessages.ErrCategoryNotFound)
 	}
 
+	if ifMatch != "" && !utils.ETagMatches(ifMatch, utils.ETag(category.Version), false) {
+		return nil, errors.New(errorMessages.ErrPreconditionFailed)
+	}
+
 	existingByName, err := s.Repository.FindByName(ctx, req.Name)
 	if err != nil {
 		return nil, err
@@ -147,17 +167,26 @@ func (s *categoryService) UpdateCategory(ctx *gin.Context, id uint, req *entity.
 		return nil, errors.New(errorMessages.ErrCategoryCodeExists)
 	}
 
+	before := utils.AuditSnapshot(category)
 	category.Code = req.Code
 	category.Name = req.Name
 
-	if err := s.Repository.UpdateCategory(ctx, category); err != nil {
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.Repository.UpdateCategory(ctx, category); err != nil {
+			return err
+		}
+
+		return s.Audit.Record(ctx, entity.AuditUpdate, entity.AuditCategory, category.ID, before, category)
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	data := entity.CategoryDataResponse{
-		ID:   category.ID,
-		Code: category.Code,
-		Name: category.Name,
+		ID:      category.ID,
+		Code:    category.Code,
+		Name:    category.Name,
+		Version: category.Version,
 	}
 
 	return &utils.Response{
@@ -167,10 +196,25 @@ func (s *categoryService) UpdateCategory(ctx *gin.Context, id uint, req *entity.
 	}, nil
 }
 
-func (s *categoryService) DeleteCategory(ctx *gin.Context, id uint) error {
-	if err := s.Repository.DeleteCategory(ctx, id); err != nil {
+func (s *categoryService) DeleteCategory(ctx context.Context, id uint, ifMatch string) error {
+	category, err := s.Repository.FindById(ctx, id)
+	if err != nil {
 		return err
 	}
 
-	return nil
+	if category == nil {
+		return errors.New(errorMessages.ErrCategoryNotFound)
+	}
+
+	if ifMatch != "" && !utils.ETagMatches(ifMatch, utils.ETag(category.Version), false) {
+		return errors.New(errorMessages.ErrPreconditionFailed)
+	}
+
+	return s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.Repository.DeleteCategory(ctx, category); err != nil {
+			return err
+		}
+
+		return s.Audit.Record(ctx, entity.AuditDelete, entity.AuditCategory, category.ID, category, nil)
+	})
 }
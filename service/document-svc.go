@@ -0,0 +1,262 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-trades/entity"
+	"go-trades/repository"
+	"go-trades/utils"
+	errorMessages "go-trades/utils/error-messages"
+	"strconv"
+)
+
+const (
+	documentMargin     = 50.0
+	documentLineHeight = 16.0
+	documentDateLayout = "2006-01-02"
+)
+
+type documentService struct {
+	OrderRepository   repository.OrderRepository
+	ProductRepository repository.ProductRepository
+	PaymentRepository repository.PaymentRepository
+	InvoiceRepository repository.InvoiceRepository
+}
+
+type DocumentService interface {
+	GetInvoicePdf(ctx context.Context, orderId uint) ([]byte, string, error)
+	GetUserInvoicePdf(ctx context.Context, userId, orderId uint) ([]byte, string, error)
+	GetPackingSlipPdf(ctx context.Context, orderId uint) ([]byte, string, error)
+	GetUserPackingSlipPdf(ctx context.Context, userId, orderId uint) ([]byte, string, error)
+}
+
+func NewDocumentService(or repository.OrderRepository, pr repository.ProductRepository, pyr repository.PaymentRepository, ir repository.InvoiceRepository) DocumentService {
+	return &documentService{
+		OrderRepository:   or,
+		ProductRepository: pr,
+		PaymentRepository: pyr,
+		InvoiceRepository: ir,
+	}
+}
+
+func (s *documentService) GetInvoicePdf(ctx context.Context, orderId uint) ([]byte, string, error) {
+	order, err := s.OrderRepository.FindById(ctx, orderId)
+	if err != nil {
+		return nil, "", err
+	}
+	if order == nil {
+		return nil, "", errors.New(errorMessages.ErrOrderNotFound)
+	}
+
+	return s.renderInvoice(ctx, order)
+}
+
+func (s *documentService) GetUserInvoicePdf(ctx context.Context, userId, orderId uint) ([]byte, string, error) {
+	order, err := s.OrderRepository.FindByUserIdWithId(ctx, userId, orderId)
+	if err != nil {
+		return nil, "", err
+	}
+	if order == nil {
+		return nil, "", errors.New(errorMessages.ErrOrderNotFound)
+	}
+
+	return s.renderInvoice(ctx, order)
+}
+
+func (s *documentService) GetPackingSlipPdf(ctx context.Context, orderId uint) ([]byte, string, error) {
+	order, err := s.OrderRepository.FindById(ctx, orderId)
+	if err != nil {
+		return nil, "", err
+	}
+	if order == nil {
+		return nil, "", errors.New(errorMessages.ErrOrderNotFound)
+	}
+
+	return s.renderPackingSlip(ctx, order)
+}
+
+func (s *documentService) GetUserPackingSlipPdf(ctx context.Context, userId, orderId uint) ([]byte, string, error) {
+	order, err := s.OrderRepository.FindByUserIdWithId(ctx, userId, orderId)
+	if err != nil {
+		return nil, "", err
+	}
+	if order == nil {
+		return nil, "", errors.New(errorMessages.ErrOrderNotFound)
+	}
+
+	return s.renderPackingSlip(ctx, order)
+}
+
+func (s *documentService) renderInvoice(ctx context.Context, order *entity.Order) ([]byte, string, error) {
+	invoice, err := s.InvoiceRepository.FindByOrderId(ctx, order.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	if invoice == nil {
+		return nil, "", errors.New(errorMessages.ErrInvoiceNotFound)
+	}
+
+	payment, err := s.PaymentRepository.FindByOrderId(ctx, order.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	if payment == nil {
+		return nil, "", errors.New(errorMessages.ErrPaymentNotFound)
+	}
+
+	productNames, err := s.findProductNames(ctx, order)
+	if err != nil {
+		return nil, "", err
+	}
+
+	w := newDocumentWriter()
+	w.heading("INVOICE " + invoice.Number)
+	w.text("Invoice date: " + invoice.IssuedAt.Format(documentDateLayout))
+	w.text(fmt.Sprintf("Order #%d placed %s", order.ID, order.Date.Format(documentDateLayout)))
+	w.gap()
+
+	w.bold("Bill to")
+	w.address(order.BillingDetail, "")
+	w.gap()
+	w.bold("Ship to")
+	w.address(order.ShippingDetail, order.ShippingAddress)
+	w.gap()
+
+	columns := []float64{documentMargin, 320, 400, 480}
+	w.row(columns, true, "Product", "Qty", "Unit price", "Subtotal")
+	w.rule()
+	for _, od := range order.OrderDetails {
+		w.row(columns, false,
+			productNames[od.ProductId],
+			strconv.FormatUint(uint64(od.Qty), 10),
+			strconv.FormatUint(uint64(od.Subtotal/od.Qty), 10),
+			strconv.FormatUint(uint64(od.Subtotal), 10))
+	}
+	w.rule()
+	w.row(columns, true, "Total", "", "", strconv.FormatUint(uint64(order.Total), 10))
+	w.gap()
+
+	w.text(fmt.Sprintf("Paid by %s on %s", payment.Method, payment.CreatedAt.Format(documentDateLayout)))
+
+	return w.doc.Bytes(), fmt.Sprintf("%s.pdf", invoice.Number), nil
+}
+
+func (s *documentService) renderPackingSlip(ctx context.Context, order *entity.Order) ([]byte, string, error) {
+	productNames, err := s.findProductNames(ctx, order)
+	if err != nil {
+		return nil, "", err
+	}
+
+	w := newDocumentWriter()
+	w.heading(fmt.Sprintf("PACKING SLIP - ORDER #%d", order.ID))
+	w.text("Order date: " + order.Date.Format(documentDateLayout))
+	w.gap()
+
+	w.bold("Ship to")
+	w.address(order.ShippingDetail, order.ShippingAddress)
+	w.gap()
+
+	columns := []float64{documentMargin, 120, 480}
+	w.row(columns, true, "Product ID", "Product", "Qty")
+	w.rule()
+	for _, od := range order.OrderDetails {
+		w.row(columns, false,
+			strconv.FormatUint(uint64(od.ProductId), 10),
+			productNames[od.ProductId],
+			strconv.FormatUint(uint64(od.Qty), 10))
+	}
+	w.rule()
+
+	return w.doc.Bytes(), fmt.Sprintf("packing-slip-%d.pdf", order.ID), nil
+}
+
+func (s *documentService) findProductNames(ctx context.Context, order *entity.Order) (map[uint]string, error) {
+	names := make(map[uint]string)
+	for _, od := range order.OrderDetails {
+		product, err := s.ProductRepository.FindById(ctx, od.ProductId)
+		if err != nil {
+			return nil, err
+		}
+
+		names[od.ProductId] = fmt.Sprintf("Product #%d", od.ProductId)
+		if product != nil {
+			names[od.ProductId] = product.Name
+		}
+	}
+	return names, nil
+}
+
+// documentWriter lays text out top to bottom and starts a new page when the
+// current one is full.
+type documentWriter struct {
+	doc *utils.PdfDocument
+	y   float64
+}
+
+func newDocumentWriter() *documentWriter {
+	return &documentWriter{
+		doc: utils.NewPdfDocument(),
+		y:   utils.PdfPageHeight - documentMargin,
+	}
+}
+
+func (w *documentWriter) advance(height float64) {
+	if w.y-height < documentMargin {
+		w.doc.AddPage()
+		w.y = utils.PdfPageHeight - documentMargin
+	}
+	w.y -= height
+}
+
+func (w *documentWriter) heading(text string) {
+	w.advance(22)
+	w.doc.Text(documentMargin, w.y, 18, true, text)
+	w.advance(8)
+}
+
+func (w *documentWriter) text(text string) {
+	w.advance(documentLineHeight)
+	w.doc.Text(documentMargin, w.y, 10, false, text)
+}
+
+func (w *documentWriter) bold(text string) {
+	w.advance(documentLineHeight)
+	w.doc.Text(documentMargin, w.y, 10, true, text)
+}
+
+func (w *documentWriter) gap() {
+	w.advance(documentLineHeight / 2)
+}
+
+func (w *documentWriter) rule() {
+	w.advance(6)
+	w.doc.Line(documentMargin, w.y, utils.PdfPageWidth-documentMargin, w.y)
+}
+
+func (w *documentWriter) row(columns []float64, bold bool, cells ...string) {
+	w.advance(documentLineHeight)
+	for i, cell := range cells {
+		w.doc.Text(columns[i], w.y, 10, bold, cell)
+	}
+}
+
+// address prints a structured address, falling back to the free-text form
+// kept on orders placed before the address book existed.
+func (w *documentWriter) address(address entity.PostalAddress, fallback string) {
+	if address.Recipient == "" {
+		if fallback == "" {
+			fallback = "-"
+		}
+		w.text(fallback)
+		return
+	}
+
+	w.text(address.Recipient)
+	w.text(address.Street)
+	w.text(address.City + " " + address.Region + " " + address.PostalCode)
+	w.text(address.Country)
+	if address.Phone != "" {
+		w.text("Phone: " + address.Phone)
+	}
+}
@@ -0,0 +1,290 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"go-trades/entity"
+	"go-trades/repository"
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+// fakeStore is an in-memory repository whose state fakeTxManager can put
+// back when a unit of work fails.
+type fakeStore interface {
+	snapshot() (restore func())
+}
+
+// fakeTxManager rolls back every store it was given when fn fails, the way
+// the database would.
+type fakeTxManager struct {
+	stores []fakeStore
+}
+
+func (m *fakeTxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	restores := make([]func(), len(m.stores))
+	for i, store := range m.stores {
+		restores[i] = store.snapshot()
+	}
+
+	err := fn(ctx)
+	if err != nil {
+		for _, restore := range restores {
+			restore()
+		}
+	}
+	return err
+}
+
+func (r *fakeAuditRepository) snapshot() func() {
+	entries := append([]entity.AuditLog(nil), r.entries...)
+	var head *entity.AuditChainHead
+	if r.head != nil {
+		saved := *r.head
+		head = &saved
+	}
+	return func() {
+		r.entries = entries
+		r.head = head
+	}
+}
+
+// fakeOrderRepository keeps orders by id. Only the locking reads are
+// implemented, so a service that reads an order it is about to change
+// without locking it panics on the embedded nil interface.
+type fakeOrderRepository struct {
+	repository.OrderRepository
+	orders map[uint]*entity.Order
+	nextId uint
+}
+
+func newFakeOrderRepository(orders ...entity.Order) *fakeOrderRepository {
+	r := &fakeOrderRepository{orders: make(map[uint]*entity.Order)}
+	for _, order := range orders {
+		r.put(order)
+		r.nextId = max(r.nextId, order.ID)
+	}
+	return r
+}
+
+func copyOrder(order entity.Order) *entity.Order {
+	order.OrderDetails = append([]entity.OrderDetail(nil), order.OrderDetails...)
+	return &order
+}
+
+func (r *fakeOrderRepository) put(order entity.Order) {
+	r.orders[order.ID] = copyOrder(order)
+}
+
+func (r *fakeOrderRepository) snapshot() func() {
+	orders := make(map[uint]*entity.Order, len(r.orders))
+	for id, order := range r.orders {
+		orders[id] = copyOrder(*order)
+	}
+	nextId := r.nextId
+	return func() {
+		r.orders = orders
+		r.nextId = nextId
+	}
+}
+
+func (r *fakeOrderRepository) FindByIdForUpdate(ctx context.Context, id uint) (*entity.Order, error) {
+	order, ok := r.orders[id]
+	if !ok {
+		return nil, nil
+	}
+	return copyOrder(*order), nil
+}
+
+func (r *fakeOrderRepository) FindByUserIdWithIdForUpdate(ctx context.Context, userId uint, id uint) (*entity.Order, error) {
+	order, ok := r.orders[id]
+	if !ok || order.UserId != userId {
+		return nil, nil
+	}
+	return copyOrder(*order), nil
+}
+
+func (r *fakeOrderRepository) CreateOrder(ctx context.Context, order *entity.Order) error {
+	r.nextId++
+	order.ID = r.nextId
+	for i := range order.OrderDetails {
+		order.OrderDetails[i].OrderId = order.ID
+	}
+	r.put(*order)
+	return nil
+}
+
+// UpdateOrder saves the order's own columns and keeps its lines, as Save
+// does once the caller has cleared OrderDetails.
+func (r *fakeOrderRepository) UpdateOrder(ctx context.Context, order *entity.Order) error {
+	details := r.orders[order.ID].OrderDetails
+	r.put(*order)
+	r.orders[order.ID].OrderDetails = details
+	return nil
+}
+
+func (r *fakeOrderRepository) ReplaceOrderDetails(ctx context.Context, orderId uint, orderDetails []entity.OrderDetail) error {
+	details := append([]entity.OrderDetail(nil), orderDetails...)
+	for i := range details {
+		details[i].OrderId = orderId
+	}
+	r.orders[orderId].OrderDetails = details
+	return nil
+}
+
+func (r *fakeOrderRepository) DeleteOrder(ctx context.Context, id uint) error {
+	delete(r.orders, id)
+	return nil
+}
+
+// fakeInventoryRepository holds one inventory per product.
+type fakeInventoryRepository struct {
+	repository.InventoryRepository
+	stock map[uint]uint
+}
+
+func newFakeInventoryRepository(stock map[uint]uint) *fakeInventoryRepository {
+	return &fakeInventoryRepository{stock: stock}
+}
+
+func (r *fakeInventoryRepository) snapshot() func() {
+	stock := make(map[uint]uint, len(r.stock))
+	for productId, qty := range r.stock {
+		stock[productId] = qty
+	}
+	return func() {
+		r.stock = stock
+	}
+}
+
+func (r *fakeInventoryRepository) FindFirstByProductId(ctx context.Context, id uint) (*entity.Inventory, error) {
+	stock, ok := r.stock[id]
+	if !ok {
+		return nil, nil
+	}
+	inventory := &entity.Inventory{ProductId: id, Stock: stock}
+	inventory.ID = id
+	return inventory, nil
+}
+
+func (r *fakeInventoryRepository) UpdateInventoryForOrder(ctx context.Context, inventory *entity.Inventory, qty uint, action string) error {
+	switch action {
+	case "create":
+		r.stock[inventory.ProductId] -= qty
+	case "cancel", "restock":
+		r.stock[inventory.ProductId] += qty
+	}
+	return nil
+}
+
+// fakeProductRepository only reads products.
+type fakeProductRepository struct {
+	repository.ProductRepository
+	products map[uint]entity.Product
+}
+
+func newFakeProductRepository(products ...entity.Product) *fakeProductRepository {
+	r := &fakeProductRepository{products: make(map[uint]entity.Product)}
+	for _, product := range products {
+		r.products[product.ID] = product
+	}
+	return r
+}
+
+func (r *fakeProductRepository) FindById(ctx context.Context, id uint) (*entity.Product, error) {
+	product, ok := r.products[id]
+	if !ok {
+		return nil, nil
+	}
+	return &product, nil
+}
+
+func (r *fakeProductRepository) FindAllByIds(ctx context.Context, ids []uint) ([]entity.Product, error) {
+	var result []entity.Product
+	for _, id := range ids {
+		if product, ok := r.products[id]; ok {
+			result = append(result, product)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result, nil
+}
+
+func testProduct(id, price uint, policy entity.BackorderPolicy) entity.Product {
+	product := entity.Product{Name: "product", Price: price, BackorderPolicy: policy}
+	product.ID = id
+	return product
+}
+
+// fakeUserRepository keeps users by id and, like the database repository,
+// reports a missing user as gorm.ErrRecordNotFound.
+type fakeUserRepository struct {
+	repository.UserRepository
+	users map[uint]entity.User
+}
+
+func newFakeUserRepository(users ...entity.User) *fakeUserRepository {
+	r := &fakeUserRepository{users: make(map[uint]entity.User)}
+	for _, user := range users {
+		r.users[user.ID] = user
+	}
+	return r
+}
+
+func (r *fakeUserRepository) snapshot() func() {
+	users := make(map[uint]entity.User, len(r.users))
+	for id, user := range r.users {
+		users[id] = user
+	}
+	return func() {
+		r.users = users
+	}
+}
+
+func (r *fakeUserRepository) FindById(ctx context.Context, id uint) (*entity.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &user, nil
+}
+
+func (r *fakeUserRepository) FindByEmail(ctx context.Context, email string) (*entity.User, error) {
+	for _, user := range r.users {
+		if user.Email == email {
+			return &user, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeUserRepository) FindByIdForUpdate(ctx context.Context, id uint) (*entity.User, error) {
+	return r.FindById(ctx, id)
+}
+
+func (r *fakeUserRepository) Update(ctx context.Context, user *entity.User) error {
+	r.users[user.ID] = *user
+	return nil
+}
+
+func testUser(id uint, blocked bool) entity.User {
+	user := entity.User{Username: "user", Email: fmt.Sprintf("user%d@example.com", id), Role: entity.Customer, IsBlocked: blocked}
+	user.ID = id
+	return user
+}
+
+// fakeAddressRepository gives every user the same default shipping address,
+// if any, and no default billing address.
+type fakeAddressRepository struct {
+	repository.AddressRepository
+	shipping *entity.Address
+}
+
+func (r *fakeAddressRepository) FindDefaultShippingByUserId(ctx context.Context, userId uint) (*entity.Address, error) {
+	return r.shipping, nil
+}
+
+func (r *fakeAddressRepository) FindDefaultBillingByUserId(ctx context.Context, userId uint) (*entity.Address, error) {
+	return nil, nil
+}
@@ -0,0 +1,657 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-trades/entity"
+	"go-trades/repository"
+	"go-trades/utils"
+	errorMessages "go-trades/utils/error-messages"
+	status "go-trades/utils/status"
+	"io"
+	"log"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	maxImportFileSize = 10 * 1024 * 1024 // 10MB
+	importDirectory   = "uploads/imports"
+	exportBatchSize   = 500
+)
+
+const (
+	importFormatCsv  = "csv"
+	importFormatJson = "jsonl"
+)
+
+type importService struct {
+	txManager           utils.TxManager
+	ImportJobRepository repository.ImportJobRepository
+	UserRepository      repository.UserRepository
+	OrderService        OrderService
+	Audit               AuditService
+}
+
+type ImportService interface {
+	GetAllImportJobs(ctx context.Context, page, size int) (*utils.Response, int64, int64, error)
+	GetImportJobById(ctx context.Context, id uint) (*utils.Response, error)
+	GetImportJobResult(ctx context.Context, id uint) (string, error)
+	ImportOrders(ctx context.Context, userId uint, file *multipart.FileHeader, dryRun bool) (*utils.Response, error)
+}
+
+func NewImportService(txManager utils.TxManager, ijr repository.ImportJobRepository, ur repository.UserRepository, os OrderService, audit AuditService) ImportService {
+	return &importService{
+		txManager:           txManager,
+		ImportJobRepository: ijr,
+		UserRepository:      ur,
+		OrderService:        os,
+		Audit:               audit,
+	}
+}
+
+// importRow is one input row and where it ended up. Rows are numbered the
+// way a spreadsheet shows them, so the CSV header is row 1.
+type importRow struct {
+	Row     int
+	Ref     string
+	OrderId uint
+	Error   string
+}
+
+// orderImportGroup collects the rows that make up a single order.
+type orderImportGroup struct {
+	Line entity.OrderImportLine
+	Rows []*importRow
+}
+
+func (s *importService) GetAllImportJobs(ctx context.Context, page, size int) (*utils.Response, int64, int64, error) {
+	jobs, totalSize, err := s.ImportJobRepository.FindAll(ctx, page, size)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	data := make([]entity.ImportJobDataResponse, len(jobs))
+	for i, job := range jobs {
+		data[i] = toImportJobDataResponse(job)
+	}
+
+	totalPage := utils.GetTotalPage(totalSize, size)
+
+	return &utils.Response{
+		Status:  200,
+		Message: "Success",
+		Data:    data,
+	}, totalSize, totalPage, nil
+}
+
+func (s *importService) GetImportJobById(ctx context.Context, id uint) (*utils.Response, error) {
+	job, err := s.ImportJobRepository.FindById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil {
+		return nil, errors.New(errorMessages.ErrImportJobNotFound)
+	}
+
+	return &utils.Response{
+		Status:  200,
+		Message: "Success",
+		Data:    toImportJobDataResponse(*job),
+	}, nil
+}
+
+func (s *importService) GetImportJobResult(ctx context.Context, id uint) (string, error) {
+	job, err := s.ImportJobRepository.FindById(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	if job == nil {
+		return "", errors.New(errorMessages.ErrImportJobNotFound)
+	}
+	if job.ResultPath == "" {
+		return "", errors.New(errorMessages.ErrImportResultNotReady)
+	}
+
+	return job.ResultPath, nil
+}
+
+// ImportOrders records the upload as a job and processes it in the
+// background; the caller polls the job and downloads the result file.
+func (s *importService) ImportOrders(ctx context.Context, userId uint, file *multipart.FileHeader, dryRun bool) (*utils.Response, error) {
+	format, content, err := readImportFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	job := entity.ImportJob{
+		Kind:      entity.OrderImport,
+		FileName:  file.Filename,
+		DryRun:    dryRun,
+		Status:    status.IMPORT_PENDING,
+		CreatedBy: userId,
+	}
+
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.ImportJobRepository.CreateImportJob(ctx, &job); err != nil {
+			return err
+		}
+
+		return s.Audit.Record(ctx, entity.AuditCreate, entity.AuditImportJob, job.ID, nil, job)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	go s.runOrderImport(job, format, content)
+
+	return &utils.Response{
+		Status:  202,
+		Message: "Import started",
+		Data:    toImportJobDataResponse(job),
+	}, nil
+}
+
+func (s *importService) runOrderImport(job entity.ImportJob, format string, content []byte) {
+	// Orders placed by the import are audited as the user who started it.
+	ctx := utils.WithCaller(context.Background(), utils.Caller{UserId: job.CreatedBy})
+
+	defer func() {
+		if r := recover(); r != nil {
+			s.finishImportJob(ctx, &job, nil, fmt.Sprint(r))
+		}
+	}()
+
+	before := utils.AuditSnapshot(job)
+	job.Status = status.IMPORT_RUNNING
+	if err := s.saveImportJob(ctx, &job, before); err != nil {
+		log.Printf("Import job %d failed to start: %v", job.ID, err)
+		return
+	}
+
+	var groups []*orderImportGroup
+	var err error
+	if format == importFormatCsv {
+		groups, err = parseOrderCsv(content)
+	} else {
+		groups, err = parseOrderJsonLines(content)
+	}
+	if err != nil {
+		s.finishImportJob(ctx, &job, nil, err.Error())
+		return
+	}
+
+	// A dry run checks each order in a short transaction of its own that is
+	// rolled back, so nothing stays locked while the file is worked through.
+	// claimed counts the units the valid orders checked so far would take.
+	var rows []*importRow
+	claimed := make(map[uint]uint)
+	for _, group := range groups {
+		s.importOrder(ctx, group, job.DryRun, claimed)
+		rows = append(rows, group.Rows...)
+	}
+
+	s.finishImportJob(ctx, &job, rows, "")
+}
+
+// importOrder places one order through OrderService.CreateOrder. Any row
+// error fails the whole order, and every row of it reports the outcome. A
+// dry run reports no order ids, since its orders are rolled back.
+func (s *importService) importOrder(ctx context.Context, group *orderImportGroup, dryRun bool, claimed map[uint]uint) {
+	orderId, err := s.createImportedOrder(ctx, group, dryRun, claimed)
+
+	for _, row := range group.Rows {
+		if err != nil && row.Error == "" {
+			row.Error = err.Error()
+		}
+		row.OrderId = orderId
+	}
+}
+
+// createImportedOrder places the group's order. A dry run asks for each line
+// plus what earlier orders in the file claimed of the product, so the stock
+// check sees the stock those orders would have left.
+func (s *importService) createImportedOrder(ctx context.Context, group *orderImportGroup, dryRun bool, claimed map[uint]uint) (uint, error) {
+	for _, row := range group.Rows {
+		if row.Error != "" {
+			return 0, errors.New(errorMessages.ErrImportOrderInvalidRows)
+		}
+	}
+
+	user, err := s.UserRepository.FindByEmail(ctx, group.Line.CustomerEmail)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, errors.New(errorMessages.ErrUserNotExists)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	items := group.Line.Items
+	if dryRun {
+		items = make([]entity.OrderDetailRequest, len(group.Line.Items))
+		for i, item := range group.Line.Items {
+			item.Qty += claimed[item.ProductId]
+			items[i] = item
+		}
+	}
+
+	resp, err := s.OrderService.CreateOrder(ctx, user.ID, &entity.CreateOrderRequest{
+		ShippingAddress:   group.Line.ShippingAddress,
+		ShippingAddressId: group.Line.ShippingAddressId,
+		BillingAddressId:  group.Line.BillingAddressId,
+		OrderDetails:      items,
+		DryRun:            dryRun,
+	})
+	if err != nil {
+		return 0, err
+	}
+	if dryRun {
+		for _, item := range group.Line.Items {
+			claimed[item.ProductId] += item.Qty
+		}
+		return 0, nil
+	}
+	return resp.Data.(entity.OrderDataResponse).ID, nil
+}
+
+// finishImportJob writes the result file and closes the job. A non-empty
+// failure means the file as a whole could not be processed.
+func (s *importService) finishImportJob(ctx context.Context, job *entity.ImportJob, rows []*importRow, failure string) {
+	before := utils.AuditSnapshot(job)
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Row < rows[j].Row })
+
+	job.TotalRows = uint(len(rows))
+	job.SucceededRows = 0
+	job.FailedRows = 0
+	for _, row := range rows {
+		if row.Error == "" {
+			job.SucceededRows++
+		} else {
+			job.FailedRows++
+		}
+	}
+
+	job.Status = status.IMPORT_COMPLETED
+	job.Error = failure
+	if failure != "" {
+		job.Status = status.IMPORT_FAILED
+	} else if path, err := writeImportResult(job, rows); err != nil {
+		job.Status = status.IMPORT_FAILED
+		job.Error = err.Error()
+	} else {
+		job.ResultPath = path
+	}
+
+	now := time.Now()
+	job.CompletedAt = &now
+	if err := s.saveImportJob(ctx, job, before); err != nil {
+		log.Printf("Import job %d failed to save: %v", job.ID, err)
+	}
+}
+
+// saveImportJob updates the job and records the change in one transaction.
+func (s *importService) saveImportJob(ctx context.Context, job *entity.ImportJob, before map[string]interface{}) error {
+	return s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.ImportJobRepository.UpdateImportJob(ctx, job); err != nil {
+			return err
+		}
+
+		return s.Audit.Record(ctx, entity.AuditUpdate, entity.AuditImportJob, job.ID, before, job)
+	})
+}
+
+func writeImportResult(job *entity.ImportJob, rows []*importRow) (string, error) {
+	if err := os.MkdirAll(importDirectory, 0755); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(importDirectory, fmt.Sprintf("import-%d-result.csv", job.ID))
+	file, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	success := "created"
+	if job.DryRun {
+		success = "valid"
+	}
+
+	w := csv.NewWriter(file)
+	w.Write([]string{"row", "orderRef", "status", "orderId", "error"})
+	for _, row := range rows {
+		result := success
+		if row.Error != "" {
+			result = "error"
+		}
+
+		orderId := ""
+		if row.OrderId != 0 {
+			orderId = strconv.FormatUint(uint64(row.OrderId), 10)
+		}
+
+		w.Write([]string{strconv.Itoa(row.Row), row.Ref, result, orderId, row.Error})
+	}
+	w.Flush()
+
+	return path, w.Error()
+}
+
+func readImportFile(file *multipart.FileHeader) (string, []byte, error) {
+	if file.Size > maxImportFileSize {
+		return "", nil, errors.New("maximum file size is 10MB")
+	}
+
+	var format string
+	switch strings.ToLower(filepath.Ext(file.Filename)) {
+	case ".csv":
+		format = importFormatCsv
+	case ".jsonl", ".ndjson":
+		format = importFormatJson
+	default:
+		return "", nil, errors.New(errorMessages.ErrImportFileFormat)
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		return "", nil, err
+	}
+	defer f.Close()
+
+	content, err := io.ReadAll(f)
+	if err != nil {
+		return "", nil, err
+	}
+	return format, content, nil
+}
+
+// parseOrderCsv reads one order line per row. Rows with the same orderRef
+// become one order; a row without an orderRef is an order on its own.
+func parseOrderCsv(content []byte) ([]*orderImportGroup, error) {
+	r := csv.NewReader(bytes.NewReader(content))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, errors.New(errorMessages.ErrImportFileEmpty)
+	}
+
+	columns := newTableColumns(header)
+	if err := columns.require("customerEmail", "productId", "qty"); err != nil {
+		return nil, err
+	}
+	field := columns.get
+
+	var groups []*orderImportGroup
+	byRef := make(map[string]*orderImportGroup)
+	for rowNumber := 2; ; rowNumber++ {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		row := &importRow{Row: rowNumber, Ref: field(record, "orderRef")}
+		line := entity.OrderImportLine{
+			OrderRef:        row.Ref,
+			CustomerEmail:   field(record, "customerEmail"),
+			ShippingAddress: field(record, "shippingAddress"),
+		}
+
+		var item entity.OrderDetailRequest
+		var parseErr error
+		if line.ShippingAddressId, parseErr = parseImportUint(field(record, "shippingAddressId"), false); parseErr == nil {
+			if line.BillingAddressId, parseErr = parseImportUint(field(record, "billingAddressId"), false); parseErr == nil {
+				if item.ProductId, parseErr = parseImportUint(field(record, "productId"), true); parseErr == nil {
+					item.Qty, parseErr = parseImportUint(field(record, "qty"), true)
+				}
+			}
+		}
+		if parseErr != nil {
+			row.Error = parseErr.Error()
+		} else if line.CustomerEmail == "" {
+			row.Error = errorMessages.ErrImportCustomerRequired
+		}
+
+		group := byRef[row.Ref]
+		if group == nil || row.Ref == "" {
+			group = &orderImportGroup{Line: line}
+			groups = append(groups, group)
+			if row.Ref != "" {
+				byRef[row.Ref] = group
+			}
+		} else if row.Error == "" && !sameOrderHeader(group.Line, line) {
+			row.Error = errorMessages.ErrImportOrderMismatch
+		}
+
+		group.Line.Items = append(group.Line.Items, item)
+		group.Rows = append(group.Rows, row)
+	}
+
+	return groups, nil
+}
+
+// parseOrderJsonLines reads one whole order per line.
+func parseOrderJsonLines(content []byte) ([]*orderImportGroup, error) {
+	var groups []*orderImportGroup
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxImportFileSize)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var line entity.OrderImportLine
+		row := &importRow{Row: lineNumber}
+		if err := json.Unmarshal([]byte(text), &line); err != nil {
+			row.Error = err.Error()
+		} else if line.CustomerEmail == "" {
+			row.Error = errorMessages.ErrImportCustomerRequired
+		} else if len(line.Items) == 0 {
+			row.Error = errorMessages.ErrOrderEmpty
+		}
+		for _, item := range line.Items {
+			if row.Error == "" && (item.ProductId == 0 || item.Qty == 0) {
+				row.Error = errorMessages.ErrImportInvalidItem
+			}
+		}
+
+		row.Ref = line.OrderRef
+		groups = append(groups, &orderImportGroup{Line: line, Rows: []*importRow{row}})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(groups) == 0 {
+		return nil, errors.New(errorMessages.ErrImportFileEmpty)
+	}
+
+	return groups, nil
+}
+
+func parseImportUint(value string, required bool) (uint, error) {
+	if value == "" {
+		if required {
+			return 0, errors.New(errorMessages.ErrImportInvalidItem)
+		}
+		return 0, nil
+	}
+
+	n, err := strconv.ParseUint(value, 10, 0)
+	if err != nil || (required && n == 0) {
+		return 0, fmt.Errorf("invalid number %q", value)
+	}
+	return uint(n), nil
+}
+
+// sameOrderHeader reports whether a follow-up row agrees with the first row
+// of its order. Blank address cells inherit from the first row.
+func sameOrderHeader(first, next entity.OrderImportLine) bool {
+	return next.CustomerEmail == first.CustomerEmail &&
+		(next.ShippingAddress == "" || next.ShippingAddress == first.ShippingAddress) &&
+		(next.ShippingAddressId == 0 || next.ShippingAddressId == first.ShippingAddressId) &&
+		(next.BillingAddressId == 0 || next.BillingAddressId == first.BillingAddressId)
+}
+
+// tableColumns maps header names, matched case-insensitively, to their
+// column index in an uploaded table.
+type tableColumns map[string]int
+
+func newTableColumns(header []string) tableColumns {
+	columns := make(tableColumns)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	return columns
+}
+
+func (c tableColumns) has(name string) bool {
+	_, ok := c[strings.ToLower(name)]
+	return ok
+}
+
+func (c tableColumns) get(record []string, name string) string {
+	i, ok := c[strings.ToLower(name)]
+	if !ok || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+func (c tableColumns) require(names ...string) error {
+	for _, name := range names {
+		if !c.has(name) {
+			return fmt.Errorf("%s: %s", errorMessages.ErrImportMissingColumn, name)
+		}
+	}
+	return nil
+}
+
+// readTableFile loads an uploaded CSV or XLSX file. The first row is the
+// header; callers skip rows that are entirely blank.
+func readTableFile(file *multipart.FileHeader) ([][]string, error) {
+	if file.Size > maxImportFileSize {
+		return nil, errors.New("maximum file size is 10MB")
+	}
+
+	format, err := utils.TableFormat(file.Filename)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	content, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := utils.ReadTable(format, content)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, errors.New(errorMessages.ErrImportFileEmpty)
+	}
+	return records, nil
+}
+
+func isBlankRecord(record []string) bool {
+	for _, cell := range record {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// parseImportDate accepts ISO dates and the day serials spreadsheets store
+// date cells as.
+func parseImportDate(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	if _, err := time.Parse("2006-01-02", value); err == nil {
+		return value, nil
+	}
+
+	serial, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return "", errors.New("invalid date format")
+	}
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	return epoch.AddDate(0, 0, int(serial)).Format("2006-01-02"), nil
+}
+
+// addImportDiff appends a row to the preview and counts it by action.
+func addImportDiff(preview *entity.ImportPreviewResponse, row entity.ImportDiffRow) {
+	switch row.Action {
+	case entity.ImportCreate:
+		preview.Created++
+	case entity.ImportUpdate:
+		preview.Updated++
+	case entity.ImportUnchanged:
+		preview.Unchanged++
+	case entity.ImportFailed:
+		preview.Failed++
+	}
+	preview.Rows = append(preview.Rows, row)
+}
+
+// diffImportFields compares the exported form of a record before and after
+// an import row. A nil before means the row creates the record.
+func diffImportFields(before, after map[string]string) (entity.ImportAction, map[string]entity.FieldChange) {
+	changes := make(map[string]entity.FieldChange)
+	for field, to := range after {
+		if from := before[field]; before == nil || from != to {
+			changes[field] = entity.FieldChange{From: before[field], To: to}
+		}
+	}
+
+	switch {
+	case before == nil:
+		return entity.ImportCreate, changes
+	case len(changes) == 0:
+		return entity.ImportUnchanged, nil
+	default:
+		return entity.ImportUpdate, changes
+	}
+}
+
+func toImportJobDataResponse(job entity.ImportJob) entity.ImportJobDataResponse {
+	return entity.ImportJobDataResponse{
+		ID:            job.ID,
+		Kind:          job.Kind,
+		FileName:      job.FileName,
+		DryRun:        job.DryRun,
+		Status:        job.Status,
+		TotalRows:     job.TotalRows,
+		SucceededRows: job.SucceededRows,
+		FailedRows:    job.FailedRows,
+		Error:         job.Error,
+		CreatedBy:     job.CreatedBy,
+		CreatedAt:     job.CreatedAt,
+		CompletedAt:   job.CompletedAt,
+	}
+}
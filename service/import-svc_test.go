@@ -0,0 +1,43 @@
+package service
+
+import (
+	"context"
+	"go-trades/entity"
+	errorMessages "go-trades/utils/error-messages"
+	"testing"
+)
+
+func TestImportOrderDryRunCountsEarlierOrders(t *testing.T) {
+	f := newOrderFixture()
+	delete(f.orders.orders, 1)
+	users := newFakeUserRepository(testUser(1, false))
+	svc := NewImportService(&fakeTxManager{}, nil, users, f.svc, NewAuditService(f.audit)).(*importService)
+
+	line := func(productId, qty uint) *orderImportGroup {
+		return &orderImportGroup{
+			Line: entity.OrderImportLine{
+				CustomerEmail:   "user1@example.com",
+				ShippingAddress: "1 Main Street",
+				Items:           []entity.OrderDetailRequest{{ProductId: productId, Qty: qty}},
+			},
+			Rows: []*importRow{{}},
+		}
+	}
+	groups := []*orderImportGroup{line(1, 3), line(1, 3), line(1, 2), line(3, 4)}
+
+	claimed := make(map[uint]uint)
+	for _, group := range groups {
+		svc.importOrder(context.Background(), group, true, claimed)
+	}
+
+	want := []string{"", errorMessages.ErrInventoryInsufficientStock, "", ""}
+	for i, group := range groups {
+		if got := group.Rows[0].Error; got != want[i] {
+			t.Errorf("order %d: error = %q, want %q", i+1, got, want[i])
+		}
+	}
+	if len(f.orders.orders) != 0 || len(f.audit.entries) != 0 {
+		t.Errorf("a dry run kept %d orders and %d audit entries", len(f.orders.orders), len(f.audit.entries))
+	}
+	f.assertStock(t, map[uint]uint{1: 5, 2: 5, 3: 1})
+}
@@ -1,36 +1,49 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"go-trades/entity"
 	"go-trades/repository"
 	"go-trades/utils"
 	errorMessages "go-trades/utils/error-messages"
-
-	"github.com/gin-gonic/gin"
+	status "go-trades/utils/status"
+	"io"
+	"mime/multipart"
+	"strconv"
 )
 
 type inventoryService struct {
+	txManager           utils.TxManager
 	InventoryRepository repository.InventoryRepository
 	ProductRepository   repository.ProductRepository
+	OrderRepository     repository.OrderRepository
+	Audit               AuditService
 }
 
 type InventoryService interface {
-	GetAllInventories(ctx *gin.Context, page, size int) (*utils.Response, int64, int64, error)
-	GetInventoryById(ctx *gin.Context, id uint) (*utils.Response, error)
-	CreateInventory(ctx *gin.Context, req *entity.CreateInventoryRequest) (*utils.Response, error)
-	UpdateInventory(ctx *gin.Context, id uint, req *entity.UpdateInventoryRequest) (*utils.Response, error)
-	DeleteInventory(ctx *gin.Context, id uint) error
+	GetAllInventories(ctx context.Context, page, size int) (*utils.Response, int64, int64, error)
+	GetInventoryById(ctx context.Context, id uint) (*utils.Response, error)
+	CreateInventory(ctx context.Context, req *entity.CreateInventoryRequest) (*utils.Response, error)
+	// UpdateInventory and DeleteInventory refuse to act when ifMatch is set
+	// and does not hold the inventory's current ETag.
+	UpdateInventory(ctx context.Context, id uint, req *entity.UpdateInventoryRequest, ifMatch string) (*utils.Response, error)
+	DeleteInventory(ctx context.Context, id uint, ifMatch string) error
+	ImportInventories(ctx context.Context, file *multipart.FileHeader, dryRun bool) (*utils.Response, error)
+	ExportInventories(ctx context.Context, format string, w io.Writer) error
 }
 
-func NewInventoryService(ir repository.InventoryRepository, pr repository.ProductRepository) InventoryService {
+func NewInventoryService(txManager utils.TxManager, ir repository.InventoryRepository, pr repository.ProductRepository, or repository.OrderRepository, audit AuditService) InventoryService {
 	return &inventoryService{
+		txManager:           txManager,
 		InventoryRepository: ir,
 		ProductRepository:   pr,
+		OrderRepository:     or,
+		Audit:               audit,
 	}
 }
 
-func (s *inventoryService) GetAllInventories(ctx *gin.Context, page, size int) (*utils.Response, int64, int64, error) {
+func (s *inventoryService) GetAllInventories(ctx context.Context, page, size int) (*utils.Response, int64, int64, error) {
 	inventories, totalSize, err := s.InventoryRepository.FindAll(ctx, page, size)
 	if err != nil {
 		return nil, 0, 0, err
@@ -42,6 +55,7 @@ func (s *inventoryService) GetAllInventories(ctx *gin.Context, page, size int) (
 			ProductId: inventory.ProductId,
 			Stock:     inventory.Stock,
 			Location:  inventory.Location,
+			Version:   inventory.Version,
 		}
 	}
 
@@ -54,7 +68,7 @@ func (s *inventoryService) GetAllInventories(ctx *gin.Context, page, size int) (
 	}, totalSize, totalPage, nil
 }
 
-func (s *inventoryService) GetInventoryById(ctx *gin.Context, id uint) (*utils.Response, error) {
+func (s *inventoryService) GetInventoryById(ctx context.Context, id uint) (*utils.Response, error) {
 	inventory, err := s.InventoryRepository.FindById(ctx, id)
 	if err != nil {
 		return nil, err
@@ -69,6 +83,7 @@ func (s *inventoryService) GetInventoryById(ctx *gin.Context, id uint) (*utils.R
 		ProductId: inventory.ProductId,
 		Stock:     inventory.Stock,
 		Location:  inventory.Location,
+		Version:   inventory.Version,
 	}
 	return &utils.Response{
 		Status:  200,
@@ -77,7 +92,7 @@ func (s *inventoryService) GetInventoryById(ctx *gin.Context, id uint) (*utils.R
 	}, nil
 }
 
-func (s *inventoryService) CreateInventory(ctx *gin.Context, req *entity.CreateInventoryRequest) (*utils.Response, error) {
+func (s *inventoryService) CreateInventory(ctx context.Context, req *entity.CreateInventoryRequest) (*utils.Response, error) {
 
 	product, err := s.ProductRepository.FindById(ctx, req.ProductId)
 	if err != nil {
@@ -93,7 +108,18 @@ func (s *inventoryService) CreateInventory(ctx *gin.Context, req *entity.CreateI
 		Location:  req.Location,
 	}
 
-	if err := s.InventoryRepository.CreateInventory(ctx, inventory); err != nil {
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.InventoryRepository.CreateInventory(ctx, inventory); err != nil {
+			return err
+		}
+
+		if err := s.allocateBackorders(ctx, inventory); err != nil {
+			return err
+		}
+
+		return s.Audit.Record(ctx, entity.AuditCreate, entity.AuditInventory, inventory.ID, nil, inventory)
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -107,6 +133,7 @@ func (s *inventoryService) CreateInventory(ctx *gin.Context, req *entity.CreateI
 		ProductId: savedInventory.ProductId,
 		Stock:     savedInventory.Stock,
 		Location:  savedInventory.Location,
+		Version:   savedInventory.Version,
 	}
 
 	return &utils.Response{
@@ -116,7 +143,7 @@ func (s *inventoryService) CreateInventory(ctx *gin.Context, req *entity.CreateI
 	}, nil
 }
 
-func (s *inventoryService) UpdateInventory(ctx *gin.Context, id uint, req *entity.UpdateInventoryRequest) (*utils.Response, error) {
+func (s *inventoryService) UpdateInventory(ctx context.Context, id uint, req *entity.UpdateInventoryRequest, ifMatch string) (*utils.Response, error) {
 
 	inventory, err := s.InventoryRepository.FindById(ctx, id)
 	if err != nil {
@@ -127,13 +154,29 @@ func (s *inventoryService) UpdateInventory(ctx *gin.Context, id uint, req *entit
 		return nil, errors.New(errorMessages.ErrInventoryNotFound)
 	}
 
+	if ifMatch != "" && !utils.ETagMatches(ifMatch, utils.ETag(inventory.Version), false) {
+		return nil, errors.New(errorMessages.ErrPreconditionFailed)
+	}
+
 	if req.Stock <= 0 {
 		return nil, errors.New(errorMessages.ErrInventoryInvalidStock)
 	}
 
+	before := utils.AuditSnapshot(inventory)
 	inventory.Stock = req.Stock
 
-	if err := s.InventoryRepository.UpdateInventory(ctx, inventory); err != nil {
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.InventoryRepository.UpdateInventory(ctx, inventory); err != nil {
+			return err
+		}
+
+		if err := s.allocateBackorders(ctx, inventory); err != nil {
+			return err
+		}
+
+		return s.Audit.Record(ctx, entity.AuditUpdate, entity.AuditInventory, inventory.ID, before, inventory)
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -142,6 +185,7 @@ func (s *inventoryService) UpdateInventory(ctx *gin.Context, id uint, req *entit
 		ProductId: inventory.ProductId,
 		Stock:     inventory.Stock,
 		Location:  inventory.Location,
+		Version:   inventory.Version,
 	}
 
 	return &utils.Response{
@@ -151,10 +195,290 @@ func (s *inventoryService) UpdateInventory(ctx *gin.Context, id uint, req *entit
 	}, nil
 }
 
-func (s *inventoryService) DeleteInventory(ctx *gin.Context, id uint) error {
-	if err := s.InventoryRepository.DeleteInventory(ctx, id); err != nil {
+func (s *inventoryService) DeleteInventory(ctx context.Context, id uint, ifMatch string) error {
+	inventory, err := s.InventoryRepository.FindById(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if inventory == nil {
+		return errors.New(errorMessages.ErrInventoryNotFound)
+	}
+
+	if ifMatch != "" && !utils.ETagMatches(ifMatch, utils.ETag(inventory.Version), false) {
+		return errors.New(errorMessages.ErrPreconditionFailed)
+	}
+
+	return s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.InventoryRepository.DeleteInventory(ctx, inventory); err != nil {
+			return err
+		}
+
+		return s.Audit.Record(ctx, entity.AuditDelete, entity.AuditInventory, inventory.ID, inventory, nil)
+	})
+}
+
+var inventoryColumns = []string{"productName", "productSku", "location", "stock"}
+
+// ImportInventories upserts inventory rows keyed by product, given by SKU or
+// name, and location. Like product imports it is all or nothing, and raised
+// stock is offered to backordered order lines straight away.
+func (s *inventoryService) ImportInventories(ctx context.Context, file *multipart.FileHeader, dryRun bool) (*utils.Response, error) {
+	records, err := readTableFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := newTableColumns(records[0])
+	if err := columns.require("location", "stock"); err != nil {
+		return nil, err
+	}
+	if !columns.has("productSku") {
+		if err := columns.require("productName"); err != nil {
+			return nil, err
+		}
+	}
+
+	preview := entity.ImportPreviewResponse{DryRun: dryRun}
+	var inventories []*entity.Inventory
+	products := make(map[string]*entity.Product)
+	seen := make(map[string]bool)
+
+	for i, record := range records[1:] {
+		if isBlankRecord(record) {
+			continue
+		}
+
+		productName := columns.get(record, "productName")
+		productSku := columns.get(record, "productSku")
+		location := columns.get(record, "location")
+		productKey := productName
+		if productSku != "" {
+			productKey = productSku
+		}
+		diff := entity.ImportDiffRow{Row: i + 2, Key: productKey + " @ " + location}
+		if seen[diff.Key] {
+			diff.Action = entity.ImportFailed
+			diff.Error = errorMessages.ErrImportDuplicateKey
+			addImportDiff(&preview, diff)
+			continue
+		}
+		seen[diff.Key] = true
+
+		inventory, before, after, err := s.planInventoryImport(ctx, productName, productSku, location, columns.get(record, "stock"), products)
+		if err != nil {
+			diff.Action = entity.ImportFailed
+			diff.Error = err.Error()
+			addImportDiff(&preview, diff)
+			continue
+		}
+
+		diff.Action, diff.Changes = diffImportFields(before, after)
+		if diff.Action != entity.ImportUnchanged {
+			inventories = append(inventories, inventory)
+		}
+		addImportDiff(&preview, diff)
+	}
+
+	if preview.Failed > 0 {
+		return &utils.Response{
+			Status:  422,
+			Message: errorMessages.ErrImportInvalidRows,
+			Data:    preview,
+		}, nil
+	}
+	if dryRun {
+		return &utils.Response{
+			Status:  200,
+			Message: "Import preview",
+			Data:    preview,
+		}, nil
+	}
+
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		for _, inventory := range inventories {
+			action := entity.AuditCreate
+			var before *entity.Inventory
+			if inventory.ID == 0 {
+				err = s.InventoryRepository.CreateInventory(ctx, inventory)
+			} else {
+				action = entity.AuditUpdate
+				before, err = s.InventoryRepository.FindById(ctx, inventory.ID)
+				if err == nil {
+					err = s.InventoryRepository.UpdateInventory(ctx, inventory)
+				}
+			}
+			if err != nil {
+				return err
+			}
+
+			if err := s.allocateBackorders(ctx, inventory); err != nil {
+				return err
+			}
+
+			if err := s.Audit.Record(ctx, action, entity.AuditInventory, inventory.ID, before, inventory); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &utils.Response{
+		Status:  200,
+		Message: "Inventories successfully imported",
+		Data:    preview,
+	}, nil
+}
+
+// planInventoryImport works out the inventory row an import row describes
+// without saving it, returning the exported fields before and after. A
+// stock of 0 is valid and empties the location.
+func (s *inventoryService) planInventoryImport(ctx context.Context, productName, productSku, location, stock string, products map[string]*entity.Product) (*entity.Inventory, map[string]string, map[string]string, error) {
+	key := "name:" + productName
+	if productSku != "" {
+		key = "sku:" + productSku
+	}
+
+	product, ok := products[key]
+	if !ok {
+		var err error
+		switch {
+		case productSku != "":
+			product, err = s.ProductRepository.FindBySku(ctx, productSku)
+		case productName != "":
+			product, err = s.ProductRepository.FindByName(ctx, productName)
+		}
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		products[key] = product
+	}
+	if product == nil {
+		return nil, nil, nil, errors.New(errorMessages.ErrProductNotFound)
+	}
+
+	if location == "" {
+		return nil, nil, nil, errors.New(errorMessages.ErrImportLocationRequired)
+	}
+
+	qty, err := strconv.ParseUint(stock, 10, 0)
+	if err != nil {
+		return nil, nil, nil, errors.New(errorMessages.ErrInventoryInvalidStock)
+	}
+
+	inventory, err := s.InventoryRepository.FindByProductIdAndLocation(ctx, product.ID, location)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var before map[string]string
+	if inventory == nil {
+		inventory = &entity.Inventory{ProductId: product.ID, Location: location}
+	} else {
+		before = inventoryExportFields(*inventory, product)
+	}
+	inventory.Stock = uint(qty)
+
+	return inventory, before, inventoryExportFields(*inventory, product), nil
+}
+
+// ExportInventories streams every inventory row to w in the import column
+// layout.
+func (s *inventoryService) ExportInventories(ctx context.Context, format string, w io.Writer) error {
+	table, err := utils.NewTableWriter(format, w, "Inventories")
+	if err != nil {
 		return err
 	}
+	if err := table.WriteRow(inventoryColumns); err != nil {
+		return err
+	}
+
+	err = s.InventoryRepository.FindInBatches(ctx, exportBatchSize, func(inventories []entity.Inventory) error {
+		productIds := make([]uint, len(inventories))
+		for i, inventory := range inventories {
+			productIds[i] = inventory.ProductId
+		}
+
+		products, err := s.ProductRepository.FindAllByIds(ctx, productIds)
+		if err != nil {
+			return err
+		}
+		productsById := make(map[uint]*entity.Product)
+		for i := range products {
+			productsById[products[i].ID] = &products[i]
+		}
+
+		for _, inventory := range inventories {
+			fields := inventoryExportFields(inventory, productsById[inventory.ProductId])
+			row := make([]string, len(inventoryColumns))
+			for i, column := range inventoryColumns {
+				row[i] = fields[column]
+			}
+			if err := table.WriteRow(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return table.Close()
+}
+
+func inventoryExportFields(inventory entity.Inventory, product *entity.Product) map[string]string {
+	productName, productSku := "", ""
+	if product != nil {
+		productName = product.Name
+		if product.Sku != nil {
+			productSku = *product.Sku
+		}
+	}
+
+	return map[string]string{
+		"productName": productName,
+		"productSku":  productSku,
+		"location":    inventory.Location,
+		"stock":       strconv.FormatUint(uint64(inventory.Stock), 10),
+	}
+}
+
+// backorderStatuses are the order statuses still waiting to ship, so only
+// their backordered lines receive new stock.
+var backorderStatuses = []uint{status.PENDING, status.PAID, status.PROCESSING, status.PARTIALLY_SHIPPED}
+
+// allocateBackorders hands newly arrived stock to backordered order lines,
+// oldest order first, and leaves the remainder on the inventory row.
+func (s *inventoryService) allocateBackorders(ctx context.Context, inventory *entity.Inventory) error {
+	orderDetails, err := s.OrderRepository.FindBackorderedDetails(ctx, inventory.ProductId, backorderStatuses)
+	if err != nil {
+		return err
+	}
+
+	for _, orderDetail := range orderDetails {
+		if inventory.Stock == 0 {
+			break
+		}
+
+		qty := min(orderDetail.BackorderedQty, inventory.Stock)
+		if err := s.InventoryRepository.UpdateInventoryForOrder(ctx, inventory, qty, "create"); err != nil {
+			return errors.New(errorMessages.ErrInventoryStockUpdate)
+		}
+		inventory.Stock -= qty
+		inventory.Version++
+
+		orderDetail.BackorderedQty -= qty
+		if orderDetail.BackorderedQty == 0 {
+			orderDetail.ExpectedAt = nil
+		}
+		if err := s.OrderRepository.UpdateOrderDetail(ctx, &orderDetail); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
@@ -0,0 +1,376 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"go-trades/config"
+	"go-trades/entity"
+	"go-trades/oidc"
+	"go-trades/repository"
+	"go-trades/utils"
+	errorMessages "go-trades/utils/error-messages"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+const oidcStateTTL = 10 * time.Minute
+
+type oidcService struct {
+	txManager      utils.TxManager
+	Repository     repository.OIDCRepository
+	UserRepository repository.UserRepository
+	RoleRepository repository.RoleRepository
+	UserService    UserService
+	Audit          AuditService
+
+	mu      sync.Mutex
+	clients map[string]*oidc.Client
+}
+
+type OIDCService interface {
+	GetAuthorizationURL(ctx context.Context, provider string) (string, error)
+	HandleCallback(ctx context.Context, provider string, req *entity.OIDCCallbackRequest) (*entity.UserLoginResponse, error)
+	DeleteExpiredStates(ctx context.Context) error
+}
+
+func NewOIDCService(txManager utils.TxManager, r repository.OIDCRepository, ur repository.UserRepository, rr repository.RoleRepository, userService UserService, audit AuditService) OIDCService {
+	return &oidcService{
+		txManager:      txManager,
+		Repository:     r,
+		UserRepository: ur,
+		RoleRepository: rr,
+		UserService:    userService,
+		Audit:          audit,
+		clients:        make(map[string]*oidc.Client),
+	}
+}
+
+// GetAuthorizationURL starts a login. The state, nonce and PKCE verifier
+// are kept server side until the IdP redirects back.
+func (s *oidcService) GetAuthorizationURL(ctx context.Context, provider string) (string, error) {
+	client, err := s.client(provider)
+	if err != nil {
+		return "", err
+	}
+
+	state, err := oidc.NewRandomString()
+	if err != nil {
+		return "", err
+	}
+	nonce, err := oidc.NewRandomString()
+	if err != nil {
+		return "", err
+	}
+	verifier, err := oidc.NewRandomString()
+	if err != nil {
+		return "", err
+	}
+
+	authURL, err := client.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.Repository.CreateState(ctx, &entity.OIDCState{
+		State:        state,
+		Provider:     client.Provider.Name,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    time.Now().Add(oidcStateTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return authURL, nil
+}
+
+// HandleCallback finishes a login: it redeems the code, maps the ID token
+// to a local user, creating one if the provider allows signup, and carries
+// on like a password login would, asking for a second factor when needed.
+func (s *oidcService) HandleCallback(ctx context.Context, provider string, req *entity.OIDCCallbackRequest) (*entity.UserLoginResponse, error) {
+	client, err := s.client(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := s.Repository.ConsumeState(ctx, req.State)
+	if err != nil {
+		return nil, err
+	}
+	if state == nil || state.Provider != client.Provider.Name || time.Now().After(state.ExpiresAt) {
+		return nil, errors.New(errorMessages.ErrOIDCInvalidState)
+	}
+
+	if req.Error != "" || req.Code == "" {
+		return nil, errors.New(errorMessages.ErrOIDCLoginFailed + ": " + req.Error + " " + req.ErrorDescription)
+	}
+
+	claims, err := client.Exchange(ctx, req.Code, state.CodeVerifier, state.Nonce)
+	if err != nil {
+		log.Printf("OIDC login with %s failed. Error : %v", client.Provider.Name, err)
+		return nil, errors.New(errorMessages.ErrOIDCLoginFailed)
+	}
+
+	user, err := s.syncUser(ctx, client.Provider, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.UserService.ContinueLogin(ctx, user.ID)
+}
+
+func (s *oidcService) DeleteExpiredStates(ctx context.Context) error {
+	return s.Repository.DeleteExpiredStates(ctx, time.Now())
+}
+
+func (s *oidcService) client(provider string) (*oidc.Client, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if client, ok := s.clients[provider]; ok {
+		return client, nil
+	}
+
+	p, ok := config.GetOIDCProvider(provider)
+	if !ok {
+		return nil, errors.New(errorMessages.ErrOIDCProviderNotFound)
+	}
+
+	client := oidc.NewClient(p)
+	s.clients[provider] = client
+	return client, nil
+}
+
+// syncUser finds the user linked to the token's subject. An unlinked
+// subject is linked to the account with the same email if the provider
+// vouches for that email, and otherwise gets a new account. Mapped groups
+// set the role on every login; the default role only applies to new users.
+func (s *oidcService) syncUser(ctx context.Context, provider *config.OIDCProvider, claims jwt.MapClaims) (*entity.User, error) {
+	subject, _ := claims["sub"].(string)
+	email, _ := claims["email"].(string)
+	emailVerified, _ := claims["email_verified"].(bool)
+	if subject == "" {
+		return nil, errors.New(errorMessages.ErrOIDCLoginFailed)
+	}
+
+	role, mapped := mapOIDCRole(provider, oidcGroups(claims[provider.GroupsClaim]))
+
+	identity, err := s.Repository.FindIdentity(ctx, provider.Name, subject)
+	if err != nil {
+		return nil, err
+	}
+
+	var user *entity.User
+	if identity != nil {
+		user, err = s.UserRepository.FindById(ctx, identity.UserId)
+		if err != nil {
+			return nil, errors.New(errorMessages.ErrUserNotExists)
+		}
+	} else {
+		if email == "" {
+			return nil, errors.New(errorMessages.ErrOIDCEmailRequired)
+		}
+		existing, err := s.UserRepository.FindByEmail(ctx, email)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		if existing != nil && !emailVerified {
+			return nil, errors.New(errorMessages.ErrOIDCEmailInUse)
+		}
+		if existing == nil && !provider.AllowSignup {
+			return nil, errors.New(errorMessages.ErrOIDCSignupDisabled)
+		}
+		if existing == nil && role == "" {
+			return nil, errors.New(errorMessages.ErrOIDCNoRole)
+		}
+		user = existing
+	}
+
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		// Hold the mapped role so it cannot be deleted while it is assigned.
+		if role != "" {
+			definition, err := s.RoleRepository.FindByNameForShare(ctx, role)
+			if err != nil {
+				return err
+			}
+			if definition == nil {
+				return errors.New(errorMessages.ErrInvalidRole)
+			}
+		}
+
+		var before map[string]interface{}
+		if user != nil {
+			// Re-read under lock so the sync cannot undo a concurrent change
+			// such as a block.
+			user, err = s.UserRepository.FindByIdForUpdate(ctx, user.ID)
+			if err != nil {
+				return err
+			}
+			before = utils.AuditSnapshot(user)
+		}
+
+		if user == nil {
+			user, err = s.newOIDCUser(ctx, provider, subject, email, role, claims)
+			if err != nil {
+				return err
+			}
+		} else if mapped && user.Role != role {
+			roleChange := entity.RoleChange{UserId: user.ID, FromRole: user.Role, ToRole: role}
+			user.Role = role
+			if err := s.UserRepository.Update(ctx, user); err != nil {
+				return err
+			}
+			if err := s.UserRepository.CreateRoleChange(ctx, &roleChange); err != nil {
+				return err
+			}
+		}
+
+		if emailVerified && user.EmailVerifiedAt == nil && strings.EqualFold(user.Email, email) {
+			now := time.Now()
+			user.EmailVerifiedAt = &now
+			if err := s.UserRepository.Update(ctx, user); err != nil {
+				return err
+			}
+		}
+
+		action := entity.AuditUpdate
+		if before == nil {
+			action = entity.AuditCreate
+		}
+		if err := s.Audit.Record(ctx, action, entity.AuditUser, user.ID, before, user); err != nil {
+			return err
+		}
+
+		if identity == nil {
+			err := s.Repository.CreateIdentity(ctx, &entity.OIDCIdentity{
+				Provider: provider.Name,
+				Subject:  subject,
+				UserId:   user.ID,
+				Email:    email,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// newOIDCUser provisions an account for a first-time SSO user. The IdP does
+// not share a birth date or phone number, so placeholders fill those
+// required columns, and the random password only works after a reset.
+func (s *oidcService) newOIDCUser(ctx context.Context, provider *config.OIDCProvider, subject, email string, role entity.Role, claims jwt.MapClaims) (*entity.User, error) {
+	username, err := s.uniqueUsername(ctx, oidcUsername(claims, email))
+	if err != nil {
+		return nil, err
+	}
+
+	firstname, _ := claims["given_name"].(string)
+	lastname, _ := claims["family_name"].(string)
+	if firstname == "" {
+		firstname, _ = claims["name"].(string)
+	}
+	if firstname == "" {
+		firstname = username
+	}
+
+	sum := sha256.Sum256([]byte(provider.Name + ":" + subject))
+	user := &entity.User{
+		Username:    username,
+		Firstname:   firstname,
+		Lastname:    lastname,
+		Dob:         time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC),
+		Email:       email,
+		Phonenumber: "oidc-" + hex.EncodeToString(sum[:8]),
+		Role:        role,
+	}
+
+	password, err := utils.NewOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	if err := user.HashPassword(password); err != nil {
+		return nil, err
+	}
+
+	if err := s.UserRepository.Create(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *oidcService) uniqueUsername(ctx context.Context, base string) (string, error) {
+	candidate := base
+	for i := 0; i < 5; i++ {
+		existing, err := s.UserRepository.FindByUsername(ctx, candidate)
+		if errors.Is(err, gorm.ErrRecordNotFound) || existing == nil {
+			return candidate, nil
+		}
+		if err != nil {
+			return "", err
+		}
+
+		suffix, err := oidc.NewRandomString()
+		if err != nil {
+			return "", err
+		}
+		candidate = base[:min(len(base), 44)] + "-" + strings.ToLower(suffix[:5])
+	}
+	return "", errors.New("could not pick a free username")
+}
+
+func oidcUsername(claims jwt.MapClaims, email string) string {
+	username, _ := claims["preferred_username"].(string)
+	if username == "" {
+		username, _, _ = strings.Cut(email, "@")
+	}
+	return username[:min(len(username), 50)]
+}
+
+// oidcGroups reads the groups claim, which providers send either as a list
+// or as a single string.
+func oidcGroups(claim interface{}) []string {
+	switch value := claim.(type) {
+	case []interface{}:
+		groups := make([]string, 0, len(value))
+		for _, group := range value {
+			if s, ok := group.(string); ok {
+				groups = append(groups, s)
+			}
+		}
+		return groups
+	case string:
+		return strings.Fields(value)
+	}
+	return nil
+}
+
+// mapOIDCRole picks the first mapping whose group the user is in. It falls
+// back to the provider's default role and reports whether a mapping
+// matched.
+func mapOIDCRole(provider *config.OIDCProvider, groups []string) (entity.Role, bool) {
+	member := make(map[string]bool, len(groups))
+	for _, group := range groups {
+		member[group] = true
+	}
+
+	for _, mapping := range provider.RoleMapping {
+		if member[mapping[0]] {
+			return entity.Role(mapping[1]), true
+		}
+	}
+	return entity.Role(provider.DefaultRole), false
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"go-trades/entity"
 	"go-trades/repository"
@@ -8,39 +9,46 @@ import (
 	errorMessages "go-trades/utils/error-messages"
 	status "go-trades/utils/status"
 	"time"
-
-	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
 )
 
+// errDryRun rolls back orders that were only placed to validate them.
+var errDryRun = errors.New("dry run")
+
 type orderService struct {
-	db                  *gorm.DB
+	txManager           utils.TxManager
 	OrderRepository     repository.OrderRepository
 	ProductRepository   repository.ProductRepository
 	InventoryRepository repository.InventoryRepository
+	ShipmentRepository  repository.ShipmentRepository
+	AddressRepository   repository.AddressRepository
+	Audit               AuditService
 }
 
 type OrderService interface {
-	GetAllOrders(ctx *gin.Context, page, size int, status uint) (*utils.Response, int64, int64, error)
-	GetOrderById(ctx *gin.Context, id uint) (*utils.Response, error)
-	GetUserOrders(ctx *gin.Context, userId uint, page, size int, status uint) (*utils.Response, int64, int64, error)
-	GetUserOrderById(ctx *gin.Context, userId, id uint) (*utils.Response, error)
-	CreateOrder(ctx *gin.Context, userId uint, req *entity.CreateOrderRequest) (*utils.Response, error)
-	ProcessOrder(ctx *gin.Context, id uint) (*utils.Response, error)
-	ConfirmOrder(ctx *gin.Context, userId uint, id uint) (*utils.Response, error)
-	CancelOrder(ctx *gin.Context, userId uint, id uint) error
+	GetAllOrders(ctx context.Context, page, size int, status uint) (*utils.Response, int64, int64, error)
+	GetOrderById(ctx context.Context, id uint) (*utils.Response, error)
+	GetUserOrders(ctx context.Context, userId uint, page, size int, status uint) (*utils.Response, int64, int64, error)
+	GetUserOrderById(ctx context.Context, userId, id uint) (*utils.Response, error)
+	CreateOrder(ctx context.Context, userId uint, req *entity.CreateOrderRequest) (*utils.Response, error)
+	AmendOrder(ctx context.Context, userId uint, id uint, req *entity.AmendOrderRequest) (*utils.Response, error)
+	ProcessOrder(ctx context.Context, id uint) (*utils.Response, error)
+	ConfirmOrder(ctx context.Context, userId uint, id uint) (*utils.Response, error)
+	CancelOrder(ctx context.Context, userId uint, id uint) error
 }
 
-func NewOrderService(db *gorm.DB, or repository.OrderRepository, pr repository.ProductRepository, ir repository.InventoryRepository) OrderService {
+func NewOrderService(txManager utils.TxManager, or repository.OrderRepository, pr repository.ProductRepository, ir repository.InventoryRepository, sr repository.ShipmentRepository, ar repository.AddressRepository, audit AuditService) OrderService {
 	return &orderService{
-		db:                  db,
+		txManager:           txManager,
 		OrderRepository:     or,
 		ProductRepository:   pr,
 		InventoryRepository: ir,
+		ShipmentRepository:  sr,
+		AddressRepository:   ar,
+		Audit:               audit,
 	}
 }
 
-func (s *orderService) GetAllOrders(ctx *gin.Context, page, size int, status uint) (*utils.Response, int64, int64, error) {
+func (s *orderService) GetAllOrders(ctx context.Context, page, size int, status uint) (*utils.Response, int64, int64, error) {
 	var orders []entity.Order
 	var totalSize int64
 	var err error
@@ -67,6 +75,8 @@ func (s *orderService) GetAllOrders(ctx *gin.Context, page, size int, status uin
 			UserId:              order.UserId,
 			Date:                order.Date,
 			ShippingAddress:     order.ShippingAddress,
+			ShippingDetail:      order.ShippingDetail,
+			BillingDetail:       order.BillingDetail,
 			Total:               order.Total,
 			Status:              order.Status,
 			OrderDetailResponse: make([]entity.OrderDetailResponse, len(order.OrderDetails)),
@@ -74,9 +84,11 @@ func (s *orderService) GetAllOrders(ctx *gin.Context, page, size int, status uin
 
 		for j, orderDetail := range order.OrderDetails {
 			data[i].OrderDetailResponse[j] = entity.OrderDetailResponse{
-				ProductId: orderDetail.ProductId,
-				Qty:       orderDetail.Qty,
-				Subtotal:  orderDetail.Subtotal,
+				ProductId:      orderDetail.ProductId,
+				Qty:            orderDetail.Qty,
+				Subtotal:       orderDetail.Subtotal,
+				BackorderedQty: orderDetail.BackorderedQty,
+				ExpectedAt:     orderDetail.ExpectedAt,
 			}
 		}
 	}
@@ -88,7 +100,7 @@ func (s *orderService) GetAllOrders(ctx *gin.Context, page, size int, status uin
 	}, totalSize, totalPage, nil
 }
 
-func (s *orderService) GetOrderById(ctx *gin.Context, id uint) (*utils.Response, error) {
+func (s *orderService) GetOrderById(ctx context.Context, id uint) (*utils.Response, error) {
 
 	order, err := s.OrderRepository.FindById(ctx, id)
 	if err != nil {
@@ -104,6 +116,8 @@ func (s *orderService) GetOrderById(ctx *gin.Context, id uint) (*utils.Response,
 		UserId:              order.UserId,
 		Date:                order.Date,
 		ShippingAddress:     order.ShippingAddress,
+		ShippingDetail:      order.ShippingDetail,
+		BillingDetail:       order.BillingDetail,
 		Total:               order.Total,
 		Status:              order.Status,
 		OrderDetailResponse: make([]entity.OrderDetailResponse, len(order.OrderDetails)),
@@ -111,11 +125,24 @@ func (s *orderService) GetOrderById(ctx *gin.Context, id uint) (*utils.Response,
 
 	for i, od := range order.OrderDetails {
 		data.OrderDetailResponse[i] = entity.OrderDetailResponse{
-			ProductId: od.ProductId,
-			Qty:       od.Qty,
-			Subtotal:  od.Subtotal,
+			ProductId:      od.ProductId,
+			Qty:            od.Qty,
+			Subtotal:       od.Subtotal,
+			BackorderedQty: od.BackorderedQty,
+			ExpectedAt:     od.ExpectedAt,
 		}
 	}
+
+	shipments, err := s.ShipmentRepository.FindAllByOrderId(ctx, order.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	data.ShipmentResponse = make([]entity.ShipmentDataResponse, len(shipments))
+	for i, shipment := range shipments {
+		data.ShipmentResponse[i] = toShipmentDataResponse(shipment)
+	}
+
 	return &utils.Response{
 		Status:  200,
 		Message: "Success",
@@ -123,7 +150,7 @@ func (s *orderService) GetOrderById(ctx *gin.Context, id uint) (*utils.Response,
 	}, nil
 }
 
-func (s *orderService) GetUserOrders(ctx *gin.Context, userId uint, page, size int, status uint) (*utils.Response, int64, int64, error) {
+func (s *orderService) GetUserOrders(ctx context.Context, userId uint, page, size int, status uint) (*utils.Response, int64, int64, error) {
 	var orders []entity.Order
 	var totalSize int64
 	var err error
@@ -150,6 +177,8 @@ func (s *orderService) GetUserOrders(ctx *gin.Context, userId uint, page, size i
 			UserId:              order.UserId,
 			Date:                order.Date,
 			ShippingAddress:     order.ShippingAddress,
+			ShippingDetail:      order.ShippingDetail,
+			BillingDetail:       order.BillingDetail,
 			Total:               order.Total,
 			Status:              order.Status,
 			OrderDetailResponse: make([]entity.OrderDetailResponse, len(order.OrderDetails)),
@@ -157,9 +186,11 @@ func (s *orderService) GetUserOrders(ctx *gin.Context, userId uint, page, size i
 
 		for j, orderDetail := range order.OrderDetails {
 			data[i].OrderDetailResponse[j] = entity.OrderDetailResponse{
-				ProductId: orderDetail.ProductId,
-				Qty:       orderDetail.Qty,
-				Subtotal:  orderDetail.Subtotal,
+				ProductId:      orderDetail.ProductId,
+				Qty:            orderDetail.Qty,
+				Subtotal:       orderDetail.Subtotal,
+				BackorderedQty: orderDetail.BackorderedQty,
+				ExpectedAt:     orderDetail.ExpectedAt,
 			}
 		}
 	}
@@ -171,7 +202,7 @@ func (s *orderService) GetUserOrders(ctx *gin.Context, userId uint, page, size i
 	}, totalSize, totalPage, nil
 }
 
-func (s *orderService) GetUserOrderById(ctx *gin.Context, userId, id uint) (*utils.Response, error) {
+func (s *orderService) GetUserOrderById(ctx context.Context, userId, id uint) (*utils.Response, error) {
 
 	order, err := s.OrderRepository.FindByUserIdWithId(ctx, userId, id)
 	if err != nil {
@@ -187,6 +218,8 @@ func (s *orderService) GetUserOrderById(ctx *gin.Context, userId, id uint) (*uti
 		UserId:              order.UserId,
 		Date:                order.Date,
 		ShippingAddress:     order.ShippingAddress,
+		ShippingDetail:      order.ShippingDetail,
+		BillingDetail:       order.BillingDetail,
 		Total:               order.Total,
 		Status:              order.Status,
 		OrderDetailResponse: make([]entity.OrderDetailResponse, len(order.OrderDetails)),
@@ -194,11 +227,24 @@ func (s *orderService) GetUserOrderById(ctx *gin.Context, userId, id uint) (*uti
 
 	for i, od := range order.OrderDetails {
 		data.OrderDetailResponse[i] = entity.OrderDetailResponse{
-			ProductId: od.ProductId,
-			Qty:       od.Qty,
-			Subtotal:  od.Subtotal,
+			ProductId:      od.ProductId,
+			Qty:            od.Qty,
+			Subtotal:       od.Subtotal,
+			BackorderedQty: od.BackorderedQty,
+			ExpectedAt:     od.ExpectedAt,
 		}
 	}
+
+	shipments, err := s.ShipmentRepository.FindAllByOrderId(ctx, order.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	data.ShipmentResponse = make([]entity.ShipmentDataResponse, len(shipments))
+	for i, shipment := range shipments {
+		data.ShipmentResponse[i] = toShipmentDataResponse(shipment)
+	}
+
 	return &utils.Response{
 		Status:  200,
 		Message: "Success",
@@ -206,7 +252,7 @@ func (s *orderService) GetUserOrderById(ctx *gin.Context, userId, id uint) (*uti
 	}, nil
 }
 
-func (s *orderService) CreateOrder(ctx *gin.Context, userId uint, req *entity.CreateOrderRequest) (*utils.Response, error) {
+func (s *orderService) CreateOrder(ctx context.Context, userId uint, req *entity.CreateOrderRequest) (*utils.Response, error) {
 
 	productIds := make(map[uint]bool)
 	for _, d := range req.OrderDetails {
@@ -216,100 +262,380 @@ func (s *orderService) CreateOrder(ctx *gin.Context, userId uint, req *entity.Cr
 		productIds[d.ProductId] = true
 	}
 
+	// A typed-in shipping address takes precedence over the address book default.
+	var shippingAddress *entity.Address
+	var err error
+	if req.ShippingAddressId != 0 || req.ShippingAddress == "" {
+		shippingAddress, err = s.resolveOrderAddress(ctx, userId, req.ShippingAddressId, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if shippingAddress == nil && req.ShippingAddress == "" {
+		return nil, errors.New(errorMessages.ErrOrderAddressRequired)
+	}
+
+	billingAddress, err := s.resolveOrderAddress(ctx, userId, req.BillingAddressId, false)
+	if err != nil {
+		return nil, err
+	}
+
 	var total uint
 	var orderDetails []entity.OrderDetail
+	var order entity.Order
+
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		for _, detail := range req.OrderDetails {
+
+			product, err := s.ProductRepository.FindById(ctx, detail.ProductId)
+			if err != nil {
+				return err
+			}
+			if product == nil {
+				return errors.New(errorMessages.ErrProductNotFound)
+			}
+
+			inventory, err := s.InventoryRepository.FindFirstByProductId(ctx, detail.ProductId)
+			if err != nil {
+				return err
+			}
+
+			var stock uint
+			if inventory != nil {
+				stock = inventory.Stock
+			}
+
+			// Products that accept backorders take whatever stock is on hand and
+			// flag the rest of the line; it is allocated when stock arrives.
+			allocated := detail.Qty
+			if stock < detail.Qty {
+				if !product.AllowsBackorder() {
+					return errors.New(errorMessages.ErrInventoryInsufficientStock)
+				}
+				allocated = stock
+			}
+
+			if allocated > 0 {
+				if err := s.InventoryRepository.UpdateInventoryForOrder(ctx, inventory, allocated, "create"); err != nil {
+					return errors.New(errorMessages.ErrInventoryStockUpdate)
+				}
+			}
+
+			price := product.Price
+			if detail.Price != nil {
+				price = *detail.Price
+			}
+
+			orderDetail := entity.OrderDetail{
+				ProductId:      detail.ProductId,
+				Qty:            detail.Qty,
+				Subtotal:       price * detail.Qty,
+				BackorderedQty: detail.Qty - allocated,
+			}
+			if orderDetail.BackorderedQty > 0 {
+				orderDetail.ExpectedAt = product.AvailableAt
+			}
+
+			total += detail.Qty * price
+			orderDetails = append(orderDetails, orderDetail)
+		}
 
-	tx := s.db.Begin()
-	utils.WithTx(ctx, tx)
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-			panic(r)
-		} else if tx != nil {
-			tx.Rollback()
+		order = entity.Order{
+			UserId:          userId,
+			Date:            time.Now(),
+			ShippingAddress: req.ShippingAddress,
+			Total:           total,
+			Status:          status.PENDING,
+			QuoteId:         req.QuoteId,
+			OrderDetails:    orderDetails,
 		}
-	}()
 
-	for _, detail := range req.OrderDetails {
+		// The chosen addresses are copied onto the order so later edits to the
+		// address book leave historical orders untouched.
+		if shippingAddress != nil {
+			order.ShippingDetail = shippingAddress.PostalAddress
+			order.ShippingAddress = shippingAddress.PostalAddress.String()
+		}
+		order.BillingDetail = order.ShippingDetail
+		if billingAddress != nil {
+			order.BillingDetail = billingAddress.PostalAddress
+		}
+		if err := s.OrderRepository.CreateOrder(ctx, &order); err != nil {
+			return err
+		}
 
-		product, err := s.ProductRepository.FindById(ctx, detail.ProductId)
-		if err != nil {
-			tx.Rollback()
-			return nil, err
+		// A dry run places the order only to roll it back.
+		if req.DryRun {
+			return errDryRun
 		}
-		if product == nil {
-			tx.Rollback()
-			return nil, errors.New(errorMessages.ErrProductNotFound)
+
+		return s.Audit.Record(ctx, entity.AuditCreate, entity.AuditOrder, order.ID, nil, order)
+	})
+	if errors.Is(err, errDryRun) {
+		return &utils.Response{
+			Status:  200,
+			Message: "Order is valid",
+		}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	data := entity.OrderDataResponse{
+		ID:                  order.ID,
+		UserId:              order.UserId,
+		Date:                order.Date,
+		ShippingAddress:     order.ShippingAddress,
+		ShippingDetail:      order.ShippingDetail,
+		BillingDetail:       order.BillingDetail,
+		Total:               order.Total,
+		OrderDetailResponse: make([]entity.OrderDetailResponse, len(order.OrderDetails)),
+	}
+
+	for i, od := range order.OrderDetails {
+		data.OrderDetailResponse[i] = entity.OrderDetailResponse{
+			ProductId:      od.ProductId,
+			Qty:            od.Qty,
+			Subtotal:       od.Subtotal,
+			BackorderedQty: od.BackorderedQty,
+			ExpectedAt:     od.ExpectedAt,
 		}
+	}
 
-		total += detail.Qty * product.Price
-		orderDetails = append(orderDetails, entity.OrderDetail{
-			ProductId: detail.ProductId,
-			Qty:       detail.Qty,
-			Subtotal:  product.Price * detail.Qty,
-		})
+	return &utils.Response{
+		Status:  201,
+		Message: "Success",
+		Data:    data,
+	}, nil
+}
+
+func (s *orderService) AmendOrder(ctx context.Context, userId uint, id uint, req *entity.AmendOrderRequest) (*utils.Response, error) {
+
+	productIds := make(map[uint]bool)
+	for _, d := range req.OrderDetails {
+		if productIds[d.ProductId] {
+			return nil, errors.New(errorMessages.ErrOrderDuplicateProduct)
+		}
+		productIds[d.ProductId] = true
+	}
 
-		inventory, err := s.InventoryRepository.FindFirstByProductId(ctx, detail.ProductId)
+	var order *entity.Order
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		order, err = s.OrderRepository.FindByUserIdWithIdForUpdate(ctx, userId, id)
 		if err != nil {
-			tx.Rollback()
-			return nil, err
+			return err
 		}
-		if inventory == nil {
-			tx.Rollback()
-			return nil, errors.New(errorMessages.ErrInventoryInsufficientStock)
+		if order == nil {
+			return errors.New(errorMessages.ErrOrderNotFound)
 		}
 
-		if inventory.Stock < detail.Qty {
-			return nil, errors.New(errorMessages.ErrInventoryInsufficientStock)
+		if order.Status != status.PENDING {
+			return errors.New(errorMessages.ErrInvalidOrderStatus)
 		}
 
-		if err := s.InventoryRepository.UpdateInventoryForOrder(ctx, inventory, detail.Qty, "create"); err != nil {
-			tx.Rollback()
-			return nil, errors.New(errorMessages.ErrInventoryStockUpdate)
+		// Amending re-prices every line, which would throw away quoted prices.
+		if order.QuoteId != nil {
+			return errors.New(errorMessages.ErrOrderFromQuote)
+		}
+		before := utils.AuditSnapshot(order)
+
+		var lineProductIds []uint
+		quantities := make(map[uint]uint)
+		backordered := make(map[uint]uint)
+		for _, od := range order.OrderDetails {
+			lineProductIds = append(lineProductIds, od.ProductId)
+			quantities[od.ProductId] = od.Qty
+			backordered[od.ProductId] = od.BackorderedQty
 		}
-	}
 
-	order := entity.Order{
-		UserId:          userId,
-		Date:            time.Now(),
-		ShippingAddress: req.ShippingAddress,
-		Total:           total,
-		Status:          status.PENDING,
-		OrderDetails:    orderDetails,
-	}
-	if err := s.OrderRepository.CreateOrder(ctx, &order); err != nil {
-		tx.Rollback()
+		// Only the difference between the old and new qty touches the inventory.
+		for _, detail := range req.OrderDetails {
+			oldQty, exists := quantities[detail.ProductId]
+			if !exists {
+				lineProductIds = append(lineProductIds, detail.ProductId)
+			}
+			quantities[detail.ProductId] = detail.Qty
+
+			if detail.Qty == oldQty {
+				continue
+			}
+
+			inventory, err := s.InventoryRepository.FindFirstByProductId(ctx, detail.ProductId)
+			if err != nil {
+				return err
+			}
+
+			if detail.Qty < oldQty {
+				// Backordered units are dropped first; only allocated units go back to stock.
+				release := oldQty - detail.Qty
+				fromBackorder := min(release, backordered[detail.ProductId])
+				backordered[detail.ProductId] -= fromBackorder
+				release -= fromBackorder
+
+				if release == 0 {
+					continue
+				}
+				if inventory == nil {
+					return errors.New(errorMessages.ErrInventoryNotFound)
+				}
+				if err := s.InventoryRepository.UpdateInventoryForOrder(ctx, inventory, release, "cancel"); err != nil {
+					return errors.New(errorMessages.ErrInventoryStockUpdate)
+				}
+				continue
+			}
+
+			product, err := s.ProductRepository.FindById(ctx, detail.ProductId)
+			if err != nil {
+				return err
+			}
+			if product == nil {
+				return errors.New(errorMessages.ErrProductNotFound)
+			}
+
+			var stock uint
+			if inventory != nil {
+				stock = inventory.Stock
+			}
+
+			delta := detail.Qty - oldQty
+			allocated := delta
+			if stock < delta {
+				if !product.AllowsBackorder() {
+					return errors.New(errorMessages.ErrInventoryInsufficientStock)
+				}
+				allocated = stock
+			}
+
+			if allocated > 0 {
+				if err := s.InventoryRepository.UpdateInventoryForOrder(ctx, inventory, allocated, "create"); err != nil {
+					return errors.New(errorMessages.ErrInventoryStockUpdate)
+				}
+			}
+			backordered[detail.ProductId] += delta - allocated
+		}
+
+		// Every remaining line is re-priced at the current product price.
+		var total uint
+		var orderDetails []entity.OrderDetail
+		for _, productId := range lineProductIds {
+			qty := quantities[productId]
+			if qty == 0 {
+				continue
+			}
+
+			product, err := s.ProductRepository.FindById(ctx, productId)
+			if err != nil {
+				return err
+			}
+			if product == nil {
+				return errors.New(errorMessages.ErrProductNotFound)
+			}
+
+			orderDetail := entity.OrderDetail{
+				ProductId:      productId,
+				Qty:            qty,
+				Subtotal:       product.Price * qty,
+				BackorderedQty: backordered[productId],
+			}
+			if orderDetail.BackorderedQty > 0 {
+				orderDetail.ExpectedAt = product.AvailableAt
+			}
+
+			total += qty * product.Price
+			orderDetails = append(orderDetails, orderDetail)
+		}
+
+		if len(orderDetails) == 0 {
+			return errors.New(errorMessages.ErrOrderEmpty)
+		}
+
+		if err := s.OrderRepository.ReplaceOrderDetails(ctx, order.ID, orderDetails); err != nil {
+			return err
+		}
+
+		if req.ShippingAddressId != 0 {
+			shippingAddress, err := s.resolveOrderAddress(ctx, userId, req.ShippingAddressId, true)
+			if err != nil {
+				return err
+			}
+			order.ShippingDetail = shippingAddress.PostalAddress
+			order.ShippingAddress = shippingAddress.PostalAddress.String()
+		} else if req.ShippingAddress != "" {
+			order.ShippingDetail = entity.PostalAddress{}
+			order.ShippingAddress = req.ShippingAddress
+		}
+
+		if req.BillingAddressId != 0 {
+			billingAddress, err := s.resolveOrderAddress(ctx, userId, req.BillingAddressId, false)
+			if err != nil {
+				return err
+			}
+			order.BillingDetail = billingAddress.PostalAddress
+		}
+
+		// The lines were already rewritten above, so keep Save from upserting them again.
+		order.Total = total
+		order.OrderDetails = nil
+		if err := s.OrderRepository.UpdateOrder(ctx, order); err != nil {
+			return err
+		}
+		order.OrderDetails = orderDetails
+
+		return s.Audit.Record(ctx, entity.AuditUpdate, entity.AuditOrder, order.ID, before, order)
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	tx.Commit()
-	tx = nil
-
 	data := entity.OrderDataResponse{
 		ID:                  order.ID,
 		UserId:              order.UserId,
 		Date:                order.Date,
 		ShippingAddress:     order.ShippingAddress,
+		ShippingDetail:      order.ShippingDetail,
+		BillingDetail:       order.BillingDetail,
 		Total:               order.Total,
+		Status:              order.Status,
 		OrderDetailResponse: make([]entity.OrderDetailResponse, len(order.OrderDetails)),
 	}
 
 	for i, od := range order.OrderDetails {
 		data.OrderDetailResponse[i] = entity.OrderDetailResponse{
-			ProductId: od.ProductId,
-			Qty:       od.Qty,
-			Subtotal:  od.Subtotal,
+			ProductId:      od.ProductId,
+			Qty:            od.Qty,
+			Subtotal:       od.Subtotal,
+			BackorderedQty: od.BackorderedQty,
+			ExpectedAt:     od.ExpectedAt,
 		}
 	}
 
 	return &utils.Response{
-		Status:  201,
-		Message: "Success",
+		Status:  200,
+		Message: "Order successfully amended",
 		Data:    data,
 	}, nil
 }
 
-func (s *orderService) ProcessOrder(ctx *gin.Context, id uint) (*utils.Response, error) {
+func (s *orderService) resolveOrderAddress(ctx context.Context, userId, addressId uint, shipping bool) (*entity.Address, error) {
+	if addressId != 0 {
+		address, err := s.AddressRepository.FindByUserIdWithId(ctx, userId, addressId)
+		if err != nil {
+			return nil, err
+		}
+		if address == nil {
+			return nil, errors.New(errorMessages.ErrAddressNotFound)
+		}
+		return address, nil
+	}
+
+	if shipping {
+		return s.AddressRepository.FindDefaultShippingByUserId(ctx, userId)
+	}
+	return s.AddressRepository.FindDefaultBillingByUserId(ctx, userId)
+}
+
+func (s *orderService) ProcessOrder(ctx context.Context, id uint) (*utils.Response, error) {
 	order, err := s.OrderRepository.FindById(ctx, id)
 	if err != nil {
 		return nil, err
@@ -323,15 +649,26 @@ func (s *orderService) ProcessOrder(ctx *gin.Context, id uint) (*utils.Response,
 		return nil, errors.New(errorMessages.ErrInvalidOrderStatus)
 	}
 
+	before := utils.AuditSnapshot(order)
 	order.Status = status.PROCESSING
-	if err := s.OrderRepository.UpdateOrder(ctx, order); err != nil {
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.OrderRepository.UpdateOrder(ctx, order); err != nil {
+			return err
+		}
+
+		return s.Audit.Record(ctx, entity.AuditUpdate, entity.AuditOrder, order.ID, before, order)
+	})
+	if err != nil {
 		return nil, err
 	}
+
 	data := entity.OrderDataResponse{
 		ID:                  order.ID,
 		UserId:              order.UserId,
 		Date:                order.Date,
 		ShippingAddress:     order.ShippingAddress,
+		ShippingDetail:      order.ShippingDetail,
+		BillingDetail:       order.BillingDetail,
 		Total:               order.Total,
 		Status:              order.Status,
 		OrderDetailResponse: make([]entity.OrderDetailResponse, len(order.OrderDetails)),
@@ -339,9 +676,11 @@ func (s *orderService) ProcessOrder(ctx *gin.Context, id uint) (*utils.Response,
 
 	for i, od := range order.OrderDetails {
 		data.OrderDetailResponse[i] = entity.OrderDetailResponse{
-			ProductId: od.ProductId,
-			Qty:       od.Qty,
-			Subtotal:  od.Subtotal,
+			ProductId:      od.ProductId,
+			Qty:            od.Qty,
+			Subtotal:       od.Subtotal,
+			BackorderedQty: od.BackorderedQty,
+			ExpectedAt:     od.ExpectedAt,
 		}
 	}
 
@@ -352,7 +691,7 @@ func (s *orderService) ProcessOrder(ctx *gin.Context, id uint) (*utils.Response,
 	}, nil
 }
 
-func (s *orderService) ConfirmOrder(ctx *gin.Context, userId uint, id uint) (*utils.Response, error) {
+func (s *orderService) ConfirmOrder(ctx context.Context, userId uint, id uint) (*utils.Response, error) {
 
 	order, err := s.OrderRepository.FindByUserIdWithId(ctx, userId, id)
 	if err != nil {
@@ -363,19 +702,31 @@ func (s *orderService) ConfirmOrder(ctx *gin.Context, userId uint, id uint) (*ut
 		return nil, errors.New(errorMessages.ErrOrderNotFound)
 	}
 
-	if order.Status != status.PROCESSING {
+	// Only orders that have shipped in full can be confirmed as received.
+	if order.Status != status.SHIPPED && order.Status != status.DELIVERED {
 		return nil, errors.New(errorMessages.ErrInvalidOrderStatus)
 	}
 
+	before := utils.AuditSnapshot(order)
 	order.Status = status.DONE
-	if err := s.OrderRepository.UpdateOrder(ctx, order); err != nil {
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.OrderRepository.UpdateOrder(ctx, order); err != nil {
+			return err
+		}
+
+		return s.Audit.Record(ctx, entity.AuditUpdate, entity.AuditOrder, order.ID, before, order)
+	})
+	if err != nil {
 		return nil, err
 	}
+
 	data := entity.OrderDataResponse{
 		ID:                  order.ID,
 		UserId:              order.UserId,
 		Date:                order.Date,
 		ShippingAddress:     order.ShippingAddress,
+		ShippingDetail:      order.ShippingDetail,
+		BillingDetail:       order.BillingDetail,
 		Total:               order.Total,
 		Status:              order.Status,
 		OrderDetailResponse: make([]entity.OrderDetailResponse, len(order.OrderDetails)),
@@ -383,9 +734,11 @@ func (s *orderService) ConfirmOrder(ctx *gin.Context, userId uint, id uint) (*ut
 
 	for i, od := range order.OrderDetails {
 		data.OrderDetailResponse[i] = entity.OrderDetailResponse{
-			ProductId: od.ProductId,
-			Qty:       od.Qty,
-			Subtotal:  od.Subtotal,
+			ProductId:      od.ProductId,
+			Qty:            od.Qty,
+			Subtotal:       od.Subtotal,
+			BackorderedQty: od.BackorderedQty,
+			ExpectedAt:     od.ExpectedAt,
 		}
 	}
 
@@ -396,52 +749,45 @@ func (s *orderService) ConfirmOrder(ctx *gin.Context, userId uint, id uint) (*ut
 	}, nil
 }
 
-func (s *orderService) CancelOrder(ctx *gin.Context, userId uint, id uint) error {
-
-	order, err := s.OrderRepository.FindByUserIdWithId(ctx, userId, id)
-	if err != nil {
-		return err
-	}
-
-	if order == nil {
-		return errors.New(errorMessages.ErrProductNotFound)
-	}
-
-	tx := s.db.Begin()
-	utils.WithTx(ctx, tx)
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-			panic(r)
-		} else if tx != nil {
-			tx.Rollback()
-		}
-	}()
-
-	for _, orderDetail := range order.OrderDetails {
-		inventory, err := s.InventoryRepository.FindFirstByProductId(ctx, orderDetail.ProductId)
+// CancelOrder locks the order before checking that it is still pending,
+// so a payment or shipment racing the cancellation cannot restock its lines.
+func (s *orderService) CancelOrder(ctx context.Context, userId uint, id uint) error {
+	return s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		order, err := s.OrderRepository.FindByUserIdWithIdForUpdate(ctx, userId, id)
 		if err != nil {
-			tx.Rollback()
 			return err
 		}
+		if order == nil {
+			return errors.New(errorMessages.ErrOrderNotFound)
+		}
 
-		if err := s.InventoryRepository.UpdateInventoryForOrder(ctx, inventory, orderDetail.Qty, "cancel"); err != nil {
-			tx.Rollback()
-			return err
+		if order.Status != status.PENDING {
+			return errors.New(errorMessages.ErrOrderUncancelable)
 		}
-	}
 
-	if order.Status != status.PENDING {
-		return errors.New(errorMessages.ErrOrderUncancelable)
-	}
+		for _, orderDetail := range order.OrderDetails {
+			allocated := orderDetail.Qty - orderDetail.BackorderedQty
+			if allocated == 0 {
+				continue
+			}
 
-	if err := s.OrderRepository.DeleteOrder(ctx, id); err != nil {
-		tx.Rollback()
-		return err
-	}
+			inventory, err := s.InventoryRepository.FindFirstByProductId(ctx, orderDetail.ProductId)
+			if err != nil {
+				return err
+			}
+			if inventory == nil {
+				return errors.New(errorMessages.ErrInventoryNotFound)
+			}
 
-	tx.Commit()
-	tx = nil
+			if err := s.InventoryRepository.UpdateInventoryForOrder(ctx, inventory, allocated, "cancel"); err != nil {
+				return err
+			}
+		}
+
+		if err := s.OrderRepository.DeleteOrder(ctx, id); err != nil {
+			return err
+		}
 
-	return nil
+		return s.Audit.Record(ctx, entity.AuditDelete, entity.AuditOrder, order.ID, order, nil)
+	})
 }
@@ -0,0 +1,255 @@
+package service
+
+import (
+	"context"
+	"go-trades/entity"
+	errorMessages "go-trades/utils/error-messages"
+	status "go-trades/utils/status"
+	"reflect"
+	"testing"
+)
+
+type orderFixture struct {
+	orders    *fakeOrderRepository
+	inventory *fakeInventoryRepository
+	audit     *fakeAuditRepository
+	svc       OrderService
+}
+
+// newOrderFixture sells product 1 and 2 at 100 and 250 without backorders
+// and product 3 at 40 on backorder. User 1's pending order 1 holds two of
+// product 1 and one of product 2.
+func newOrderFixture(orders ...entity.Order) *orderFixture {
+	if len(orders) == 0 {
+		orders = []entity.Order{{
+			ID:     1,
+			UserId: 1,
+			Status: status.PENDING,
+			Total:  450,
+			OrderDetails: []entity.OrderDetail{
+				{OrderId: 1, ProductId: 1, Qty: 2, Subtotal: 200},
+				{OrderId: 1, ProductId: 2, Qty: 1, Subtotal: 250},
+			},
+		}}
+	}
+
+	f := &orderFixture{
+		orders:    newFakeOrderRepository(orders...),
+		inventory: newFakeInventoryRepository(map[uint]uint{1: 5, 2: 5, 3: 1}),
+		audit:     &fakeAuditRepository{},
+	}
+	products := newFakeProductRepository(
+		testProduct(1, 100, entity.NoBackorder),
+		testProduct(2, 250, entity.NoBackorder),
+		testProduct(3, 40, entity.Backorder),
+	)
+	txManager := &fakeTxManager{stores: []fakeStore{f.orders, f.inventory, f.audit}}
+	f.svc = NewOrderService(txManager, f.orders, products, f.inventory, nil, &fakeAddressRepository{}, NewAuditService(f.audit))
+	return f
+}
+
+func (f *orderFixture) assertStock(t *testing.T, want map[uint]uint) {
+	t.Helper()
+	if !reflect.DeepEqual(f.inventory.stock, want) {
+		t.Errorf("stock = %v, want %v", f.inventory.stock, want)
+	}
+}
+
+func TestAmendOrderMovesOnlyTheDifference(t *testing.T) {
+	f := newOrderFixture()
+
+	_, err := f.svc.AmendOrder(context.Background(), 1, 1, &entity.AmendOrderRequest{
+		OrderDetails: []entity.AmendOrderDetailRequest{
+			{ProductId: 1, Qty: 4},
+			{ProductId: 2, Qty: 0},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f.assertStock(t, map[uint]uint{1: 3, 2: 6, 3: 1})
+	order := f.orders.orders[1]
+	want := []entity.OrderDetail{{OrderId: 1, ProductId: 1, Qty: 4, Subtotal: 400}}
+	if !reflect.DeepEqual(order.OrderDetails, want) {
+		t.Errorf("lines = %+v, want %+v", order.OrderDetails, want)
+	}
+	if order.Total != 400 {
+		t.Errorf("total = %d, want 400", order.Total)
+	}
+	if len(f.audit.entries) != 1 || f.audit.entries[0].Action != entity.AuditUpdate {
+		t.Errorf("audit entries = %+v, want one update", f.audit.entries)
+	}
+}
+
+func TestAmendOrderBackordersShortfall(t *testing.T) {
+	f := newOrderFixture()
+
+	_, err := f.svc.AmendOrder(context.Background(), 1, 1, &entity.AmendOrderRequest{
+		OrderDetails: []entity.AmendOrderDetailRequest{{ProductId: 3, Qty: 3}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f.assertStock(t, map[uint]uint{1: 5, 2: 5, 3: 0})
+	var line *entity.OrderDetail
+	for i, detail := range f.orders.orders[1].OrderDetails {
+		if detail.ProductId == 3 {
+			line = &f.orders.orders[1].OrderDetails[i]
+		}
+	}
+	if line == nil || line.Qty != 3 || line.BackorderedQty != 2 {
+		t.Errorf("product 3 line = %+v, want 3 ordered with 2 backordered", line)
+	}
+}
+
+func TestAmendOrderReleasesBackorderBeforeStock(t *testing.T) {
+	f := newOrderFixture(entity.Order{
+		ID:           1,
+		UserId:       1,
+		Status:       status.PENDING,
+		OrderDetails: []entity.OrderDetail{{OrderId: 1, ProductId: 3, Qty: 3, BackorderedQty: 2}},
+	})
+
+	_, err := f.svc.AmendOrder(context.Background(), 1, 1, &entity.AmendOrderRequest{
+		OrderDetails: []entity.AmendOrderDetailRequest{{ProductId: 3, Qty: 1}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f.assertStock(t, map[uint]uint{1: 5, 2: 5, 3: 1})
+	line := f.orders.orders[1].OrderDetails[0]
+	if line.Qty != 1 || line.BackorderedQty != 0 {
+		t.Errorf("line = %+v, want 1 ordered and none backordered", line)
+	}
+}
+
+func TestAmendOrderRejected(t *testing.T) {
+	quoteId := uint(9)
+	tests := []struct {
+		name   string
+		order  entity.Order
+		userId uint
+		lines  []entity.AmendOrderDetailRequest
+		err    string
+	}{
+		{
+			name:   "another user's order",
+			userId: 2,
+			lines:  []entity.AmendOrderDetailRequest{{ProductId: 1, Qty: 1}},
+			err:    errorMessages.ErrOrderNotFound,
+		},
+		{
+			name:  "paid order",
+			order: entity.Order{ID: 1, UserId: 1, Status: status.PAID, OrderDetails: []entity.OrderDetail{{OrderId: 1, ProductId: 1, Qty: 2}}},
+			lines: []entity.AmendOrderDetailRequest{{ProductId: 1, Qty: 1}},
+			err:   errorMessages.ErrInvalidOrderStatus,
+		},
+		{
+			name:  "quoted order",
+			order: entity.Order{ID: 1, UserId: 1, Status: status.PENDING, QuoteId: &quoteId, OrderDetails: []entity.OrderDetail{{OrderId: 1, ProductId: 1, Qty: 2}}},
+			lines: []entity.AmendOrderDetailRequest{{ProductId: 1, Qty: 1}},
+			err:   errorMessages.ErrOrderFromQuote,
+		},
+		{
+			name:  "duplicate product",
+			lines: []entity.AmendOrderDetailRequest{{ProductId: 1, Qty: 1}, {ProductId: 1, Qty: 2}},
+			err:   errorMessages.ErrOrderDuplicateProduct,
+		},
+		{
+			name:  "not enough stock",
+			lines: []entity.AmendOrderDetailRequest{{ProductId: 2, Qty: 0}, {ProductId: 1, Qty: 9}},
+			err:   errorMessages.ErrInventoryInsufficientStock,
+		},
+		{
+			name:  "every line removed",
+			lines: []entity.AmendOrderDetailRequest{{ProductId: 1, Qty: 0}, {ProductId: 2, Qty: 0}},
+			err:   errorMessages.ErrOrderEmpty,
+		},
+	}
+
+	for _, tt := range tests {
+		var f *orderFixture
+		if tt.order.ID != 0 {
+			f = newOrderFixture(tt.order)
+		} else {
+			f = newOrderFixture()
+		}
+		userId := tt.userId
+		if userId == 0 {
+			userId = 1
+		}
+		before := copyOrder(*f.orders.orders[1])
+
+		_, err := f.svc.AmendOrder(context.Background(), userId, 1, &entity.AmendOrderRequest{OrderDetails: tt.lines})
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("%s: error = %v, want %s", tt.name, err, tt.err)
+		}
+		f.assertStock(t, map[uint]uint{1: 5, 2: 5, 3: 1})
+		if !reflect.DeepEqual(f.orders.orders[1], before) {
+			t.Errorf("%s: order changed to %+v", tt.name, f.orders.orders[1])
+		}
+		if len(f.audit.entries) != 0 {
+			t.Errorf("%s: recorded %d audit entries, want 0", tt.name, len(f.audit.entries))
+		}
+	}
+}
+
+func TestCancelOrderRestocksAllocatedUnits(t *testing.T) {
+	f := newOrderFixture(entity.Order{
+		ID:     1,
+		UserId: 1,
+		Status: status.PENDING,
+		OrderDetails: []entity.OrderDetail{
+			{OrderId: 1, ProductId: 1, Qty: 2},
+			{OrderId: 1, ProductId: 3, Qty: 3, BackorderedQty: 2},
+		},
+	})
+
+	if err := f.svc.CancelOrder(context.Background(), 1, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	f.assertStock(t, map[uint]uint{1: 7, 2: 5, 3: 2})
+	if _, ok := f.orders.orders[1]; ok {
+		t.Error("cancelled order was not deleted")
+	}
+	if len(f.audit.entries) != 1 || f.audit.entries[0].Action != entity.AuditDelete {
+		t.Errorf("audit entries = %+v, want one delete", f.audit.entries)
+	}
+}
+
+func TestCancelOrderRejected(t *testing.T) {
+	for _, orderStatus := range []uint{status.PAID, status.PROCESSING, status.SHIPPED} {
+		f := newOrderFixture(entity.Order{
+			ID:           1,
+			UserId:       1,
+			Status:       orderStatus,
+			OrderDetails: []entity.OrderDetail{{OrderId: 1, ProductId: 1, Qty: 2}},
+		})
+
+		err := f.svc.CancelOrder(context.Background(), 1, 1)
+		if err == nil || err.Error() != errorMessages.ErrOrderUncancelable {
+			t.Errorf("status %d: error = %v, want %s", orderStatus, err, errorMessages.ErrOrderUncancelable)
+		}
+		f.assertStock(t, map[uint]uint{1: 5, 2: 5, 3: 1})
+		if _, ok := f.orders.orders[1]; !ok {
+			t.Errorf("status %d: order was deleted", orderStatus)
+		}
+	}
+
+	f := newOrderFixture()
+	missing := []struct{ userId, id uint }{
+		{1, 2}, // no such order
+		{2, 1}, // another user's order
+	}
+	for _, m := range missing {
+		err := f.svc.CancelOrder(context.Background(), m.userId, m.id)
+		if err == nil || err.Error() != errorMessages.ErrOrderNotFound {
+			t.Errorf("user %d order %d: error = %v, want %s", m.userId, m.id, err, errorMessages.ErrOrderNotFound)
+		}
+	}
+	f.assertStock(t, map[uint]uint{1: 5, 2: 5, 3: 1})
+}
@@ -0,0 +1,485 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"go-trades/entity"
+	"go-trades/repository"
+	"go-trades/utils"
+	errorMessages "go-trades/utils/error-messages"
+	status "go-trades/utils/status"
+	"time"
+)
+
+type orderReturnService struct {
+	txManager             utils.TxManager
+	OrderReturnRepository repository.OrderReturnRepository
+	OrderRepository       repository.OrderRepository
+	InventoryRepository   repository.InventoryRepository
+	PaymentRepository     repository.PaymentRepository
+	Audit                 AuditService
+}
+
+type OrderReturnService interface {
+	GetAllOrderReturns(ctx context.Context, page, size int) (*utils.Response, int64, int64, error)
+	GetOrderReturnById(ctx context.Context, id uint) (*utils.Response, error)
+	GetUserOrderReturns(ctx context.Context, userId uint, page, size int) (*utils.Response, int64, int64, error)
+	GetUserOrderReturnById(ctx context.Context, userId, id uint) (*utils.Response, error)
+	CreateOrderReturn(ctx context.Context, userId uint, req *entity.CreateOrderReturnRequest) (*utils.Response, error)
+	ApproveOrderReturn(ctx context.Context, id uint, req *entity.ReviewOrderReturnRequest) (*utils.Response, error)
+	RejectOrderReturn(ctx context.Context, id uint, req *entity.ReviewOrderReturnRequest) (*utils.Response, error)
+	ReceiveOrderReturn(ctx context.Context, id uint) (*utils.Response, error)
+	InspectOrderReturn(ctx context.Context, id uint, req *entity.InspectOrderReturnRequest) (*utils.Response, error)
+	RefundOrderReturn(ctx context.Context, id uint) (*utils.Response, error)
+}
+
+func NewOrderReturnService(txManager utils.TxManager, orr repository.OrderReturnRepository, or repository.OrderRepository, ir repository.InventoryRepository, pr repository.PaymentRepository, audit AuditService) OrderReturnService {
+	return &orderReturnService{
+		txManager:             txManager,
+		OrderReturnRepository: orr,
+		OrderRepository:       or,
+		InventoryRepository:   ir,
+		PaymentRepository:     pr,
+		Audit:                 audit,
+	}
+}
+
+func (s *orderReturnService) GetAllOrderReturns(ctx context.Context, page, size int) (*utils.Response, int64, int64, error) {
+	orderReturns, totalSize, err := s.OrderReturnRepository.FindAll(ctx, page, size)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	data := make([]entity.OrderReturnDataResponse, len(orderReturns))
+	for i, orderReturn := range orderReturns {
+		data[i] = toOrderReturnDataResponse(orderReturn)
+	}
+
+	totalPage := utils.GetTotalPage(totalSize, size)
+
+	return &utils.Response{
+		Status:  200,
+		Message: "Success",
+		Data:    data,
+	}, totalSize, totalPage, nil
+}
+
+func (s *orderReturnService) GetOrderReturnById(ctx context.Context, id uint) (*utils.Response, error) {
+	orderReturn, err := s.OrderReturnRepository.FindById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if orderReturn == nil {
+		return nil, errors.New(errorMessages.ErrReturnNotFound)
+	}
+
+	return &utils.Response{
+		Status:  200,
+		Message: "Success",
+		Data:    toOrderReturnDataResponse(*orderReturn),
+	}, nil
+}
+
+func (s *orderReturnService) GetUserOrderReturns(ctx context.Context, userId uint, page, size int) (*utils.Response, int64, int64, error) {
+	orderReturns, totalSize, err := s.OrderReturnRepository.FindAllByUserId(ctx, userId, page, size)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	data := make([]entity.OrderReturnDataResponse, len(orderReturns))
+	for i, orderReturn := range orderReturns {
+		data[i] = toOrderReturnDataResponse(orderReturn)
+	}
+
+	totalPage := utils.GetTotalPage(totalSize, size)
+
+	return &utils.Response{
+		Status:  200,
+		Message: "Success",
+		Data:    data,
+	}, totalSize, totalPage, nil
+}
+
+func (s *orderReturnService) GetUserOrderReturnById(ctx context.Context, userId, id uint) (*utils.Response, error) {
+	orderReturn, err := s.OrderReturnRepository.FindByUserIdWithId(ctx, userId, id)
+	if err != nil {
+		return nil, err
+	}
+	if orderReturn == nil {
+		return nil, errors.New(errorMessages.ErrReturnNotFound)
+	}
+
+	return &utils.Response{
+		Status:  200,
+		Message: "Success",
+		Data:    toOrderReturnDataResponse(*orderReturn),
+	}, nil
+}
+
+// CreateOrderReturn requests a return of part of a delivered or completed
+// order. The order stays locked while the quantities already claimed by its
+// other returns are summed, so two requests cannot both claim the same units.
+func (s *orderReturnService) CreateOrderReturn(ctx context.Context, userId uint, req *entity.CreateOrderReturnRequest) (*utils.Response, error) {
+	if len(req.OrderReturnItems) == 0 {
+		return nil, errors.New(errorMessages.ErrReturnEmpty)
+	}
+
+	var orderReturn entity.OrderReturn
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		order, err := s.OrderRepository.FindByUserIdWithIdForUpdate(ctx, userId, req.OrderId)
+		if err != nil {
+			return err
+		}
+		if order == nil {
+			return errors.New(errorMessages.ErrOrderNotFound)
+		}
+
+		if order.Status != status.DELIVERED && order.Status != status.DONE {
+			return errors.New(errorMessages.ErrReturnOrderNotReturnable)
+		}
+
+		existingReturns, err := s.OrderReturnRepository.FindAllByOrderId(ctx, order.ID)
+		if err != nil {
+			return err
+		}
+
+		// returnable holds the qty of each ordered product not yet claimed by another return
+		returnable := make(map[uint]uint)
+		unitPrice := make(map[uint]uint)
+		for _, od := range order.OrderDetails {
+			returnable[od.ProductId] = od.Qty
+			unitPrice[od.ProductId] = od.Subtotal / od.Qty
+		}
+		for _, existing := range existingReturns {
+			if existing.Status == status.RETURN_REJECTED {
+				continue
+			}
+			for _, item := range existing.OrderReturnItems {
+				if left, exists := returnable[item.ProductId]; exists {
+					returnable[item.ProductId] = left - min(item.Qty, left)
+				}
+			}
+		}
+
+		var orderReturnItems []entity.OrderReturnItem
+		productIds := make(map[uint]bool)
+		for _, item := range req.OrderReturnItems {
+			if productIds[item.ProductId] {
+				return errors.New(errorMessages.ErrReturnDuplicateProduct)
+			}
+			productIds[item.ProductId] = true
+
+			left, exists := returnable[item.ProductId]
+			if !exists {
+				return errors.New(errorMessages.ErrReturnProductNotInOrder)
+			}
+			if item.Qty > left {
+				return errors.New(errorMessages.ErrReturnQtyExceeded)
+			}
+
+			orderReturnItems = append(orderReturnItems, entity.OrderReturnItem{
+				ProductId: item.ProductId,
+				Qty:       item.Qty,
+				Subtotal:  unitPrice[item.ProductId] * item.Qty,
+			})
+		}
+
+		orderReturn = entity.OrderReturn{
+			OrderId:          order.ID,
+			UserId:           userId,
+			Reason:           req.Reason,
+			Status:           status.RETURN_REQUESTED,
+			RequestedAt:      time.Now(),
+			OrderReturnItems: orderReturnItems,
+		}
+		if err := s.OrderReturnRepository.CreateOrderReturn(ctx, &orderReturn); err != nil {
+			return err
+		}
+
+		return s.Audit.Record(ctx, entity.AuditCreate, entity.AuditOrderReturn, orderReturn.ID, nil, orderReturn)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &utils.Response{
+		Status:  201,
+		Message: "Return successfully requested",
+		Data:    toOrderReturnDataResponse(orderReturn),
+	}, nil
+}
+
+func (s *orderReturnService) ApproveOrderReturn(ctx context.Context, id uint, req *entity.ReviewOrderReturnRequest) (*utils.Response, error) {
+	return s.reviewOrderReturn(ctx, id, req, status.RETURN_APPROVED)
+}
+
+func (s *orderReturnService) RejectOrderReturn(ctx context.Context, id uint, req *entity.ReviewOrderReturnRequest) (*utils.Response, error) {
+	return s.reviewOrderReturn(ctx, id, req, status.RETURN_REJECTED)
+}
+
+func (s *orderReturnService) reviewOrderReturn(ctx context.Context, id uint, req *entity.ReviewOrderReturnRequest, newStatus uint) (*utils.Response, error) {
+	orderReturn, err := s.OrderReturnRepository.FindById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if orderReturn == nil {
+		return nil, errors.New(errorMessages.ErrReturnNotFound)
+	}
+
+	if orderReturn.Status != status.RETURN_REQUESTED {
+		return nil, errors.New(errorMessages.ErrInvalidReturnStatus)
+	}
+
+	before := utils.AuditSnapshot(orderReturn)
+	now := time.Now()
+	orderReturn.Status = newStatus
+	orderReturn.ReviewNote = req.Note
+	orderReturn.ReviewedAt = &now
+
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.OrderReturnRepository.UpdateOrderReturn(ctx, orderReturn); err != nil {
+			return err
+		}
+
+		return s.Audit.Record(ctx, entity.AuditUpdate, entity.AuditOrderReturn, orderReturn.ID, before, orderReturn)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &utils.Response{
+		Status:  200,
+		Message: "Return successfully reviewed",
+		Data:    toOrderReturnDataResponse(*orderReturn),
+	}, nil
+}
+
+func (s *orderReturnService) ReceiveOrderReturn(ctx context.Context, id uint) (*utils.Response, error) {
+	orderReturn, err := s.OrderReturnRepository.FindById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if orderReturn == nil {
+		return nil, errors.New(errorMessages.ErrReturnNotFound)
+	}
+
+	if orderReturn.Status != status.RETURN_APPROVED {
+		return nil, errors.New(errorMessages.ErrInvalidReturnStatus)
+	}
+
+	before := utils.AuditSnapshot(orderReturn)
+	now := time.Now()
+	orderReturn.Status = status.RETURN_RECEIVED
+	orderReturn.ReceivedAt = &now
+
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.OrderReturnRepository.UpdateOrderReturn(ctx, orderReturn); err != nil {
+			return err
+		}
+
+		return s.Audit.Record(ctx, entity.AuditUpdate, entity.AuditOrderReturn, orderReturn.ID, before, orderReturn)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &utils.Response{
+		Status:  200,
+		Message: "Return goods received",
+		Data:    toOrderReturnDataResponse(*orderReturn),
+	}, nil
+}
+
+func (s *orderReturnService) InspectOrderReturn(ctx context.Context, id uint, req *entity.InspectOrderReturnRequest) (*utils.Response, error) {
+	var orderReturn *entity.OrderReturn
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		orderReturn, err = s.OrderReturnRepository.FindById(ctx, id)
+		if err != nil {
+			return err
+		}
+		if orderReturn == nil {
+			return errors.New(errorMessages.ErrReturnNotFound)
+		}
+
+		if orderReturn.Status != status.RETURN_RECEIVED {
+			return errors.New(errorMessages.ErrInvalidReturnStatus)
+		}
+		before := utils.AuditSnapshot(orderReturn)
+
+		// Lines missing from the request are written off entirely.
+		restockQty := make(map[uint]uint)
+		for _, item := range req.InspectItems {
+			if _, exists := restockQty[item.ProductId]; exists {
+				return errors.New(errorMessages.ErrReturnDuplicateProduct)
+			}
+			restockQty[item.ProductId] = item.RestockQty
+		}
+
+		for i, item := range orderReturn.OrderReturnItems {
+			restock := restockQty[item.ProductId]
+			delete(restockQty, item.ProductId)
+
+			if restock > item.Qty {
+				return errors.New(errorMessages.ErrReturnRestockQtyExceeded)
+			}
+
+			if restock > 0 {
+				inventory, err := s.InventoryRepository.FindFirstByProductId(ctx, item.ProductId)
+				if err != nil {
+					return err
+				}
+				if inventory == nil {
+					return errors.New(errorMessages.ErrInventoryNotFound)
+				}
+
+				if err := s.InventoryRepository.UpdateInventoryForOrder(ctx, inventory, restock, "restock"); err != nil {
+					return errors.New(errorMessages.ErrInventoryStockUpdate)
+				}
+			}
+
+			orderReturn.OrderReturnItems[i].RestockedQty = restock
+			orderReturn.OrderReturnItems[i].WrittenOffQty = item.Qty - restock
+		}
+
+		if len(restockQty) > 0 {
+			return errors.New(errorMessages.ErrReturnProductNotInOrder)
+		}
+
+		now := time.Now()
+		orderReturn.Status = status.RETURN_INSPECTED
+		orderReturn.InspectedAt = &now
+
+		if err := s.OrderReturnRepository.UpdateOrderReturn(ctx, orderReturn); err != nil {
+			return err
+		}
+
+		return s.Audit.Record(ctx, entity.AuditUpdate, entity.AuditOrderReturn, orderReturn.ID, before, orderReturn)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &utils.Response{
+		Status:  200,
+		Message: "Return goods inspected",
+		Data:    toOrderReturnDataResponse(*orderReturn),
+	}, nil
+}
+
+func (s *orderReturnService) RefundOrderReturn(ctx context.Context, id uint) (*utils.Response, error) {
+	var orderReturn *entity.OrderReturn
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		orderReturn, err = s.OrderReturnRepository.FindById(ctx, id)
+		if err != nil {
+			return err
+		}
+		if orderReturn == nil {
+			return errors.New(errorMessages.ErrReturnNotFound)
+		}
+
+		// Locking the order serializes refunds of its returns against the payment.
+		order, err := s.OrderRepository.FindByIdForUpdate(ctx, orderReturn.OrderId)
+		if err != nil {
+			return err
+		}
+		if order == nil {
+			return errors.New(errorMessages.ErrOrderNotFound)
+		}
+
+		orderReturn, err = s.OrderReturnRepository.FindById(ctx, id)
+		if err != nil {
+			return err
+		}
+		if orderReturn.Status != status.RETURN_INSPECTED {
+			return errors.New(errorMessages.ErrInvalidReturnStatus)
+		}
+
+		payment, err := s.PaymentRepository.FindByOrderId(ctx, order.ID)
+		if err != nil {
+			return err
+		}
+		if payment == nil || payment.Status != status.PAYMENT_PAID {
+			return errors.New(errorMessages.ErrOrderNotPaid)
+		}
+
+		before := utils.AuditSnapshot(orderReturn)
+		var refundAmount uint
+		for _, item := range orderReturn.OrderReturnItems {
+			refundAmount += item.Subtotal
+		}
+
+		refunded, err := s.PaymentRepository.SumRefunds(ctx, payment.ID)
+		if err != nil {
+			return err
+		}
+		if refunded+refundAmount > payment.Amount {
+			return errors.New(errorMessages.ErrRefundExceedsPayment)
+		}
+
+		refund := &entity.Payment{
+			OrderId:  order.ID,
+			Method:   payment.Method,
+			Amount:   refundAmount,
+			Status:   status.PAYMENT_REFUNDED,
+			RefundOf: &payment.ID,
+		}
+		if err := s.PaymentRepository.CreatePayment(ctx, refund); err != nil {
+			return err
+		}
+
+		if err := s.Audit.Record(ctx, entity.AuditCreate, entity.AuditPayment, refund.ID, nil, refund); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		orderReturn.Status = status.RETURN_REFUNDED
+		orderReturn.RefundAmount = refundAmount
+		orderReturn.RefundedAt = &now
+
+		if err := s.OrderReturnRepository.UpdateOrderReturn(ctx, orderReturn); err != nil {
+			return err
+		}
+
+		return s.Audit.Record(ctx, entity.AuditUpdate, entity.AuditOrderReturn, orderReturn.ID, before, orderReturn)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &utils.Response{
+		Status:  200,
+		Message: "Return successfully refunded",
+		Data:    toOrderReturnDataResponse(*orderReturn),
+	}, nil
+}
+
+func toOrderReturnDataResponse(orderReturn entity.OrderReturn) entity.OrderReturnDataResponse {
+	data := entity.OrderReturnDataResponse{
+		ID:                      orderReturn.ID,
+		OrderId:                 orderReturn.OrderId,
+		UserId:                  orderReturn.UserId,
+		Reason:                  orderReturn.Reason,
+		Status:                  orderReturn.Status,
+		ReviewNote:              orderReturn.ReviewNote,
+		RefundAmount:            orderReturn.RefundAmount,
+		RequestedAt:             orderReturn.RequestedAt,
+		ReviewedAt:              orderReturn.ReviewedAt,
+		ReceivedAt:              orderReturn.ReceivedAt,
+		InspectedAt:             orderReturn.InspectedAt,
+		RefundedAt:              orderReturn.RefundedAt,
+		OrderReturnItemResponse: make([]entity.OrderReturnItemResponse, len(orderReturn.OrderReturnItems)),
+	}
+
+	for i, item := range orderReturn.OrderReturnItems {
+		data.OrderReturnItemResponse[i] = entity.OrderReturnItemResponse{
+			ProductId:     item.ProductId,
+			Qty:           item.Qty,
+			Subtotal:      item.Subtotal,
+			RestockedQty:  item.RestockedQty,
+			WrittenOffQty: item.WrittenOffQty,
+		}
+	}
+	return data
+}
@@ -0,0 +1,301 @@
+package service
+
+import (
+	"context"
+	"go-trades/entity"
+	"go-trades/repository"
+	errorMessages "go-trades/utils/error-messages"
+	status "go-trades/utils/status"
+	"testing"
+)
+
+// fakeOrderReturnRepository keeps returns in the order they were requested.
+type fakeOrderReturnRepository struct {
+	repository.OrderReturnRepository
+	returns []entity.OrderReturn
+}
+
+func copyOrderReturn(orderReturn entity.OrderReturn) entity.OrderReturn {
+	orderReturn.OrderReturnItems = append([]entity.OrderReturnItem(nil), orderReturn.OrderReturnItems...)
+	return orderReturn
+}
+
+func (r *fakeOrderReturnRepository) snapshot() func() {
+	returns := make([]entity.OrderReturn, len(r.returns))
+	for i, orderReturn := range r.returns {
+		returns[i] = copyOrderReturn(orderReturn)
+	}
+	return func() {
+		r.returns = returns
+	}
+}
+
+func (r *fakeOrderReturnRepository) FindById(ctx context.Context, id uint) (*entity.OrderReturn, error) {
+	for _, orderReturn := range r.returns {
+		if orderReturn.ID == id {
+			orderReturn = copyOrderReturn(orderReturn)
+			return &orderReturn, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeOrderReturnRepository) FindAllByOrderId(ctx context.Context, orderId uint) ([]entity.OrderReturn, error) {
+	var result []entity.OrderReturn
+	for _, orderReturn := range r.returns {
+		if orderReturn.OrderId == orderId {
+			result = append(result, copyOrderReturn(orderReturn))
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeOrderReturnRepository) CreateOrderReturn(ctx context.Context, orderReturn *entity.OrderReturn) error {
+	orderReturn.ID = uint(len(r.returns) + 1)
+	r.returns = append(r.returns, copyOrderReturn(*orderReturn))
+	return nil
+}
+
+func (r *fakeOrderReturnRepository) UpdateOrderReturn(ctx context.Context, orderReturn *entity.OrderReturn) error {
+	for i := range r.returns {
+		if r.returns[i].ID == orderReturn.ID {
+			r.returns[i] = copyOrderReturn(*orderReturn)
+		}
+	}
+	return nil
+}
+
+// fakePaymentRepository keeps payments and refunds alike.
+type fakePaymentRepository struct {
+	repository.PaymentRepository
+	payments []entity.Payment
+}
+
+func (r *fakePaymentRepository) snapshot() func() {
+	payments := append([]entity.Payment(nil), r.payments...)
+	return func() {
+		r.payments = payments
+	}
+}
+
+func (r *fakePaymentRepository) FindByOrderId(ctx context.Context, orderId uint) (*entity.Payment, error) {
+	for _, payment := range r.payments {
+		if payment.OrderId == orderId && payment.RefundOf == nil {
+			return &payment, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakePaymentRepository) SumRefunds(ctx context.Context, paymentId uint) (uint, error) {
+	var total uint
+	for _, payment := range r.payments {
+		if payment.RefundOf != nil && *payment.RefundOf == paymentId {
+			total += payment.Amount
+		}
+	}
+	return total, nil
+}
+
+func (r *fakePaymentRepository) CreatePayment(ctx context.Context, payment *entity.Payment) error {
+	payment.ID = uint(len(r.payments) + 1)
+	r.payments = append(r.payments, *payment)
+	return nil
+}
+
+type returnFixture struct {
+	orders   *fakeOrderRepository
+	returns  *fakeOrderReturnRepository
+	payments *fakePaymentRepository
+	audit    *fakeAuditRepository
+	svc      OrderReturnService
+}
+
+// newReturnFixture has user 1's order 1 in orderStatus, holding two of
+// product 1 at 100 and one of product 2 at 250, paid in full as payment 1.
+func newReturnFixture(orderStatus uint, returns ...entity.OrderReturn) *returnFixture {
+	f := &returnFixture{
+		orders: newFakeOrderRepository(entity.Order{
+			ID:     1,
+			UserId: 1,
+			Status: orderStatus,
+			Total:  450,
+			OrderDetails: []entity.OrderDetail{
+				{OrderId: 1, ProductId: 1, Qty: 2, Subtotal: 200},
+				{OrderId: 1, ProductId: 2, Qty: 1, Subtotal: 250},
+			},
+		}),
+		returns:  &fakeOrderReturnRepository{returns: returns},
+		payments: &fakePaymentRepository{payments: []entity.Payment{{ID: 1, OrderId: 1, Method: entity.Transfer, Amount: 450, Status: status.PAYMENT_PAID}}},
+		audit:    &fakeAuditRepository{},
+	}
+	txManager := &fakeTxManager{stores: []fakeStore{f.orders, f.returns, f.payments, f.audit}}
+	f.svc = NewOrderReturnService(txManager, f.returns, f.orders, nil, f.payments, NewAuditService(f.audit))
+	return f
+}
+
+func returnRequest(items ...entity.OrderReturnItemRequest) *entity.CreateOrderReturnRequest {
+	return &entity.CreateOrderReturnRequest{OrderId: 1, Reason: "damaged", OrderReturnItems: items}
+}
+
+func TestCreateOrderReturnFromReturnableOrder(t *testing.T) {
+	for _, orderStatus := range []uint{status.DELIVERED, status.DONE} {
+		f := newReturnFixture(orderStatus)
+
+		_, err := f.svc.CreateOrderReturn(context.Background(), 1, returnRequest(entity.OrderReturnItemRequest{ProductId: 1, Qty: 2}))
+		if err != nil {
+			t.Fatalf("status %d: %v", orderStatus, err)
+		}
+
+		if len(f.returns.returns) != 1 {
+			t.Fatalf("status %d: requested %d returns, want 1", orderStatus, len(f.returns.returns))
+		}
+		got := f.returns.returns[0]
+		if got.Status != status.RETURN_REQUESTED || got.OrderReturnItems[0].Subtotal != 200 {
+			t.Errorf("status %d: return = %+v, want a requested return worth 200", orderStatus, got)
+		}
+	}
+}
+
+func TestCreateOrderReturnCountsEarlierReturns(t *testing.T) {
+	earlier := []entity.OrderReturn{
+		{ID: 1, OrderId: 1, UserId: 1, Status: status.RETURN_APPROVED, OrderReturnItems: []entity.OrderReturnItem{{OrderReturnId: 1, ProductId: 1, Qty: 1}}},
+		{ID: 2, OrderId: 1, UserId: 1, Status: status.RETURN_REJECTED, OrderReturnItems: []entity.OrderReturnItem{{OrderReturnId: 2, ProductId: 2, Qty: 1}}},
+		// Claims more than was ordered, as racing requests could before the
+		// order was locked.
+		{ID: 3, OrderId: 1, UserId: 1, Status: status.RETURN_REQUESTED, OrderReturnItems: []entity.OrderReturnItem{{OrderReturnId: 3, ProductId: 1, Qty: 5}}},
+	}
+
+	tests := []struct {
+		name    string
+		returns []entity.OrderReturn
+		item    entity.OrderReturnItemRequest
+		err     string
+	}{
+		{"rest of a line", earlier[:1], entity.OrderReturnItemRequest{ProductId: 1, Qty: 1}, ""},
+		{"more than is left", earlier[:1], entity.OrderReturnItemRequest{ProductId: 1, Qty: 2}, errorMessages.ErrReturnQtyExceeded},
+		{"line of a rejected return", earlier[:2], entity.OrderReturnItemRequest{ProductId: 2, Qty: 1}, ""},
+		{"line returned in full", earlier, entity.OrderReturnItemRequest{ProductId: 1, Qty: 1}, errorMessages.ErrReturnQtyExceeded},
+	}
+
+	for _, tt := range tests {
+		f := newReturnFixture(status.DELIVERED, tt.returns...)
+
+		_, err := f.svc.CreateOrderReturn(context.Background(), 1, returnRequest(tt.item))
+		if tt.err == "" && err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if tt.err != "" && (err == nil || err.Error() != tt.err) {
+			t.Errorf("%s: error = %v, want %s", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestCreateOrderReturnRejected(t *testing.T) {
+	tests := []struct {
+		name        string
+		orderStatus uint
+		userId      uint
+		items       []entity.OrderReturnItemRequest
+		err         string
+	}{
+		{"undelivered order", status.SHIPPED, 1, []entity.OrderReturnItemRequest{{ProductId: 1, Qty: 1}}, errorMessages.ErrReturnOrderNotReturnable},
+		{"another user's order", status.DELIVERED, 2, []entity.OrderReturnItemRequest{{ProductId: 1, Qty: 1}}, errorMessages.ErrOrderNotFound},
+		{"product not ordered", status.DELIVERED, 1, []entity.OrderReturnItemRequest{{ProductId: 3, Qty: 1}}, errorMessages.ErrReturnProductNotInOrder},
+		{"duplicate product", status.DELIVERED, 1, []entity.OrderReturnItemRequest{{ProductId: 1, Qty: 1}, {ProductId: 1, Qty: 1}}, errorMessages.ErrReturnDuplicateProduct},
+		{"no items", status.DELIVERED, 1, nil, errorMessages.ErrReturnEmpty},
+	}
+
+	for _, tt := range tests {
+		f := newReturnFixture(tt.orderStatus)
+
+		_, err := f.svc.CreateOrderReturn(context.Background(), tt.userId, returnRequest(tt.items...))
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("%s: error = %v, want %s", tt.name, err, tt.err)
+		}
+		if len(f.returns.returns) != 0 || len(f.audit.entries) != 0 {
+			t.Errorf("%s: a rejected return was recorded", tt.name)
+		}
+	}
+}
+
+func inspectedReturn(id uint, productId, qty, subtotal uint) entity.OrderReturn {
+	return entity.OrderReturn{
+		ID:               id,
+		OrderId:          1,
+		UserId:           1,
+		Status:           status.RETURN_INSPECTED,
+		OrderReturnItems: []entity.OrderReturnItem{{OrderReturnId: id, ProductId: productId, Qty: qty, Subtotal: subtotal}},
+	}
+}
+
+func TestRefundOrderReturn(t *testing.T) {
+	f := newReturnFixture(status.DELIVERED, inspectedReturn(1, 1, 2, 200))
+
+	if _, err := f.svc.RefundOrderReturn(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	refund := f.payments.payments[len(f.payments.payments)-1]
+	if refund.RefundOf == nil || *refund.RefundOf != 1 || refund.Amount != 200 || refund.Status != status.PAYMENT_REFUNDED {
+		t.Errorf("refund = %+v, want 200 refunded against payment 1", refund)
+	}
+	if got := f.returns.returns[0]; got.Status != status.RETURN_REFUNDED || got.RefundAmount != 200 {
+		t.Errorf("return = %+v, want it refunded for 200", got)
+	}
+
+	_, err := f.svc.RefundOrderReturn(context.Background(), 1)
+	if err == nil || err.Error() != errorMessages.ErrInvalidReturnStatus {
+		t.Errorf("second refund error = %v, want %s", err, errorMessages.ErrInvalidReturnStatus)
+	}
+	if len(f.payments.payments) != 2 {
+		t.Errorf("recorded %d payments, want the payment and one refund", len(f.payments.payments))
+	}
+}
+
+func TestRefundOrderReturnRejected(t *testing.T) {
+	refundOf := uint(1)
+	tests := []struct {
+		name     string
+		returns  []entity.OrderReturn
+		payments []entity.Payment
+		err      string
+	}{
+		{
+			name:    "not inspected",
+			returns: []entity.OrderReturn{{ID: 1, OrderId: 1, UserId: 1, Status: status.RETURN_RECEIVED}},
+			err:     errorMessages.ErrInvalidReturnStatus,
+		},
+		{
+			name:     "unpaid order",
+			returns:  []entity.OrderReturn{inspectedReturn(1, 1, 2, 200)},
+			payments: []entity.Payment{},
+			err:      errorMessages.ErrOrderNotPaid,
+		},
+		{
+			name:    "refunds exceed the payment",
+			returns: []entity.OrderReturn{inspectedReturn(1, 2, 1, 250)},
+			payments: []entity.Payment{
+				{ID: 1, OrderId: 1, Amount: 450, Status: status.PAYMENT_PAID},
+				{ID: 2, OrderId: 1, Amount: 300, Status: status.PAYMENT_REFUNDED, RefundOf: &refundOf},
+			},
+			err: errorMessages.ErrRefundExceedsPayment,
+		},
+	}
+
+	for _, tt := range tests {
+		f := newReturnFixture(status.DELIVERED, tt.returns...)
+		if tt.payments != nil {
+			f.payments.payments = tt.payments
+		}
+		paymentCount := len(f.payments.payments)
+
+		_, err := f.svc.RefundOrderReturn(context.Background(), 1)
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("%s: error = %v, want %s", tt.name, err, tt.err)
+		}
+		if len(f.payments.payments) != paymentCount || f.returns.returns[0].Status == status.RETURN_REFUNDED {
+			t.Errorf("%s: a rejected refund was recorded", tt.name)
+		}
+	}
+}
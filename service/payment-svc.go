@@ -1,37 +1,41 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"go-trades/entity"
 	"go-trades/repository"
 	"go-trades/utils"
 	errorMessages "go-trades/utils/error-messages"
-
-	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
+	"time"
 )
 
 type paymentService struct {
-	db                *gorm.DB
+	txManager         utils.TxManager
 	PaymentRepository repository.PaymentRepository
 	OrderRepository   repository.OrderRepository
+	InvoiceRepository repository.InvoiceRepository
+	Audit             AuditService
 }
 
 type PaymentService interface {
-	GetAllPayments(ctx *gin.Context, page, size int) (*utils.Response, int64, int64, error)
-	CreatePayment(ctx *gin.Context, userId uint, req *entity.PaymentRequest) (*utils.Response, error)
-	GetUserPayments(ctx *gin.Context, userId uint, page, size int) (*utils.Response, int64, int64, error)
+	GetAllPayments(ctx context.Context, page, size int) (*utils.Response, int64, int64, error)
+	CreatePayment(ctx context.Context, userId uint, req *entity.PaymentRequest) (*utils.Response, error)
+	GetUserPayments(ctx context.Context, userId uint, page, size int) (*utils.Response, int64, int64, error)
 }
 
-func NewPaymentService(db *gorm.DB, pr repository.PaymentRepository, or repository.OrderRepository) PaymentService {
+func NewPaymentService(txManager utils.TxManager, pr repository.PaymentRepository, or repository.OrderRepository, ir repository.InvoiceRepository, audit AuditService) PaymentService {
 	return &paymentService{
-		db:                db,
+		txManager:         txManager,
 		PaymentRepository: pr,
 		OrderRepository:   or,
+		InvoiceRepository: ir,
+		Audit:             audit,
 	}
 }
 
-func (s *paymentService) GetAllPayments(ctx *gin.Context, page, size int) (*utils.Response, int64, int64, error) {
+func (s *paymentService) GetAllPayments(ctx context.Context, page, size int) (*utils.Response, int64, int64, error) {
 	payments, totalSize, err := s.PaymentRepository.FindAll(ctx, page, size)
 	if err != nil {
 		return nil, 0, 0, err
@@ -44,6 +48,7 @@ func (s *paymentService) GetAllPayments(ctx *gin.Context, page, size int) (*util
 			Method:    payment.Method,
 			Amount:    payment.Amount,
 			Status:    payment.Status,
+			RefundOf:  payment.RefundOf,
 			CreatedAt: payment.CreatedAt,
 		}
 	}
@@ -57,7 +62,7 @@ func (s *paymentService) GetAllPayments(ctx *gin.Context, page, size int) (*util
 	}, totalSize, totalPage, nil
 }
 
-func (s *paymentService) GetUserPayments(ctx *gin.Context, userId uint, page, size int) (*utils.Response, int64, int64, error) {
+func (s *paymentService) GetUserPayments(ctx context.Context, userId uint, page, size int) (*utils.Response, int64, int64, error) {
 	payments, totalSize, err := s.PaymentRepository.FindAllByUserId(ctx, userId, page, size)
 	if err != nil {
 		return nil, 0, 0, err
@@ -71,6 +76,7 @@ func (s *paymentService) GetUserPayments(ctx *gin.Context, userId uint, page, si
 			Method:    payment.Method,
 			Amount:    payment.Amount,
 			Status:    payment.Status,
+			RefundOf:  payment.RefundOf,
 			CreatedAt: payment.CreatedAt,
 		}
 	}
@@ -84,66 +90,80 @@ func (s *paymentService) GetUserPayments(ctx *gin.Context, userId uint, page, si
 	}, totalSize, totalPage, nil
 }
 
-func (s *paymentService) CreatePayment(ctx *gin.Context, userId uint, req *entity.PaymentRequest) (*utils.Response, error) {
+func (s *paymentService) CreatePayment(ctx context.Context, userId uint, req *entity.PaymentRequest) (*utils.Response, error) {
 
-	tx := s.db.Begin()
-	utils.WithTx(ctx, tx)
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-			panic(r)
-		} else if tx != nil {
-			tx.Rollback()
+	var payment *entity.Payment
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		order, err := s.OrderRepository.FindByUserIdWithId(ctx, userId, req.OrderId)
+		if err != nil {
+			return err
+		}
+		if order == nil {
+			return errors.New(errorMessages.ErrOrderNotFound)
 		}
-	}()
 
-	order, err := s.OrderRepository.FindByUserIdWithId(ctx, userId, req.OrderId)
-	if err != nil {
-		tx.Rollback()
-		return nil, err
-	}
-	if order == nil {
-		tx.Rollback()
-		return nil, errors.New(errorMessages.ErrOrderNotFound)
-	}
+		if order.Status != 1 {
+			return errors.New(errorMessages.ErrAlreadyPaid)
+		}
 
-	if order.Status != 1 {
-		tx.Rollback()
-		return nil, errors.New(errorMessages.ErrAlreadyPaid)
-	}
+		if order.Total != req.Amount {
+			return errors.New(errorMessages.ErrPaymentAmountInsufficient)
+		}
 
-	if order.Total != req.Amount {
-		tx.Rollback()
-		return nil, errors.New(errorMessages.ErrPaymentAmountInsufficient)
-	}
+		before := utils.AuditSnapshot(order)
+		order.Status = 2
+		if err := s.OrderRepository.UpdateOrder(ctx, order); err != nil {
+			return err
+		}
 
-	order.Status = 2
-	if err := s.OrderRepository.UpdateOrder(ctx, order); err != nil {
-		tx.Rollback()
-		return nil, err
-	}
+		if err := s.Audit.Record(ctx, entity.AuditUpdate, entity.AuditOrder, order.ID, before, order); err != nil {
+			return err
+		}
 
-	payment := &entity.Payment{
-		OrderId: req.OrderId,
-		Method:  req.Method,
-		Amount:  req.Amount,
-		Status:  2,
-	}
+		payment = &entity.Payment{
+			OrderId: req.OrderId,
+			Method:  req.Method,
+			Amount:  req.Amount,
+			Status:  2,
+		}
+
+		if err := s.PaymentRepository.CreatePayment(ctx, payment); err != nil {
+			return err
+		}
 
-	if err := s.PaymentRepository.CreatePayment(ctx, payment); err != nil {
-		tx.Rollback()
+		if err := s.Audit.Record(ctx, entity.AuditCreate, entity.AuditPayment, payment.ID, nil, payment); err != nil {
+			return err
+		}
+
+		sequence, err := s.InvoiceRepository.NextSequence(ctx)
+		if err != nil {
+			return err
+		}
+
+		invoice := &entity.Invoice{
+			OrderId:   order.ID,
+			PaymentId: payment.ID,
+			Sequence:  sequence,
+			Number:    fmt.Sprintf("INV-%06d", sequence),
+			IssuedAt:  time.Now(),
+		}
+		if err := s.InvoiceRepository.CreateInvoice(ctx, invoice); err != nil {
+			return err
+		}
+
+		return s.Audit.Record(ctx, entity.AuditCreate, entity.AuditInvoice, invoice.ID, nil, invoice)
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	tx.Commit()
-	tx = nil
-
 	data := entity.PaymentDataResponse{
 		ID:        payment.ID,
 		OrderId:   payment.OrderId,
 		Method:    payment.Method,
 		Amount:    payment.Amount,
 		Status:    payment.Status,
+		RefundOf:  payment.RefundOf,
 		CreatedAt: payment.CreatedAt,
 	}
 
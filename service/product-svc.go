@@ -1,38 +1,51 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"go-trades/entity"
 	"go-trades/repository"
 	"go-trades/utils"
+	"io"
+	"mime/multipart"
+	"strconv"
+	"strings"
+	"time"
 
 	errorMessages "go-trades/utils/error-messages"
 
-	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
 type productService struct {
+	txManager          utils.TxManager
 	ProductRepository  repository.ProductRepository
 	CategoryRepository repository.CategoryRepository
+	Audit              AuditService
 }
 
 type ProductService interface {
-	GetAllProducts(ctx *gin.Context, page, size int, categoryId uint) (*utils.Response, int64, int64, error)
-	GetProductById(ctx *gin.Context, id uint) (*utils.Response, error)
-	CreateProduct(ctx *gin.Context, req *entity.CreateProductRequest) (*utils.Response, error)
-	UpdateProduct(ctx *gin.Context, id uint, req *entity.UpdateProductRequest) (*utils.Response, error)
-	DeleteProduct(ctx *gin.Context, id uint) error
+	GetAllProducts(ctx context.Context, page, size int, categoryId uint) (*utils.Response, int64, int64, error)
+	GetProductById(ctx context.Context, id uint) (*utils.Response, error)
+	CreateProduct(ctx context.Context, req *entity.CreateProductRequest) (*utils.Response, error)
+	// UpdateProduct and DeleteProduct refuse to act when ifMatch is set and
+	// does not hold the product's current ETag.
+	UpdateProduct(ctx context.Context, id uint, req *entity.UpdateProductRequest, ifMatch string) (*utils.Response, error)
+	DeleteProduct(ctx context.Context, id uint, ifMatch string) error
+	ImportProducts(ctx context.Context, file *multipart.FileHeader, dryRun bool) (*utils.Response, error)
+	ExportProducts(ctx context.Context, format string, w io.Writer) error
 }
 
-func NewProductService(pr repository.ProductRepository, cr repository.CategoryRepository) ProductService {
+func NewProductService(txManager utils.TxManager, pr repository.ProductRepository, cr repository.CategoryRepository, audit AuditService) ProductService {
 	return &productService{
+		txManager:          txManager,
 		ProductRepository:  pr,
 		CategoryRepository: cr,
+		Audit:              audit,
 	}
 }
 
-func (s *productService) GetAllProducts(ctx *gin.Context, page, size int, categoryId uint) (*utils.Response, int64, int64, error) {
+func (s *productService) GetAllProducts(ctx context.Context, page, size int, categoryId uint) (*utils.Response, int64, int64, error) {
 
 	var data []entity.ProductDataResponse
 	var totalSize int64
@@ -60,7 +73,7 @@ func (s *productService) GetAllProducts(ctx *gin.Context, page, size int, catego
 	}, totalSize, totalPage, nil
 }
 
-func (s *productService) GetProductById(ctx *gin.Context, id uint) (*utils.Response, error) {
+func (s *productService) GetProductById(ctx context.Context, id uint) (*utils.Response, error) {
 	data, err := s.ProductRepository.FindByIdWithStock(ctx, id)
 	if err != nil {
 		return nil, err
@@ -77,7 +90,7 @@ func (s *productService) GetProductById(ctx *gin.Context, id uint) (*utils.Respo
 	}, nil
 }
 
-func (s *productService) CreateProduct(ctx *gin.Context, req *entity.CreateProductRequest) (*utils.Response, error) {
+func (s *productService) CreateProduct(ctx context.Context, req *entity.CreateProductRequest) (*utils.Response, error) {
 
 	category, err := s.CategoryRepository.FindById(ctx, req.CategoryId)
 	if err != nil {
@@ -95,25 +108,52 @@ func (s *productService) CreateProduct(ctx *gin.Context, req *entity.CreateProdu
 		return nil, err
 	}
 
+	sku := productSku(req.Sku)
+	if err := s.checkSkuFree(ctx, sku, 0); err != nil {
+		return nil, err
+	}
+
+	if req.BackorderPolicy == "" {
+		req.BackorderPolicy = entity.NoBackorder
+	}
+	availableAt, err := parseBackorderSettings(req.BackorderPolicy, req.AvailableAt)
+	if err != nil {
+		return nil, err
+	}
+
 	product := &entity.Product{
-		CategoryId:  req.CategoryId,
-		Name:        req.Name,
-		Description: req.Description,
-		Price:       req.Price,
+		CategoryId:      req.CategoryId,
+		Name:            req.Name,
+		Sku:             sku,
+		Description:     req.Description,
+		Price:           req.Price,
+		BackorderPolicy: req.BackorderPolicy,
+		AvailableAt:     availableAt,
 	}
 
-	if err := s.ProductRepository.CreateProduct(ctx, product); err != nil {
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.ProductRepository.CreateProduct(ctx, product); err != nil {
+			return err
+		}
+
+		return s.Audit.Record(ctx, entity.AuditCreate, entity.AuditProduct, product.ID, nil, product)
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	data := entity.ProductDataResponse{
-		ID:          product.ID,
-		CategoryId:  product.CategoryId,
-		Name:        product.Name,
-		Description: product.Description,
-		Price:       product.Price,
-		CreatedAt:   product.CreatedAt,
-		UpdatedAt:   product.UpdatedAt,
+		ID:              product.ID,
+		CategoryId:      product.CategoryId,
+		Name:            product.Name,
+		Sku:             product.Sku,
+		Description:     product.Description,
+		Price:           product.Price,
+		BackorderPolicy: product.BackorderPolicy,
+		AvailableAt:     product.AvailableAt,
+		Version:         product.Version,
+		CreatedAt:       product.CreatedAt,
+		UpdatedAt:       product.UpdatedAt,
 	}
 
 	return &utils.Response{
@@ -123,7 +163,7 @@ func (s *productService) CreateProduct(ctx *gin.Context, req *entity.CreateProdu
 	}, nil
 }
 
-func (s *productService) UpdateProduct(ctx *gin.Context, id uint, req *entity.UpdateProductRequest) (*utils.Response, error) {
+func (s *productService) UpdateProduct(ctx context.Context, id uint, req *entity.UpdateProductRequest, ifMatch string) (*utils.Response, error) {
 
 	product, err := s.ProductRepository.FindById(ctx, id)
 	if err != nil {
@@ -134,6 +174,10 @@ func (s *productService) UpdateProduct(ctx *gin.Context, id uint, req *entity.Up
 		return nil, errors.New(errorMessages.ErrProductNotFound)
 	}
 
+	if err := s.checkIfMatch(ctx, id, ifMatch); err != nil {
+		return nil, err
+	}
+
 	category, err := s.CategoryRepository.FindById(ctx, req.CategoryId)
 	if err != nil {
 		return nil, err
@@ -150,23 +194,42 @@ func (s *productService) UpdateProduct(ctx *gin.Context, id uint, req *entity.Up
 		return nil, errors.New(errorMessages.ErrProductNameExists)
 	}
 
+	sku := productSku(req.Sku)
+	if err := s.checkSkuFree(ctx, sku, id); err != nil {
+		return nil, err
+	}
+
+	if req.BackorderPolicy == "" {
+		req.BackorderPolicy = product.BackorderPolicy
+	}
+	availableAt, err := parseBackorderSettings(req.BackorderPolicy, req.AvailableAt)
+	if err != nil {
+		return nil, err
+	}
+
+	before := utils.AuditSnapshot(product)
 	product.CategoryId = req.CategoryId
 	product.Name = req.Name
+	product.Sku = sku
 	product.Description = req.Description
 	product.Price = req.Price
+	product.BackorderPolicy = req.BackorderPolicy
+	product.AvailableAt = availableAt
 
-	if err := s.ProductRepository.UpdateProduct(ctx, product); err != nil {
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.ProductRepository.UpdateProduct(ctx, product); err != nil {
+			return err
+		}
+
+		return s.Audit.Record(ctx, entity.AuditUpdate, entity.AuditProduct, product.ID, before, product)
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	data := entity.ProductDataResponse{
-		ID:          product.ID,
-		CategoryId:  product.CategoryId,
-		Name:        product.Name,
-		Description: product.Description,
-		Price:       product.Price,
-		CreatedAt:   product.CreatedAt,
-		UpdatedAt:   product.UpdatedAt,
+	data, err := s.ProductRepository.FindByIdWithStock(ctx, product.ID)
+	if err != nil || data == nil {
+		return nil, errors.New("error loading product data")
 	}
 
 	return &utils.Response{
@@ -176,10 +239,394 @@ func (s *productService) UpdateProduct(ctx *gin.Context, id uint, req *entity.Up
 	}, nil
 }
 
-func (s *productService) DeleteProduct(ctx *gin.Context, id uint) error {
-	if err := s.ProductRepository.DeleteProduct(ctx, id); err != nil {
+func (s *productService) DeleteProduct(ctx context.Context, id uint, ifMatch string) error {
+	product, err := s.ProductRepository.FindById(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if product == nil {
+		return errors.New(errorMessages.ErrProductNotFound)
+	}
+
+	if err := s.checkIfMatch(ctx, id, ifMatch); err != nil {
+		return err
+	}
+
+	return s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.ProductRepository.DeleteProduct(ctx, product); err != nil {
+			return err
+		}
+
+		return s.Audit.Record(ctx, entity.AuditDelete, entity.AuditProduct, product.ID, product, nil)
+	})
+}
+
+// ProductETag tags a product as GET /products/:id shows it. Its stock is
+// summed from the inventories, which change without bumping the product's
+// version, so the stock is part of the tag.
+func ProductETag(data *entity.ProductDataResponse) string {
+	return utils.ETag(data.Version, data.Stock)
+}
+
+// checkIfMatch compares an If-Match header with the product's current tag.
+func (s *productService) checkIfMatch(ctx context.Context, id uint, ifMatch string) error {
+	if ifMatch == "" {
+		return nil
+	}
+
+	data, err := s.ProductRepository.FindByIdWithStock(ctx, id)
+	if err != nil {
 		return err
 	}
+	if data == nil {
+		return errors.New(errorMessages.ErrProductNotFound)
+	}
+
+	if !utils.ETagMatches(ifMatch, ProductETag(data), false) {
+		return errors.New(errorMessages.ErrPreconditionFailed)
+	}
+	return nil
+}
+
+// productSku stores a blank SKU as NULL, so products without one do not
+// collide on the unique index.
+func productSku(sku string) *string {
+	sku = strings.TrimSpace(sku)
+	if sku == "" {
+		return nil
+	}
+	return &sku
+}
+
+// checkSkuFree fails when sku belongs to a product other than id.
+func (s *productService) checkSkuFree(ctx context.Context, sku *string, id uint) error {
+	if sku == nil {
+		return nil
+	}
 
+	existing, err := s.ProductRepository.FindBySku(ctx, *sku)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.ID != id {
+		return errors.New(errorMessages.ErrProductSkuExists)
+	}
 	return nil
 }
+
+// productColumns is the column layout shared by product exports and imports,
+// so an exported file can be edited and imported back.
+var productColumns = []string{"name", "sku", "categoryCode", "description", "price", "backorderPolicy", "availableAt"}
+
+// ImportProducts upserts products by SKU or, for rows without one, by name
+// from a CSV or XLSX file. Nothing is written unless every row is valid; a
+// dry run only returns the diff. A column left out of the file keeps its
+// current value on updated products.
+func (s *productService) ImportProducts(ctx context.Context, file *multipart.FileHeader, dryRun bool) (*utils.Response, error) {
+	records, err := readTableFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := newTableColumns(records[0])
+	if !columns.has("sku") {
+		if err := columns.require("name"); err != nil {
+			return nil, err
+		}
+	}
+
+	preview := entity.ImportPreviewResponse{DryRun: dryRun}
+	var products []*entity.Product
+	categories := make(map[string]*entity.Category)
+	seen := make(map[string]bool)
+
+	for i, record := range records[1:] {
+		if isBlankRecord(record) {
+			continue
+		}
+
+		name := columns.get(record, "name")
+		sku := columns.get(record, "sku")
+		diff := entity.ImportDiffRow{Row: i + 2, Key: name}
+		if sku != "" {
+			diff.Key = sku
+		}
+		if (name != "" && seen["name:"+name]) || (sku != "" && seen["sku:"+sku]) {
+			diff.Action = entity.ImportFailed
+			diff.Error = errorMessages.ErrImportDuplicateKey
+			addImportDiff(&preview, diff)
+			continue
+		}
+		seen["name:"+name] = true
+		seen["sku:"+sku] = true
+
+		product, before, after, err := s.planProductImport(ctx, columns, record, categories)
+		if err != nil {
+			diff.Action = entity.ImportFailed
+			diff.Error = err.Error()
+			addImportDiff(&preview, diff)
+			continue
+		}
+
+		diff.Action, diff.Changes = diffImportFields(before, after)
+		if diff.Action != entity.ImportUnchanged {
+			products = append(products, product)
+		}
+		addImportDiff(&preview, diff)
+	}
+
+	if preview.Failed > 0 {
+		return &utils.Response{
+			Status:  422,
+			Message: errorMessages.ErrImportInvalidRows,
+			Data:    preview,
+		}, nil
+	}
+	if dryRun {
+		return &utils.Response{
+			Status:  200,
+			Message: "Import preview",
+			Data:    preview,
+		}, nil
+	}
+
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		for _, product := range products {
+			action := entity.AuditCreate
+			var before *entity.Product
+			if product.ID == 0 {
+				err = s.ProductRepository.CreateProduct(ctx, product)
+			} else {
+				action = entity.AuditUpdate
+				// The planned product was changed in place; the stored row
+				// still holds the old values.
+				before, err = s.ProductRepository.FindById(ctx, product.ID)
+				if err == nil {
+					err = s.ProductRepository.UpdateProduct(ctx, product)
+				}
+			}
+			if err == nil {
+				err = s.Audit.Record(ctx, action, entity.AuditProduct, product.ID, before, product)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &utils.Response{
+		Status:  200,
+		Message: "Products successfully imported",
+		Data:    preview,
+	}, nil
+}
+
+// planProductImport works out the product a row describes without saving
+// it, returning the exported fields before and after the change.
+func (s *productService) planProductImport(ctx context.Context, columns tableColumns, record []string, categories map[string]*entity.Category) (*entity.Product, map[string]string, map[string]string, error) {
+	name := columns.get(record, "name")
+	sku := columns.get(record, "sku")
+	if name == "" && sku == "" {
+		return nil, nil, nil, errors.New(errorMessages.ErrImportProductKeyRequired)
+	}
+
+	// A SKU finds the product even when the row renames it. Rows without a
+	// match by SKU fall back to the name, which also gives existing products
+	// their SKU.
+	var existing *entity.Product
+	var err error
+	if sku != "" {
+		existing, err = s.ProductRepository.FindBySku(ctx, sku)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	if existing == nil && name != "" {
+		existing, err = s.ProductRepository.FindByName(ctx, name)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if existing != nil && sku != "" && existing.Sku != nil {
+			return nil, nil, nil, errors.New(errorMessages.ErrProductNameExists)
+		}
+	}
+	if existing == nil && name == "" {
+		return nil, nil, nil, errors.New(errorMessages.ErrImportNameRequired)
+	}
+
+	product := &entity.Product{Name: name, BackorderPolicy: entity.NoBackorder}
+	var before map[string]string
+	categoryCode := ""
+	if existing != nil {
+		category, err := s.CategoryRepository.FindById(ctx, existing.CategoryId)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if category != nil {
+			categoryCode = category.Code
+		}
+
+		before = productExportFields(*existing, categoryCode)
+		product = existing
+	}
+
+	if name != "" && name != product.Name {
+		other, err := s.ProductRepository.FindByName(ctx, name)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if other != nil {
+			return nil, nil, nil, errors.New(errorMessages.ErrProductNameExists)
+		}
+		product.Name = name
+	}
+	if sku != "" {
+		product.Sku = &sku
+	}
+
+	if columns.has("categoryCode") {
+		categoryCode = columns.get(record, "categoryCode")
+		category, ok := categories[categoryCode]
+		if !ok {
+			category, err = s.CategoryRepository.FindByCode(ctx, categoryCode)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			categories[categoryCode] = category
+		}
+		if category == nil {
+			return nil, nil, nil, errors.New(errorMessages.ErrCategoryNotFound)
+		}
+		product.CategoryId = category.ID
+	}
+	if product.CategoryId == 0 {
+		return nil, nil, nil, errors.New(errorMessages.ErrCategoryNotFound)
+	}
+
+	if columns.has("description") {
+		product.Description = columns.get(record, "description")
+	}
+
+	if columns.has("price") {
+		price, err := strconv.ParseUint(columns.get(record, "price"), 10, 0)
+		if err != nil {
+			return nil, nil, nil, errors.New(errorMessages.ErrImportInvalidPrice)
+		}
+		product.Price = uint(price)
+	}
+	if product.Price == 0 {
+		return nil, nil, nil, errors.New(errorMessages.ErrImportInvalidPrice)
+	}
+
+	if columns.has("backorderPolicy") {
+		product.BackorderPolicy = entity.BackorderPolicy(columns.get(record, "backorderPolicy"))
+		if product.BackorderPolicy == "" {
+			product.BackorderPolicy = entity.NoBackorder
+		}
+	}
+
+	availableAt := ""
+	if product.AvailableAt != nil {
+		availableAt = product.AvailableAt.Format("2006-01-02")
+	}
+	if columns.has("availableAt") {
+		availableAt, err = parseImportDate(columns.get(record, "availableAt"))
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	product.AvailableAt, err = parseBackorderSettings(product.BackorderPolicy, availableAt)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return product, before, productExportFields(*product, categoryCode), nil
+}
+
+// ExportProducts streams every product to w in the import column layout.
+func (s *productService) ExportProducts(ctx context.Context, format string, w io.Writer) error {
+	table, err := utils.NewTableWriter(format, w, "Products")
+	if err != nil {
+		return err
+	}
+	if err := table.WriteRow(productColumns); err != nil {
+		return err
+	}
+
+	categoryCodes := make(map[uint]string)
+	err = s.ProductRepository.FindInBatches(ctx, exportBatchSize, func(products []entity.Product) error {
+		for _, product := range products {
+			code, ok := categoryCodes[product.CategoryId]
+			if !ok {
+				category, err := s.CategoryRepository.FindById(ctx, product.CategoryId)
+				if err != nil {
+					return err
+				}
+				if category != nil {
+					code = category.Code
+				}
+				categoryCodes[product.CategoryId] = code
+			}
+
+			fields := productExportFields(product, code)
+			row := make([]string, len(productColumns))
+			for i, column := range productColumns {
+				row[i] = fields[column]
+			}
+			if err := table.WriteRow(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return table.Close()
+}
+
+func productExportFields(product entity.Product, categoryCode string) map[string]string {
+	availableAt := ""
+	if product.AvailableAt != nil {
+		availableAt = product.AvailableAt.Format("2006-01-02")
+	}
+
+	sku := ""
+	if product.Sku != nil {
+		sku = *product.Sku
+	}
+
+	return map[string]string{
+		"name":            product.Name,
+		"sku":             sku,
+		"categoryCode":    categoryCode,
+		"description":     product.Description,
+		"price":           strconv.FormatUint(uint64(product.Price), 10),
+		"backorderPolicy": string(product.BackorderPolicy),
+		"availableAt":     availableAt,
+	}
+}
+
+func parseBackorderSettings(policy entity.BackorderPolicy, availableAt string) (*time.Time, error) {
+	switch policy {
+	case entity.NoBackorder, entity.Backorder, entity.Preorder:
+	default:
+		return nil, errors.New(errorMessages.ErrInvalidBackorderPolicy)
+	}
+
+	if availableAt == "" {
+		return nil, nil
+	}
+
+	date, err := time.Parse("2006-01-02", availableAt)
+	if err != nil {
+		return nil, errors.New("invalid date format")
+	}
+	return &date, nil
+}
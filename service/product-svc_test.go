@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"go-trades/entity"
+	errorMessages "go-trades/utils/error-messages"
+	"testing"
+)
+
+// fakeStockedProductRepository adds the stock summed from the inventories to
+// fakeProductRepository.
+type fakeStockedProductRepository struct {
+	*fakeProductRepository
+	stock map[uint]uint
+}
+
+func (r *fakeStockedProductRepository) FindByIdWithStock(ctx context.Context, id uint) (*entity.ProductDataResponse, error) {
+	product, ok := r.products[id]
+	if !ok {
+		return nil, nil
+	}
+	return &entity.ProductDataResponse{ID: product.ID, Name: product.Name, Price: product.Price, Stock: r.stock[id], Version: product.Version}, nil
+}
+
+func (r *fakeStockedProductRepository) DeleteProduct(ctx context.Context, product *entity.Product) error {
+	delete(r.products, product.ID)
+	return nil
+}
+
+func TestDeleteProductIfMatchCoversStock(t *testing.T) {
+	product := testProduct(1, 100, entity.NoBackorder)
+	product.Version = 3
+	products := &fakeStockedProductRepository{fakeProductRepository: newFakeProductRepository(product), stock: map[uint]uint{1: 12}}
+	svc := NewProductService(&fakeTxManager{}, products, nil, NewAuditService(&fakeAuditRepository{}))
+
+	data, _ := products.FindByIdWithStock(context.Background(), 1)
+	etag := ProductETag(data)
+	if etag != `"3-12"` {
+		t.Fatalf("ETag = %s, want \"3-12\"", etag)
+	}
+
+	// An order takes stock without touching the product row.
+	products.stock[1] = 10
+	err := svc.DeleteProduct(context.Background(), 1, etag)
+	if err == nil || err.Error() != errorMessages.ErrPreconditionFailed {
+		t.Errorf("stale tag: error = %v, want %s", err, errorMessages.ErrPreconditionFailed)
+	}
+
+	if err := svc.DeleteProduct(context.Background(), 1, `"3-10"`); err != nil {
+		t.Fatalf("current tag: %v", err)
+	}
+	if _, ok := products.products[1]; ok {
+		t.Error("product was not deleted")
+	}
+}
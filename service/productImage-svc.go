@@ -2,6 +2,7 @@ package service
 
 import (
 	"archive/zip"
+	"context"
 	"errors"
 	"go-trades/entity"
 	"go-trades/repository"
@@ -12,9 +13,7 @@ import (
 	"path/filepath"
 	"time"
 
-	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"gorm.io/gorm"
 )
 
 const (
@@ -23,25 +22,27 @@ const (
 )
 
 type productImageService struct {
-	DB                     *gorm.DB
+	txManager              utils.TxManager
 	ProductImageRepository repository.ProductImageRepository
 	ProductRepository      repository.ProductRepository
+	Audit                  AuditService
 }
 
 type ProductImageService interface {
-	UploadProductImage(ctx *gin.Context, productId uint, image *multipart.FileHeader) error
-	DownloadProductImages(ctx *gin.Context, productId uint) (string, error)
+	UploadProductImage(ctx context.Context, productId uint, image *multipart.FileHeader) error
+	DownloadProductImages(ctx context.Context, productId uint) (string, error)
 }
 
-func NewProductImageService(db *gorm.DB, pir repository.ProductImageRepository, pr repository.ProductRepository) ProductImageService {
+func NewProductImageService(txManager utils.TxManager, pir repository.ProductImageRepository, pr repository.ProductRepository, audit AuditService) ProductImageService {
 	return &productImageService{
 		ProductImageRepository: pir,
 		ProductRepository:      pr,
-		DB:                     db,
+		txManager:              txManager,
+		Audit:                  audit,
 	}
 }
 
-func (s *productImageService) UploadProductImage(ctx *gin.Context, productId uint, image *multipart.FileHeader) error {
+func (s *productImageService) UploadProductImage(ctx context.Context, productId uint, image *multipart.FileHeader) error {
 
 	if image.Size > maxFileSize {
 		return errors.New("maximum file size is 2MB")
@@ -59,42 +60,28 @@ func (s *productImageService) UploadProductImage(ctx *gin.Context, productId uin
 		return errors.New("product not found")
 	}
 
-	tx := s.DB.Begin()
-	utils.WithTx(ctx, tx)
-
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-			panic(r)
-		} else if tx != nil {
-			tx.Rollback()
+	return s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		imagePath := filepath.Join(uploadDirectory, image.Filename)
+		if err := utils.SaveUploadedFile(image, imagePath); err != nil {
+			return errors.New("error uploading file")
 		}
-	}()
-
-	imagePath := filepath.Join(uploadDirectory, image.Filename)
-	if err := ctx.SaveUploadedFile(image, imagePath); err != nil {
-		return errors.New("error uploading file")
-	}
-
-	productImage := &entity.ProductImage{
-		ProductId:  productId,
-		ImageUrl:   imagePath,
-		FileName:   image.Filename,
-		UploadedAt: time.Now(),
-	}
 
-	if err := s.ProductImageRepository.CreateProductImage(ctx, productImage); err != nil {
-		tx.Rollback()
-		return err
-	}
+		productImage := &entity.ProductImage{
+			ProductId:  productId,
+			ImageUrl:   imagePath,
+			FileName:   image.Filename,
+			UploadedAt: time.Now(),
+		}
 
-	tx.Commit()
-	tx = nil
+		if err := s.ProductImageRepository.CreateProductImage(ctx, productImage); err != nil {
+			return err
+		}
 
-	return nil
+		return s.Audit.Record(ctx, entity.AuditCreate, entity.AuditProductImage, productImage.ID, nil, productImage)
+	})
 }
 
-func (s *productImageService) DownloadProductImages(ctx *gin.Context, productId uint) (string, error) {
+func (s *productImageService) DownloadProductImages(ctx context.Context, productId uint) (string, error) {
 	product, err := s.ProductRepository.FindById(ctx, productId)
 	if err != nil {
 		return "", err
@@ -0,0 +1,390 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"go-trades/entity"
+	"go-trades/repository"
+	"go-trades/utils"
+	errorMessages "go-trades/utils/error-messages"
+	status "go-trades/utils/status"
+	"time"
+)
+
+const defaultQuoteValidity = 30 * 24 * time.Hour
+
+type quoteService struct {
+	txManager         utils.TxManager
+	QuoteRepository   repository.QuoteRepository
+	ProductRepository repository.ProductRepository
+	OrderService      OrderService
+	Audit             AuditService
+}
+
+type QuoteService interface {
+	GetAllQuotes(ctx context.Context, page, size int) (*utils.Response, int64, int64, error)
+	GetQuoteById(ctx context.Context, id uint) (*utils.Response, error)
+	GetUserQuotes(ctx context.Context, userId uint, page, size int) (*utils.Response, int64, int64, error)
+	GetUserQuoteById(ctx context.Context, userId, id uint) (*utils.Response, error)
+	CreateQuote(ctx context.Context, userId uint, req *entity.CreateQuoteRequest) (*utils.Response, error)
+	AdjustQuote(ctx context.Context, id uint, req *entity.AdjustQuoteRequest) (*utils.Response, error)
+	SendQuote(ctx context.Context, id uint, req *entity.SendQuoteRequest) (*utils.Response, error)
+	AcceptQuote(ctx context.Context, userId, id uint, req *entity.AcceptQuoteRequest) (*utils.Response, error)
+	ExpireQuotes(ctx context.Context) error
+}
+
+func NewQuoteService(txManager utils.TxManager, qr repository.QuoteRepository, pr repository.ProductRepository, os OrderService, audit AuditService) QuoteService {
+	return &quoteService{
+		txManager:         txManager,
+		QuoteRepository:   qr,
+		ProductRepository: pr,
+		OrderService:      os,
+		Audit:             audit,
+	}
+}
+
+func (s *quoteService) GetAllQuotes(ctx context.Context, page, size int) (*utils.Response, int64, int64, error) {
+	quotes, totalSize, err := s.QuoteRepository.FindAll(ctx, page, size)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	data := make([]entity.QuoteDataResponse, len(quotes))
+	for i, quote := range quotes {
+		data[i] = toQuoteDataResponse(quote)
+	}
+
+	totalPage := utils.GetTotalPage(totalSize, size)
+
+	return &utils.Response{
+		Status:  200,
+		Message: "Success",
+		Data:    data,
+	}, totalSize, totalPage, nil
+}
+
+func (s *quoteService) GetQuoteById(ctx context.Context, id uint) (*utils.Response, error) {
+	quote, err := s.QuoteRepository.FindById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if quote == nil {
+		return nil, errors.New(errorMessages.ErrQuoteNotFound)
+	}
+
+	return &utils.Response{
+		Status:  200,
+		Message: "Success",
+		Data:    toQuoteDataResponse(*quote),
+	}, nil
+}
+
+func (s *quoteService) GetUserQuotes(ctx context.Context, userId uint, page, size int) (*utils.Response, int64, int64, error) {
+	quotes, totalSize, err := s.QuoteRepository.FindAllByUserId(ctx, userId, page, size)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	data := make([]entity.QuoteDataResponse, len(quotes))
+	for i, quote := range quotes {
+		data[i] = toQuoteDataResponse(quote)
+	}
+
+	totalPage := utils.GetTotalPage(totalSize, size)
+
+	return &utils.Response{
+		Status:  200,
+		Message: "Success",
+		Data:    data,
+	}, totalSize, totalPage, nil
+}
+
+func (s *quoteService) GetUserQuoteById(ctx context.Context, userId, id uint) (*utils.Response, error) {
+	quote, err := s.QuoteRepository.FindByUserIdWithId(ctx, userId, id)
+	if err != nil {
+		return nil, err
+	}
+	if quote == nil {
+		return nil, errors.New(errorMessages.ErrQuoteNotFound)
+	}
+
+	return &utils.Response{
+		Status:  200,
+		Message: "Success",
+		Data:    toQuoteDataResponse(*quote),
+	}, nil
+}
+
+// CreateQuote prices every line at the current catalogue price. Stock is not
+// checked or reserved here; that only happens once the quote is accepted.
+func (s *quoteService) CreateQuote(ctx context.Context, userId uint, req *entity.CreateQuoteRequest) (*utils.Response, error) {
+	if len(req.QuoteItems) == 0 {
+		return nil, errors.New(errorMessages.ErrOrderEmpty)
+	}
+
+	var total uint
+	var items []entity.QuoteItem
+	productIds := make(map[uint]bool)
+	for _, item := range req.QuoteItems {
+		if productIds[item.ProductId] {
+			return nil, errors.New(errorMessages.ErrOrderDuplicateProduct)
+		}
+		productIds[item.ProductId] = true
+
+		product, err := s.ProductRepository.FindById(ctx, item.ProductId)
+		if err != nil {
+			return nil, err
+		}
+		if product == nil {
+			return nil, errors.New(errorMessages.ErrProductNotFound)
+		}
+
+		items = append(items, entity.QuoteItem{
+			ProductId: item.ProductId,
+			Qty:       item.Qty,
+			Price:     product.Price,
+			Subtotal:  product.Price * item.Qty,
+		})
+		total += product.Price * item.Qty
+	}
+
+	quote := entity.Quote{
+		UserId:     userId,
+		Status:     status.QUOTE_DRAFT,
+		Note:       req.Note,
+		ValidUntil: time.Now().Add(defaultQuoteValidity),
+		Total:      total,
+		QuoteItems: items,
+	}
+
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.QuoteRepository.CreateQuote(ctx, &quote); err != nil {
+			return err
+		}
+
+		return s.Audit.Record(ctx, entity.AuditCreate, entity.AuditQuote, quote.ID, nil, quote)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &utils.Response{
+		Status:  201,
+		Message: "Quote successfully created",
+		Data:    toQuoteDataResponse(quote),
+	}, nil
+}
+
+func (s *quoteService) AdjustQuote(ctx context.Context, id uint, req *entity.AdjustQuoteRequest) (*utils.Response, error) {
+	var quote *entity.Quote
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		quote, err = s.QuoteRepository.FindById(ctx, id)
+		if err != nil {
+			return err
+		}
+		if quote == nil {
+			return errors.New(errorMessages.ErrQuoteNotFound)
+		}
+
+		// Prices are only negotiable until the quote goes out to the customer.
+		if quote.Status != status.QUOTE_DRAFT {
+			return errors.New(errorMessages.ErrInvalidQuoteStatus)
+		}
+		before := utils.AuditSnapshot(quote)
+
+		for _, adjustment := range req.QuoteItems {
+			var item *entity.QuoteItem
+			for i := range quote.QuoteItems {
+				if quote.QuoteItems[i].ProductId == adjustment.ProductId {
+					item = &quote.QuoteItems[i]
+					break
+				}
+			}
+			if item == nil {
+				return errors.New(errorMessages.ErrQuoteProductNotFound)
+			}
+
+			item.Price = adjustment.Price
+			item.Subtotal = adjustment.Price * item.Qty
+			if err := s.QuoteRepository.UpdateQuoteItem(ctx, item); err != nil {
+				return err
+			}
+		}
+
+		quote.Total = 0
+		for _, item := range quote.QuoteItems {
+			quote.Total += item.Subtotal
+		}
+
+		if err := s.QuoteRepository.UpdateQuote(ctx, quote); err != nil {
+			return err
+		}
+
+		return s.Audit.Record(ctx, entity.AuditUpdate, entity.AuditQuote, quote.ID, before, quote)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &utils.Response{
+		Status:  200,
+		Message: "Quote successfully updated",
+		Data:    toQuoteDataResponse(*quote),
+	}, nil
+}
+
+func (s *quoteService) SendQuote(ctx context.Context, id uint, req *entity.SendQuoteRequest) (*utils.Response, error) {
+	quote, err := s.QuoteRepository.FindById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if quote == nil {
+		return nil, errors.New(errorMessages.ErrQuoteNotFound)
+	}
+
+	if quote.Status != status.QUOTE_DRAFT {
+		return nil, errors.New(errorMessages.ErrInvalidQuoteStatus)
+	}
+
+	// The validity period starts when the customer receives the quote. An
+	// explicit date is valid through the end of that day.
+	validUntil := time.Now().Add(defaultQuoteValidity)
+	if req.ValidUntil != "" {
+		date, err := time.ParseInLocation("2006-01-02", req.ValidUntil, time.Local)
+		if err != nil {
+			return nil, errors.New("invalid date format")
+		}
+		validUntil = date.AddDate(0, 0, 1).Add(-time.Second)
+		if validUntil.Before(time.Now()) {
+			return nil, errors.New(errorMessages.ErrQuoteExpired)
+		}
+	}
+
+	before := utils.AuditSnapshot(quote)
+	quote.Status = status.QUOTE_SENT
+	quote.ValidUntil = validUntil
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.QuoteRepository.UpdateQuote(ctx, quote); err != nil {
+			return err
+		}
+
+		return s.Audit.Record(ctx, entity.AuditUpdate, entity.AuditQuote, quote.ID, before, quote)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &utils.Response{
+		Status:  200,
+		Message: "Quote sent",
+		Data:    toQuoteDataResponse(*quote),
+	}, nil
+}
+
+// AcceptQuote turns a sent quote into an order through the regular order
+// path, with every line fixed at its quoted price.
+func (s *quoteService) AcceptQuote(ctx context.Context, userId, id uint, req *entity.AcceptQuoteRequest) (*utils.Response, error) {
+	quote, err := s.QuoteRepository.FindByUserIdWithId(ctx, userId, id)
+	if err != nil {
+		return nil, err
+	}
+	if quote == nil {
+		return nil, errors.New(errorMessages.ErrQuoteNotFound)
+	}
+
+	if quote.Status != status.QUOTE_SENT {
+		return nil, errors.New(errorMessages.ErrInvalidQuoteStatus)
+	}
+	before := utils.AuditSnapshot(quote)
+
+	if time.Now().After(quote.ValidUntil) {
+		if _, err := s.QuoteRepository.UpdateStatus(ctx, quote.ID, status.QUOTE_SENT, status.QUOTE_EXPIRED); err != nil {
+			return nil, err
+		}
+		return nil, errors.New(errorMessages.ErrQuoteExpired)
+	}
+
+	orderDetails := make([]entity.OrderDetailRequest, len(quote.QuoteItems))
+	for i, item := range quote.QuoteItems {
+		price := item.Price
+		orderDetails[i] = entity.OrderDetailRequest{
+			ProductId: item.ProductId,
+			Qty:       item.Qty,
+			Price:     &price,
+		}
+	}
+
+	var resp *utils.Response
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		// Claim the quote before ordering so a second accept cannot create
+		// another order; the claim rolls back with the order if it fails.
+		claimed, err := s.QuoteRepository.UpdateStatus(ctx, quote.ID, status.QUOTE_SENT, status.QUOTE_ACCEPTED)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			return errors.New(errorMessages.ErrInvalidQuoteStatus)
+		}
+
+		resp, err = s.OrderService.CreateOrder(ctx, userId, &entity.CreateOrderRequest{
+			ShippingAddress:   req.ShippingAddress,
+			ShippingAddressId: req.ShippingAddressId,
+			BillingAddressId:  req.BillingAddressId,
+			OrderDetails:      orderDetails,
+			QuoteId:           &quote.ID,
+		})
+		if err != nil {
+			return err
+		}
+
+		orderId := resp.Data.(entity.OrderDataResponse).ID
+		quote.Status = status.QUOTE_ACCEPTED
+		quote.OrderId = &orderId
+		if err := s.QuoteRepository.UpdateQuote(ctx, quote); err != nil {
+			return err
+		}
+
+		return s.Audit.Record(ctx, entity.AuditUpdate, entity.AuditQuote, quote.ID, before, quote)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &utils.Response{
+		Status:  201,
+		Message: "Quote accepted",
+		Data:    resp.Data,
+	}, nil
+}
+
+// ExpireQuotes marks sent quotes past their validity as expired. It runs from
+// the scheduler; AcceptQuote checks the date itself, so a late run only
+// affects what listings show.
+func (s *quoteService) ExpireQuotes(ctx context.Context) error {
+	return s.QuoteRepository.ExpireSent(ctx, time.Now(), status.QUOTE_SENT, status.QUOTE_EXPIRED)
+}
+
+func toQuoteDataResponse(quote entity.Quote) entity.QuoteDataResponse {
+	data := entity.QuoteDataResponse{
+		ID:                quote.ID,
+		UserId:            quote.UserId,
+		Status:            quote.Status,
+		Note:              quote.Note,
+		ValidUntil:        quote.ValidUntil,
+		Total:             quote.Total,
+		OrderId:           quote.OrderId,
+		CreatedAt:         quote.CreatedAt,
+		QuoteItemResponse: make([]entity.QuoteItemResponse, len(quote.QuoteItems)),
+	}
+
+	for i, item := range quote.QuoteItems {
+		data.QuoteItemResponse[i] = entity.QuoteItemResponse{
+			ProductId: item.ProductId,
+			Qty:       item.Qty,
+			Price:     item.Price,
+			Subtotal:  item.Subtotal,
+		}
+	}
+	return data
+}
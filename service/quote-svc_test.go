@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"go-trades/entity"
+	"go-trades/repository"
+	errorMessages "go-trades/utils/error-messages"
+	status "go-trades/utils/status"
+	"testing"
+	"time"
+)
+
+// fakeQuoteRepository keeps quotes by id.
+type fakeQuoteRepository struct {
+	repository.QuoteRepository
+	quotes map[uint]entity.Quote
+}
+
+func (r *fakeQuoteRepository) snapshot() func() {
+	quotes := make(map[uint]entity.Quote, len(r.quotes))
+	for id, quote := range r.quotes {
+		quotes[id] = quote
+	}
+	return func() {
+		r.quotes = quotes
+	}
+}
+
+func (r *fakeQuoteRepository) FindByUserIdWithId(ctx context.Context, userId uint, id uint) (*entity.Quote, error) {
+	quote, ok := r.quotes[id]
+	if !ok || quote.UserId != userId {
+		return nil, nil
+	}
+	quote.QuoteItems = append([]entity.QuoteItem(nil), quote.QuoteItems...)
+	return &quote, nil
+}
+
+func (r *fakeQuoteRepository) UpdateStatus(ctx context.Context, id uint, from, to uint) (bool, error) {
+	quote, ok := r.quotes[id]
+	if !ok || quote.Status != from {
+		return false, nil
+	}
+	quote.Status = to
+	r.quotes[id] = quote
+	return true, nil
+}
+
+func (r *fakeQuoteRepository) UpdateQuote(ctx context.Context, quote *entity.Quote) error {
+	r.quotes[quote.ID] = *quote
+	return nil
+}
+
+type quoteFixture struct {
+	quotes    *fakeQuoteRepository
+	orders    *fakeOrderRepository
+	inventory *fakeInventoryRepository
+	svc       QuoteService
+}
+
+// newQuoteFixture has user 1's sent quote 1 for qty of product 1 at 80
+// instead of the list price of 100, valid until validUntil.
+func newQuoteFixture(qty uint, validUntil time.Time) *quoteFixture {
+	f := &quoteFixture{
+		quotes: &fakeQuoteRepository{quotes: map[uint]entity.Quote{1: {
+			ID:         1,
+			UserId:     1,
+			Status:     status.QUOTE_SENT,
+			ValidUntil: validUntil,
+			Total:      80 * qty,
+			QuoteItems: []entity.QuoteItem{{QuoteId: 1, ProductId: 1, Qty: qty, Price: 80, Subtotal: 80 * qty}},
+		}}},
+		orders:    newFakeOrderRepository(),
+		inventory: newFakeInventoryRepository(map[uint]uint{1: 5}),
+	}
+	products := newFakeProductRepository(testProduct(1, 100, entity.NoBackorder))
+	audit := &fakeAuditRepository{}
+	txManager := &fakeTxManager{stores: []fakeStore{f.quotes, f.orders, f.inventory, audit}}
+	orders := NewOrderService(txManager, f.orders, products, f.inventory, nil, &fakeAddressRepository{}, NewAuditService(audit))
+	f.svc = NewQuoteService(txManager, f.quotes, products, orders, NewAuditService(audit))
+	return f
+}
+
+var acceptQuoteRequest = &entity.AcceptQuoteRequest{ShippingAddress: "1 Main Street"}
+
+func TestAcceptQuotePlacesOrderAtQuotedPrices(t *testing.T) {
+	f := newQuoteFixture(2, time.Now().Add(time.Hour))
+
+	if _, err := f.svc.AcceptQuote(context.Background(), 1, 1, acceptQuoteRequest); err != nil {
+		t.Fatal(err)
+	}
+
+	order, ok := f.orders.orders[1]
+	if !ok || order.Total != 160 || order.QuoteId == nil || *order.QuoteId != 1 {
+		t.Fatalf("order = %+v, want order 1 from quote 1 over 160", order)
+	}
+	if quote := f.quotes.quotes[1]; quote.Status != status.QUOTE_ACCEPTED || quote.OrderId == nil || *quote.OrderId != 1 {
+		t.Errorf("quote = %+v, want it accepted as order 1", quote)
+	}
+	if f.inventory.stock[1] != 3 {
+		t.Errorf("stock = %d, want 3", f.inventory.stock[1])
+	}
+
+	_, err := f.svc.AcceptQuote(context.Background(), 1, 1, acceptQuoteRequest)
+	if err == nil || err.Error() != errorMessages.ErrInvalidQuoteStatus {
+		t.Errorf("second accept: error = %v, want %s", err, errorMessages.ErrInvalidQuoteStatus)
+	}
+	if len(f.orders.orders) != 1 {
+		t.Errorf("placed %d orders, want 1", len(f.orders.orders))
+	}
+}
+
+func TestAcceptQuoteExpired(t *testing.T) {
+	f := newQuoteFixture(2, time.Now().Add(-time.Hour))
+
+	_, err := f.svc.AcceptQuote(context.Background(), 1, 1, acceptQuoteRequest)
+	if err == nil || err.Error() != errorMessages.ErrQuoteExpired {
+		t.Errorf("error = %v, want %s", err, errorMessages.ErrQuoteExpired)
+	}
+	if quote := f.quotes.quotes[1]; quote.Status != status.QUOTE_EXPIRED {
+		t.Errorf("quote status = %d, want expired", quote.Status)
+	}
+	if len(f.orders.orders) != 0 {
+		t.Error("an expired quote placed an order")
+	}
+}
+
+func TestAcceptQuoteRejected(t *testing.T) {
+	tests := []struct {
+		name   string
+		qty    uint
+		userId uint
+		err    string
+	}{
+		{"not enough stock", 9, 1, errorMessages.ErrInventoryInsufficientStock},
+		{"another user's quote", 2, 2, errorMessages.ErrQuoteNotFound},
+	}
+
+	for _, tt := range tests {
+		f := newQuoteFixture(tt.qty, time.Now().Add(time.Hour))
+
+		_, err := f.svc.AcceptQuote(context.Background(), tt.userId, 1, acceptQuoteRequest)
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("%s: error = %v, want %s", tt.name, err, tt.err)
+		}
+		if quote := f.quotes.quotes[1]; quote.Status != status.QUOTE_SENT || quote.OrderId != nil {
+			t.Errorf("%s: quote = %+v, want it still sent", tt.name, quote)
+		}
+		if len(f.orders.orders) != 0 || f.inventory.stock[1] != 5 {
+			t.Errorf("%s: a rejected accept placed an order", tt.name)
+		}
+	}
+}
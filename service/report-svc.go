@@ -1,20 +1,24 @@
 package service
 
 import (
+	"context"
 	"go-trades/entity"
 	"go-trades/repository"
 	"go-trades/utils"
+	status "go-trades/utils/status"
 	"time"
-
-	"github.com/gin-gonic/gin"
 )
 
+// salesStatuses are the order statuses that count as a sale: every paid
+// order, however far it has got towards the customer.
+var salesStatuses = []uint{status.PAID, status.PROCESSING, status.DONE, status.PARTIALLY_SHIPPED, status.SHIPPED, status.DELIVERED}
+
 type reportService struct {
 	Repository repository.ReportRepository
 }
 
 type ReportService interface {
-	GetReport(ctx *gin.Context, start time.Time, end time.Time) (*utils.Response, error)
+	GetReport(ctx context.Context, start time.Time, end time.Time) (*utils.Response, error)
 }
 
 func NewReportService(r repository.ReportRepository) ReportService {
@@ -23,8 +27,8 @@ func NewReportService(r repository.ReportRepository) ReportService {
 	}
 }
 
-func (r *reportService) GetReport(ctx *gin.Context, start time.Time, end time.Time) (*utils.Response, error) {
-	BestSelling, err := r.Repository.FindBestSelling(ctx, start, end)
+func (r *reportService) GetReport(ctx context.Context, start time.Time, end time.Time) (*utils.Response, error) {
+	BestSelling, err := r.Repository.FindBestSelling(ctx, start, end, salesStatuses)
 	if err != nil {
 		return nil, err
 	}
@@ -34,7 +38,7 @@ func (r *reportService) GetReport(ctx *gin.Context, start time.Time, end time.Ti
 		return nil, err
 	}
 
-	OrderSummary, err := r.Repository.GenerateOrderSummary(ctx, start, end)
+	OrderSummary, err := r.Repository.GenerateOrderSummary(ctx, start, end, salesStatuses)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+	"go-trades/entity"
+	"go-trades/repository"
+	status "go-trades/utils/status"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeReportRepository records the order statuses each report asked for.
+type fakeReportRepository struct {
+	repository.ReportRepository
+	bestSelling, summary []uint
+}
+
+func (r *fakeReportRepository) FindBestSelling(ctx context.Context, start, end time.Time, statuses []uint) ([]entity.BestSellingProduct, error) {
+	r.bestSelling = statuses
+	return nil, nil
+}
+
+func (r *fakeReportRepository) FindLowStock(ctx context.Context) ([]entity.LowInventoryItem, error) {
+	return nil, nil
+}
+
+func (r *fakeReportRepository) GenerateOrderSummary(ctx context.Context, start, end time.Time, statuses []uint) (*entity.OrderSummary, error) {
+	r.summary = statuses
+	return &entity.OrderSummary{}, nil
+}
+
+func TestGetReportCountsEveryPaidOrder(t *testing.T) {
+	reports := &fakeReportRepository{}
+	svc := NewReportService(reports)
+
+	if _, err := svc.GetReport(context.Background(), time.Now().AddDate(0, -1, 0), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []uint{status.PAID, status.PROCESSING, status.DONE, status.PARTIALLY_SHIPPED, status.SHIPPED, status.DELIVERED}
+	if !reflect.DeepEqual(reports.bestSelling, want) || !reflect.DeepEqual(reports.summary, want) {
+		t.Errorf("statuses = %v and %v, want %v", reports.bestSelling, reports.summary, want)
+	}
+}
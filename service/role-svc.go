@@ -0,0 +1,215 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"go-trades/entity"
+	"go-trades/repository"
+	"go-trades/utils"
+	errorMessages "go-trades/utils/error-messages"
+)
+
+type roleService struct {
+	txManager  utils.TxManager
+	Repository repository.RoleRepository
+	Audit      AuditService
+}
+
+type RoleService interface {
+	GetAllRoles(ctx context.Context) (*utils.Response, error)
+	GetAllPermissions(ctx context.Context) *utils.Response
+	CreateRole(ctx context.Context, req *entity.CreateRoleRequest) (*utils.Response, error)
+	UpdateRole(ctx context.Context, id uint, req *entity.UpdateRoleRequest) (*utils.Response, error)
+	DeleteRole(ctx context.Context, id uint) error
+}
+
+func NewRoleService(txManager utils.TxManager, r repository.RoleRepository, audit AuditService) RoleService {
+	return &roleService{
+		txManager:  txManager,
+		Repository: r,
+		Audit:      audit,
+	}
+}
+
+func (s *roleService) GetAllRoles(ctx context.Context) (*utils.Response, error) {
+	roles, err := s.Repository.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]entity.RoleDataResponse, len(roles))
+	for i := range roles {
+		data[i] = toRoleDataResponse(&roles[i])
+	}
+
+	return &utils.Response{
+		Status:  200,
+		Message: "Success",
+		Data:    data,
+	}, nil
+}
+
+func (s *roleService) GetAllPermissions(ctx context.Context) *utils.Response {
+	return &utils.Response{
+		Status:  200,
+		Message: "Success",
+		Data: map[string]interface{}{
+			"staff":    entity.StaffPermissions,
+			"customer": entity.CustomerPermissions,
+		},
+	}
+}
+
+func (s *roleService) CreateRole(ctx context.Context, req *entity.CreateRoleRequest) (*utils.Response, error) {
+	existing, err := s.Repository.FindByName(ctx, req.Name)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, errors.New(errorMessages.ErrRoleNameExists)
+	}
+
+	permissions, err := toRolePermissions(req.Permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	role := &entity.RoleDefinition{
+		Name:        req.Name,
+		Description: req.Description,
+		Permissions: permissions,
+	}
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.Repository.CreateRole(ctx, role); err != nil {
+			return err
+		}
+
+		return s.Audit.Record(ctx, entity.AuditCreate, entity.AuditRole, role.ID, nil, toRoleDataResponse(role))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &utils.Response{
+		Status:  201,
+		Message: "Role successfully created",
+		Data:    toRoleDataResponse(role),
+	}, nil
+}
+
+func (s *roleService) UpdateRole(ctx context.Context, id uint, req *entity.UpdateRoleRequest) (*utils.Response, error) {
+	role, err := s.findRole(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Admin keeps every staff permission so nobody can lock themselves out
+	// of role management.
+	if role.Name == entity.Admin {
+		return nil, errors.New(errorMessages.ErrRoleBuiltIn)
+	}
+
+	permissions, err := toRolePermissions(req.Permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	before := toRoleDataResponse(role)
+	role.Description = req.Description
+	role.Permissions = permissions
+
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.Repository.UpdateRole(ctx, role); err != nil {
+			return err
+		}
+
+		return s.Audit.Record(ctx, entity.AuditUpdate, entity.AuditRole, role.ID, before, toRoleDataResponse(role))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &utils.Response{
+		Status:  200,
+		Message: "Role successfully updated",
+		Data:    toRoleDataResponse(role),
+	}, nil
+}
+
+func (s *roleService) DeleteRole(ctx context.Context, id uint) error {
+	role, err := s.findRole(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if role.Name.IsBuiltIn() {
+		return errors.New(errorMessages.ErrRoleBuiltIn)
+	}
+
+	// Assigning a role locks it for share, so with the role locked here no
+	// user can be given it between the count and the delete.
+	return s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		role, err := s.Repository.FindByIdForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if role == nil {
+			return errors.New(errorMessages.ErrRoleNotFound)
+		}
+
+		users, err := s.Repository.CountUsers(ctx, role.Name)
+		if err != nil {
+			return err
+		}
+		if users > 0 {
+			return errors.New(errorMessages.ErrRoleInUse)
+		}
+
+		if err := s.Repository.DeleteRole(ctx, role.ID); err != nil {
+			return err
+		}
+
+		return s.Audit.Record(ctx, entity.AuditDelete, entity.AuditRole, role.ID, toRoleDataResponse(role), nil)
+	})
+}
+
+func (s *roleService) findRole(ctx context.Context, id uint) (*entity.RoleDefinition, error) {
+	role, err := s.Repository.FindById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, errors.New(errorMessages.ErrRoleNotFound)
+	}
+	return role, nil
+}
+
+func toRolePermissions(permissions []entity.Permission) ([]entity.RolePermission, error) {
+	seen := make(map[entity.Permission]bool, len(permissions))
+	result := make([]entity.RolePermission, 0, len(permissions))
+	for _, permission := range permissions {
+		if !entity.IsValidPermission(permission) {
+			return nil, errors.New(errorMessages.ErrInvalidPermission + ": " + string(permission))
+		}
+		if seen[permission] {
+			continue
+		}
+		seen[permission] = true
+		result = append(result, entity.RolePermission{Permission: permission})
+	}
+	return result, nil
+}
+
+func toRoleDataResponse(role *entity.RoleDefinition) entity.RoleDataResponse {
+	permissions := make([]entity.Permission, len(role.Permissions))
+	for i, permission := range role.Permissions {
+		permissions[i] = permission.Permission
+	}
+
+	return entity.RoleDataResponse{
+		ID:          role.ID,
+		Name:        role.Name,
+		Description: role.Description,
+		Permissions: permissions,
+	}
+}
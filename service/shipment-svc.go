@@ -0,0 +1,280 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"go-trades/entity"
+	"go-trades/repository"
+	"go-trades/utils"
+	errorMessages "go-trades/utils/error-messages"
+	status "go-trades/utils/status"
+	"time"
+)
+
+type shipmentService struct {
+	txManager          utils.TxManager
+	ShipmentRepository repository.ShipmentRepository
+	OrderRepository    repository.OrderRepository
+	Audit              AuditService
+}
+
+type ShipmentService interface {
+	GetOrderShipments(ctx context.Context, orderId uint) (*utils.Response, error)
+	GetUserOrderShipments(ctx context.Context, userId, orderId uint) (*utils.Response, error)
+	CreateShipment(ctx context.Context, orderId uint, req *entity.CreateShipmentRequest) (*utils.Response, error)
+	DeliverShipment(ctx context.Context, id uint) (*utils.Response, error)
+}
+
+func NewShipmentService(txManager utils.TxManager, sr repository.ShipmentRepository, or repository.OrderRepository, audit AuditService) ShipmentService {
+	return &shipmentService{
+		txManager:          txManager,
+		ShipmentRepository: sr,
+		OrderRepository:    or,
+		Audit:              audit,
+	}
+}
+
+func (s *shipmentService) GetOrderShipments(ctx context.Context, orderId uint) (*utils.Response, error) {
+	order, err := s.OrderRepository.FindById(ctx, orderId)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil {
+		return nil, errors.New(errorMessages.ErrOrderNotFound)
+	}
+
+	return s.toOrderShipmentsResponse(ctx, orderId)
+}
+
+// GetUserOrderShipments lists the shipments of one of the user's own orders.
+// Another user's order is reported as not found.
+func (s *shipmentService) GetUserOrderShipments(ctx context.Context, userId, orderId uint) (*utils.Response, error) {
+	order, err := s.OrderRepository.FindByUserIdWithId(ctx, userId, orderId)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil {
+		return nil, errors.New(errorMessages.ErrOrderNotFound)
+	}
+
+	return s.toOrderShipmentsResponse(ctx, orderId)
+}
+
+func (s *shipmentService) toOrderShipmentsResponse(ctx context.Context, orderId uint) (*utils.Response, error) {
+	shipments, err := s.ShipmentRepository.FindAllByOrderId(ctx, orderId)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]entity.ShipmentDataResponse, len(shipments))
+	for i, shipment := range shipments {
+		data[i] = toShipmentDataResponse(shipment)
+	}
+
+	return &utils.Response{
+		Status:  200,
+		Message: "Success",
+		Data:    data,
+	}, nil
+}
+
+func (s *shipmentService) CreateShipment(ctx context.Context, orderId uint, req *entity.CreateShipmentRequest) (*utils.Response, error) {
+	if len(req.ShipmentItems) == 0 {
+		return nil, errors.New(errorMessages.ErrShipmentEmpty)
+	}
+
+	var shipment entity.Shipment
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		// Locking the order lines keeps two shipments from both counting
+		// the same remaining quantity.
+		order, err := s.OrderRepository.FindByIdForUpdate(ctx, orderId)
+		if err != nil {
+			return err
+		}
+		if order == nil {
+			return errors.New(errorMessages.ErrOrderNotFound)
+		}
+
+		if order.Status != status.PROCESSING && order.Status != status.PARTIALLY_SHIPPED {
+			return errors.New(errorMessages.ErrInvalidOrderStatus)
+		}
+
+		shipments, err := s.ShipmentRepository.FindAllByOrderId(ctx, orderId)
+		if err != nil {
+			return err
+		}
+
+		// remaining holds the qty of each ordered product not yet covered by a
+		// shipment; shippable leaves out units still waiting on a backorder.
+		remaining := make(map[uint]uint)
+		shippable := make(map[uint]uint)
+		for _, od := range order.OrderDetails {
+			remaining[od.ProductId] = od.Qty
+			shippable[od.ProductId] = od.Qty - od.BackorderedQty
+		}
+		for _, shipment := range shipments {
+			for _, item := range shipment.ShipmentItems {
+				remaining[item.ProductId] -= item.Qty
+				shippable[item.ProductId] -= item.Qty
+			}
+		}
+
+		var shipmentItems []entity.ShipmentItem
+		productIds := make(map[uint]bool)
+		for _, item := range req.ShipmentItems {
+			if productIds[item.ProductId] {
+				return errors.New(errorMessages.ErrShipmentDuplicateProduct)
+			}
+			productIds[item.ProductId] = true
+
+			left, exists := shippable[item.ProductId]
+			if !exists {
+				return errors.New(errorMessages.ErrShipmentProductNotInOrder)
+			}
+			if item.Qty > left {
+				return errors.New(errorMessages.ErrShipmentQtyExceeded)
+			}
+			remaining[item.ProductId] -= item.Qty
+			shippable[item.ProductId] -= item.Qty
+
+			shipmentItems = append(shipmentItems, entity.ShipmentItem{
+				ProductId: item.ProductId,
+				Qty:       item.Qty,
+			})
+		}
+
+		shipment = entity.Shipment{
+			OrderId:        orderId,
+			Carrier:        req.Carrier,
+			TrackingNumber: req.TrackingNumber,
+			ShippedAt:      time.Now(),
+			ShipmentItems:  shipmentItems,
+		}
+		if err := s.ShipmentRepository.CreateShipment(ctx, &shipment); err != nil {
+			return err
+		}
+
+		if err := s.Audit.Record(ctx, entity.AuditCreate, entity.AuditShipment, shipment.ID, nil, shipment); err != nil {
+			return err
+		}
+
+		before := utils.AuditSnapshot(order)
+		order.Status = status.SHIPPED
+		for _, left := range remaining {
+			if left > 0 {
+				order.Status = status.PARTIALLY_SHIPPED
+				break
+			}
+		}
+
+		if err := s.OrderRepository.UpdateOrder(ctx, order); err != nil {
+			return err
+		}
+
+		return s.Audit.Record(ctx, entity.AuditUpdate, entity.AuditOrder, order.ID, before, order)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &utils.Response{
+		Status:  201,
+		Message: "Shipment successfully created",
+		Data:    toShipmentDataResponse(shipment),
+	}, nil
+}
+
+// DeliverShipment marks a shipment delivered. Once every shipment of a
+// fully shipped order has arrived, the order moves to DELIVERED.
+func (s *shipmentService) DeliverShipment(ctx context.Context, id uint) (*utils.Response, error) {
+	shipment, err := s.ShipmentRepository.FindById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if shipment == nil {
+		return nil, errors.New(errorMessages.ErrShipmentNotFound)
+	}
+
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		order, err := s.OrderRepository.FindByIdForUpdate(ctx, shipment.OrderId)
+		if err != nil {
+			return err
+		}
+		if order == nil {
+			return errors.New(errorMessages.ErrOrderNotFound)
+		}
+
+		// Re-read under the order lock so two deliveries of the same
+		// shipment cannot both go through.
+		shipment, err = s.ShipmentRepository.FindById(ctx, id)
+		if err != nil {
+			return err
+		}
+		if shipment.DeliveredAt != nil {
+			return errors.New(errorMessages.ErrShipmentAlreadyDelivered)
+		}
+
+		before := utils.AuditSnapshot(shipment)
+		now := time.Now()
+		shipment.DeliveredAt = &now
+
+		if err := s.ShipmentRepository.UpdateShipment(ctx, shipment); err != nil {
+			return err
+		}
+
+		if err := s.Audit.Record(ctx, entity.AuditUpdate, entity.AuditShipment, shipment.ID, before, shipment); err != nil {
+			return err
+		}
+
+		if order.Status != status.SHIPPED {
+			return nil
+		}
+
+		shipments, err := s.ShipmentRepository.FindAllByOrderId(ctx, order.ID)
+		if err != nil {
+			return err
+		}
+		for _, other := range shipments {
+			if other.DeliveredAt == nil {
+				return nil
+			}
+		}
+
+		orderBefore := utils.AuditSnapshot(order)
+		order.Status = status.DELIVERED
+		if err := s.OrderRepository.UpdateOrder(ctx, order); err != nil {
+			return err
+		}
+
+		return s.Audit.Record(ctx, entity.AuditUpdate, entity.AuditOrder, order.ID, orderBefore, order)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &utils.Response{
+		Status:  200,
+		Message: "Shipment marked as delivered",
+		Data:    toShipmentDataResponse(*shipment),
+	}, nil
+}
+
+func toShipmentDataResponse(shipment entity.Shipment) entity.ShipmentDataResponse {
+	data := entity.ShipmentDataResponse{
+		ID:                   shipment.ID,
+		OrderId:              shipment.OrderId,
+		Carrier:              shipment.Carrier,
+		TrackingNumber:       shipment.TrackingNumber,
+		ShippedAt:            shipment.ShippedAt,
+		DeliveredAt:          shipment.DeliveredAt,
+		ShipmentItemResponse: make([]entity.ShipmentItemResponse, len(shipment.ShipmentItems)),
+	}
+
+	for i, item := range shipment.ShipmentItems {
+		data.ShipmentItemResponse[i] = entity.ShipmentItemResponse{
+			ProductId: item.ProductId,
+			Qty:       item.Qty,
+		}
+	}
+	return data
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"go-trades/entity"
+	"go-trades/repository"
+	errorMessages "go-trades/utils/error-messages"
+	"testing"
+)
+
+// fakeOrderReader answers the plain order reads that list shipments.
+type fakeOrderReader struct {
+	repository.OrderRepository
+	orders map[uint]entity.Order
+}
+
+func (r *fakeOrderReader) FindById(ctx context.Context, id uint) (*entity.Order, error) {
+	order, ok := r.orders[id]
+	if !ok {
+		return nil, nil
+	}
+	return &order, nil
+}
+
+func (r *fakeOrderReader) FindByUserIdWithId(ctx context.Context, userId uint, id uint) (*entity.Order, error) {
+	order, ok := r.orders[id]
+	if !ok || order.UserId != userId {
+		return nil, nil
+	}
+	return &order, nil
+}
+
+type fakeShipmentRepository struct {
+	repository.ShipmentRepository
+	shipments []entity.Shipment
+}
+
+func (r *fakeShipmentRepository) FindAllByOrderId(ctx context.Context, orderId uint) ([]entity.Shipment, error) {
+	var result []entity.Shipment
+	for _, shipment := range r.shipments {
+		if shipment.OrderId == orderId {
+			result = append(result, shipment)
+		}
+	}
+	return result, nil
+}
+
+func TestGetUserOrderShipmentsOnlyForOwnOrders(t *testing.T) {
+	orders := &fakeOrderReader{orders: map[uint]entity.Order{1: {ID: 1, UserId: 1}}}
+	shipments := &fakeShipmentRepository{shipments: []entity.Shipment{
+		{ID: 1, OrderId: 1, Carrier: "post"},
+		{ID: 2, OrderId: 2, Carrier: "post"},
+	}}
+	svc := NewShipmentService(&fakeTxManager{}, shipments, orders, nil)
+
+	resp, err := svc.GetUserOrderShipments(context.Background(), 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data := resp.Data.([]entity.ShipmentDataResponse); len(data) != 1 || data[0].ID != 1 {
+		t.Errorf("shipments = %+v, want shipment 1", data)
+	}
+
+	_, err = svc.GetUserOrderShipments(context.Background(), 2, 1)
+	if err == nil || err.Error() != errorMessages.ErrOrderNotFound {
+		t.Errorf("another user's order: error = %v, want %s", err, errorMessages.ErrOrderNotFound)
+	}
+
+	if _, err := svc.GetOrderShipments(context.Background(), 1); err != nil {
+		t.Errorf("staff listing: %v", err)
+	}
+}
@@ -0,0 +1,483 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"go-trades/entity"
+	"go-trades/repository"
+	"go-trades/utils"
+	errorMessages "go-trades/utils/error-messages"
+	status "go-trades/utils/status"
+	"log"
+	"time"
+)
+
+const (
+	maxSubscriptionRetries = 3
+	subscriptionRetryDelay = time.Hour
+	subscriptionRunLease   = 10 * time.Minute
+)
+
+type subscriptionService struct {
+	txManager              utils.TxManager
+	SubscriptionRepository repository.SubscriptionRepository
+	UserRepository         repository.UserRepository
+	ProductRepository      repository.ProductRepository
+	AddressRepository      repository.AddressRepository
+	OrderService           OrderService
+	Audit                  AuditService
+}
+
+type SubscriptionService interface {
+	GetAllSubscriptions(ctx context.Context, page, size int) (*utils.Response, int64, int64, error)
+	GetSubscriptionById(ctx context.Context, id uint) (*utils.Response, error)
+	GetUserSubscriptions(ctx context.Context, userId uint, page, size int) (*utils.Response, int64, int64, error)
+	GetUserSubscriptionById(ctx context.Context, userId, id uint) (*utils.Response, error)
+	CreateSubscription(ctx context.Context, userId uint, req *entity.CreateSubscriptionRequest) (*utils.Response, error)
+	PauseSubscription(ctx context.Context, userId, id uint) (*utils.Response, error)
+	ResumeSubscription(ctx context.Context, userId, id uint) (*utils.Response, error)
+	CancelSubscription(ctx context.Context, userId, id uint) error
+	RunDueSubscriptions(ctx context.Context) error
+}
+
+func NewSubscriptionService(txManager utils.TxManager, sr repository.SubscriptionRepository, ur repository.UserRepository, pr repository.ProductRepository, ar repository.AddressRepository, os OrderService, audit AuditService) SubscriptionService {
+	return &subscriptionService{
+		txManager:              txManager,
+		SubscriptionRepository: sr,
+		UserRepository:         ur,
+		ProductRepository:      pr,
+		AddressRepository:      ar,
+		OrderService:           os,
+		Audit:                  audit,
+	}
+}
+
+func (s *subscriptionService) GetAllSubscriptions(ctx context.Context, page, size int) (*utils.Response, int64, int64, error) {
+	subscriptions, totalSize, err := s.SubscriptionRepository.FindAll(ctx, page, size)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	data := make([]entity.SubscriptionDataResponse, len(subscriptions))
+	for i, subscription := range subscriptions {
+		data[i] = toSubscriptionDataResponse(subscription)
+	}
+
+	totalPage := utils.GetTotalPage(totalSize, size)
+
+	return &utils.Response{
+		Status:  200,
+		Message: "Success",
+		Data:    data,
+	}, totalSize, totalPage, nil
+}
+
+func (s *subscriptionService) GetSubscriptionById(ctx context.Context, id uint) (*utils.Response, error) {
+	subscription, err := s.SubscriptionRepository.FindById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if subscription == nil {
+		return nil, errors.New(errorMessages.ErrSubscriptionNotFound)
+	}
+
+	return &utils.Response{
+		Status:  200,
+		Message: "Success",
+		Data:    toSubscriptionDataResponse(*subscription),
+	}, nil
+}
+
+func (s *subscriptionService) GetUserSubscriptions(ctx context.Context, userId uint, page, size int) (*utils.Response, int64, int64, error) {
+	subscriptions, totalSize, err := s.SubscriptionRepository.FindAllByUserId(ctx, userId, page, size)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	data := make([]entity.SubscriptionDataResponse, len(subscriptions))
+	for i, subscription := range subscriptions {
+		data[i] = toSubscriptionDataResponse(subscription)
+	}
+
+	totalPage := utils.GetTotalPage(totalSize, size)
+
+	return &utils.Response{
+		Status:  200,
+		Message: "Success",
+		Data:    data,
+	}, totalSize, totalPage, nil
+}
+
+func (s *subscriptionService) GetUserSubscriptionById(ctx context.Context, userId, id uint) (*utils.Response, error) {
+	subscription, err := s.SubscriptionRepository.FindByUserIdWithId(ctx, userId, id)
+	if err != nil {
+		return nil, err
+	}
+	if subscription == nil {
+		return nil, errors.New(errorMessages.ErrSubscriptionNotFound)
+	}
+
+	return &utils.Response{
+		Status:  200,
+		Message: "Success",
+		Data:    toSubscriptionDataResponse(*subscription),
+	}, nil
+}
+
+func (s *subscriptionService) CreateSubscription(ctx context.Context, userId uint, req *entity.CreateSubscriptionRequest) (*utils.Response, error) {
+	if len(req.SubscriptionItems) == 0 {
+		return nil, errors.New(errorMessages.ErrOrderEmpty)
+	}
+
+	var items []entity.SubscriptionItem
+	productIds := make(map[uint]bool)
+	for _, item := range req.SubscriptionItems {
+		if productIds[item.ProductId] {
+			return nil, errors.New(errorMessages.ErrOrderDuplicateProduct)
+		}
+		productIds[item.ProductId] = true
+
+		product, err := s.ProductRepository.FindById(ctx, item.ProductId)
+		if err != nil {
+			return nil, err
+		}
+		if product == nil {
+			return nil, errors.New(errorMessages.ErrProductNotFound)
+		}
+
+		items = append(items, entity.SubscriptionItem{
+			ProductId: item.ProductId,
+			Qty:       item.Qty,
+		})
+	}
+
+	for _, addressId := range []uint{req.ShippingAddressId, req.BillingAddressId} {
+		if addressId == 0 {
+			continue
+		}
+		address, err := s.AddressRepository.FindByUserIdWithId(ctx, userId, addressId)
+		if err != nil {
+			return nil, err
+		}
+		if address == nil {
+			return nil, errors.New(errorMessages.ErrAddressNotFound)
+		}
+	}
+
+	firstRun, err := nextSubscriptionRun(req.Frequency, req.CronExpr, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if req.StartAt != "" {
+		firstRun, err = time.ParseInLocation("2006-01-02", req.StartAt, time.Local)
+		if err != nil {
+			return nil, errors.New("invalid date format")
+		}
+	}
+
+	subscription := entity.Subscription{
+		UserId:            userId,
+		Frequency:         req.Frequency,
+		CronExpr:          req.CronExpr,
+		ShippingAddressId: req.ShippingAddressId,
+		BillingAddressId:  req.BillingAddressId,
+		Status:            status.SUBSCRIPTION_ACTIVE,
+		NextRunAt:         firstRun,
+		ScheduledAt:       firstRun,
+		SubscriptionItems: items,
+	}
+
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.SubscriptionRepository.CreateSubscription(ctx, &subscription); err != nil {
+			return err
+		}
+
+		return s.Audit.Record(ctx, entity.AuditCreate, entity.AuditSubscription, subscription.ID, nil, subscription)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &utils.Response{
+		Status:  201,
+		Message: "Subscription successfully created",
+		Data:    toSubscriptionDataResponse(subscription),
+	}, nil
+}
+
+func (s *subscriptionService) PauseSubscription(ctx context.Context, userId, id uint) (*utils.Response, error) {
+	subscription, err := s.SubscriptionRepository.FindByUserIdWithId(ctx, userId, id)
+	if err != nil {
+		return nil, err
+	}
+	if subscription == nil {
+		return nil, errors.New(errorMessages.ErrSubscriptionNotFound)
+	}
+
+	if subscription.Status != status.SUBSCRIPTION_ACTIVE {
+		return nil, errors.New(errorMessages.ErrSubscriptionStatus)
+	}
+
+	before := utils.AuditSnapshot(subscription)
+	subscription.Status = status.SUBSCRIPTION_PAUSED
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.SubscriptionRepository.UpdateSubscription(ctx, subscription); err != nil {
+			return err
+		}
+
+		return s.Audit.Record(ctx, entity.AuditUpdate, entity.AuditSubscription, subscription.ID, before, subscription)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &utils.Response{
+		Status:  200,
+		Message: "Subscription paused",
+		Data:    toSubscriptionDataResponse(*subscription),
+	}, nil
+}
+
+func (s *subscriptionService) ResumeSubscription(ctx context.Context, userId, id uint) (*utils.Response, error) {
+	subscription, err := s.SubscriptionRepository.FindByUserIdWithId(ctx, userId, id)
+	if err != nil {
+		return nil, err
+	}
+	if subscription == nil {
+		return nil, errors.New(errorMessages.ErrSubscriptionNotFound)
+	}
+
+	if subscription.Status != status.SUBSCRIPTION_PAUSED {
+		return nil, errors.New(errorMessages.ErrSubscriptionStatus)
+	}
+
+	// Runs missed while paused are skipped rather than replayed.
+	before := utils.AuditSnapshot(subscription)
+	subscription.Status = status.SUBSCRIPTION_ACTIVE
+	subscription.RetryCount = 0
+	if err := advanceSubscriptionSchedule(subscription, time.Now()); err != nil {
+		return nil, err
+	}
+
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.SubscriptionRepository.UpdateSubscription(ctx, subscription); err != nil {
+			return err
+		}
+
+		return s.Audit.Record(ctx, entity.AuditUpdate, entity.AuditSubscription, subscription.ID, before, subscription)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &utils.Response{
+		Status:  200,
+		Message: "Subscription resumed",
+		Data:    toSubscriptionDataResponse(*subscription),
+	}, nil
+}
+
+func (s *subscriptionService) CancelSubscription(ctx context.Context, userId, id uint) error {
+	subscription, err := s.SubscriptionRepository.FindByUserIdWithId(ctx, userId, id)
+	if err != nil {
+		return err
+	}
+	if subscription == nil {
+		return errors.New(errorMessages.ErrSubscriptionNotFound)
+	}
+
+	if subscription.Status == status.SUBSCRIPTION_CANCELLED {
+		return errors.New(errorMessages.ErrSubscriptionStatus)
+	}
+
+	before := utils.AuditSnapshot(subscription)
+	subscription.Status = status.SUBSCRIPTION_CANCELLED
+	return s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.SubscriptionRepository.UpdateSubscription(ctx, subscription); err != nil {
+			return err
+		}
+
+		return s.Audit.Record(ctx, entity.AuditUpdate, entity.AuditSubscription, subscription.ID, before, subscription)
+	})
+}
+
+// RunDueSubscriptions places an order for every active subscription whose run
+// time has passed. It is driven by the scheduler; a failing subscription is
+// recorded and logged without stopping the others.
+func (s *subscriptionService) RunDueSubscriptions(ctx context.Context) error {
+	now := time.Now()
+
+	subscriptions, err := s.SubscriptionRepository.FindDue(ctx, now, status.SUBSCRIPTION_ACTIVE)
+	if err != nil {
+		return err
+	}
+
+	for i := range subscriptions {
+		if err := s.runSubscription(ctx, &subscriptions[i], now); err != nil {
+			log.Printf("Subscription %d run failed: %v", subscriptions[i].ID, err)
+		}
+	}
+
+	return nil
+}
+
+// runSubscription claims the subscription, places its order, records the run
+// and moves the schedule on in one transaction, so a crash part-way leaves
+// the subscription due instead of ordered without a run. Subscriptions of a
+// blocked user are paused rather than ordered.
+func (s *subscriptionService) runSubscription(ctx context.Context, subscription *entity.Subscription, now time.Time) error {
+	return s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		claimed, err := s.SubscriptionRepository.ClaimDue(ctx, subscription.ID, status.SUBSCRIPTION_ACTIVE, subscription.NextRunAt, now.Add(subscriptionRunLease))
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			return nil
+		}
+
+		run := entity.SubscriptionRun{
+			SubscriptionId: subscription.ID,
+			RunAt:          now,
+			Attempt:        subscription.RetryCount + 1,
+		}
+
+		owner, err := s.UserRepository.FindByIdForUpdate(ctx, subscription.UserId)
+		if err != nil {
+			return err
+		}
+		if owner.IsBlocked {
+			run.Error = errorMessages.ErrUserBlocked
+			if err := s.SubscriptionRepository.CreateSubscriptionRun(ctx, &run); err != nil {
+				return err
+			}
+
+			before := utils.AuditSnapshot(subscription)
+			subscription.Status = status.SUBSCRIPTION_PAUSED
+			subscription.RetryCount = 0
+			if err := s.SubscriptionRepository.UpdateSubscription(ctx, subscription); err != nil {
+				return err
+			}
+
+			return s.Audit.Record(ctx, entity.AuditUpdate, entity.AuditSubscription, subscription.ID, before, subscription)
+		}
+
+		orderDetails := make([]entity.OrderDetailRequest, len(subscription.SubscriptionItems))
+		for i, item := range subscription.SubscriptionItems {
+			orderDetails[i] = entity.OrderDetailRequest{
+				ProductId: item.ProductId,
+				Qty:       item.Qty,
+			}
+		}
+
+		// CreateOrder runs in a savepoint, so a rejected order is undone on
+		// its own and the failed run is still recorded.
+		resp, orderErr := s.OrderService.CreateOrder(ctx, subscription.UserId, &entity.CreateOrderRequest{
+			ShippingAddressId: subscription.ShippingAddressId,
+			BillingAddressId:  subscription.BillingAddressId,
+			OrderDetails:      orderDetails,
+		})
+
+		switch {
+		case orderErr == nil:
+			orderId := resp.Data.(entity.OrderDataResponse).ID
+			run.Success = true
+			run.OrderId = &orderId
+			subscription.RetryCount = 0
+			err = advanceSubscriptionSchedule(subscription, now)
+		case orderErr.Error() == errorMessages.ErrInventoryInsufficientStock && subscription.RetryCount < maxSubscriptionRetries:
+			run.Error = orderErr.Error()
+			subscription.RetryCount++
+			subscription.NextRunAt = now.Add(subscriptionRetryDelay * time.Duration(subscription.RetryCount))
+		default:
+			run.Error = orderErr.Error()
+			subscription.RetryCount = 0
+			err = advanceSubscriptionSchedule(subscription, now)
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := s.SubscriptionRepository.CreateSubscriptionRun(ctx, &run); err != nil {
+			return err
+		}
+
+		return s.SubscriptionRepository.UpdateSchedule(ctx, subscription)
+	})
+}
+
+// advanceSubscriptionSchedule moves the subscription to its first regular run
+// after now. Retries never shift the base schedule, since it steps from
+// ScheduledAt rather than from the time of the last attempt.
+func advanceSubscriptionSchedule(subscription *entity.Subscription, now time.Time) error {
+	next := subscription.ScheduledAt
+	for !next.After(now) {
+		n, err := nextSubscriptionRun(subscription.Frequency, subscription.CronExpr, next)
+		if err != nil {
+			return err
+		}
+		next = n
+	}
+
+	subscription.ScheduledAt = next
+	subscription.NextRunAt = next
+	return nil
+}
+
+func nextSubscriptionRun(frequency entity.Frequency, cronExpr string, after time.Time) (time.Time, error) {
+	switch frequency {
+	case entity.Daily:
+		return after.AddDate(0, 0, 1), nil
+	case entity.Weekly:
+		return after.AddDate(0, 0, 7), nil
+	case entity.Monthly:
+		return after.AddDate(0, 1, 0), nil
+	case entity.Cron:
+		schedule, err := utils.ParseCron(cronExpr)
+		if err != nil {
+			return time.Time{}, err
+		}
+		next := schedule.Next(after)
+		if next.IsZero() {
+			return time.Time{}, errors.New(errorMessages.ErrInvalidSchedule)
+		}
+		return next, nil
+	default:
+		return time.Time{}, errors.New(errorMessages.ErrInvalidSchedule)
+	}
+}
+
+func toSubscriptionDataResponse(subscription entity.Subscription) entity.SubscriptionDataResponse {
+	data := entity.SubscriptionDataResponse{
+		ID:                       subscription.ID,
+		UserId:                   subscription.UserId,
+		Frequency:                subscription.Frequency,
+		CronExpr:                 subscription.CronExpr,
+		ShippingAddressId:        subscription.ShippingAddressId,
+		BillingAddressId:         subscription.BillingAddressId,
+		Status:                   subscription.Status,
+		NextRunAt:                subscription.NextRunAt,
+		RetryCount:               subscription.RetryCount,
+		CreatedAt:                subscription.CreatedAt,
+		SubscriptionItemResponse: make([]entity.OrderDetailRequest, len(subscription.SubscriptionItems)),
+		SubscriptionRunResponse:  make([]entity.SubscriptionRunResponse, len(subscription.SubscriptionRuns)),
+	}
+
+	for i, item := range subscription.SubscriptionItems {
+		data.SubscriptionItemResponse[i] = entity.OrderDetailRequest{
+			ProductId: item.ProductId,
+			Qty:       item.Qty,
+		}
+	}
+
+	for i, run := range subscription.SubscriptionRuns {
+		data.SubscriptionRunResponse[i] = entity.SubscriptionRunResponse{
+			ID:      run.ID,
+			RunAt:   run.RunAt,
+			Attempt: run.Attempt,
+			Success: run.Success,
+			OrderId: run.OrderId,
+			Error:   run.Error,
+		}
+	}
+	return data
+}
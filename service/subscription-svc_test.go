@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"go-trades/entity"
+	"go-trades/repository"
+	errorMessages "go-trades/utils/error-messages"
+	status "go-trades/utils/status"
+	"testing"
+	"time"
+)
+
+// fakeSubscriptionRepository holds one subscription and the runs recorded
+// against it.
+type fakeSubscriptionRepository struct {
+	repository.SubscriptionRepository
+	subscription entity.Subscription
+	runs         []entity.SubscriptionRun
+}
+
+func (r *fakeSubscriptionRepository) snapshot() func() {
+	subscription := r.subscription
+	runs := append([]entity.SubscriptionRun(nil), r.runs...)
+	return func() {
+		r.subscription = subscription
+		r.runs = runs
+	}
+}
+
+func (r *fakeSubscriptionRepository) ClaimDue(ctx context.Context, id uint, status uint, nextRunAt, leaseUntil time.Time) (bool, error) {
+	if r.subscription.ID != id || r.subscription.Status != status || !r.subscription.NextRunAt.Equal(nextRunAt) {
+		return false, nil
+	}
+	r.subscription.NextRunAt = leaseUntil
+	return true, nil
+}
+
+func (r *fakeSubscriptionRepository) UpdateSubscription(ctx context.Context, subscription *entity.Subscription) error {
+	r.subscription = *subscription
+	return nil
+}
+
+func (r *fakeSubscriptionRepository) UpdateSchedule(ctx context.Context, subscription *entity.Subscription) error {
+	r.subscription.NextRunAt = subscription.NextRunAt
+	r.subscription.ScheduledAt = subscription.ScheduledAt
+	r.subscription.RetryCount = subscription.RetryCount
+	return nil
+}
+
+func (r *fakeSubscriptionRepository) CreateSubscriptionRun(ctx context.Context, run *entity.SubscriptionRun) error {
+	r.runs = append(r.runs, *run)
+	return nil
+}
+
+type subscriptionFixture struct {
+	subscriptions *fakeSubscriptionRepository
+	orders        *fakeOrderRepository
+	inventory     *fakeInventoryRepository
+	audit         *fakeAuditRepository
+	svc           *subscriptionService
+}
+
+var subscriptionNow = time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+
+// newSubscriptionFixture has user 1's daily subscription for qty of product
+// 1, due now, over the same catalogue and stock as newOrderFixture. User 2
+// is blocked.
+func newSubscriptionFixture(userId, qty uint) *subscriptionFixture {
+	f := &subscriptionFixture{
+		subscriptions: &fakeSubscriptionRepository{subscription: entity.Subscription{
+			ID:                1,
+			UserId:            userId,
+			Frequency:         entity.Daily,
+			Status:            status.SUBSCRIPTION_ACTIVE,
+			NextRunAt:         subscriptionNow,
+			ScheduledAt:       subscriptionNow,
+			SubscriptionItems: []entity.SubscriptionItem{{SubscriptionId: 1, ProductId: 1, Qty: qty}},
+		}},
+		orders:    newFakeOrderRepository(),
+		inventory: newFakeInventoryRepository(map[uint]uint{1: 5, 2: 5, 3: 1}),
+		audit:     &fakeAuditRepository{},
+	}
+	products := newFakeProductRepository(
+		testProduct(1, 100, entity.NoBackorder),
+		testProduct(2, 250, entity.NoBackorder),
+		testProduct(3, 40, entity.Backorder),
+	)
+	users := newFakeUserRepository(testUser(1, false), testUser(2, true))
+	addresses := &fakeAddressRepository{shipping: &entity.Address{PostalAddress: entity.PostalAddress{Street: "1 Main Street"}}}
+
+	txManager := &fakeTxManager{stores: []fakeStore{f.subscriptions, f.orders, f.inventory, f.audit}}
+	audit := NewAuditService(f.audit)
+	orders := NewOrderService(txManager, f.orders, products, f.inventory, nil, addresses, audit)
+	f.svc = NewSubscriptionService(txManager, f.subscriptions, users, products, addresses, orders, audit).(*subscriptionService)
+	return f
+}
+
+func (f *subscriptionFixture) run(t *testing.T) {
+	t.Helper()
+	subscription := f.subscriptions.subscription
+	if err := f.svc.runSubscription(context.Background(), &subscription, subscriptionNow); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunSubscriptionPlacesOrder(t *testing.T) {
+	f := newSubscriptionFixture(1, 2)
+
+	f.run(t)
+
+	if len(f.orders.orders) != 1 {
+		t.Fatalf("placed %d orders, want 1", len(f.orders.orders))
+	}
+	runs := f.subscriptions.runs
+	if len(runs) != 1 || !runs[0].Success || runs[0].OrderId == nil || *runs[0].OrderId != 1 {
+		t.Errorf("runs = %+v, want one successful run for order 1", runs)
+	}
+	next := subscriptionNow.AddDate(0, 0, 1)
+	if got := f.subscriptions.subscription; !got.NextRunAt.Equal(next) || !got.ScheduledAt.Equal(next) {
+		t.Errorf("next run = %v, scheduled %v, want both %v", got.NextRunAt, got.ScheduledAt, next)
+	}
+}
+
+func TestRunSubscriptionRetriesShortStock(t *testing.T) {
+	f := newSubscriptionFixture(1, 9)
+
+	f.run(t)
+
+	if len(f.orders.orders) != 0 {
+		t.Error("placed an order without stock")
+	}
+	if f.inventory.stock[1] != 5 {
+		t.Errorf("stock = %d, want 5", f.inventory.stock[1])
+	}
+	runs := f.subscriptions.runs
+	if len(runs) != 1 || runs[0].Success || runs[0].Error != errorMessages.ErrInventoryInsufficientStock {
+		t.Errorf("runs = %+v, want one run failed for stock", runs)
+	}
+	got := f.subscriptions.subscription
+	if got.RetryCount != 1 || !got.NextRunAt.Equal(subscriptionNow.Add(subscriptionRetryDelay)) || !got.ScheduledAt.Equal(subscriptionNow) {
+		t.Errorf("subscription = %+v, want the first retry an hour from now", got)
+	}
+}
+
+func TestRunSubscriptionPausesBlockedOwner(t *testing.T) {
+	f := newSubscriptionFixture(2, 2)
+
+	f.run(t)
+
+	if len(f.orders.orders) != 0 {
+		t.Error("placed an order for a blocked user")
+	}
+	got := f.subscriptions.subscription
+	if got.Status != status.SUBSCRIPTION_PAUSED || !got.NextRunAt.Equal(subscriptionNow) {
+		t.Errorf("subscription = %+v, want it paused and still due now", got)
+	}
+	runs := f.subscriptions.runs
+	if len(runs) != 1 || runs[0].Error != errorMessages.ErrUserBlocked {
+		t.Errorf("runs = %+v, want one run failed for the blocked user", runs)
+	}
+	if len(f.audit.entries) != 1 || f.audit.entries[0].Action != entity.AuditUpdate {
+		t.Errorf("audit entries = %+v, want the pause", f.audit.entries)
+	}
+}
+
+func TestRunSubscriptionSkipsClaimedRun(t *testing.T) {
+	f := newSubscriptionFixture(1, 2)
+	subscription := f.subscriptions.subscription
+	f.subscriptions.subscription.NextRunAt = subscriptionNow.Add(subscriptionRunLease)
+
+	if err := f.svc.runSubscription(context.Background(), &subscription, subscriptionNow); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(f.orders.orders) != 0 || len(f.subscriptions.runs) != 0 {
+		t.Errorf("a run claimed elsewhere placed %d orders and recorded %d runs", len(f.orders.orders), len(f.subscriptions.runs))
+	}
+}
@@ -0,0 +1,457 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"go-trades/config"
+	"go-trades/entity"
+	"go-trades/repository"
+	"go-trades/utils"
+	errorMessages "go-trades/utils/error-messages"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	twoFactorIssuer    = "go-trades"
+	twoFactorTokenTTL  = 5 * time.Minute
+	twoFactorPurpose   = "2fa"
+	recoveryCodeCount  = 10
+	recoveryCodeLength = 10
+)
+
+// Wrong second-factor codes back off and lock out per user, like failed
+// logins, so the six digits cannot be brute-forced.
+const (
+	twoFactorBackoffAfter = 3
+	twoFactorLockoutAfter = 5
+)
+
+type twoFactorService struct {
+	txManager           utils.TxManager
+	Repository          repository.TwoFactorRepository
+	UserRepository      repository.UserRepository
+	UserTokenRepository repository.UserTokenRepository
+	LoginAttemptStore   repository.LoginAttemptStore
+	UserService         UserService
+	Audit               AuditService
+}
+
+type TwoFactorService interface {
+	Enroll(ctx context.Context, userId uint) (*entity.TwoFactorEnrollResponse, error)
+	Confirm(ctx context.Context, userId uint, req *entity.TwoFactorCodeRequest) (*entity.TwoFactorConfirmResponse, error)
+	Disable(ctx context.Context, userId uint, req *entity.TwoFactorCodeRequest) error
+	RegenerateRecoveryCodes(ctx context.Context, userId uint, req *entity.TwoFactorCodeRequest) (*entity.TwoFactorConfirmResponse, error)
+	EnrollForLogin(ctx context.Context, req *entity.TwoFactorTokenRequest) (*entity.TwoFactorEnrollResponse, error)
+	ConfirmForLogin(ctx context.Context, req *entity.TwoFactorLoginRequest) (*entity.TwoFactorConfirmResponse, error)
+	CompleteLogin(ctx context.Context, req *entity.TwoFactorLoginRequest) (*entity.UserLoginResponse, error)
+}
+
+func NewTwoFactorService(txManager utils.TxManager, r repository.TwoFactorRepository, ur repository.UserRepository, utr repository.UserTokenRepository, las repository.LoginAttemptStore, userService UserService, audit AuditService) TwoFactorService {
+	return &twoFactorService{
+		txManager:           txManager,
+		Repository:          r,
+		UserRepository:      ur,
+		UserTokenRepository: utr,
+		LoginAttemptStore:   las,
+		UserService:         userService,
+		Audit:               audit,
+	}
+}
+
+// Enroll starts enrolment with a fresh secret. Calling it again before
+// confirming replaces the secret, e.g. when the QR code was never scanned.
+func (s *twoFactorService) Enroll(ctx context.Context, userId uint) (*entity.TwoFactorEnrollResponse, error) {
+	user, err := s.UserRepository.FindById(ctx, userId)
+	if err != nil {
+		return nil, errors.New(errorMessages.ErrUserNotExists)
+	}
+
+	twoFactor, err := s.Repository.FindByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if twoFactor != nil && twoFactor.ConfirmedAt != nil {
+		return nil, errors.New(errorMessages.ErrTwoFactorEnabled)
+	}
+
+	secret, err := utils.NewTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	action := entity.AuditUpdate
+	before := utils.AuditSnapshot(twoFactor)
+	if twoFactor == nil {
+		action = entity.AuditCreate
+		twoFactor = &entity.TwoFactor{UserId: userId}
+	}
+	twoFactor.Secret = secret
+	twoFactor.LastUsedStep = 0
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.Repository.Save(ctx, twoFactor); err != nil {
+			return err
+		}
+
+		return s.Audit.Record(ctx, action, entity.AuditTwoFactor, userId, before, twoFactor)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.TwoFactorEnrollResponse{
+		Secret:          secret,
+		ProvisioningUri: utils.TOTPProvisioningURI(twoFactorIssuer, user.Username, secret),
+	}, nil
+}
+
+// Confirm enables 2FA once the user enters a code from their app, and
+// returns the recovery codes. They are shown only this once.
+func (s *twoFactorService) Confirm(ctx context.Context, userId uint, req *entity.TwoFactorCodeRequest) (*entity.TwoFactorConfirmResponse, error) {
+	twoFactor, err := s.Repository.FindByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if twoFactor == nil {
+		return nil, errors.New(errorMessages.ErrTwoFactorNotEnrolled)
+	}
+	if twoFactor.ConfirmedAt != nil {
+		return nil, errors.New(errorMessages.ErrTwoFactorEnabled)
+	}
+
+	now := time.Now()
+	var step int64
+	err = s.checkCode(ctx, userId, func() error {
+		var ok bool
+		step, ok = utils.VerifyTOTP(twoFactor.Secret, req.Code, now)
+		if !ok {
+			return errors.New(errorMessages.ErrInvalidTwoFactorCode)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	before := utils.AuditSnapshot(twoFactor)
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		twoFactor.ConfirmedAt = &now
+		twoFactor.LastUsedStep = step
+		if err := s.Repository.Save(ctx, twoFactor); err != nil {
+			return err
+		}
+
+		if err := s.Repository.ReplaceRecoveryCodes(ctx, userId, hashes); err != nil {
+			return err
+		}
+
+		return s.Audit.Record(ctx, entity.AuditUpdate, entity.AuditTwoFactor, userId, before, twoFactor)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.TwoFactorConfirmResponse{RecoveryCodes: codes}, nil
+}
+
+func (s *twoFactorService) Disable(ctx context.Context, userId uint, req *entity.TwoFactorCodeRequest) error {
+	user, err := s.UserRepository.FindById(ctx, userId)
+	if err != nil {
+		return errors.New(errorMessages.ErrUserNotExists)
+	}
+
+	if requiresTwoFactor(user) {
+		return errors.New(errorMessages.ErrTwoFactorRequired)
+	}
+
+	twoFactor, err := s.findEnabled(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	if err := s.verify(ctx, twoFactor, req.Code, ""); err != nil {
+		return err
+	}
+
+	return s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.Repository.Delete(ctx, userId); err != nil {
+			return err
+		}
+
+		return s.Audit.Record(ctx, entity.AuditDelete, entity.AuditTwoFactor, userId, twoFactor, nil)
+	})
+}
+
+func (s *twoFactorService) RegenerateRecoveryCodes(ctx context.Context, userId uint, req *entity.TwoFactorCodeRequest) (*entity.TwoFactorConfirmResponse, error) {
+	twoFactor, err := s.findEnabled(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.verify(ctx, twoFactor, req.Code, ""); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.Repository.ReplaceRecoveryCodes(ctx, userId, hashes); err != nil {
+			return err
+		}
+
+		// Recovery codes are only stored hashed, so the entry just notes
+		// when they were replaced.
+		after := map[string]interface{}{"recoveryCodesRegeneratedAt": time.Now()}
+		return s.Audit.Record(ctx, entity.AuditUpdate, entity.AuditTwoFactor, userId, nil, after)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.TwoFactorConfirmResponse{RecoveryCodes: codes}, nil
+}
+
+// EnrollForLogin lets a user the policy forces into 2FA enrol with the
+// interim token from Login, before they have a session.
+func (s *twoFactorService) EnrollForLogin(ctx context.Context, req *entity.TwoFactorTokenRequest) (*entity.TwoFactorEnrollResponse, error) {
+	token, err := s.findLoginToken(ctx, req.TwoFactorToken)
+	if err != nil {
+		return nil, err
+	}
+	return s.Enroll(ctx, token.UserId)
+}
+
+// ConfirmForLogin finishes a forced enrolment and logs the user in.
+func (s *twoFactorService) ConfirmForLogin(ctx context.Context, req *entity.TwoFactorLoginRequest) (*entity.TwoFactorConfirmResponse, error) {
+	token, err := s.findLoginToken(ctx, req.TwoFactorToken)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.Confirm(ctx, token.UserId, &entity.TwoFactorCodeRequest{Code: req.Code})
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Login, err = s.redeemLoginToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// CompleteLogin is the second step of a login for users with 2FA enabled.
+func (s *twoFactorService) CompleteLogin(ctx context.Context, req *entity.TwoFactorLoginRequest) (*entity.UserLoginResponse, error) {
+	token, err := s.findLoginToken(ctx, req.TwoFactorToken)
+	if err != nil {
+		return nil, err
+	}
+
+	twoFactor, err := s.findEnabled(ctx, token.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.verify(ctx, twoFactor, req.Code, req.RecoveryCode); err != nil {
+		return nil, err
+	}
+
+	return s.redeemLoginToken(ctx, token)
+}
+
+// findLoginToken checks an interim token from Login and returns its stored
+// record. A token that already logged in is refused.
+func (s *twoFactorService) findLoginToken(ctx context.Context, tokenString string) (*entity.UserToken, error) {
+	userId, jti, err := parseTwoFactorToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := s.UserTokenRepository.FindByHash(ctx, entity.TwoFactorLoginToken, utils.HashToken(jti))
+	if err != nil {
+		return nil, err
+	}
+	if token == nil || token.UserId != userId || token.UsedAt != nil || time.Now().After(token.ExpiresAt) {
+		return nil, errors.New(errorMessages.ErrInvalidTwoFactorToken)
+	}
+	return token, nil
+}
+
+// redeemLoginToken uses up the interim token and starts the session it was
+// issued for. Of two requests racing with the same token only one wins.
+func (s *twoFactorService) redeemLoginToken(ctx context.Context, token *entity.UserToken) (*entity.UserLoginResponse, error) {
+	var resp *entity.UserLoginResponse
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		used, err := s.UserTokenRepository.MarkUsed(ctx, token.ID, time.Now())
+		if err != nil {
+			return err
+		}
+		if !used {
+			return errors.New(errorMessages.ErrInvalidTwoFactorToken)
+		}
+
+		resp, err = s.UserService.StartSession(ctx, token.UserId)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (s *twoFactorService) findEnabled(ctx context.Context, userId uint) (*entity.TwoFactor, error) {
+	twoFactor, err := s.Repository.FindByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if twoFactor == nil || twoFactor.ConfirmedAt == nil {
+		return nil, errors.New(errorMessages.ErrTwoFactorNotEnrolled)
+	}
+	return twoFactor, nil
+}
+
+// verify accepts an authenticator code or, failing that, a recovery code.
+// Both are single use, and wrong ones count towards a lockout.
+func (s *twoFactorService) verify(ctx context.Context, twoFactor *entity.TwoFactor, code, recoveryCode string) error {
+	return s.checkCode(ctx, twoFactor.UserId, func() error {
+		return s.verifyCode(ctx, twoFactor, code, recoveryCode)
+	})
+}
+
+// checkCode runs check as an attempt against the user's second-factor
+// limit. The attempt is counted before check runs and forgiven once it
+// passes; while the limit is locked check does not run at all.
+func (s *twoFactorService) checkCode(ctx context.Context, userId uint, check func() error) error {
+	limit := loginLimit{"2fa:" + strconv.FormatUint(uint64(userId), 10), twoFactorBackoffAfter, twoFactorLockoutAfter}
+	if _, err := reserveLoginAttempt(ctx, s.LoginAttemptStore, limit); err != nil {
+		return err
+	}
+
+	if err := check(); err != nil {
+		return err
+	}
+	return s.LoginAttemptStore.Reset(ctx, limit.key)
+}
+
+func (s *twoFactorService) verifyCode(ctx context.Context, twoFactor *entity.TwoFactor, code, recoveryCode string) error {
+	if code != "" {
+		step, ok := utils.VerifyTOTP(twoFactor.Secret, code, time.Now())
+		if !ok {
+			return errors.New(errorMessages.ErrInvalidTwoFactorCode)
+		}
+		fresh, err := s.Repository.MarkStepUsed(ctx, twoFactor.UserId, step)
+		if err != nil {
+			return err
+		}
+		if !fresh {
+			return errors.New(errorMessages.ErrInvalidTwoFactorCode)
+		}
+		return nil
+	}
+
+	if recoveryCode != "" {
+		used, err := s.Repository.UseRecoveryCode(ctx, twoFactor.UserId, utils.HashToken(normalizeRecoveryCode(recoveryCode)), time.Now())
+		if err != nil {
+			return err
+		}
+		if !used {
+			return errors.New(errorMessages.ErrInvalidTwoFactorCode)
+		}
+		return nil
+	}
+
+	return errors.New(errorMessages.ErrInvalidTwoFactorCode)
+}
+
+func requiresTwoFactor(user *entity.User) bool {
+	return config.IsAdminTwoFactorRequired() && user.Role == entity.Admin
+}
+
+// newTwoFactorToken signs the interim token Login returns when a second
+// factor is needed, and returns its jti with it. It has no session id, so
+// AuthMiddleware rejects it.
+func newTwoFactorToken(userId uint, now time.Time) (string, string, error) {
+	signingKey, err := config.GetJWTSigningKey()
+	if err != nil {
+		return "", "", err
+	}
+
+	jti := uuid.NewString()
+	token := jwt.NewWithClaims(signingKey.Method, jwt.MapClaims{
+		"userId":  userId,
+		"purpose": twoFactorPurpose,
+		"jti":     jti,
+		"iat":     now.Unix(),
+		"exp":     now.Add(twoFactorTokenTTL).Unix(),
+	})
+	if signingKey.KeyId != "" {
+		token.Header["kid"] = signingKey.KeyId
+	}
+
+	signed, err := token.SignedString(signingKey.Key)
+	if err != nil {
+		return "", "", err
+	}
+	return signed, jti, nil
+}
+
+func parseTwoFactorToken(tokenString string) (uint, string, error) {
+	token, err := jwt.Parse(tokenString, config.JWTKeyfunc)
+	if err != nil || !token.Valid {
+		return 0, "", errors.New(errorMessages.ErrInvalidTwoFactorToken)
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["purpose"] != twoFactorPurpose {
+		return 0, "", errors.New(errorMessages.ErrInvalidTwoFactorToken)
+	}
+
+	userId, ok := claims["userId"].(float64)
+	if !ok {
+		return 0, "", errors.New(errorMessages.ErrInvalidTwoFactorToken)
+	}
+	jti, ok := claims["jti"].(string)
+	if !ok || jti == "" {
+		return 0, "", errors.New(errorMessages.ErrInvalidTwoFactorToken)
+	}
+	return uint(userId), jti, nil
+}
+
+// newRecoveryCodes returns the codes to show the user and the hashes to
+// store.
+func newRecoveryCodes() ([]string, []string, error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+
+	for i := range codes {
+		b := make([]byte, 8)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(encoding.EncodeToString(b)[:recoveryCodeLength])
+		codes[i] = code[:5] + "-" + code[5:]
+		hashes[i] = utils.HashToken(code)
+	}
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
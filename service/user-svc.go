@@ -1,57 +1,119 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"go-trades/config"
 	"go-trades/entity"
+	"go-trades/mailer"
 	"go-trades/repository"
+	"go-trades/utils"
+	errorMessages "go-trades/utils/error-messages"
+	"log"
+	"strings"
+	"sync"
 	"time"
 
-	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
+const (
+	passwordResetTokenTTL     = time.Hour
+	emailVerificationTokenTTL = 48 * time.Hour
+)
+
+// Failed login limits. The IP limits are higher since several users may
+// share an address.
+const (
+	loginAttemptWindow   = time.Hour
+	accountBackoffAfter  = 3
+	accountLockoutAfter  = 10
+	ipBackoffAfter       = 20
+	ipLockoutAfter       = 100
+	maxLoginBackoff      = time.Minute
+	loginLockoutDuration = 15 * time.Minute
+)
+
+// LoginThrottledError is returned while failed logins keep a username or IP
+// locked.
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottledError) Error() string {
+	return errorMessages.ErrTooManyLoginAttempts
+}
+
 type userService struct {
-	Repository repository.UserRepository
+	txManager              utils.TxManager
+	Repository             repository.UserRepository
+	RoleRepository         repository.RoleRepository
+	SessionRepository      repository.SessionRepository
+	UserTokenRepository    repository.UserTokenRepository
+	TwoFactorRepository    repository.TwoFactorRepository
+	ApiKeyRepository       repository.ApiKeyRepository
+	LoginAttemptStore      repository.LoginAttemptStore
+	LoginLockoutRepository repository.LoginLockoutRepository
+	Mailer                 mailer.Mailer
+	Audit                  AuditService
 }
 
 type UserService interface {
-	GetUserById(ctx *gin.Context, userId uint) (*entity.UserDataResponse, error)
-	Register(ctx *gin.Context, req *entity.UserRegisterRequest) (*entity.UserDataResponse, error)
-	Login(ctx *gin.Context, req *entity.UserLoginRequest) (*entity.UserLoginResponse, error)
-	ChangePassword(ctx *gin.Context, userId uint, req *entity.UserChangePassword) (*entity.UserChangePasswordResponse, error)
-	AssignAsAdmin(ctx *gin.Context, userId uint) error
+	GetUserById(ctx context.Context, userId uint) (*entity.UserDataResponse, error)
+	Register(ctx context.Context, req *entity.UserRegisterRequest) (*entity.UserDataResponse, error)
+	Login(ctx context.Context, req *entity.UserLoginRequest) (*entity.UserLoginResponse, error)
+	ContinueLogin(ctx context.Context, userId uint) (*entity.UserLoginResponse, error)
+	StartSession(ctx context.Context, userId uint) (*entity.UserLoginResponse, error)
+	RefreshToken(ctx context.Context, req *entity.RefreshTokenRequest) (*entity.UserLoginResponse, error)
+	Logout(ctx context.Context, sessionId string) error
+	LogoutAll(ctx context.Context, userId uint) error
+	GetLoginLockouts(ctx context.Context, page, size int) (*utils.Response, int64, int64, error)
+	ForgotPassword(ctx context.Context, req *entity.ForgotPasswordRequest)
+	ConfirmPasswordReset(ctx context.Context, req *entity.ConfirmPasswordResetRequest) error
+	SendEmailVerification(ctx context.Context, userId uint) error
+	VerifyEmail(ctx context.Context, req *entity.VerifyEmailRequest) error
+	// ChangePassword signs out every session but sessionId, the one making
+	// the change. An empty sessionId signs out all of them.
+	ChangePassword(ctx context.Context, userId uint, sessionId string, req *entity.UserChangePassword) (*entity.UserChangePasswordResponse, error)
+	AssignAsAdmin(ctx context.Context, actorId, userId uint) error
+	GetAllUsers(ctx context.Context, filter entity.UserFilter, page, size int) (*utils.Response, int64, int64, error)
+	GetUserDetail(ctx context.Context, id uint) (*utils.Response, error)
+	UpdateUser(ctx context.Context, id uint, req *entity.UpdateUserRequest) (*utils.Response, error)
+	SetUserBlocked(ctx context.Context, actorId, id uint, blocked bool) (*utils.Response, error)
+	ChangeUserRole(ctx context.Context, actorId, id uint, req *entity.ChangeRoleRequest) (*utils.Response, error)
+	ResetUserPassword(ctx context.Context, id uint, req *entity.ResetPasswordRequest) error
 }
 
-func NewUserService(r repository.UserRepository) UserService {
+func NewUserService(txManager utils.TxManager, r repository.UserRepository, rr repository.RoleRepository, sr repository.SessionRepository, utr repository.UserTokenRepository, tfr repository.TwoFactorRepository, akr repository.ApiKeyRepository, las repository.LoginAttemptStore, llr repository.LoginLockoutRepository, m mailer.Mailer, audit AuditService) UserService {
 	return &userService{
-		Repository: r,
+		txManager:              txManager,
+		Repository:             r,
+		RoleRepository:         rr,
+		SessionRepository:      sr,
+		UserTokenRepository:    utr,
+		TwoFactorRepository:    tfr,
+		ApiKeyRepository:       akr,
+		LoginAttemptStore:      las,
+		LoginLockoutRepository: llr,
+		Mailer:                 m,
+		Audit:                  audit,
 	}
 }
 
-func (s *userService) GetUserById(ctx *gin.Context, userId uint) (*entity.UserDataResponse, error) {
+func (s *userService) GetUserById(ctx context.Context, userId uint) (*entity.UserDataResponse, error) {
 	user, err := s.Repository.FindById(ctx, userId)
 	if err != nil {
 		return nil, err
 	}
 
-	return &entity.UserDataResponse{
-		Id:          int(user.ID),
-		Username:    user.Username,
-		Firstname:   user.Firstname,
-		Lastname:    user.Lastname,
-		Dob:         user.Dob,
-		Address:     user.Address,
-		Email:       user.Email,
-		Phonenumber: user.Phonenumber,
-		Role:        user.Role,
-		CreatedAt:   user.CreatedAt,
-		UpdatedAt:   user.UpdatedAt,
-	}, nil
+	return toUserDataResponse(user), nil
 }
 
-func (s *userService) Register(ctx *gin.Context, req *entity.UserRegisterRequest) (*entity.UserDataResponse, error) {
+func (s *userService) Register(ctx context.Context, req *entity.UserRegisterRequest) (*entity.UserDataResponse, error) {
 
 	var user *entity.User
 	var err error
@@ -91,85 +153,908 @@ func (s *userService) Register(ctx *gin.Context, req *entity.UserRegisterRequest
 		return nil, err
 	}
 
-	if err := s.Repository.Create(ctx, user); err != nil {
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.Repository.Create(ctx, user); err != nil {
+			return err
+		}
+
+		return s.Audit.Record(ctx, entity.AuditCreate, entity.AuditUser, user.ID, nil, user)
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	return &entity.UserDataResponse{
-		Id:          int(user.ID),
-		Username:    user.Username,
-		Firstname:   user.Firstname,
-		Lastname:    user.Lastname,
-		Dob:         user.Dob,
-		Address:     user.Address,
-		Email:       user.Email,
-		Phonenumber: user.Phonenumber,
-		Role:        user.Role,
-		CreatedAt:   user.CreatedAt,
-		UpdatedAt:   user.UpdatedAt,
-	}, nil
+	// The account exists either way; the user can ask for another mail.
+	if err := s.sendEmailVerification(ctx, user); err != nil {
+		log.Printf("Sending verification email to user %d failed. Error : %v", user.ID, err)
+	}
+
+	return toUserDataResponse(user), nil
 }
 
-func (s *userService) Login(ctx *gin.Context, req *entity.UserLoginRequest) (*entity.UserLoginResponse, error) {
-	user, err := s.Repository.FindByUsername(ctx, req.Username)
+// Login answers an unknown username and a wrong password with the same
+// error after the same bcrypt work, so it cannot be used to find accounts.
+// Failures are counted per username and per IP; see reserveLoginAttempt.
+func (s *userService) Login(ctx context.Context, req *entity.UserLoginRequest) (*entity.UserLoginResponse, error) {
+	accountLimit := loginLimit{"user:" + strings.ToLower(req.Username), accountBackoffAfter, accountLockoutAfter}
+	ipLimit := loginLimit{"ip:" + utils.GetCaller(ctx).IP, ipBackoffAfter, ipLockoutAfter}
+
+	attempts, err := reserveLoginAttempt(ctx, s.LoginAttemptStore, accountLimit, ipLimit)
 	if err != nil {
-		return nil, errors.New("username/password is invalid")
+		return nil, err
+	}
+
+	user, err := s.Repository.FindByUsername(ctx, req.Username)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if user == nil {
+		bcrypt.CompareHashAndPassword(getDummyPasswordHash(), []byte(req.Password))
+		return nil, s.recordLoginLockouts(ctx, req.Username, attempts, accountLimit, ipLimit)
 	}
 
 	if err := user.CheckPassword(req.Password); err != nil {
-		return nil, errors.New("invalid password")
+		return nil, s.recordLoginLockouts(ctx, req.Username, attempts, accountLimit, ipLimit)
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"userId": user.ID,
-		"role":   user.Role,
-		"exp":    time.Now().Add(config.GetJWTExpirationDuration()).Unix(),
+	if err := s.LoginAttemptStore.Reset(ctx, accountLimit.key); err != nil {
+		return nil, err
+	}
+	if err := s.LoginAttemptStore.Release(ctx, ipLimit.key, ipLimit.backoff); err != nil {
+		return nil, err
+	}
+
+	return s.continueLogin(ctx, user)
+}
+
+// ContinueLogin takes a user whose identity was proven some other way, as
+// by single sign-on, through the same second factor step as Login.
+func (s *userService) ContinueLogin(ctx context.Context, userId uint) (*entity.UserLoginResponse, error) {
+	user, err := s.findUser(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	return s.continueLogin(ctx, user)
+}
+
+// continueLogin returns an interim token when the user has 2FA enabled or
+// the policy requires it, and starts a session otherwise.
+func (s *userService) continueLogin(ctx context.Context, user *entity.User) (*entity.UserLoginResponse, error) {
+	if user.IsBlocked {
+		return nil, errors.New(errorMessages.ErrUserBlocked)
+	}
+
+	twoFactor, err := s.TwoFactorRepository.FindByUserId(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	enabled := twoFactor != nil && twoFactor.ConfirmedAt != nil
+	if enabled || requiresTwoFactor(user) {
+		token, err := s.issueTwoFactorToken(ctx, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		return &entity.UserLoginResponse{
+			ExpiresIn:          int64(twoFactorTokenTTL.Seconds()),
+			TwoFactorRequired:  true,
+			EnrollmentRequired: !enabled,
+			TwoFactorToken:     token,
+		}, nil
+	}
+
+	return s.startSession(ctx, user)
+}
+
+// issueTwoFactorToken returns an interim token for the second login step
+// and stores its jti, so the token can only be redeemed once.
+func (s *userService) issueTwoFactorToken(ctx context.Context, userId uint) (string, error) {
+	now := time.Now()
+	token, jti, err := newTwoFactorToken(userId, now)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.UserTokenRepository.Create(ctx, &entity.UserToken{
+		UserId:    userId,
+		Purpose:   entity.TwoFactorLoginToken,
+		TokenHash: utils.HashToken(jti),
+		ExpiresAt: now.Add(twoFactorTokenTTL),
 	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
 
-	tokenString, err := token.SignedString(config.GetJWTSecret())
+// StartSession logs in a user who has already passed every factor.
+func (s *userService) StartSession(ctx context.Context, userId uint) (*entity.UserLoginResponse, error) {
+	user, err := s.findUser(ctx, userId)
 	if err != nil {
 		return nil, err
 	}
 
-	return &entity.UserLoginResponse{Token: tokenString}, nil
+	if user.IsBlocked {
+		return nil, errors.New(errorMessages.ErrUserBlocked)
+	}
+
+	return s.startSession(ctx, user)
 }
 
-func (s *userService) ChangePassword(ctx *gin.Context, userId uint, req *entity.UserChangePassword) (*entity.UserChangePasswordResponse, error) {
-	user, err := s.Repository.FindById(ctx, userId)
+func (s *userService) startSession(ctx context.Context, user *entity.User) (*entity.UserLoginResponse, error) {
+	caller := utils.GetCaller(ctx)
+	session := &entity.Session{
+		ID:        uuid.NewString(),
+		UserId:    user.ID,
+		IP:        caller.IP,
+		UserAgent: caller.UserAgent,
+	}
+
+	var resp *entity.UserLoginResponse
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.SessionRepository.CreateSession(ctx, session); err != nil {
+			return err
+		}
+
+		var err error
+		resp, err = s.issueTokens(ctx, user, session.ID)
+		return err
+	})
 	if err != nil {
-		return nil, errors.New("user not found")
+		return nil, err
 	}
 
-	if err := user.CheckPassword(req.OldPassword); err != nil {
-		return nil, errors.New("invalid old password")
+	return resp, nil
+}
+
+// RefreshToken rotates a refresh token: the presented token is marked used
+// and a new pair is issued for the same session. Presenting a token that was
+// already used means it leaked, so the whole session is revoked.
+func (s *userService) RefreshToken(ctx context.Context, req *entity.RefreshTokenRequest) (*entity.UserLoginResponse, error) {
+	refreshToken, err := s.SessionRepository.FindRefreshTokenByHash(ctx, utils.HashToken(req.RefreshToken))
+	if err != nil {
+		return nil, err
+	}
+	if refreshToken == nil {
+		return nil, errors.New(errorMessages.ErrInvalidRefreshToken)
 	}
 
-	if err := user.HashPassword(req.NewPassword); err != nil {
+	session, err := s.SessionRepository.FindById(ctx, refreshToken.SessionId)
+	if err != nil {
 		return nil, err
 	}
+	if session == nil || session.RevokedAt != nil {
+		return nil, errors.New(errorMessages.ErrSessionRevoked)
+	}
 
-	if err := s.Repository.Update(ctx, user); err != nil {
+	if refreshToken.UsedAt != nil {
+		if err := s.SessionRepository.RevokeSession(ctx, session.ID, entity.SessionTokenReused); err != nil {
+			return nil, err
+		}
+		return nil, errors.New(errorMessages.ErrRefreshTokenReused)
+	}
+
+	now := time.Now()
+	if now.After(refreshToken.ExpiresAt) {
+		return nil, errors.New(errorMessages.ErrInvalidRefreshToken)
+	}
+
+	user, err := s.Repository.FindById(ctx, session.UserId)
+	if err != nil {
+		return nil, errors.New(errorMessages.ErrUserNotExists)
+	}
+	if user.IsBlocked {
+		return nil, errors.New(errorMessages.ErrUserBlocked)
+	}
+
+	// Sessions opened before 2FA became mandatory end at the next refresh
+	// until the user enrols.
+	if requiresTwoFactor(user) {
+		twoFactor, err := s.TwoFactorRepository.FindByUserId(ctx, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		if twoFactor == nil || twoFactor.ConfirmedAt == nil {
+			return nil, errors.New(errorMessages.ErrTwoFactorRequired)
+		}
+	}
+
+	var resp *entity.UserLoginResponse
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		// Losing this race to a concurrent refresh with the same token is
+		// reuse as well.
+		claimed, err := s.SessionRepository.MarkRefreshTokenUsed(ctx, refreshToken.ID, now)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			return errors.New(errorMessages.ErrRefreshTokenReused)
+		}
+
+		resp, err = s.issueTokens(ctx, user, session.ID)
+		return err
+	})
+	if err != nil {
+		if err.Error() == errorMessages.ErrRefreshTokenReused {
+			if err := s.SessionRepository.RevokeSession(ctx, session.ID, entity.SessionTokenReused); err != nil {
+				return nil, err
+			}
+		}
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (s *userService) Logout(ctx context.Context, sessionId string) error {
+	return s.SessionRepository.RevokeSession(ctx, sessionId, entity.SessionLogout)
+}
+
+func (s *userService) LogoutAll(ctx context.Context, userId uint) error {
+	return s.SessionRepository.RevokeAllByUserId(ctx, userId, entity.SessionLogoutAll)
+}
+
+func (s *userService) ChangePassword(ctx context.Context, userId uint, sessionId string, req *entity.UserChangePassword) (*entity.UserChangePasswordResponse, error) {
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		user, err := s.Repository.FindByIdForUpdate(ctx, userId)
+		if err != nil {
+			return errors.New("user not found")
+		}
+
+		if err := user.CheckPassword(req.OldPassword); err != nil {
+			return errors.New("invalid old password")
+		}
+
+		before := utils.AuditSnapshot(user)
+		if err := user.HashPassword(req.NewPassword); err != nil {
+			return err
+		}
+
+		if err := s.Repository.Update(ctx, user); err != nil {
+			return err
+		}
+
+		if err := s.Audit.Record(ctx, entity.AuditUpdate, entity.AuditUser, user.ID, before, user); err != nil {
+			return err
+		}
+
+		if sessionId == "" {
+			return s.SessionRepository.RevokeAllByUserId(ctx, user.ID, entity.SessionPasswordNew)
+		}
+		return s.SessionRepository.RevokeOthersByUserId(ctx, user.ID, sessionId, entity.SessionPasswordNew)
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return &entity.UserChangePasswordResponse{Message: "Password changed successfully"}, nil
 }
 
-func (s *userService) AssignAsAdmin(ctx *gin.Context, userId uint) error {
-	user, err := s.Repository.FindById(ctx, userId)
+// AssignAsAdmin promotes a user and records the change like ChangeUserRole.
+func (s *userService) AssignAsAdmin(ctx context.Context, actorId, userId uint) error {
+	return s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		user, err := s.Repository.FindByIdForUpdate(ctx, userId)
+		if err != nil {
+			return errors.New("user not found")
+		}
+
+		if user.Role == entity.Admin {
+			return errors.New("user is already admin")
+		}
+
+		return s.updateRole(ctx, actorId, user, entity.Admin)
+	})
+}
+
+func (s *userService) GetAllUsers(ctx context.Context, filter entity.UserFilter, page, size int) (*utils.Response, int64, int64, error) {
+	users, totalSize, err := s.Repository.FindAllWithFilter(ctx, filter, page, size)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	data := make([]*entity.UserDataResponse, len(users))
+	for i := range users {
+		data[i] = toUserDataResponse(&users[i])
+	}
+
+	totalPage := utils.GetTotalPage(totalSize, size)
+
+	return &utils.Response{
+		Status:  200,
+		Message: "Success",
+		Data:    data,
+	}, totalSize, totalPage, nil
+}
+
+func (s *userService) GetUserDetail(ctx context.Context, id uint) (*utils.Response, error) {
+	user, err := s.findUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	roleChanges, err := s.Repository.FindRoleChangesByUserId(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	data := toUserDataResponse(user)
+	for _, roleChange := range roleChanges {
+		data.RoleChanges = append(data.RoleChanges, entity.RoleChangeResponse{
+			FromRole:  roleChange.FromRole,
+			ToRole:    roleChange.ToRole,
+			ChangedBy: roleChange.ChangedBy,
+			CreatedAt: roleChange.CreatedAt,
+		})
+	}
+
+	return &utils.Response{
+		Status:  200,
+		Message: "Success",
+		Data:    data,
+	}, nil
+}
+
+func (s *userService) UpdateUser(ctx context.Context, id uint, req *entity.UpdateUserRequest) (*utils.Response, error) {
+	var dob time.Time
+	if req.Dob != "" {
+		var err error
+		dob, err = time.Parse("2006-01-02", req.Dob)
+		if err != nil {
+			return nil, errors.New("invalid date format")
+		}
+	}
+
+	var user *entity.User
+	var emailChanged bool
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		user, err = s.findUserForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		before := utils.AuditSnapshot(user)
+
+		if req.Dob != "" {
+			user.Dob = dob
+		}
+
+		// A new email has to be verified again.
+		emailChanged = req.Email != "" && req.Email != user.Email
+		if emailChanged {
+			existing, err := s.Repository.FindByEmail(ctx, req.Email)
+			if existing != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("email already taken")
+			}
+			user.Email = req.Email
+			user.EmailVerifiedAt = nil
+		}
+
+		if req.Phonenumber != "" && req.Phonenumber != user.Phonenumber {
+			existing, err := s.Repository.FindByPhoneNumber(ctx, req.Phonenumber)
+			if existing != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("phone number already taken")
+			}
+			user.Phonenumber = req.Phonenumber
+		}
+
+		if req.Firstname != "" {
+			user.Firstname = req.Firstname
+		}
+		if req.Lastname != "" {
+			user.Lastname = req.Lastname
+		}
+		if req.Address != "" {
+			user.Address = req.Address
+		}
+
+		if err := s.Repository.Update(ctx, user); err != nil {
+			return err
+		}
+
+		return s.Audit.Record(ctx, entity.AuditUpdate, entity.AuditUser, user.ID, before, user)
+	})
 	if err != nil {
-		return errors.New("user not found")
+		return nil, err
+	}
+
+	if emailChanged {
+		if err := s.sendEmailVerification(ctx, user); err != nil {
+			log.Printf("Sending verification email to user %d failed. Error : %v", user.ID, err)
+		}
+	}
+
+	return &utils.Response{
+		Status:  200,
+		Message: "User successfully updated",
+		Data:    toUserDataResponse(user),
+	}, nil
+}
+
+// SetUserBlocked enables or disables an account. Blocking also revokes the
+// user's sessions and API keys in the same transaction, so nothing issued
+// before the block keeps working.
+func (s *userService) SetUserBlocked(ctx context.Context, actorId, id uint, blocked bool) (*utils.Response, error) {
+	if actorId == id {
+		return nil, errors.New(errorMessages.ErrOwnAccountChange)
+	}
+
+	var user *entity.User
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		user, err = s.findUserForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		before := utils.AuditSnapshot(user)
+		user.IsBlocked = blocked
+		if err := s.Repository.Update(ctx, user); err != nil {
+			return err
+		}
+
+		if blocked {
+			if err := s.SessionRepository.RevokeAllByUserId(ctx, user.ID, entity.SessionBlocked); err != nil {
+				return err
+			}
+			if err := s.ApiKeyRepository.RevokeAllByUserId(ctx, user.ID); err != nil {
+				return err
+			}
+		}
+
+		return s.Audit.Record(ctx, entity.AuditUpdate, entity.AuditUser, user.ID, before, user)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	if user.Role == entity.Admin {
-		return errors.New("user is already admin")
+	message := "User enabled"
+	if blocked {
+		message = "User disabled"
 	}
 
-	user.Role = entity.Admin
+	return &utils.Response{
+		Status:  200,
+		Message: message,
+		Data:    toUserDataResponse(user),
+	}, nil
+}
+
+func (s *userService) ChangeUserRole(ctx context.Context, actorId, id uint, req *entity.ChangeRoleRequest) (*utils.Response, error) {
+	// Admins cannot demote themselves, so there is always someone left who
+	// can manage roles.
+	if actorId == id {
+		return nil, errors.New(errorMessages.ErrOwnAccountChange)
+	}
+
+	var user *entity.User
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		// Hold the role so it cannot be deleted while it is being assigned.
+		role, err := s.RoleRepository.FindByNameForShare(ctx, req.Role)
+		if err != nil {
+			return err
+		}
+		if role == nil {
+			return errors.New(errorMessages.ErrInvalidRole)
+		}
+
+		user, err = s.findUserForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if user.Role == req.Role {
+			return errors.New(errorMessages.ErrUserRoleUnchanged)
+		}
+
+		return s.updateRole(ctx, actorId, user, req.Role)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &utils.Response{
+		Status:  200,
+		Message: "User role updated",
+		Data:    toUserDataResponse(user),
+	}, nil
+}
+
+func (s *userService) ResetUserPassword(ctx context.Context, id uint, req *entity.ResetPasswordRequest) error {
+	return s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		user, err := s.findUserForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		before := utils.AuditSnapshot(user)
+		if err := user.HashPassword(req.NewPassword); err != nil {
+			return err
+		}
+
+		if err := s.Repository.Update(ctx, user); err != nil {
+			return err
+		}
+
+		if err := s.Audit.Record(ctx, entity.AuditUpdate, entity.AuditUser, user.ID, before, user); err != nil {
+			return err
+		}
+
+		return s.SessionRepository.RevokeAllByUserId(ctx, user.ID, entity.SessionPasswordSet)
+	})
+}
+
+// loginLimit throttles failed attempts counted under key: they back off
+// from backoffAfter failures and lock out at lockoutAfter.
+type loginLimit struct {
+	key          string
+	backoffAfter int
+	lockoutAfter int
+}
+
+func (l loginLimit) backoff(failures int) time.Duration {
+	return loginBackoff(failures, l.backoffAfter, l.lockoutAfter)
+}
+
+// reserveLoginAttempt counts the attempt as failed under every limit before
+// the credentials are checked, so parallel guesses cannot all get in before
+// a lockout applies; a successful attempt gives its reservation back. While
+// any limit is locked the attempt is refused and nothing is counted. The IP
+// limit catches one client guessing across many usernames.
+func reserveLoginAttempt(ctx context.Context, store repository.LoginAttemptStore, limits ...loginLimit) ([]*entity.LoginAttempt, error) {
+	now := time.Now()
+	attempts := make([]*entity.LoginAttempt, 0, len(limits))
+
+	for _, limit := range limits {
+		attempt, counted, err := store.RecordFailure(ctx, limit.key, now, loginAttemptWindow, limit.backoff)
+		if err == nil && counted {
+			attempts = append(attempts, attempt)
+			continue
+		}
+
+		for i, reserved := range attempts {
+			if err := store.Release(ctx, reserved.Key, limits[i].backoff); err != nil {
+				log.Printf("Releasing login attempt for %s failed. Error : %v", reserved.Key, err)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		return nil, &LoginThrottledError{RetryAfter: attempt.LockedUntil.Sub(now)}
+	}
+
+	return attempts, nil
+}
+
+// recordLoginLockouts keeps a record of every limit the failed attempt
+// locked out. It returns the error for the caller.
+func (s *userService) recordLoginLockouts(ctx context.Context, username string, attempts []*entity.LoginAttempt, limits ...loginLimit) error {
+	for i, attempt := range attempts {
+		if attempt.Failures < limits[i].lockoutAfter || attempt.LockedUntil == nil {
+			continue
+		}
+
+		err := s.LoginLockoutRepository.Create(ctx, &entity.LoginLockout{
+			Key:         attempt.Key,
+			Username:    username,
+			IP:          utils.GetCaller(ctx).IP,
+			Failures:    attempt.Failures,
+			LockedUntil: *attempt.LockedUntil,
+		})
+		if err != nil {
+			log.Printf("Recording login lockout for %s failed. Error : %v", attempt.Key, err)
+		}
+	}
+
+	return errors.New(errorMessages.ErrInvalidCredentials)
+}
+
+// loginBackoff doubles the wait from one second for every failure past
+// backoffAfter, up to maxLoginBackoff, and locks out for
+// loginLockoutDuration once failures reach lockoutAfter.
+func loginBackoff(failures, backoffAfter, lockoutAfter int) time.Duration {
+	if failures >= lockoutAfter {
+		return loginLockoutDuration
+	}
+	if failures < backoffAfter {
+		return 0
+	}
+	return min(time.Second<<(failures-backoffAfter), maxLoginBackoff)
+}
+
+var (
+	dummyPasswordHash     []byte
+	dummyPasswordHashOnce sync.Once
+)
+
+// getDummyPasswordHash is compared against for unknown usernames so they
+// take as long to reject as wrong passwords.
+func getDummyPasswordHash() []byte {
+	dummyPasswordHashOnce.Do(func() {
+		dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+	})
+	return dummyPasswordHash
+}
+
+func (s *userService) GetLoginLockouts(ctx context.Context, page, size int) (*utils.Response, int64, int64, error) {
+	lockouts, totalSize, err := s.LoginLockoutRepository.FindAll(ctx, page, size)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	totalPage := utils.GetTotalPage(totalSize, size)
+
+	return &utils.Response{
+		Status:  200,
+		Message: "Success",
+		Data:    lockouts,
+	}, totalSize, totalPage, nil
+}
+
+// ForgotPassword mails a reset link. It looks up the email and sends in the
+// background, so neither the response nor its timing tells which emails
+// have accounts.
+func (s *userService) ForgotPassword(ctx context.Context, req *entity.ForgotPasswordRequest) {
+	// The request context ends with the response; keep only the caller.
+	ctx = utils.WithCaller(context.Background(), utils.GetCaller(ctx))
+	email := req.Email
+
+	go func() {
+		if err := s.sendPasswordReset(ctx, email); err != nil {
+			log.Printf("Sending password reset email failed. Error : %v", err)
+		}
+	}()
+}
+
+func (s *userService) sendPasswordReset(ctx context.Context, email string) error {
+	user, err := s.Repository.FindByEmail(ctx, email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if user.IsBlocked {
+		return nil
+	}
+
+	token, err := s.issueUserToken(ctx, user.ID, entity.PasswordResetToken, passwordResetTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("Hi %s,\n\nUse the link below to choose a new password. It expires in %s.\n\n%s/reset-password?token=%s\n\nIf you did not ask for this, you can ignore this email.\n",
+		user.Firstname, passwordResetTokenTTL, config.GetAppURL(), token)
+	return s.Mailer.Send(user.Email, "Reset your password", body)
+}
+
+// ConfirmPasswordReset sets the new password and signs the user out
+// everywhere, since whoever held the old password may still be logged in.
+func (s *userService) ConfirmPasswordReset(ctx context.Context, req *entity.ConfirmPasswordResetRequest) error {
+	return s.redeemUserToken(ctx, entity.PasswordResetToken, req.Token, func(ctx context.Context, user *entity.User) error {
+		if err := user.HashPassword(req.NewPassword); err != nil {
+			return err
+		}
+		if err := s.Repository.Update(ctx, user); err != nil {
+			return err
+		}
+		return s.SessionRepository.RevokeAllByUserId(ctx, user.ID, entity.SessionPasswordSet)
+	})
+}
+
+func (s *userService) SendEmailVerification(ctx context.Context, userId uint) error {
+	user, err := s.findUser(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	if user.EmailVerifiedAt != nil {
+		return errors.New(errorMessages.ErrEmailAlreadyVerified)
+	}
+
+	return s.sendEmailVerification(ctx, user)
+}
+
+func (s *userService) VerifyEmail(ctx context.Context, req *entity.VerifyEmailRequest) error {
+	return s.redeemUserToken(ctx, entity.EmailVerificationToken, req.Token, func(ctx context.Context, user *entity.User) error {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+		return s.Repository.Update(ctx, user)
+	})
+}
+
+func (s *userService) sendEmailVerification(ctx context.Context, user *entity.User) error {
+	token, err := s.issueUserToken(ctx, user.ID, entity.EmailVerificationToken, emailVerificationTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("Hi %s,\n\nPlease confirm your email address. The link expires in %s.\n\n%s/verify-email?token=%s\n",
+		user.Firstname, emailVerificationTokenTTL, config.GetAppURL(), token)
+	return s.Mailer.Send(user.Email, "Verify your email address", body)
+}
+
+// issueUserToken replaces any outstanding token of the purpose with a new
+// one and returns the plain token for mailing.
+func (s *userService) issueUserToken(ctx context.Context, userId uint, purpose entity.TokenPurpose, ttl time.Duration) (string, error) {
+	token, err := utils.NewOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.UserTokenRepository.InvalidateByUserId(ctx, userId, purpose); err != nil {
+			return err
+		}
+
+		err = s.UserTokenRepository.Create(ctx, &entity.UserToken{
+			UserId:    userId,
+			Purpose:   purpose,
+			TokenHash: utils.HashToken(token),
+			ExpiresAt: time.Now().Add(ttl),
+		})
+		if err != nil {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// redeemUserToken marks the token used and applies fn to its user in the
+// same transaction, so a failed update leaves the token usable.
+func (s *userService) redeemUserToken(ctx context.Context, purpose entity.TokenPurpose, token string, fn func(ctx context.Context, user *entity.User) error) error {
+	userToken, err := s.UserTokenRepository.FindByHash(ctx, purpose, utils.HashToken(token))
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if userToken == nil || userToken.UsedAt != nil || now.After(userToken.ExpiresAt) {
+		return errors.New(errorMessages.ErrInvalidUserToken)
+	}
+
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		claimed, err := s.UserTokenRepository.MarkUsed(ctx, userToken.ID, now)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			return errors.New(errorMessages.ErrInvalidUserToken)
+		}
+
+		user, err := s.findUserForUpdate(ctx, userToken.UserId)
+		if err != nil {
+			return err
+		}
+		before := utils.AuditSnapshot(user)
+
+		if err := fn(ctx, user); err != nil {
+			return err
+		}
+
+		return s.Audit.Record(ctx, entity.AuditUpdate, entity.AuditUser, user.ID, before, user)
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// issueTokens signs an access token for the session and stores a new
+// refresh token for it.
+func (s *userService) issueTokens(ctx context.Context, user *entity.User, sessionId string) (*entity.UserLoginResponse, error) {
+	now := time.Now()
+	expiresIn := config.GetJWTExpirationDuration()
+
+	signingKey, err := config.GetJWTSigningKey()
+	if err != nil {
+		return nil, err
+	}
+
+	token := jwt.NewWithClaims(signingKey.Method, jwt.MapClaims{
+		"userId": user.ID,
+		"role":   user.Role,
+		"sid":    sessionId,
+		"jti":    uuid.NewString(),
+		"iat":    now.Unix(),
+		"exp":    now.Add(expiresIn).Unix(),
+	})
+
+	if signingKey.KeyId != "" {
+		token.Header["kid"] = signingKey.KeyId
+	}
+
+	tokenString, err := token.SignedString(signingKey.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := utils.NewOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.SessionRepository.CreateRefreshToken(ctx, &entity.RefreshToken{
+		SessionId: sessionId,
+		TokenHash: utils.HashToken(refreshToken),
+		ExpiresAt: now.Add(config.GetRefreshTokenExpirationDuration()),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.UserLoginResponse{
+		Token:        tokenString,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(expiresIn.Seconds()),
+	}, nil
+}
+
+// updateRole saves the new role together with its RoleChange record. It
+// runs in the caller's transaction, which must hold the user locked.
+func (s *userService) updateRole(ctx context.Context, actorId uint, user *entity.User, role entity.Role) error {
+	roleChange := entity.RoleChange{
+		UserId:    user.ID,
+		FromRole:  user.Role,
+		ToRole:    role,
+		ChangedBy: actorId,
+	}
+	before := utils.AuditSnapshot(user)
+	user.Role = role
 
 	if err := s.Repository.Update(ctx, user); err != nil {
 		return err
 	}
 
-	return err
+	if err := s.Repository.CreateRoleChange(ctx, &roleChange); err != nil {
+		return err
+	}
+
+	return s.Audit.Record(ctx, entity.AuditUpdate, entity.AuditUser, user.ID, before, user)
+}
+
+func (s *userService) findUser(ctx context.Context, id uint) (*entity.User, error) {
+	user, err := s.Repository.FindById(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New(errorMessages.ErrUserNotExists)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *userService) findUserForUpdate(ctx context.Context, id uint) (*entity.User, error) {
+	user, err := s.Repository.FindByIdForUpdate(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New(errorMessages.ErrUserNotExists)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func toUserDataResponse(user *entity.User) *entity.UserDataResponse {
+	return &entity.UserDataResponse{
+		Id:              int(user.ID),
+		Username:        user.Username,
+		Firstname:       user.Firstname,
+		Lastname:        user.Lastname,
+		Dob:             user.Dob,
+		Address:         user.Address,
+		Email:           user.Email,
+		Phonenumber:     user.Phonenumber,
+		Role:            user.Role,
+		IsBlocked:       user.IsBlocked,
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		CreatedAt:       user.CreatedAt,
+		UpdatedAt:       user.UpdatedAt,
+	}
 }
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"go-trades/entity"
+	"go-trades/repository"
+	errorMessages "go-trades/utils/error-messages"
+	"testing"
+)
+
+// fakeSessionRepository records which users had all their sessions revoked.
+type fakeSessionRepository struct {
+	repository.SessionRepository
+	revoked map[uint]string
+}
+
+func (r *fakeSessionRepository) snapshot() func() {
+	revoked := make(map[uint]string, len(r.revoked))
+	for userId, reason := range r.revoked {
+		revoked[userId] = reason
+	}
+	return func() {
+		r.revoked = revoked
+	}
+}
+
+func (r *fakeSessionRepository) RevokeAllByUserId(ctx context.Context, userId uint, reason string) error {
+	r.revoked[userId] = reason
+	return nil
+}
+
+// fakeApiKeyRepository records which users had their API keys revoked.
+type fakeApiKeyRepository struct {
+	repository.ApiKeyRepository
+	revoked map[uint]bool
+}
+
+func (r *fakeApiKeyRepository) snapshot() func() {
+	revoked := make(map[uint]bool, len(r.revoked))
+	for userId := range r.revoked {
+		revoked[userId] = true
+	}
+	return func() {
+		r.revoked = revoked
+	}
+}
+
+func (r *fakeApiKeyRepository) RevokeAllByUserId(ctx context.Context, userId uint) error {
+	r.revoked[userId] = true
+	return nil
+}
+
+type userFixture struct {
+	users    *fakeUserRepository
+	sessions *fakeSessionRepository
+	apiKeys  *fakeApiKeyRepository
+	audit    *fakeAuditRepository
+	svc      UserService
+}
+
+// newUserFixture has admin 1, active user 2 and blocked user 3.
+func newUserFixture() *userFixture {
+	f := &userFixture{
+		users:    newFakeUserRepository(testUser(1, false), testUser(2, false), testUser(3, true)),
+		sessions: &fakeSessionRepository{revoked: make(map[uint]string)},
+		apiKeys:  &fakeApiKeyRepository{revoked: make(map[uint]bool)},
+		audit:    &fakeAuditRepository{},
+	}
+	txManager := &fakeTxManager{stores: []fakeStore{f.users, f.sessions, f.apiKeys, f.audit}}
+	f.svc = NewUserService(txManager, f.users, nil, f.sessions, nil, nil, f.apiKeys, nil, nil, nil, NewAuditService(f.audit))
+	return f
+}
+
+func TestSetUserBlockedRevokesAccess(t *testing.T) {
+	f := newUserFixture()
+
+	if _, err := f.svc.SetUserBlocked(context.Background(), 1, 2, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if !f.users.users[2].IsBlocked {
+		t.Error("user was not blocked")
+	}
+	if reason, ok := f.sessions.revoked[2]; !ok || reason != entity.SessionBlocked {
+		t.Errorf("sessions revoked = %v, want user 2 revoked as %s", f.sessions.revoked, entity.SessionBlocked)
+	}
+	if !f.apiKeys.revoked[2] {
+		t.Error("API keys were not revoked")
+	}
+	if len(f.audit.entries) != 1 {
+		t.Errorf("recorded %d audit entries, want 1", len(f.audit.entries))
+	}
+}
+
+func TestSetUserBlockedUnblockKeepsAccess(t *testing.T) {
+	f := newUserFixture()
+
+	if _, err := f.svc.SetUserBlocked(context.Background(), 1, 3, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if f.users.users[3].IsBlocked {
+		t.Error("user is still blocked")
+	}
+	if len(f.sessions.revoked) != 0 || len(f.apiKeys.revoked) != 0 {
+		t.Errorf("unblocking revoked sessions %v and API keys %v", f.sessions.revoked, f.apiKeys.revoked)
+	}
+}
+
+func TestSetUserBlockedRejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		actorId uint
+		id      uint
+		err     string
+	}{
+		{"own account", 1, 1, errorMessages.ErrOwnAccountChange},
+		{"missing user", 1, 9, errorMessages.ErrUserNotExists},
+	}
+
+	for _, tt := range tests {
+		f := newUserFixture()
+
+		_, err := f.svc.SetUserBlocked(context.Background(), tt.actorId, tt.id, true)
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("%s: error = %v, want %s", tt.name, err, tt.err)
+		}
+		if f.users.users[1].IsBlocked {
+			t.Errorf("%s: admin was blocked", tt.name)
+		}
+		if len(f.sessions.revoked) != 0 || len(f.apiKeys.revoked) != 0 || len(f.audit.entries) != 0 {
+			t.Errorf("%s: a rejected block wrote changes", tt.name)
+		}
+	}
+}
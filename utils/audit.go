@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// auditIgnoredFields change on every write and would only add noise.
+var auditIgnoredFields = map[string]bool{
+	"CreatedAt": true,
+	"UpdatedAt": true,
+	"DeletedAt": true,
+}
+
+// auditRedactedFields never have their values written to the audit log.
+var auditRedactedFields = []string{"password", "secret", "hash", "token"}
+
+// AuditSnapshot captures the JSON form of v, so later changes to v do not
+// alter it. Snapshot a record before modifying it.
+func AuditSnapshot(v interface{}) map[string]interface{} {
+	if v == nil {
+		return nil
+	}
+	if m, ok := v.(map[string]interface{}); ok {
+		return m
+	}
+
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil
+	}
+	return m
+}
+
+// AuditDiff returns the changed fields of two snapshots as JSON of the form
+// {"field": {"before": x, "after": y}}. A nil before describes a create and
+// a nil after a delete.
+func AuditDiff(before, after map[string]interface{}) (string, error) {
+	keys := make(map[string]bool)
+	for key := range before {
+		keys[key] = true
+	}
+	for key := range after {
+		keys[key] = true
+	}
+
+	names := make([]string, 0, len(keys))
+	for key := range keys {
+		names = append(names, key)
+	}
+	sort.Strings(names)
+
+	diff := make(map[string]map[string]interface{})
+	for _, key := range names {
+		if auditIgnoredFields[key] {
+			continue
+		}
+
+		oldValue, hadOld := before[key]
+		newValue, hasNew := after[key]
+		if hadOld && hasNew && reflect.DeepEqual(oldValue, newValue) {
+			continue
+		}
+		if isEmptyAuditValue(oldValue) && isEmptyAuditValue(newValue) {
+			continue
+		}
+
+		if auditRedacted(key) {
+			oldValue, newValue = "[redacted]", "[redacted]"
+		}
+
+		change := make(map[string]interface{})
+		if before != nil {
+			change["before"] = oldValue
+		}
+		if after != nil {
+			change["after"] = newValue
+		}
+		diff[key] = change
+	}
+
+	raw, err := json.Marshal(diff)
+	if err != nil {
+		return "", err
+	}
+	return string(raw), nil
+}
+
+func auditRedacted(key string) bool {
+	key = strings.ToLower(key)
+	for _, field := range auditRedactedFields {
+		if strings.Contains(key, field) {
+			return true
+		}
+	}
+	return false
+}
+
+func isEmptyAuditValue(v interface{}) bool {
+	switch value := v.(type) {
+	case nil:
+		return true
+	case []interface{}:
+		return len(value) == 0
+	}
+	return false
+}
@@ -1,23 +1,103 @@
 package utils
 
 import (
-	"github.com/gin-gonic/gin"
+	"context"
+
 	"gorm.io/gorm"
 )
 
-const ctxTxKey = "db_tx"
+type txKey struct{}
+
+type beforeCommitKey struct{}
+
+type callerKey struct{}
+
+// TxManager runs a unit of work in one database transaction. The
+// transaction travels in the context handed to fn, so every repository call
+// made with that context joins it. fn's error or panic rolls it back; a
+// unit of work started inside another runs in a savepoint. Work deferred
+// with BeforeCommit runs once the outermost fn has returned.
+type TxManager interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type txManager struct {
+	db *gorm.DB
+}
+
+func NewTxManager(db *gorm.DB) TxManager {
+	return &txManager{
+		db: db,
+	}
+}
+
+func (m *txManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	parent, nested := ctx.Value(beforeCommitKey{}).(*[]func(ctx context.Context) error)
 
-func WithTx(ctx *gin.Context, tx *gorm.DB) {
-	ctx.Set(ctxTxKey, tx)
+	return GetTx(ctx, m.db).Transaction(func(tx *gorm.DB) error {
+		var hooks []func(ctx context.Context) error
+		ctx := context.WithValue(context.WithValue(ctx, txKey{}, tx), beforeCommitKey{}, &hooks)
+		if err := fn(ctx); err != nil {
+			return err
+		}
+
+		// A savepoint hands its hooks to the enclosing transaction, so they
+		// are only dropped if the savepoint is rolled back.
+		if nested {
+			*parent = append(*parent, hooks...)
+			return nil
+		}
+
+		// A hook may defer further work of its own.
+		for i := 0; i < len(hooks); i++ {
+			if err := hooks[i](ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
-func GetTx(ctx *gin.Context, db *gorm.DB) *gorm.DB {
-	tx, exists := ctx.Get(ctxTxKey)
-	if !exists {
-		return db
+// BeforeCommit defers fn to the end of the transaction in ctx: it runs in
+// that transaction after the rest of the unit of work, just before the
+// commit, and not at all if the transaction rolls back. Without a
+// transaction fn runs at once.
+func BeforeCommit(ctx context.Context, fn func(ctx context.Context) error) error {
+	hooks, ok := ctx.Value(beforeCommitKey{}).(*[]func(ctx context.Context) error)
+	if !ok {
+		return fn(ctx)
 	}
-	if dbTx, ok := tx.(*gorm.DB); ok {
-		return dbTx
+
+	*hooks = append(*hooks, fn)
+	return nil
+}
+
+// GetTx returns the transaction ctx is running in, or db bound to ctx when
+// there is none.
+func GetTx(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx
 	}
-	return db
+	return db.WithContext(ctx)
+}
+
+// Caller describes who a call into the services is made for. HTTP requests
+// fill it in from middleware; background jobs set what applies to them.
+type Caller struct {
+	UserId    uint
+	ApiKeyId  uint
+	RequestId string
+	IP        string
+	UserAgent string
+}
+
+func WithCaller(ctx context.Context, caller Caller) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// GetCaller returns the caller stored in ctx, or the zero Caller for calls
+// made on nobody's behalf.
+func GetCaller(ctx context.Context) Caller {
+	caller, _ := ctx.Value(callerKey{}).(Caller)
+	return caller
 }
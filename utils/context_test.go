@@ -0,0 +1,156 @@
+package utils
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// recordingPool stands in for the database and logs the statements that
+// frame a transaction.
+type recordingPool struct {
+	log *[]string
+}
+
+func (p recordingPool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (p recordingPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	*p.log = append(*p.log, strings.Fields(query)[0])
+	return driver.RowsAffected(0), nil
+}
+
+func (p recordingPool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return nil, errors.New("not supported")
+}
+
+func (p recordingPool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return nil
+}
+
+func (p recordingPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	*p.log = append(*p.log, "BEGIN")
+	return &recordingTx{p}, nil
+}
+
+type recordingTx struct {
+	recordingPool
+}
+
+func (t *recordingTx) Commit() error {
+	*t.log = append(*t.log, "COMMIT")
+	return nil
+}
+
+func (t *recordingTx) Rollback() error {
+	*t.log = append(*t.log, "ROLLBACK")
+	return nil
+}
+
+func newRecordingTxManager(t *testing.T) (TxManager, *[]string) {
+	t.Helper()
+	log := &[]string{}
+	db, err := gorm.Open(mysql.New(mysql.Config{Conn: recordingPool{log}, SkipInitializeWithVersion: true}), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewTxManager(db), log
+}
+
+func TestBeforeCommitRunsAtEndOfTransaction(t *testing.T) {
+	m, log := newRecordingTxManager(t)
+
+	err := m.WithinTx(context.Background(), func(ctx context.Context) error {
+		*log = append(*log, "work")
+		if err := BeforeCommit(ctx, func(ctx context.Context) error {
+			*log = append(*log, "hook 1")
+			return BeforeCommit(ctx, func(ctx context.Context) error {
+				*log = append(*log, "hook 3")
+				return nil
+			})
+		}); err != nil {
+			return err
+		}
+
+		err := m.WithinTx(ctx, func(ctx context.Context) error {
+			return BeforeCommit(ctx, func(ctx context.Context) error {
+				*log = append(*log, "hook 2")
+				return nil
+			})
+		})
+		*log = append(*log, "more work")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"BEGIN", "work", "SAVEPOINT", "more work", "hook 1", "hook 2", "hook 3", "COMMIT"}
+	if !reflect.DeepEqual(*log, want) {
+		t.Errorf("log = %v, want %v", *log, want)
+	}
+}
+
+func TestBeforeCommitDroppedOnRollback(t *testing.T) {
+	m, log := newRecordingTxManager(t)
+	failed := errors.New("failed")
+
+	err := m.WithinTx(context.Background(), func(ctx context.Context) error {
+		err := m.WithinTx(ctx, func(ctx context.Context) error {
+			if err := BeforeCommit(ctx, func(ctx context.Context) error {
+				*log = append(*log, "rolled back savepoint hook")
+				return nil
+			}); err != nil {
+				return err
+			}
+			return failed
+		})
+		if !errors.Is(err, failed) {
+			t.Errorf("savepoint error = %v, want %v", err, failed)
+		}
+		return BeforeCommit(ctx, func(ctx context.Context) error {
+			*log = append(*log, "hook")
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = m.WithinTx(context.Background(), func(ctx context.Context) error {
+		if err := BeforeCommit(ctx, func(ctx context.Context) error {
+			*log = append(*log, "rolled back hook")
+			return nil
+		}); err != nil {
+			return err
+		}
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Errorf("error = %v, want %v", err, failed)
+	}
+
+	want := []string{"BEGIN", "SAVEPOINT", "ROLLBACK", "hook", "COMMIT", "BEGIN", "ROLLBACK"}
+	if !reflect.DeepEqual(*log, want) {
+		t.Errorf("log = %v, want %v", *log, want)
+	}
+}
+
+func TestBeforeCommitWithoutTransaction(t *testing.T) {
+	ran := false
+	err := BeforeCommit(context.Background(), func(ctx context.Context) error {
+		ran = true
+		return nil
+	})
+	if err != nil || !ran {
+		t.Errorf("ran = %v, err = %v, want it run at once", ran, err)
+	}
+}
@@ -0,0 +1,147 @@
+package utils
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSchedule is a parsed five-field cron expression
+// (minute hour day-of-month month day-of-week). Each field accepts "*",
+// numbers, ranges ("1-5"), lists ("1,15") and steps ("*/10", "0-30/5").
+type CronSchedule struct {
+	minutes    map[int]bool
+	hours      map[int]bool
+	daysOfMon  map[int]bool
+	months     map[int]bool
+	daysOfWeek map[int]bool
+	anyDom     bool
+	anyDow     bool
+}
+
+var errInvalidCron = errors.New("invalid cron expression")
+
+func ParseCron(expr string) (*CronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, errInvalidCron
+	}
+
+	var err error
+	c := &CronSchedule{
+		anyDom: fields[2] == "*",
+		anyDow: fields[4] == "*",
+	}
+	if c.minutes, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, err
+	}
+	if c.hours, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, err
+	}
+	if c.daysOfMon, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, err
+	}
+	if c.months, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, err
+	}
+	if c.daysOfWeek, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, err
+	}
+	if c.daysOfWeek[7] {
+		c.daysOfWeek[0] = true
+	}
+
+	return c, nil
+}
+
+// Next returns the first matching minute strictly after t, or the zero time
+// if nothing matches within five years (e.g. "0 0 30 2 *").
+func (c *CronSchedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if !c.months[int(t.Month())] {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.hours[t.Hour()] {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.minutes[t.Minute()] {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+
+	return time.Time{}
+}
+
+// matchDay follows the classic cron rule: when both day fields are
+// restricted, a day matching either one is enough.
+func (c *CronSchedule) matchDay(t time.Time) bool {
+	dom := c.daysOfMon[t.Day()]
+	dow := c.daysOfWeek[int(t.Weekday())]
+
+	switch {
+	case c.anyDom && c.anyDow:
+		return true
+	case c.anyDom:
+		return dow
+	case c.anyDow:
+		return dom
+	default:
+		return dom || dow
+	}
+}
+
+func parseCronField(field string, min, max int) (map[int]bool, error) {
+	values := make(map[int]bool)
+
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if rangePart, stepPart, found := strings.Cut(part, "/"); found {
+			s, err := strconv.Atoi(stepPart)
+			if err != nil || s <= 0 {
+				return nil, errInvalidCron
+			}
+			step = s
+			part = rangePart
+		}
+
+		lo, hi := min, max
+		if part != "*" {
+			loPart, hiPart, isRange := strings.Cut(part, "-")
+
+			var err error
+			if lo, err = strconv.Atoi(loPart); err != nil {
+				return nil, errInvalidCron
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(hiPart); err != nil {
+					return nil, errInvalidCron
+				}
+			} else if step > 1 {
+				hi = max
+			}
+		}
+
+		if lo < min || hi > max || lo > hi {
+			return nil, errInvalidCron
+		}
+
+		for v := lo; v <= hi; v += step {
+			values[v] = true
+		}
+	}
+
+	return values, nil
+}
@@ -3,11 +3,51 @@ package errormessages
 var (
 	ErrAlreadyPaid                = "order has been paid"
 	ErrPaymentAmountInsufficient  = "payment amount insufficient"
+	ErrInvoiceNotFound            = "invoice not found"
 	ErrPaymentNotFound            = "payment not found"
+	ErrOrderNotPaid               = "order has not been paid"
+	ErrRefundExceedsPayment       = "refund amount exceeds payment"
+	ErrInvalidOrderId             = "invalid order id"
 	ErrOrderNotFound              = "order not found"
 	ErrOrderDuplicateProduct      = "order contains duplicate product"
+	ErrOrderEmpty                 = "order must contain at least one product"
 	ErrOrderUncancelable          = "order unable to be canceled"
 	ErrInvalidOrderStatus         = "invalid order status"
+	ErrInvalidAddressId           = "invalid address id"
+	ErrAddressNotFound            = "address not found"
+	ErrOrderFromQuote             = "order placed from a quote cannot be amended"
+	ErrInvalidQuoteId             = "invalid quote id"
+	ErrQuoteNotFound              = "quote not found"
+	ErrInvalidQuoteStatus         = "invalid quote status"
+	ErrQuoteExpired               = "quote has expired"
+	ErrQuoteProductNotFound       = "quote does not contain product"
+	ErrOrderAddressRequired       = "shipping address is required"
+	ErrCartEmpty                  = "cart is empty"
+	ErrCartItemNotFound           = "cart item not found"
+	ErrCartNotFound               = "cart not found"
+	ErrCartCheckoutInvalid        = "cart contains invalid items"
+	ErrCartProductUnavailable     = "product is no longer available"
+	ErrCartPriceChanged           = "product price has changed"
+	ErrInvalidSubscriptionId      = "invalid subscription id"
+	ErrSubscriptionNotFound       = "subscription not found"
+	ErrSubscriptionStatus         = "invalid subscription status"
+	ErrInvalidSchedule            = "invalid subscription schedule"
+	ErrInvalidReturnId            = "invalid return id"
+	ErrReturnNotFound             = "return not found"
+	ErrReturnOrderNotReturnable   = "order is not eligible for return"
+	ErrReturnEmpty                = "return contains no items"
+	ErrReturnDuplicateProduct     = "return contains duplicate product"
+	ErrReturnProductNotInOrder    = "return contains product not in order"
+	ErrReturnQtyExceeded          = "return qty exceeds returnable qty"
+	ErrReturnRestockQtyExceeded   = "restock qty exceeds returned qty"
+	ErrInvalidReturnStatus        = "invalid return status"
+	ErrInvalidShipmentId          = "invalid shipment id"
+	ErrShipmentNotFound           = "shipment not found"
+	ErrShipmentAlreadyDelivered   = "shipment already delivered"
+	ErrShipmentDuplicateProduct   = "shipment contains duplicate product"
+	ErrShipmentEmpty              = "shipment contains no items"
+	ErrShipmentProductNotInOrder  = "shipment contains product not in order"
+	ErrShipmentQtyExceeded        = "shipment qty exceeds unshipped qty"
 	ErrInvalidInventoryId         = "invalid inventory id"
 	ErrInventoryNotFound          = "inventory not found"
 	ErrInventoryInvalidStock      = "invalid inventory stock"
@@ -15,10 +55,74 @@ var (
 	ErrInventoryStockUpdate       = "failed to update inventory stock"
 	ErrInvalidProductId           = "invalid product id"
 	ErrProductNotFound            = "product not found"
+	ErrInvalidBackorderPolicy     = "invalid backorder policy"
 	ErrProductNameExists          = "product name exists"
+	ErrProductSkuExists           = "product sku exists"
 	ErrInvalidCategoryId          = "invalid category id"
 	ErrCategoryNotFound           = "category not found"
 	ErrCategoryNameExists         = "category name exists"
 	ErrCategoryCodeExists         = "category code exists"
 	ErrUserNotExists              = "user not exists"
+	ErrInvalidUserId              = "invalid user id"
+	ErrUserBlocked                = "account is disabled"
+	ErrInvalidRole                = "invalid role"
+	ErrUserRoleUnchanged          = "user already has this role"
+	ErrOwnAccountChange           = "admins cannot block or demote their own account"
+	ErrInvalidUserToken           = "invalid or expired token"
+	ErrEmailAlreadyVerified       = "email already verified"
+	ErrEmailNotVerified           = "email address is not verified"
+	ErrInvalidCredentials         = "invalid username or password"
+	ErrTooManyLoginAttempts       = "too many failed login attempts, try again later"
+	ErrTooManyRequests            = "too many requests, try again later"
+	ErrRateLimitUnavailable       = "rate limiting is unavailable, try again later"
+	ErrInvalidIdempotencyKey      = "Idempotency-Key must be 1 to 128 printable characters"
+	ErrIdempotencyKeyReused       = "Idempotency-Key was already used for a different request"
+	ErrIdempotencyInProgress      = "a request with this Idempotency-Key is still in progress"
+	ErrPreconditionFailed         = "resource has changed since it was read"
+	ErrTwoFactorNotEnrolled       = "two-factor authentication is not enrolled"
+	ErrTwoFactorEnabled           = "two-factor authentication is already enabled"
+	ErrTwoFactorRequired          = "two-factor authentication is required for this account"
+	ErrInvalidTwoFactorCode       = "invalid two-factor code"
+	ErrInvalidTwoFactorToken      = "invalid or expired two-factor token"
+	ErrInvalidRefreshToken        = "invalid or expired refresh token"
+	ErrRefreshTokenReused         = "refresh token reuse detected, session revoked"
+	ErrSessionRevoked             = "session has been revoked"
+	ErrInvalidApiKeyId            = "invalid api key id"
+	ErrApiKeyNotFound             = "api key not found"
+	ErrInvalidApiKey              = "invalid, expired or revoked api key"
+	ErrApiKeyIPNotAllowed         = "api key is not allowed from this address"
+	ErrInvalidIPRule              = "invalid ip address or range"
+	ErrApiKeyExpiryPassed         = "api key expiry must be in the future"
+	ErrPermissionNotGranted       = "owner's role does not grant permission"
+	ErrPermissionNotHeld          = "you do not hold permission"
+	ErrApiKeyForOtherUser         = "creating api keys for other users requires roles:write"
+	ErrOIDCProviderNotFound       = "unknown identity provider"
+	ErrOIDCInvalidState           = "invalid or expired login state"
+	ErrOIDCLoginFailed            = "identity provider login failed"
+	ErrOIDCEmailRequired          = "identity provider did not share an email"
+	ErrOIDCEmailInUse             = "email belongs to an account that is not linked"
+	ErrOIDCSignupDisabled         = "no account is linked to this identity"
+	ErrOIDCNoRole                 = "none of your groups grant access"
+	ErrInvalidRoleId              = "invalid role id"
+	ErrRoleNotFound               = "role not found"
+	ErrRoleNameExists             = "role name exists"
+	ErrRoleBuiltIn                = "built-in role cannot be changed"
+	ErrRoleInUse                  = "role is assigned to users"
+	ErrInvalidPermission          = "invalid permission"
+	ErrInvalidImportJobId         = "invalid import job id"
+	ErrImportJobNotFound          = "import job not found"
+	ErrImportResultNotReady       = "import result is not ready"
+	ErrImportFileFormat           = "import file must be .csv or .jsonl"
+	ErrImportFileEmpty            = "import file is empty"
+	ErrImportMissingColumn        = "import file is missing column"
+	ErrImportCustomerRequired     = "customer email is required"
+	ErrImportInvalidItem          = "product id and qty are required"
+	ErrImportOrderMismatch        = "row disagrees with the rest of its order"
+	ErrImportOrderInvalidRows     = "order has invalid rows"
+	ErrImportInvalidRows          = "import file has invalid rows"
+	ErrImportDuplicateKey         = "row repeats an earlier row's key"
+	ErrImportNameRequired         = "product name is required"
+	ErrImportProductKeyRequired   = "product name or sku is required"
+	ErrImportInvalidPrice         = "price must be a positive whole number"
+	ErrImportLocationRequired     = "location is required"
 )
@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ETag builds a strong entity tag from a record's version, e.g. "3". A
+// representation that also shows data kept outside the record passes that
+// too, e.g. "3-12".
+func ETag(version uint, more ...uint) string {
+	tag := strconv.FormatUint(uint64(version), 10)
+	for _, part := range more {
+		tag += "-" + strconv.FormatUint(uint64(part), 10)
+	}
+	return `"` + tag + `"`
+}
+
+// ETagMatches reports whether an If-Match or If-None-Match header lists
+// etag or is "*". With weak set a W/ prefix is ignored, as If-None-Match
+// requires; If-Match needs an exact match.
+func ETagMatches(header, etag string, weak bool) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if weak {
+			tag = strings.TrimPrefix(tag, "W/")
+		}
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// NotModified sets the ETag header and answers 304 Not Modified when the
+// client's If-None-Match already holds that version. It reports whether the
+// response was written.
+func NotModified(ctx *gin.Context, etag string) bool {
+	ctx.Header("ETag", etag)
+	if ETagMatches(ctx.GetHeader("If-None-Match"), etag, true) {
+		ctx.Status(304)
+		return true
+	}
+	return false
+}
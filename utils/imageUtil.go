@@ -1,7 +1,9 @@
 package utils
 
 import (
+	"io"
 	"mime/multipart"
+	"os"
 	"path/filepath"
 	"strings"
 )
@@ -16,3 +18,21 @@ func IsValidExtension(image *multipart.FileHeader) bool {
 	ext := strings.ToLower(filepath.Ext(image.Filename))
 	return validExtension[ext]
 }
+
+// SaveUploadedFile copies an uploaded multipart file to dst.
+func SaveUploadedFile(file *multipart.FileHeader, dst string) error {
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, src)
+	return err
+}
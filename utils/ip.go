@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"net"
+	"strings"
+)
+
+// ValidIPRule reports whether rule is an IP address or a CIDR range.
+func ValidIPRule(rule string) bool {
+	if net.ParseIP(rule) != nil {
+		return true
+	}
+	_, _, err := net.ParseCIDR(rule)
+	return err == nil
+}
+
+// IPAllowed reports whether ip matches one of the comma-separated rules.
+// An empty rule list allows every address.
+func IPAllowed(ip string, rules string) bool {
+	if strings.TrimSpace(rules) == "" {
+		return true
+	}
+
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+
+	for _, rule := range strings.Split(rules, ",") {
+		rule = strings.TrimSpace(rule)
+		if _, network, err := net.ParseCIDR(rule); err == nil {
+			if network.Contains(addr) {
+				return true
+			}
+			continue
+		}
+		if allowed := net.ParseIP(rule); allowed != nil && allowed.Equal(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,8 @@
+package status
+
+const (
+	IMPORT_PENDING   uint = 1
+	IMPORT_RUNNING   uint = 2
+	IMPORT_COMPLETED uint = 3
+	IMPORT_FAILED    uint = 4
+)
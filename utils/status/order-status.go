@@ -1,8 +1,11 @@
 package status
 
 const (
-	PENDING    uint = 1
-	PAID       uint = 2
-	PROCESSING uint = 3
-	DONE       uint = 4
+	PENDING           uint = 1
+	PAID              uint = 2
+	PROCESSING        uint = 3
+	DONE              uint = 4
+	PARTIALLY_SHIPPED uint = 5
+	SHIPPED           uint = 6
+	DELIVERED         uint = 7
 )
@@ -0,0 +1,6 @@
+package status
+
+const (
+	PAYMENT_PAID     uint = 2
+	PAYMENT_REFUNDED uint = 3
+)
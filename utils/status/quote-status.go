@@ -0,0 +1,8 @@
+package status
+
+const (
+	QUOTE_DRAFT    uint = 1
+	QUOTE_SENT     uint = 2
+	QUOTE_ACCEPTED uint = 3
+	QUOTE_EXPIRED  uint = 4
+)
@@ -0,0 +1,10 @@
+package status
+
+const (
+	RETURN_REQUESTED uint = 1
+	RETURN_APPROVED  uint = 2
+	RETURN_REJECTED  uint = 3
+	RETURN_RECEIVED  uint = 4
+	RETURN_INSPECTED uint = 5
+	RETURN_REFUNDED  uint = 6
+)
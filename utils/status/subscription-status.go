@@ -0,0 +1,7 @@
+package status
+
+const (
+	SUBSCRIPTION_ACTIVE    uint = 1
+	SUBSCRIPTION_PAUSED    uint = 2
+	SUBSCRIPTION_CANCELLED uint = 3
+)
@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	TableCsv  = "csv"
+	TableXlsx = "xlsx"
+)
+
+// TableWriter writes rows of a CSV or XLSX export.
+type TableWriter interface {
+	WriteRow(cells []string) error
+	Close() error
+}
+
+// TableFormat picks the table format from a file name's extension.
+func TableFormat(fileName string) (string, error) {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".csv":
+		return TableCsv, nil
+	case ".xlsx":
+		return TableXlsx, nil
+	}
+	return "", errors.New("file must be .csv or .xlsx")
+}
+
+func ReadTable(format string, content []byte) ([][]string, error) {
+	if format == TableXlsx {
+		return ReadXlsx(content)
+	}
+
+	r := csv.NewReader(bytes.NewReader(content))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	return r.ReadAll()
+}
+
+func NewTableWriter(format string, w io.Writer, sheetName string) (TableWriter, error) {
+	if format == TableXlsx {
+		return NewXlsxWriter(w, sheetName)
+	}
+	return &csvTableWriter{csv.NewWriter(w)}, nil
+}
+
+type csvTableWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvTableWriter) WriteRow(cells []string) error {
+	return c.w.Write(cells)
+}
+
+func (c *csvTableWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func TableContentType(format string) string {
+	if format == TableXlsx {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv"
+}
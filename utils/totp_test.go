@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA1 seed from RFC 6238 appendix B.
+var rfc6238Secret = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPCodeRFC6238Vectors(t *testing.T) {
+	// The RFC lists 8-digit codes; a 6-digit code is their last six digits.
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		code, err := TOTPCode(rfc6238Secret, TOTPStep(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("TOTPCode at %d: %v", tt.unix, err)
+		}
+		if code != tt.code {
+			t.Errorf("TOTPCode at %d = %s, want %s", tt.unix, code, tt.code)
+		}
+	}
+}
+
+func TestTOTPCodeLowercaseSecret(t *testing.T) {
+	code, err := TOTPCode(strings.ToLower(rfc6238Secret), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != "287082" {
+		t.Errorf("TOTPCode = %s, want 287082", code)
+	}
+}
+
+func TestTOTPCodeInvalidSecret(t *testing.T) {
+	if _, err := TOTPCode("not base32!", 1); err == nil {
+		t.Error("TOTPCode accepted an invalid secret")
+	}
+}
+
+func TestVerifyTOTP(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := TOTPStep(now)
+
+	tests := []struct {
+		name  string
+		step  int64
+		valid bool
+	}{
+		{"current step", current, true},
+		{"previous step", current - 1, true},
+		{"next step", current + 1, true},
+		{"two steps behind", current - 2, false},
+		{"two steps ahead", current + 2, false},
+	}
+
+	for _, tt := range tests {
+		code, err := TOTPCode(rfc6238Secret, tt.step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		step, ok := VerifyTOTP(rfc6238Secret, code, now)
+		if ok != tt.valid {
+			t.Errorf("%s: VerifyTOTP = %v, want %v", tt.name, ok, tt.valid)
+		}
+		if ok && step != tt.step {
+			t.Errorf("%s: VerifyTOTP step = %d, want %d", tt.name, step, tt.step)
+		}
+	}
+}
+
+func TestVerifyTOTPFormatting(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+
+	if _, ok := VerifyTOTP(rfc6238Secret, " 050 471 ", now); !ok {
+		t.Error("VerifyTOTP rejected a code with spaces")
+	}
+	for _, code := range []string{"", "05047", "0504711", "50471"} {
+		if _, ok := VerifyTOTP(rfc6238Secret, code, now); ok {
+			t.Errorf("VerifyTOTP accepted %q", code)
+		}
+	}
+	if _, ok := VerifyTOTP("not base32!", "050471", now); ok {
+		t.Error("VerifyTOTP accepted an invalid secret")
+	}
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := TOTPProvisioningURI("Go Trades", "user@example.com", "ABC")
+	want := "otpauth://totp/Go%20Trades:user@example.com?algorithm=SHA1&digits=6&issuer=Go+Trades&period=30&secret=ABC"
+	if uri != want {
+		t.Errorf("TOTPProvisioningURI = %s, want %s", uri, want)
+	}
+}
@@ -0,0 +1,277 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const xlsxMainNamespace = "http://schemas.openxmlformats.org/spreadsheetml/2006/main"
+
+// Limits on what ReadXlsx unpacks, so a small upload cannot expand into a
+// zip bomb or a sparse sheet that allocates millions of empty cells.
+const (
+	maxXlsxPartSize = 50 << 20 // 50MB
+	maxXlsxCells    = 1_000_000
+)
+
+var errXlsxTooLarge = errors.New("xlsx file is too large")
+
+var xlsxStaticParts = []struct{ name, body string }{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`},
+}
+
+// XlsxWriter streams a single-sheet workbook. Rows go straight to the
+// underlying writer, so exports never hold the whole sheet in memory.
+// Every cell is written as an inline string.
+type XlsxWriter struct {
+	zip   *zip.Writer
+	sheet io.Writer
+	row   int
+}
+
+func NewXlsxWriter(w io.Writer, sheetName string) (*XlsxWriter, error) {
+	zw := zip.NewWriter(w)
+
+	for _, part := range xlsxStaticParts {
+		f, err := zw.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, part.body); err != nil {
+			return nil, err
+		}
+	}
+
+	f, err := zw.Create("xl/workbook.xml")
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(f, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="%s" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets></workbook>`, xlsxMainNamespace, escapeXml(sheetName))
+
+	sheet, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fmt.Fprintf(sheet, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="%s"><sheetData>`, xlsxMainNamespace); err != nil {
+		return nil, err
+	}
+
+	return &XlsxWriter{zip: zw, sheet: sheet}, nil
+}
+
+func (x *XlsxWriter) WriteRow(cells []string) error {
+	x.row++
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<row r="%d">`, x.row)
+	for i, cell := range cells {
+		fmt.Fprintf(&b, `<c r="%s%d" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, xlsxColumnName(i), x.row, escapeXml(cell))
+	}
+	b.WriteString("</row>")
+
+	_, err := io.WriteString(x.sheet, b.String())
+	return err
+}
+
+func (x *XlsxWriter) Close() error {
+	if _, err := io.WriteString(x.sheet, "</sheetData></worksheet>"); err != nil {
+		return err
+	}
+	return x.zip.Close()
+}
+
+type xlsxText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.T
+	}
+	var b strings.Builder
+	for _, run := range t.Runs {
+		b.WriteString(run.T)
+	}
+	return b.String()
+}
+
+type xlsxSheet struct {
+	Rows []struct {
+		R     int `xml:"r,attr"`
+		Cells []struct {
+			R  string    `xml:"r,attr"`
+			T  string    `xml:"t,attr"`
+			V  string    `xml:"v"`
+			Is *xlsxText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// ReadXlsx returns the cell values of the first worksheet, one slice per
+// row. Shared, inline and plain cells are all read as their text value.
+func ReadXlsx(content []byte) ([][]string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, errors.New("invalid xlsx file")
+	}
+
+	files := make(map[string]*zip.File)
+	var sheets []string
+	for _, f := range zr.File {
+		files[f.Name] = f
+		if strings.HasPrefix(f.Name, "xl/worksheets/") && strings.HasSuffix(f.Name, ".xml") {
+			sheets = append(sheets, f.Name)
+		}
+	}
+	if len(sheets) == 0 {
+		return nil, errors.New("xlsx file has no worksheet")
+	}
+	sort.Strings(sheets)
+	sheetName := sheets[0]
+	if _, ok := files["xl/worksheets/sheet1.xml"]; ok {
+		sheetName = "xl/worksheets/sheet1.xml"
+	}
+
+	var shared []string
+	if f, ok := files["xl/sharedStrings.xml"]; ok {
+		var sst struct {
+			Items []xlsxText `xml:"si"`
+		}
+		if err := decodeXlsxPart(f, &sst); err != nil {
+			return nil, err
+		}
+		for _, item := range sst.Items {
+			shared = append(shared, item.String())
+		}
+	}
+
+	var sheet xlsxSheet
+	if err := decodeXlsxPart(files[sheetName], &sheet); err != nil {
+		return nil, err
+	}
+
+	var rows [][]string
+	allocated := 0
+	for _, row := range sheet.Rows {
+		rowNumber := row.R
+		if rowNumber == 0 {
+			rowNumber = len(rows) + 1
+		}
+		if rowNumber < 0 {
+			return nil, errors.New("invalid xlsx file")
+		}
+		if grow := rowNumber - len(rows); grow > 0 {
+			if grow > maxXlsxCells-allocated {
+				return nil, errXlsxTooLarge
+			}
+			allocated += grow
+		}
+		for len(rows) < rowNumber {
+			rows = append(rows, nil)
+		}
+
+		var cells []string
+		for i, c := range row.Cells {
+			col := i
+			if c.R != "" {
+				col = xlsxColumnIndex(c.R)
+			}
+			if col < 0 {
+				return nil, errors.New("invalid xlsx cell reference")
+			}
+			if grow := col + 1 - len(cells); grow > 0 {
+				if grow > maxXlsxCells-allocated {
+					return nil, errXlsxTooLarge
+				}
+				allocated += grow
+			}
+			for len(cells) <= col {
+				cells = append(cells, "")
+			}
+
+			switch {
+			case c.T == "s":
+				idx, err := strconv.Atoi(c.V)
+				if err != nil || idx < 0 || idx >= len(shared) {
+					return nil, errors.New("invalid xlsx shared string")
+				}
+				cells[col] = shared[idx]
+			case c.T == "inlineStr" && c.Is != nil:
+				cells[col] = c.Is.String()
+			default:
+				cells[col] = c.V
+			}
+		}
+		rows[rowNumber-1] = cells
+	}
+
+	return rows, nil
+}
+
+// decodeXlsxPart refuses parts that unpack to more than maxXlsxPartSize.
+// The size in the zip header can lie, so the read itself is capped too.
+func decodeXlsxPart(f *zip.File, v interface{}) error {
+	if f.UncompressedSize64 > maxXlsxPartSize {
+		return errXlsxTooLarge
+	}
+
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	limited := &io.LimitedReader{R: r, N: maxXlsxPartSize + 1}
+	err = xml.NewDecoder(limited).Decode(v)
+	if limited.N <= 0 {
+		return errXlsxTooLarge
+	}
+	if err != nil {
+		return errors.New("invalid xlsx file")
+	}
+	return nil
+}
+
+func xlsxColumnName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+// xlsxColumnIndex turns a cell reference such as "AB12" into a 0-based
+// column index.
+func xlsxColumnIndex(ref string) int {
+	col := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		col = col*26 + int(r-'A'+1)
+	}
+	return col - 1
+}
+
+func escapeXml(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
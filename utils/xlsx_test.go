@@ -0,0 +1,156 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// buildXlsx zips the given parts into an xlsx file.
+func buildXlsx(t *testing.T, parts map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, body := range parts {
+		f, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func xlsxWorksheet(rows string) string {
+	return `<?xml version="1.0" encoding="UTF-8"?><worksheet xmlns="` + xlsxMainNamespace + `"><sheetData>` + rows + `</sheetData></worksheet>`
+}
+
+func TestReadXlsxWriterRoundTrip(t *testing.T) {
+	want := [][]string{
+		{"sku", "name", "price"},
+		{"A-1", "Bolts & <nuts>", "12.50"},
+		{"A-2", "  padded  ", ""},
+	}
+
+	var buf bytes.Buffer
+	w, err := NewXlsxWriter(&buf, "Products")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range want {
+		if err := w.WriteRow(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := ReadXlsx(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("ReadXlsx = %q, want %q", rows, want)
+	}
+}
+
+func TestReadXlsxCellTypes(t *testing.T) {
+	content := buildXlsx(t, map[string]string{
+		"xl/sharedStrings.xml": `<sst xmlns="` + xlsxMainNamespace + `"><si><t>shared</t></si><si><r><t>rich </t></r><r><t>text</t></r></si></sst>`,
+		"xl/worksheets/sheet1.xml": xlsxWorksheet(
+			`<row r="1"><c r="A1" t="s"><v>0</v></c><c r="C1" t="s"><v>1</v></c></row>` +
+				`<row r="3"><c r="B3" t="inlineStr"><is><t>inline</t></is></c><c r="C3"><v>42</v></c></row>`),
+	})
+
+	rows, err := ReadXlsx(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"shared", "", "rich text"},
+		nil,
+		{"", "inline", "42"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("ReadXlsx = %q, want %q", rows, want)
+	}
+}
+
+func TestReadXlsxPicksFirstSheet(t *testing.T) {
+	content := buildXlsx(t, map[string]string{
+		"xl/worksheets/sheet2.xml": xlsxWorksheet(`<row><c><v>second</v></c></row>`),
+		"xl/worksheets/sheet1.xml": xlsxWorksheet(`<row><c><v>first</v></c></row>`),
+	})
+
+	rows, err := ReadXlsx(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0][0] != "first" {
+		t.Errorf("ReadXlsx = %q, want the first sheet", rows)
+	}
+}
+
+func TestReadXlsxInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+	}{
+		{"not a zip", []byte("name,price\n")},
+		{"no worksheet", buildXlsx(t, map[string]string{"xl/workbook.xml": "<workbook/>"})},
+		{"malformed sheet", buildXlsx(t, map[string]string{"xl/worksheets/sheet1.xml": "<worksheet><sheetData><row>"})},
+		{"shared string out of range", buildXlsx(t, map[string]string{
+			"xl/worksheets/sheet1.xml": xlsxWorksheet(`<row><c t="s"><v>3</v></c></row>`),
+		})},
+		{"negative row", buildXlsx(t, map[string]string{
+			"xl/worksheets/sheet1.xml": xlsxWorksheet(`<row r="-1"><c><v>x</v></c></row>`),
+		})},
+		{"bad cell reference", buildXlsx(t, map[string]string{
+			"xl/worksheets/sheet1.xml": xlsxWorksheet(`<row><c r="1A"><v>x</v></c></row>`),
+		})},
+	}
+
+	for _, tt := range tests {
+		if _, err := ReadXlsx(tt.content); err == nil {
+			t.Errorf("%s: ReadXlsx returned no error", tt.name)
+		}
+	}
+}
+
+func TestReadXlsxTooLarge(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+	}{
+		{"distant row", buildXlsx(t, map[string]string{
+			"xl/worksheets/sheet1.xml": xlsxWorksheet(`<row r="2000000"><c><v>x</v></c></row>`),
+		})},
+		{"distant column", buildXlsx(t, map[string]string{
+			"xl/worksheets/sheet1.xml": xlsxWorksheet(`<row r="1"><c r="ZZZZZ1"><v>x</v></c></row>`),
+		})},
+		{"many sparse rows", buildXlsx(t, map[string]string{
+			"xl/worksheets/sheet1.xml": xlsxWorksheet(
+				`<row r="1"><c r="XFD1"><v>x</v></c></row>` + strings.Repeat(`<row><c r="XFD1"><v>x</v></c></row>`, 100)),
+		})},
+		{"oversized part", buildXlsx(t, map[string]string{
+			"xl/worksheets/sheet1.xml": xlsxWorksheet(strings.Repeat(" ", maxXlsxPartSize)),
+		})},
+	}
+
+	for _, tt := range tests {
+		_, err := ReadXlsx(tt.content)
+		if !errors.Is(err, errXlsxTooLarge) {
+			t.Errorf("%s: ReadXlsx error = %v, want %v", tt.name, err, errXlsxTooLarge)
+		}
+	}
+}